@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+)
+
+// APIKeyPrefix is the prefix of all API keys, it distinguishes API keys from JWTs.
+const APIKeyPrefix = "groshi_"
+
+// apiKeySize is the count of random bytes an API key consists of.
+const apiKeySize = 32
+
+// apiKeyDisplayLength is the length of the beginning of an API key which is stored as is to help users recognize keys.
+const apiKeyDisplayLength = len(APIKeyPrefix) + 6
+
+// Scopes of API keys. Each scope grants access to a group of routes, requests authenticated with JWTs have all scopes.
+const (
+	ScopeCategoriesRead    = "categories:read"
+	ScopeCategoriesWrite   = "categories:write"
+	ScopeTagsRead          = "tags:read"
+	ScopeTagsWrite         = "tags:write"
+	ScopePayeesRead        = "payees:read"
+	ScopeRulesRead         = "rules:read"
+	ScopeRulesWrite        = "rules:write"
+	ScopeTransactionsRead  = "transactions:read"
+	ScopeTransactionsWrite = "transactions:write"
+	ScopeStatsRead         = "stats:read"
+	ScopeLedgersRead       = "ledgers:read"
+)
+
+// Scopes contains all scopes of API keys.
+var Scopes = []string{
+	ScopeCategoriesRead,
+	ScopeCategoriesWrite,
+	ScopeTagsRead,
+	ScopeTagsWrite,
+	ScopePayeesRead,
+	ScopeRulesRead,
+	ScopeRulesWrite,
+	ScopeTransactionsRead,
+	ScopeTransactionsWrite,
+	ScopeStatsRead,
+	ScopeLedgersRead,
+}
+
+// ValidScope returns true if the scope is one of [Scopes].
+func ValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// NewAPIKey generates a new random API key.
+func NewAPIKey() (string, error) {
+	bytes := make([]byte, apiKeySize)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return APIKeyPrefix + base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// IsAPIKey returns true if the token is an API key rather than a JWT.
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// HashAPIKey returns hash of an API key which is stored instead of the key itself.
+func HashAPIKey(key string) string {
+	return hashToken(key)
+}
+
+// APIKeyDisplayPrefix returns the beginning of an API key which is shown to the user to help recognize the key.
+func APIKeyDisplayPrefix(key string) string {
+	if len(key) < apiKeyDisplayLength {
+		return key
+	}
+	return key[:apiKeyDisplayLength]
+}
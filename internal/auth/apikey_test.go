@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	key, err := NewAPIKey()
+	if assert.NoError(t, err) {
+		assert.True(t, IsAPIKey(key))
+		assert.Len(t, key, len(APIKeyPrefix)+43)
+		assert.Equal(t, key[:len(APIKeyPrefix)+6], APIKeyDisplayPrefix(key))
+		assert.Len(t, HashAPIKey(key), 64)
+	}
+}
+
+func TestIsAPIKey(t *testing.T) {
+	assert.True(t, IsAPIKey("groshi_abc"))
+	assert.False(t, IsAPIKey("eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.e30.c2ln"))
+}
+
+func TestValidScope(t *testing.T) {
+	assert.True(t, ValidScope(ScopeTransactionsWrite))
+	assert.True(t, ValidScope("stats:read"))
+	assert.False(t, ValidScope("stats:write"))
+	assert.False(t, ValidScope(""))
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"crypto/rand"
+	"strings"
+)
+
+// Registration modes which define who can create a new user.
+const (
+	// RegistrationOpen allows anyone to create a user, an invite code is optional.
+	RegistrationOpen = "open"
+
+	// RegistrationInviteOnly requires a valid invite code to create a user.
+	RegistrationInviteOnly = "invite-only"
+
+	// RegistrationClosed forbids creation of users through the API.
+	RegistrationClosed = "closed"
+)
+
+// inviteCodeSize is the count of random bytes an invite code consists of.
+const inviteCodeSize = 15
+
+// NewInviteCode generates a new random invite code. Invite codes are entered by people,
+// so they consist only of lowercase letters and digits.
+func NewInviteCode() (string, error) {
+	bytes := make([]byte, inviteCodeSize)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return strings.ToLower(totpEncoding.EncodeToString(bytes)), nil
+}
+
+// HashInviteCode returns hash of an invite code which is stored instead of the code itself.
+// The code is normalized first, so that codes entered in another case are accepted.
+func HashInviteCode(code string) string {
+	return hashToken(strings.ToLower(strings.TrimSpace(code)))
+}
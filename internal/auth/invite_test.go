@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewInviteCode(t *testing.T) {
+	code, err := NewInviteCode()
+	if assert.NoError(t, err) {
+		assert.Len(t, code, 24)
+		assert.Regexp(t, "^[a-z2-7]+$", code)
+	}
+}
+
+func TestHashInviteCode(t *testing.T) {
+	assert.Len(t, HashInviteCode("abcdef"), 64)
+	assert.Equal(t, HashInviteCode("abcdef"), HashInviteCode(" ABCdef\n"))
+	assert.NotEqual(t, HashInviteCode("abcdef"), HashInviteCode("abcdeg"))
+}
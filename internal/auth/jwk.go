@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"math/big"
+)
+
+// minRSAKeySize is the minimal size of RSA keys in bits.
+const minRSAKeySize = 2048
+
+var (
+	// ErrInvalidKeyPEM is returned when a key could not be decoded from PEM.
+	ErrInvalidKeyPEM = errors.New("invalid PEM-encoded key")
+
+	// ErrUnsupportedKey is returned when a key is neither an RSA nor an Ed25519 key.
+	ErrUnsupportedKey = errors.New("unsupported key type, expected RSA or Ed25519 key")
+)
+
+// JWTKey is a key which is used to sign or verify JWTs.
+type JWTKey struct {
+	// ID of the key which is put in the `kid` header of tokens, it is the RFC 7638 thumbprint of the public key.
+	ID string
+
+	// Signing method used with the key.
+	Method jwt.SigningMethod
+
+	// Private key, it is nil if the key can only be used to verify tokens.
+	private crypto.Signer
+
+	// Public key.
+	public crypto.PublicKey
+}
+
+// CanSign returns true if the key contains a private key.
+func (k *JWTKey) CanSign() bool {
+	return k.private != nil
+}
+
+// ParseJWTKeyPEM parses a PEM-encoded RSA or Ed25519 key. Private keys can be used both to sign and verify tokens,
+// public keys can only be used to verify tokens.
+func ParseJWTKeyPEM(data []byte) (*JWTKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidKeyPEM
+	}
+
+	var (
+		key any
+		err error
+	)
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: unexpected block type %q", ErrInvalidKeyPEM, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKeyPEM, err)
+	}
+
+	return newJWTKey(key)
+}
+
+// newJWTKey creates a new [JWTKey] from an RSA or Ed25519 private or public key.
+func newJWTKey(key any) (*JWTKey, error) {
+	k := &JWTKey{}
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		k.private, k.public = key, &key.PublicKey
+	case *rsa.PublicKey:
+		k.public = key
+	case ed25519.PrivateKey:
+		k.private, k.public = key, key.Public()
+	case ed25519.PublicKey:
+		k.public = key
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < minRSAKeySize {
+			return nil, fmt.Errorf("RSA key must be at least %d bits long", minRSAKeySize)
+		}
+		k.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		k.Method = jwt.SigningMethodEdDSA
+	}
+
+	id, err := k.jwk().thumbprint()
+	if err != nil {
+		return nil, err
+	}
+	k.ID = id
+	return k, nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+
+	// Parameters of RSA keys.
+	Modulus  string `json:"n,omitempty"`
+	Exponent string `json:"e,omitempty"`
+
+	// Parameters of Ed25519 keys.
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKSet is a set of public keys in JSON Web Key format which is published so that other services can verify tokens.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// jwk returns the public key in JSON Web Key format.
+func (k *JWTKey) jwk() JWK {
+	jwk := JWK{KeyID: k.ID, Use: "sig"}
+	if k.Method != nil {
+		jwk.Algorithm = k.Method.Alg()
+	}
+	switch public := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.Modulus = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.Exponent = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+// thumbprint returns the RFC 7638 thumbprint of the key: SHA-256 hash of the required members of the key
+// in lexicographic order.
+func (j JWK) thumbprint() (string, error) {
+	var members any
+	if j.KeyType == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{j.Exponent, j.KeyType, j.Modulus}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{j.Curve, j.KeyType, j.X}
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
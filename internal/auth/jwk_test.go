@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// encodePEM encodes DER-encoded key to PEM.
+func encodePEM(blockType string, der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
+
+func TestParseJWTKeyPEM(t *testing.T) {
+	t.Run("parse RSA keys", func(t *testing.T) {
+		private, err := rsa.GenerateKey(rand.Reader, 2048)
+		if !assert.NoError(t, err) {
+			return
+		}
+		privateDER, _ := x509.MarshalPKCS8PrivateKey(private)
+		publicDER, _ := x509.MarshalPKIXPublicKey(&private.PublicKey)
+
+		privateKey, err := ParseJWTKeyPEM(encodePEM("PRIVATE KEY", privateDER))
+		if assert.NoError(t, err) {
+			assert.True(t, privateKey.CanSign())
+			assert.Equal(t, jwt.SigningMethodRS256, privateKey.Method)
+		}
+		pkcs1Key, err := ParseJWTKeyPEM(encodePEM("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(private)))
+		if assert.NoError(t, err) {
+			assert.Equal(t, privateKey.ID, pkcs1Key.ID)
+		}
+		publicKey, err := ParseJWTKeyPEM(encodePEM("PUBLIC KEY", publicDER))
+		if assert.NoError(t, err) {
+			assert.False(t, publicKey.CanSign())
+			assert.Equal(t, privateKey.ID, publicKey.ID)
+		}
+
+		jwk := privateKey.jwk()
+		assert.Equal(t, "RSA", jwk.KeyType)
+		assert.Equal(t, "RS256", jwk.Algorithm)
+		assert.Equal(t, "AQAB", jwk.Exponent)
+	})
+
+	t.Run("parse Ed25519 keys", func(t *testing.T) {
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if !assert.NoError(t, err) {
+			return
+		}
+		privateDER, _ := x509.MarshalPKCS8PrivateKey(private)
+		publicDER, _ := x509.MarshalPKIXPublicKey(public)
+
+		privateKey, err := ParseJWTKeyPEM(encodePEM("PRIVATE KEY", privateDER))
+		if assert.NoError(t, err) {
+			assert.True(t, privateKey.CanSign())
+			assert.Equal(t, jwt.SigningMethodEdDSA, privateKey.Method)
+		}
+		publicKey, err := ParseJWTKeyPEM(encodePEM("PUBLIC KEY", publicDER))
+		if assert.NoError(t, err) {
+			assert.Equal(t, privateKey.ID, publicKey.ID)
+		}
+
+		jwk := publicKey.jwk()
+		assert.Equal(t, "OKP", jwk.KeyType)
+		assert.Equal(t, "Ed25519", jwk.Curve)
+		assert.Equal(t, "EdDSA", jwk.Algorithm)
+	})
+
+	t.Run("parse short RSA key", func(t *testing.T) {
+		private, err := rsa.GenerateKey(rand.Reader, 1024)
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, err = ParseJWTKeyPEM(encodePEM("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(private)))
+		assert.Error(t, err)
+	})
+
+	t.Run("parse invalid PEM", func(t *testing.T) {
+		_, err := ParseJWTKeyPEM([]byte("not a key"))
+		assert.ErrorIs(t, err, ErrInvalidKeyPEM)
+	})
+}
+
+func TestJWK_Thumbprint(t *testing.T) {
+	// example from RFC 7638, section 3.1:
+	jwk := JWK{
+		KeyType:  "RSA",
+		Modulus:  "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		Exponent: "AQAB",
+	}
+	thumbprint, err := jwk.thumbprint()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", thumbprint)
+	}
+}
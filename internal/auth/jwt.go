@@ -1,54 +1,285 @@
 package auth
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"github.com/golang-jwt/jwt/v4"
 	"time"
 )
 
-// JWTClaimUsername is claims key which holds user's username.
-var JWTClaimUsername = "username"
+// ChallengeTokenTTL is duration of a challenge token validity.
+const ChallengeTokenTTL = 5 * time.Minute
 
-// JWTSigningMethod is a signing method used to sign JWT claims.
+// Types of tokens which are put in the `typ` header, so that a token of one type can not be used as a token of another.
+const (
+	accessTokenType    = "at+jwt"
+	challengeTokenType = "2fa+jwt"
+	oidcStateTokenType = "oidc-state+jwt"
+)
+
+// Labels of keys derived from the secret of [DefaultJWTAuthenticator].
+const (
+	internalKeyLabel   = "groshi internal token signing key"
+	encryptionKeyLabel = "groshi token claims encryption key"
+)
+
+// ErrTokenPurpose is returned when a token is used for another purpose than it was issued for,
+// for example, when a challenge token is used as an access token.
+var ErrTokenPurpose = errors.New("token is issued for another purpose")
+
+// JWTClaimSubject is claims key which holds UUID of the user, it does not change when the user changes username.
+var JWTClaimSubject = "sub"
+
+// JWTClaimTokenID is claims key which holds unique ID of the token, it is used to revoke the token.
+var JWTClaimTokenID = "jti"
+
+// JWTClaimSessionID is claims key which holds UUID of the session the token belongs to.
+var JWTClaimSessionID = "sid"
+
+// JWTClaimVersion is claims key which holds version of the user's tokens the token was issued with.
+var JWTClaimVersion = "ver"
+
+// JWTClaimDeviceName is claims key which holds device name provided on login.
+var JWTClaimDeviceName = "device"
+
+// JWTSigningMethod is a signing method used to sign JWT claims with a shared secret key.
 var JWTSigningMethod = jwt.SigningMethodHS256
 
+// jwtHeaderKeyID is the header which holds ID of the key a token is signed with.
+const jwtHeaderKeyID = "kid"
+
+// jwtHeaderType is the header which holds type of a token.
+const jwtHeaderType = "typ"
+
+// internalSigningMethod is a signing method used to sign tokens which are verified only by groshi itself.
+var internalSigningMethod = jwt.SigningMethodHS256
+
+// TokenClaims represents custom claims of a JWT.
+type TokenClaims struct {
+	// UUID of the user the token is issued to.
+	UserID string
+
+	// Unique ID of the token, it is used to revoke the token.
+	TokenID string
+
+	// UUID of the session the token belongs to.
+	SessionID string
+
+	// Version of the user's tokens which is increased when the user's password changes.
+	Version int
+}
+
+// ChallengeClaims represents custom claims of a challenge token.
+type ChallengeClaims struct {
+	// UUID of the user the token is issued to.
+	UserID string
+
+	// Unique ID of the token, it is recorded when the challenge is completed, so that the token can not be used twice.
+	TokenID string
+
+	// Version of the user's tokens the challenge was issued with, the challenge can not be completed after it changes.
+	Version int
+
+	// Device name provided on login.
+	DeviceName string
+}
+
 // JWTAuthenticator is an interface for a JWT authenticator: it can create and verify tokens.
 type JWTAuthenticator interface {
-	// CreateToken generates a new JWT and returns its string representation and expiration timestamp.
+	// CreateToken generates a new JWT with the given claims and returns its string representation and expiration timestamp.
 	// todo: should `expires` be returned and is it necessary for a user?
-	CreateToken(username string) (token string, expires time.Time, err error)
+	CreateToken(claims *TokenClaims) (token string, expires time.Time, err error)
 
 	// VerifyToken verifies that JWT token is valid and not expired, returns claims it contains.
 	VerifyToken(token string) (jwt.MapClaims, error)
+
+	// CreateChallengeToken generates a new short-lived token which proves that the user has entered the correct password
+	// and is exchanged for an access token after the second factor is verified.
+	CreateChallengeToken(claims *ChallengeClaims) (token string, expires time.Time, err error)
+
+	// VerifyChallengeToken verifies that challenge token is valid and not expired, returns claims it contains.
+	VerifyChallengeToken(token string) (*ChallengeClaims, error)
+
+	// CreateOIDCStateToken generates a new short-lived token which keeps the state of an OIDC login
+	// between its start and the callback.
+	CreateOIDCStateToken(state *OIDCState) (token string, expires time.Time, err error)
+
+	// VerifyOIDCStateToken verifies that OIDC state token is valid, not expired and contains the given state,
+	// returns the OIDC login state it contains.
+	VerifyOIDCStateToken(token string, state string) (*OIDCState, error)
+
+	// JWKS returns public keys which can be used to verify tokens. It is empty if tokens are signed with a shared secret key.
+	JWKS() *JWKSet
+}
+
+// verificationKey is a key which is used to verify tokens signed using the signing method.
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    any
 }
 
 // DefaultJWTAuthenticator is the default JWT authenticator.
 type DefaultJWTAuthenticator struct {
-	// secret key which is used to sign token claims.
-	secretKey []byte
+	// signing method and key which are used to sign token claims.
+	signingMethod jwt.SigningMethod
+	signingKey    any
+
+	// ID of the signing key which is put in the `kid` header of tokens, it is empty for a shared secret key.
+	signingKeyID string
+
+	// keys which are used to verify tokens by their IDs.
+	verificationKeys map[string]verificationKey
+
+	// public keys which are published.
+	jwks *JWKSet
+
+	// key which signs challenge and OIDC state tokens. It is never published, so that these tokens
+	// can not be mistaken for access tokens by other services which verify tokens using the published keys.
+	internalKey []byte
+
+	// key which encrypts secret claims of tokens kept by clients.
+	encryptionKey []byte
 
 	// duration of a token validity.
 	tokenTTL time.Duration
 }
 
-// NewJWTAuthenticator creates a new instance of [DefaultJWTAuthenticator] and returns pointer to it.
+// NewJWTAuthenticator creates a new instance of [DefaultJWTAuthenticator] which signs tokens
+// with a shared secret key using [JWTSigningMethod] and returns pointer to it.
 func NewJWTAuthenticator(secretKey string, tokenTTL time.Duration) *DefaultJWTAuthenticator {
+	key := []byte(secretKey)
 	return &DefaultJWTAuthenticator{
-		secretKey: []byte(secretKey),
-		tokenTTL:  tokenTTL,
+		signingMethod:    JWTSigningMethod,
+		signingKey:       key,
+		verificationKeys: map[string]verificationKey{"": {method: JWTSigningMethod, key: key}},
+		jwks:             &JWKSet{Keys: []JWK{}},
+		internalKey:      deriveKey(key, internalKeyLabel),
+		encryptionKey:    deriveKey(key, encryptionKeyLabel),
+		tokenTTL:         tokenTTL,
 	}
 }
 
-// CreateToken generates a new JWT and returns its string representation and expiration timestamp.
-func (a *DefaultJWTAuthenticator) CreateToken(username string) (string, time.Time, error) {
+// NewAsymmetricJWTAuthenticator creates a new instance of [DefaultJWTAuthenticator] which signs tokens
+// with the private RSA or Ed25519 key and returns pointer to it. Tokens signed with the signing key
+// or any of the verification keys are accepted, so that the signing key can be rotated without invalidating issued tokens.
+// Challenge and OIDC state tokens are signed with a key derived from the private key, so they are invalidated by rotation.
+func NewAsymmetricJWTAuthenticator(signingKey *JWTKey, verificationKeys []*JWTKey, tokenTTL time.Duration) (*DefaultJWTAuthenticator, error) {
+	if !signingKey.CanSign() {
+		return nil, errors.New("signing key must be a private key")
+	}
+	secret, err := x509.MarshalPKCS8PrivateKey(signingKey.private)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &DefaultJWTAuthenticator{
+		signingMethod:    signingKey.Method,
+		signingKey:       signingKey.private,
+		signingKeyID:     signingKey.ID,
+		verificationKeys: make(map[string]verificationKey),
+		jwks:             &JWKSet{Keys: make([]JWK, 0, len(verificationKeys)+1)},
+		internalKey:      deriveKey(secret, internalKeyLabel),
+		encryptionKey:    deriveKey(secret, encryptionKeyLabel),
+		tokenTTL:         tokenTTL,
+	}
+	for _, key := range append([]*JWTKey{signingKey}, verificationKeys...) {
+		if _, ok := a.verificationKeys[key.ID]; ok {
+			continue
+		}
+		a.verificationKeys[key.ID] = verificationKey{method: key.Method, key: key.public}
+		a.jwks.Keys = append(a.jwks.Keys, key.jwk())
+	}
+	return a, nil
+}
+
+// deriveKey derives a 256-bit key with the label from the secret.
+func deriveKey(secret []byte, label string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(label))
+	return mac.Sum(nil)
+}
+
+// signToken signs the claims of an access token with the signing key and returns the token.
+func (a *DefaultJWTAuthenticator) signToken(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(a.signingMethod, claims)
+	token.Header[jwtHeaderType] = accessTokenType
+	if a.signingKeyID != "" {
+		token.Header[jwtHeaderKeyID] = a.signingKeyID
+	}
+	return token.SignedString(a.signingKey)
+}
+
+// signInternalToken signs the claims of a token of the type, which is verified only by groshi itself,
+// with the internal key and returns the token.
+func (a *DefaultJWTAuthenticator) signInternalToken(tokenType string, claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(internalSigningMethod, claims)
+	token.Header[jwtHeaderType] = tokenType
+	return token.SignedString(a.internalKey)
+}
+
+// encryptClaim encrypts value of a secret claim with the encryption key using AES-GCM.
+func (a *DefaultJWTAuthenticator) encryptClaim(value string) (string, error) {
+	aead, err := a.claimCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(value), nil)), nil
+}
+
+// decryptClaim decrypts value of a secret claim encrypted by [DefaultJWTAuthenticator.encryptClaim].
+func (a *DefaultJWTAuthenticator) decryptClaim(value string) (string, error) {
+	aead, err := a.claimCipher()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", jwt.ErrTokenMalformed
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", jwt.ErrTokenMalformed
+	}
+	return string(plaintext), nil
+}
+
+// claimCipher returns AES-GCM cipher with the encryption key.
+func (a *DefaultJWTAuthenticator) claimCipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(a.encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// JWKS returns public keys which can be used to verify tokens.
+func (a *DefaultJWTAuthenticator) JWKS() *JWKSet {
+	return a.jwks
+}
+
+// CreateToken generates a new JWT with the given claims and returns its string representation and expiration timestamp.
+func (a *DefaultJWTAuthenticator) CreateToken(claims *TokenClaims) (string, time.Time, error) {
 	issued := time.Now()
 	expires := time.Now().Add(a.tokenTTL)
-	token := jwt.NewWithClaims(JWTSigningMethod, jwt.MapClaims{
-		JWTClaimUsername: username,
-		"exp":            expires.Unix(),
-		"iat":            issued.Unix(),
+	tokenString, err := a.signToken(jwt.MapClaims{
+		JWTClaimSubject:   claims.UserID,
+		JWTClaimTokenID:   claims.TokenID,
+		JWTClaimSessionID: claims.SessionID,
+		JWTClaimVersion:   claims.Version,
+		"exp":             expires.Unix(),
+		"iat":             issued.Unix(),
 	})
-
-	tokenString, err := token.SignedString(a.secretKey)
 	if err != nil {
 		return "", expires, err
 	}
@@ -57,10 +288,73 @@ func (a *DefaultJWTAuthenticator) CreateToken(username string) (string, time.Tim
 }
 
 // VerifyToken verifies that JWT token is valid and not expired, returns claims it contains.
+// Tokens of other types, such as challenge tokens, are rejected.
 func (a *DefaultJWTAuthenticator) VerifyToken(tokenString string) (jwt.MapClaims, error) {
+	return a.parseToken(tokenString, accessTokenType)
+}
+
+// CreateChallengeToken generates a new short-lived token which proves that the user has entered the correct password.
+func (a *DefaultJWTAuthenticator) CreateChallengeToken(claims *ChallengeClaims) (string, time.Time, error) {
+	issued := time.Now()
+	expires := issued.Add(ChallengeTokenTTL)
+	tokenString, err := a.signInternalToken(challengeTokenType, jwt.MapClaims{
+		JWTClaimSubject:    claims.UserID,
+		JWTClaimTokenID:    claims.TokenID,
+		JWTClaimVersion:    claims.Version,
+		JWTClaimDeviceName: claims.DeviceName,
+		"exp":              expires.Unix(),
+		"iat":              issued.Unix(),
+	})
+	if err != nil {
+		return "", expires, err
+	}
+
+	return tokenString, expires, nil
+}
+
+// VerifyChallengeToken verifies that challenge token is valid and not expired, returns claims it contains.
+func (a *DefaultJWTAuthenticator) VerifyChallengeToken(tokenString string) (*ChallengeClaims, error) {
+	claims, err := a.parseToken(tokenString, challengeTokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &ChallengeClaims{}
+	c.UserID, _ = claims[JWTClaimSubject].(string)
+	c.TokenID, _ = claims[JWTClaimTokenID].(string)
+	version, _ := claims[JWTClaimVersion].(float64)
+	c.Version = int(version)
+	c.DeviceName, _ = claims[JWTClaimDeviceName].(string)
+	if c.UserID == "" || c.TokenID == "" {
+		return nil, jwt.ErrTokenMalformed
+	}
+	return c, nil
+}
+
+// parseToken verifies type, signature and expiration time of a token and returns claims it contains.
+// Access tokens must be signed with one of the verification keys using the signing method of the key,
+// tokens of other types must be signed with the internal key.
+func (a *DefaultJWTAuthenticator) parseToken(tokenString string, tokenType string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-		// todo: verify signing method (https://pkg.go.dev/github.com/golang-jwt/jwt/v5#example-Parse-Hmac)
-		return a.secretKey, nil
+		if token.Header[jwtHeaderType] != tokenType {
+			return nil, ErrTokenPurpose
+		}
+		if tokenType != accessTokenType {
+			if token.Method.Alg() != internalSigningMethod.Alg() {
+				return nil, fmt.Errorf("unexpected signing method %q", token.Method.Alg())
+			}
+			return a.internalKey, nil
+		}
+
+		keyID, _ := token.Header[jwtHeaderKeyID].(string)
+		key, ok := a.verificationKeys[keyID]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", keyID)
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %q", token.Method.Alg())
+		}
+		return key.key, nil
 	})
 	if err != nil {
 		return nil, err
@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -20,7 +22,7 @@ func NewTestJWTAuthenticator(tokenTTL time.Duration) *DefaultJWTAuthenticator {
 func TestAuthority_CreateToken(t *testing.T) {
 	jwtAuth := NewTestJWTAuthenticator(longTokenTTL)
 
-	token, expires, err := jwtAuth.CreateToken("test-username")
+	token, expires, err := jwtAuth.CreateToken(&TokenClaims{UserID: "test-user-id", TokenID: "test-token-id", SessionID: "test-session-id"})
 	if assert.NoError(t, err) {
 		assert.NotEmpty(t, token)
 		assert.NotZero(t, expires)
@@ -28,18 +30,24 @@ func TestAuthority_CreateToken(t *testing.T) {
 }
 
 func TestAuthority_VerifyToken(t *testing.T) {
-	const testUsername = "test-username"
+	const (
+		testUserID    = "test-user-id"
+		testTokenID   = "test-token-id"
+		testSessionID = "test-session-id"
+	)
 
 	t.Run("verify valid token", func(t *testing.T) {
 		jwtAuth := NewTestJWTAuthenticator(longTokenTTL)
 
 		// create a new token for the test user:
-		token, _, _ := jwtAuth.CreateToken(testUsername)
+		token, _, _ := jwtAuth.CreateToken(&TokenClaims{UserID: testUserID, TokenID: testTokenID, SessionID: testSessionID})
 
 		claims, err := jwtAuth.VerifyToken(token)
 		if assert.NoError(t, err) {
 			if assert.NotEmpty(t, claims) {
-				assert.Equal(t, testUsername, claims[JWTClaimUsername])
+				assert.Equal(t, testUserID, claims[JWTClaimSubject])
+				assert.Equal(t, testTokenID, claims[JWTClaimTokenID])
+				assert.Equal(t, testSessionID, claims[JWTClaimSessionID])
 			}
 		}
 	})
@@ -48,7 +56,7 @@ func TestAuthority_VerifyToken(t *testing.T) {
 		jwtAuth := NewTestJWTAuthenticator(zeroTokenTTL)
 
 		// create a new token for the test user:
-		token, _, _ := jwtAuth.CreateToken(testUsername)
+		token, _, _ := jwtAuth.CreateToken(&TokenClaims{UserID: testUserID, TokenID: testTokenID, SessionID: testSessionID})
 
 		claims, err := jwtAuth.VerifyToken(token)
 		if assert.Error(t, err) {
@@ -70,3 +78,137 @@ func TestAuthority_VerifyToken(t *testing.T) {
 	})
 
 }
+
+func TestAuthority_ChallengeToken(t *testing.T) {
+	jwtAuth := NewTestJWTAuthenticator(longTokenTTL)
+
+	t.Run("verify valid challenge token", func(t *testing.T) {
+		claims := &ChallengeClaims{UserID: "test-user-id", TokenID: "test-token-id", Version: 2, DeviceName: "test-device"}
+		token, expires, err := jwtAuth.CreateChallengeToken(claims)
+		if assert.NoError(t, err) {
+			assert.WithinDuration(t, time.Now().Add(ChallengeTokenTTL), expires, time.Second)
+		}
+
+		verified, err := jwtAuth.VerifyChallengeToken(token)
+		if assert.NoError(t, err) {
+			assert.Equal(t, claims, verified)
+		}
+	})
+
+	t.Run("use challenge token as access token", func(t *testing.T) {
+		token, _, _ := jwtAuth.CreateChallengeToken(&ChallengeClaims{UserID: "test-user-id", TokenID: "test-token-id"})
+
+		_, err := jwtAuth.VerifyToken(token)
+		assert.ErrorIs(t, err, ErrTokenPurpose)
+	})
+
+	t.Run("use access token as challenge token", func(t *testing.T) {
+		token, _, _ := jwtAuth.CreateToken(&TokenClaims{UserID: "test-user-id"})
+
+		_, err := jwtAuth.VerifyChallengeToken(token)
+		assert.ErrorIs(t, err, ErrTokenPurpose)
+	})
+}
+
+// newTestJWTKey generates a new Ed25519 key.
+func newTestJWTKey() *JWTKey {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	key, err := newJWTKey(private)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func TestAuthority_Asymmetric(t *testing.T) {
+	var (
+		claims = &TokenClaims{UserID: "test-user-id", TokenID: "test-token-id", SessionID: "test-session-id"}
+
+		oldKey = newTestJWTKey()
+		newKey = newTestJWTKey()
+	)
+
+	t.Run("verify token signed with the signing key", func(t *testing.T) {
+		jwtAuth, err := NewAsymmetricJWTAuthenticator(newKey, nil, longTokenTTL)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		token, _, err := jwtAuth.CreateToken(claims)
+		if assert.NoError(t, err) {
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+			if assert.NoError(t, err) {
+				assert.Equal(t, newKey.ID, parsed.Header["kid"])
+				assert.Equal(t, "EdDSA", parsed.Header["alg"])
+			}
+
+			_, err = jwtAuth.VerifyToken(token)
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("verify token signed with a rotated key", func(t *testing.T) {
+		oldAuth, _ := NewAsymmetricJWTAuthenticator(oldKey, nil, longTokenTTL)
+		token, _, _ := oldAuth.CreateToken(claims)
+
+		jwtAuth, err := NewAsymmetricJWTAuthenticator(newKey, []*JWTKey{oldKey}, longTokenTTL)
+		if assert.NoError(t, err) {
+			_, err = jwtAuth.VerifyToken(token)
+			assert.NoError(t, err)
+			assert.Len(t, jwtAuth.JWKS().Keys, 2)
+		}
+
+		jwtAuth, _ = NewAsymmetricJWTAuthenticator(newKey, nil, longTokenTTL)
+		_, err = jwtAuth.VerifyToken(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("verify token signed with a shared secret key", func(t *testing.T) {
+		token, _, _ := NewTestJWTAuthenticator(longTokenTTL).CreateToken(claims)
+
+		jwtAuth, _ := NewAsymmetricJWTAuthenticator(newKey, nil, longTokenTTL)
+		_, err := jwtAuth.VerifyToken(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("verify token with mismatching signing method", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{JWTClaimSubject: "test-user-id"})
+		token.Header["kid"] = newKey.ID
+		tokenString, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		jwtAuth, _ := NewAsymmetricJWTAuthenticator(newKey, nil, longTokenTTL)
+		_, err = jwtAuth.VerifyToken(tokenString)
+		assert.Error(t, err)
+	})
+
+	t.Run("sign challenge token with an unpublished key", func(t *testing.T) {
+		jwtAuth, _ := NewAsymmetricJWTAuthenticator(newKey, nil, longTokenTTL)
+		token, _, err := jwtAuth.CreateChallengeToken(&ChallengeClaims{UserID: "test-user-id", TokenID: "test-token-id"})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+		if assert.NoError(t, err) {
+			assert.Equal(t, "2fa+jwt", parsed.Header["typ"])
+			assert.NotContains(t, parsed.Header, "kid")
+		}
+		_, err = jwt.Parse(token, func(*jwt.Token) (any, error) { return newKey.public, nil })
+		assert.Error(t, err)
+
+		_, err = jwtAuth.VerifyChallengeToken(token)
+		assert.NoError(t, err)
+	})
+
+	t.Run("use public key as signing key", func(t *testing.T) {
+		publicKey := &JWTKey{ID: newKey.ID, Method: newKey.Method, public: newKey.public}
+		_, err := NewAsymmetricJWTAuthenticator(publicKey, nil, longTokenTTL)
+		assert.Error(t, err)
+	})
+}
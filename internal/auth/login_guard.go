@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoginFailureStore interface describes a type which stores counters of failed login attempts.
+// Counters are identified by keys, for example, by username or IP address.
+type LoginFailureStore interface {
+	// LoginFailures returns count of failures of the key and time of the last failure.
+	// Zero values are returned if there are no failures.
+	LoginFailures(ctx context.Context, key string) (count int, lastFailureAt time.Time, err error)
+
+	// AddLoginFailure increases count of failures of the key and sets time of the last failure to now.
+	// Failures are counted from scratch if the last failure happened before resetBefore.
+	// Returns the updated count.
+	AddLoginFailure(ctx context.Context, key string, now time.Time, resetBefore time.Time) (count int, err error)
+
+	// ResetLoginFailures deletes failures of the key.
+	ResetLoginFailures(ctx context.Context, key string) error
+
+	// PruneLoginFailures deletes failures of all keys whose last failure happened before the given time.
+	PruneLoginFailures(ctx context.Context, before time.Time) error
+}
+
+// LoginGuardConfig is configuration of [LoginGuard].
+type LoginGuardConfig struct {
+	// Count of failures after which login attempts are delayed.
+	FreeAttempts int
+
+	// Delay after the first delayed failure, it is doubled after each next failure up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// Count of failures of a username and of an IP address after which login is locked.
+	UserLockoutThreshold int
+	IPLockoutThreshold   int
+
+	// Duration of a lockout. Failures are forgotten if there were no failures during this time.
+	LockoutDuration time.Duration
+}
+
+// DefaultLoginGuardConfig returns the default configuration of [LoginGuard].
+func DefaultLoginGuardConfig() LoginGuardConfig {
+	return LoginGuardConfig{
+		FreeAttempts:         3,
+		BaseDelay:            time.Second,
+		MaxDelay:             time.Minute,
+		UserLockoutThreshold: 10,
+		IPLockoutThreshold:   50,
+		LockoutDuration:      15 * time.Minute,
+	}
+}
+
+// LoginGuard protects login from brute-force attacks. It counts failed login attempts per username and per IP address,
+// delays next attempts with exponential backoff and locks login temporarily after too many failures.
+type LoginGuard struct {
+	store  LoginFailureStore
+	config LoginGuardConfig
+
+	// now returns the current time, it is overridden in tests.
+	now func() time.Time
+}
+
+// NewLoginGuard creates a new instance of [LoginGuard] and returns pointer to it.
+func NewLoginGuard(store LoginFailureStore, config LoginGuardConfig) *LoginGuard {
+	return &LoginGuard{store: store, config: config, now: time.Now}
+}
+
+// Config returns configuration of the login guard.
+func (g *LoginGuard) Config() LoginGuardConfig {
+	return g.config
+}
+
+// userKey returns the counter key of a username. Usernames are compared case-insensitively,
+// so that changing the case does not bypass the protection.
+func userKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+// ipKey returns the counter key of an IP address.
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// blockedFor returns duration for which attempts are blocked after the given count of failures.
+func (g *LoginGuard) blockedFor(count int, lastFailureAt time.Time, lockoutThreshold int) time.Duration {
+	if count < g.config.FreeAttempts {
+		return 0
+	}
+
+	var delay time.Duration
+	if count >= lockoutThreshold {
+		delay = g.config.LockoutDuration
+	} else {
+		delay = g.config.BaseDelay
+		for i := g.config.FreeAttempts; i < count && delay < g.config.MaxDelay; i++ {
+			delay *= 2
+		}
+		if delay > g.config.MaxDelay {
+			delay = g.config.MaxDelay
+		}
+	}
+
+	remaining := lastFailureAt.Add(delay).Sub(g.now())
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// Check returns duration for which login attempts of the username from the IP address are blocked.
+// Zero is returned if an attempt is allowed.
+func (g *LoginGuard) Check(ctx context.Context, username string, ip string) (time.Duration, error) {
+	userCount, userLastFailureAt, err := g.store.LoginFailures(ctx, userKey(username))
+	if err != nil {
+		return 0, err
+	}
+	ipCount, ipLastFailureAt, err := g.store.LoginFailures(ctx, ipKey(ip))
+	if err != nil {
+		return 0, err
+	}
+
+	return max(
+		g.blockedFor(userCount, userLastFailureAt, g.config.UserLockoutThreshold),
+		g.blockedFor(ipCount, ipLastFailureAt, g.config.IPLockoutThreshold),
+	), nil
+}
+
+// Fail registers a failed login attempt of the username from the IP address.
+// Returns duration for which next attempts are blocked.
+func (g *LoginGuard) Fail(ctx context.Context, username string, ip string) (time.Duration, error) {
+	now := g.now()
+	resetBefore := now.Add(-g.config.LockoutDuration)
+
+	userCount, err := g.store.AddLoginFailure(ctx, userKey(username), now, resetBefore)
+	if err != nil {
+		return 0, err
+	}
+	ipCount, err := g.store.AddLoginFailure(ctx, ipKey(ip), now, resetBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	return max(
+		g.blockedFor(userCount, now, g.config.UserLockoutThreshold),
+		g.blockedFor(ipCount, now, g.config.IPLockoutThreshold),
+	), nil
+}
+
+// Succeed resets failures of the username after a successful login. Failures of the IP address are kept,
+// so that an attacker can not reset them by logging in to their own account.
+func (g *LoginGuard) Succeed(ctx context.Context, username string) error {
+	return g.store.ResetLoginFailures(ctx, userKey(username))
+}
+
+// Prune deletes counters of failures which are already forgotten, so that the store does not grow unbounded.
+func (g *LoginGuard) Prune(ctx context.Context) error {
+	return g.store.PruneLoginFailures(ctx, g.now().Add(-g.config.LockoutDuration))
+}
+
+// Unlock resets failures of the username, so that the user can log in immediately.
+func (g *LoginGuard) Unlock(ctx context.Context, username string) error {
+	return g.store.ResetLoginFailures(ctx, userKey(username))
+}
+
+// loginFailures is a counter of [MemoryLoginFailureStore].
+type loginFailures struct {
+	count         int
+	lastFailureAt time.Time
+}
+
+// MemoryLoginFailureStore is an in-memory [LoginFailureStore]. It is not shared between replicas.
+type MemoryLoginFailureStore struct {
+	mu       sync.Mutex
+	failures map[string]*loginFailures
+
+	// count of counters after the last removal of outdated counters.
+	sweepSize int
+}
+
+// NewMemoryLoginFailureStore creates a new instance of [MemoryLoginFailureStore] and returns pointer to it.
+func NewMemoryLoginFailureStore() *MemoryLoginFailureStore {
+	return &MemoryLoginFailureStore{failures: make(map[string]*loginFailures)}
+}
+
+func (s *MemoryLoginFailureStore) LoginFailures(_ context.Context, key string) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failures, ok := s.failures[key]
+	if !ok {
+		return 0, time.Time{}, nil
+	}
+	return failures.count, failures.lastFailureAt, nil
+}
+
+func (s *MemoryLoginFailureStore) AddLoginFailure(_ context.Context, key string, now time.Time, resetBefore time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// remove outdated counters when count of counters doubles, so that memory does not grow unbounded:
+	if len(s.failures) >= 2*s.sweepSize+1024 {
+		for k, failures := range s.failures {
+			if failures.lastFailureAt.Before(resetBefore) {
+				delete(s.failures, k)
+			}
+		}
+		s.sweepSize = len(s.failures)
+	}
+
+	failures, ok := s.failures[key]
+	if !ok || failures.lastFailureAt.Before(resetBefore) {
+		failures = &loginFailures{}
+		s.failures[key] = failures
+	}
+	failures.count++
+	failures.lastFailureAt = now
+	return failures.count, nil
+}
+
+func (s *MemoryLoginFailureStore) ResetLoginFailures(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, key)
+	return nil
+}
+
+func (s *MemoryLoginFailureStore) PruneLoginFailures(_ context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, failures := range s.failures {
+		if failures.lastFailureAt.Before(before) {
+			delete(s.failures, key)
+		}
+	}
+	s.sweepSize = len(s.failures)
+	return nil
+}
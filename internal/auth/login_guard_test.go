@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// newTestLoginGuard creates a new login guard with in-memory store and a manually advanced clock.
+func newTestLoginGuard() (*LoginGuard, *time.Time) {
+	now := time.Date(2026, 3, 20, 12, 0, 0, 0, time.UTC)
+	guard := NewLoginGuard(NewMemoryLoginFailureStore(), LoginGuardConfig{
+		FreeAttempts:         2,
+		BaseDelay:            time.Second,
+		MaxDelay:             4 * time.Second,
+		UserLockoutThreshold: 6,
+		IPLockoutThreshold:   8,
+		LockoutDuration:      time.Minute,
+	})
+	guard.now = func() time.Time { return now }
+	return guard, &now
+}
+
+func TestLoginGuard(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("delay attempts with exponential backoff", func(t *testing.T) {
+		guard, _ := newTestLoginGuard()
+
+		expected := []time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second, time.Minute}
+		for i, delay := range expected {
+			retryAfter, err := guard.Fail(ctx, "jieggii", "203.0.113.7")
+			if assert.NoError(t, err) {
+				assert.Equal(t, delay, retryAfter, "failure %d", i+1)
+			}
+		}
+	})
+
+	t.Run("lock out and unlock a user", func(t *testing.T) {
+		guard, now := newTestLoginGuard()
+
+		for i := 0; i < 6; i++ {
+			_, _ = guard.Fail(ctx, "jieggii", "203.0.113.7")
+		}
+		retryAfter, err := guard.Check(ctx, "JIEGGII", "198.51.100.1")
+		if assert.NoError(t, err) {
+			assert.Equal(t, time.Minute, retryAfter)
+		}
+
+		*now = now.Add(30 * time.Second)
+		retryAfter, _ = guard.Check(ctx, "jieggii", "198.51.100.1")
+		assert.Equal(t, 30*time.Second, retryAfter)
+
+		assert.NoError(t, guard.Unlock(ctx, "jieggii"))
+		retryAfter, _ = guard.Check(ctx, "jieggii", "198.51.100.1")
+		assert.Zero(t, retryAfter)
+	})
+
+	t.Run("forget failures after lockout duration", func(t *testing.T) {
+		guard, now := newTestLoginGuard()
+
+		for i := 0; i < 5; i++ {
+			_, _ = guard.Fail(ctx, "jieggii", "203.0.113.7")
+		}
+		*now = now.Add(2 * time.Minute)
+
+		retryAfter, err := guard.Fail(ctx, "jieggii", "203.0.113.7")
+		if assert.NoError(t, err) {
+			assert.Zero(t, retryAfter)
+		}
+	})
+
+	t.Run("lock out an IP address", func(t *testing.T) {
+		guard, _ := newTestLoginGuard()
+
+		for i := 0; i < 8; i++ {
+			_, _ = guard.Fail(ctx, "user-"+string(rune('a'+i)), "203.0.113.7")
+		}
+		retryAfter, _ := guard.Check(ctx, "another-user", "203.0.113.7")
+		assert.Equal(t, time.Minute, retryAfter)
+
+		retryAfter, _ = guard.Check(ctx, "another-user", "198.51.100.1")
+		assert.Zero(t, retryAfter)
+	})
+
+	t.Run("reset failures of the user after successful login", func(t *testing.T) {
+		guard, _ := newTestLoginGuard()
+
+		for i := 0; i < 3; i++ {
+			_, _ = guard.Fail(ctx, "jieggii", "203.0.113.7")
+		}
+		assert.NoError(t, guard.Succeed(ctx, "jieggii"))
+
+		count, _, _ := guard.store.LoginFailures(ctx, userKey("jieggii"))
+		assert.Zero(t, count)
+		count, _, _ = guard.store.LoginFailures(ctx, ipKey("203.0.113.7"))
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("prune forgotten failures", func(t *testing.T) {
+		guard, now := newTestLoginGuard()
+
+		_, _ = guard.Fail(ctx, "jieggii", "203.0.113.7")
+		*now = now.Add(2 * time.Minute)
+		_, _ = guard.Fail(ctx, "alice", "198.51.100.1")
+		assert.NoError(t, guard.Prune(ctx))
+
+		store := guard.store.(*MemoryLoginFailureStore)
+		assert.Len(t, store.failures, 2)
+		assert.Contains(t, store.failures, userKey("alice"))
+		assert.Contains(t, store.failures, ipKey("198.51.100.1"))
+	})
+}
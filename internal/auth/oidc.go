@@ -0,0 +1,408 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDCStateTokenTTL is duration of an OIDC state token validity, login at the identity provider must be completed during it.
+const OIDCStateTokenTTL = 10 * time.Minute
+
+// oidcRandomValueSize is the count of random bytes state, nonce and code verifier consist of.
+const oidcRandomValueSize = 32
+
+// oidcKeysRefreshInterval is the minimal interval between fetches of the identity provider's keys,
+// keys are fetched again when an ID token is signed with an unknown key.
+const oidcKeysRefreshInterval = time.Minute
+
+// oidcMaxResponseSize is the maximal size of responses of the identity provider.
+const oidcMaxResponseSize = 1 << 20
+
+var (
+	// ErrOIDCLoginRejected is returned when the identity provider rejects the authorization code
+	// or returns an invalid ID token.
+	ErrOIDCLoginRejected = errors.New("identity provider rejected the login")
+
+	// ErrOIDCStateMismatch is returned when the state returned by the identity provider does not match the state token.
+	ErrOIDCStateMismatch = errors.New("oidc state does not match")
+)
+
+// OIDCConfig is configuration of [OIDCProvider].
+type OIDCConfig struct {
+	// URL of the identity provider, its discovery document is fetched from `/.well-known/openid-configuration`.
+	Issuer string
+
+	// Credentials of groshi registered at the identity provider.
+	ClientID     string
+	ClientSecret string
+
+	// URL of the client application the identity provider redirects to after login with `code` and `state` query params.
+	RedirectURL string
+
+	// Scopes which are requested in addition to the `openid` scope.
+	Scopes []string
+
+	// Create accounts on the first login of identities which are not linked to any user.
+	CreateUsers bool
+}
+
+// OIDCIdentity is an identity of a user at the identity provider.
+type OIDCIdentity struct {
+	Issuer  string
+	Subject string
+
+	// Optional claims which are used to choose username of created accounts.
+	PreferredUsername string
+	Email             string
+}
+
+// OIDCState is a state of an OIDC login which is kept by the client between the start of the login and the callback.
+type OIDCState struct {
+	// Random value which is passed to the identity provider and returned back with the authorization code.
+	State string
+
+	// Random value which must be contained by the ID token.
+	Nonce string
+
+	// PKCE code verifier, the identity provider receives only its challenge.
+	CodeVerifier string
+
+	// Device name provided on the start of the login.
+	DeviceName string
+}
+
+// NewOIDCState generates a new OIDC login state with random state, nonce and code verifier.
+func NewOIDCState(deviceName string) (*OIDCState, error) {
+	values := make([]string, 3)
+	for i := range values {
+		bytes := make([]byte, oidcRandomValueSize)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, err
+		}
+		values[i] = base64.RawURLEncoding.EncodeToString(bytes)
+	}
+	return &OIDCState{State: values[0], Nonce: values[1], CodeVerifier: values[2], DeviceName: deviceName}, nil
+}
+
+// PKCEChallenge returns the S256 PKCE code challenge of the code verifier.
+func PKCEChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// CreateOIDCStateToken generates a new short-lived token which contains the OIDC login state.
+// The code verifier is encrypted, so that the client can not read it.
+func (a *DefaultJWTAuthenticator) CreateOIDCStateToken(state *OIDCState) (string, time.Time, error) {
+	issued := time.Now()
+	expires := issued.Add(OIDCStateTokenTTL)
+	codeVerifier, err := a.encryptClaim(state.CodeVerifier)
+	if err != nil {
+		return "", expires, err
+	}
+	tokenString, err := a.signInternalToken(oidcStateTokenType, jwt.MapClaims{
+		"state":            state.State,
+		"nonce":            state.Nonce,
+		"code_verifier":    codeVerifier,
+		JWTClaimDeviceName: state.DeviceName,
+		"exp":              expires.Unix(),
+		"iat":              issued.Unix(),
+	})
+	if err != nil {
+		return "", expires, err
+	}
+
+	return tokenString, expires, nil
+}
+
+// VerifyOIDCStateToken verifies that OIDC state token is valid and not expired and that it contains the given state,
+// returns the OIDC login state it contains.
+func (a *DefaultJWTAuthenticator) VerifyOIDCStateToken(tokenString string, state string) (*OIDCState, error) {
+	claims, err := a.parseToken(tokenString, oidcStateTokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &OIDCState{}
+	s.State, _ = claims["state"].(string)
+	s.Nonce, _ = claims["nonce"].(string)
+	codeVerifier, _ := claims["code_verifier"].(string)
+	s.DeviceName, _ = claims[JWTClaimDeviceName].(string)
+	if s.State == "" || s.Nonce == "" || codeVerifier == "" {
+		return nil, jwt.ErrTokenMalformed
+	}
+	if s.CodeVerifier, err = a.decryptClaim(codeVerifier); err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(s.State), []byte(state)) != 1 {
+		return nil, ErrOIDCStateMismatch
+	}
+	return s, nil
+}
+
+// oidcDiscovery is the part of the identity provider's discovery document used by groshi.
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCProvider is an OpenID Connect identity provider users can log in with.
+// It implements the authorization code flow with PKCE.
+type OIDCProvider struct {
+	config    OIDCConfig
+	client    *http.Client
+	discovery oidcDiscovery
+
+	// keys of the identity provider by their IDs, and time when they were fetched.
+	mu            sync.Mutex
+	keys          map[string]*JWTKey
+	keysFetchedAt time.Time
+}
+
+// NewOIDCProvider fetches the discovery document of the identity provider, creates a new instance of [OIDCProvider]
+// and returns pointer to it.
+func NewOIDCProvider(ctx context.Context, config OIDCConfig, client *http.Client) (*OIDCProvider, error) {
+	p := &OIDCProvider{config: config, client: client}
+
+	discoveryURL := strings.TrimSuffix(config.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.doJSON(req, &p.discovery); err != nil {
+		return nil, fmt.Errorf("could not fetch discovery document: %w", err)
+	}
+	if p.discovery.Issuer != config.Issuer {
+		return nil, fmt.Errorf("issuer %q of the discovery document does not match %q", p.discovery.Issuer, config.Issuer)
+	}
+	if p.discovery.AuthorizationEndpoint == "" || p.discovery.TokenEndpoint == "" || p.discovery.JWKSURI == "" {
+		return nil, errors.New("discovery document does not contain required endpoints")
+	}
+	return p, nil
+}
+
+// Config returns configuration of the identity provider.
+func (p *OIDCProvider) Config() OIDCConfig {
+	return p.config
+}
+
+// AuthCodeURL returns URL of the identity provider's login page the user must be redirected to.
+func (p *OIDCProvider) AuthCodeURL(state *OIDCState) string {
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(append([]string{"openid"}, p.config.Scopes...), " ")},
+		"state":                 {state.State},
+		"nonce":                 {state.Nonce},
+		"code_challenge":        {PKCEChallenge(state.CodeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(p.discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return p.discovery.AuthorizationEndpoint + separator + query.Encode()
+}
+
+// Exchange exchanges the authorization code for an ID token, verifies it and returns the identity it contains.
+// [ErrOIDCLoginRejected] is returned if the code is rejected or the ID token is invalid.
+func (p *OIDCProvider) Exchange(ctx context.Context, code string, state *OIDCState) (*OIDCIdentity, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {state.CodeVerifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	tokens := &struct {
+		IDToken string `json:"id_token"`
+	}{}
+	if err := p.doJSON(req, tokens); err != nil {
+		return nil, err
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("%w: token response does not contain id token", ErrOIDCLoginRejected)
+	}
+
+	return p.verifyIDToken(ctx, tokens.IDToken, state.Nonce)
+}
+
+// verifyIDToken verifies signature, issuer, audience, expiration time and nonce of the ID token
+// and returns the identity it contains.
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, idToken string, nonce string) (*OIDCIdentity, error) {
+	var keyErr error
+	token, err := jwt.Parse(idToken, func(token *jwt.Token) (any, error) {
+		keyID, _ := token.Header[jwtHeaderKeyID].(string)
+		key, err := p.key(ctx, keyID)
+		if err != nil {
+			keyErr = err
+			return nil, err
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.public, nil
+	})
+	if keyErr != nil {
+		return nil, keyErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrOIDCLoginRejected, err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid id token claims", ErrOIDCLoginRejected)
+	}
+	if !claims.VerifyIssuer(p.config.Issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected id token issuer", ErrOIDCLoginRejected)
+	}
+	if !claims.VerifyAudience(p.config.ClientID, true) {
+		return nil, fmt.Errorf("%w: unexpected id token audience", ErrOIDCLoginRejected)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("%w: id token has no expiration time", ErrOIDCLoginRejected)
+	}
+	if azp, ok := claims["azp"].(string); ok && azp != p.config.ClientID {
+		return nil, fmt.Errorf("%w: unexpected id token authorized party", ErrOIDCLoginRejected)
+	}
+	if tokenNonce, _ := claims["nonce"].(string); subtle.ConstantTimeCompare([]byte(tokenNonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: unexpected id token nonce", ErrOIDCLoginRejected)
+	}
+
+	identity := &OIDCIdentity{Issuer: p.config.Issuer}
+	identity.Subject, _ = claims["sub"].(string)
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("%w: id token has no subject", ErrOIDCLoginRejected)
+	}
+	identity.PreferredUsername, _ = claims["preferred_username"].(string)
+	if verified, _ := claims["email_verified"].(bool); verified {
+		identity.Email, _ = claims["email"].(string)
+	}
+	return identity, nil
+}
+
+// key returns the identity provider's key with the ID. Keys are fetched again if the key is unknown,
+// but not more often than once in [oidcKeysRefreshInterval].
+func (p *OIDCProvider) key(ctx context.Context, keyID string) (*JWTKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[keyID]; ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < oidcKeysRefreshInterval {
+		return nil, fmt.Errorf("%w: id token is signed with unknown key", ErrOIDCLoginRejected)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.discovery.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	set := &JWKSet{}
+	if err := p.doJSON(req, set); err != nil {
+		return nil, fmt.Errorf("could not fetch keys of the identity provider: %w", err)
+	}
+	p.keysFetchedAt = time.Now()
+
+	// keys of unsupported types are skipped:
+	p.keys = make(map[string]*JWTKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.publicKey(); err == nil {
+			p.keys[jwk.KeyID] = key
+		}
+	}
+
+	if key, ok := p.keys[keyID]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: id token is signed with unknown key", ErrOIDCLoginRejected)
+}
+
+// doJSON sends the request and decodes JSON response body into v. Error responses of the token endpoint are returned
+// as [ErrOIDCLoginRejected].
+func (p *OIDCProvider) doJSON(req *http.Request, v any) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body := io.LimitReader(resp.Body, oidcMaxResponseSize)
+	if resp.StatusCode != http.StatusOK {
+		errResp := &struct {
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}{}
+		if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized {
+			if err := json.NewDecoder(body).Decode(errResp); err == nil && errResp.Error != "" {
+				return fmt.Errorf("%w: %s %s", ErrOIDCLoginRejected, errResp.Error, errResp.ErrorDescription)
+			}
+		}
+		return fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+	return json.NewDecoder(body).Decode(v)
+}
+
+// publicKey returns the RSA or Ed25519 public key in JSON Web Key format as [JWTKey].
+func (j JWK) publicKey() (*JWTKey, error) {
+	switch j.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.Modulus)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.Exponent)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return newJWTKey(&rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())})
+	case "OKP":
+		if j.Curve != "Ed25519" {
+			return nil, ErrUnsupportedKey
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return newJWTKey(ed25519.PublicKey(x))
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
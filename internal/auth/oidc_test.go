@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"context"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/groshi-project/groshi/internal/auth/oidctest"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+const testRedirectURL = "https://app.example.com/oidc/callback"
+
+func newTestOIDCProvider(t *testing.T, server *oidctest.Server) *OIDCProvider {
+	provider, err := NewOIDCProvider(context.Background(), OIDCConfig{
+		Issuer:       server.Issuer(),
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"profile", "email"},
+	}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestPKCEChallenge(t *testing.T) {
+	// example from RFC 7636, appendix B:
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", PKCEChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+}
+
+func TestOIDCProvider_AuthCodeURL(t *testing.T) {
+	server := oidctest.NewServer()
+	defer server.Close()
+	provider := newTestOIDCProvider(t, server)
+
+	state, err := NewOIDCState("test-device")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(provider.AuthCodeURL(state))
+	if assert.NoError(t, err) {
+		query := u.Query()
+		assert.Equal(t, server.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+		assert.Equal(t, oidctest.ClientID, query.Get("client_id"))
+		assert.Equal(t, testRedirectURL, query.Get("redirect_uri"))
+		assert.Equal(t, "openid profile email", query.Get("scope"))
+		assert.Equal(t, state.State, query.Get("state"))
+		assert.Equal(t, state.Nonce, query.Get("nonce"))
+		assert.Equal(t, PKCEChallenge(state.CodeVerifier), query.Get("code_challenge"))
+		assert.Equal(t, "S256", query.Get("code_challenge_method"))
+		assert.Empty(t, query.Get("code_verifier"))
+	}
+}
+
+func TestOIDCProvider_Exchange(t *testing.T) {
+	server := oidctest.NewServer()
+	defer server.Close()
+	provider := newTestOIDCProvider(t, server)
+
+	// login returns code of the test subject at the identity provider with additional claims:
+	login := func(claims jwt.MapClaims) (*OIDCState, string) {
+		state, err := NewOIDCState("test-device")
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, _, err := server.Authorize(provider.AuthCodeURL(state), "test-subject", claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return state, code
+	}
+
+	t.Run("exchange valid code", func(t *testing.T) {
+		state, code := login(jwt.MapClaims{"preferred_username": "jieggii", "email": "jieggii@example.com", "email_verified": true})
+		identity, err := provider.Exchange(context.Background(), code, state)
+		if assert.NoError(t, err) {
+			assert.Equal(t, &OIDCIdentity{
+				Issuer:            server.Issuer(),
+				Subject:           "test-subject",
+				PreferredUsername: "jieggii",
+				Email:             "jieggii@example.com",
+			}, identity)
+		}
+	})
+
+	t.Run("ignore unverified email", func(t *testing.T) {
+		state, code := login(jwt.MapClaims{"email": "jieggii@example.com"})
+		identity, err := provider.Exchange(context.Background(), code, state)
+		if assert.NoError(t, err) {
+			assert.Empty(t, identity.Email)
+		}
+	})
+
+	t.Run("exchange code twice", func(t *testing.T) {
+		state, code := login(nil)
+		_, err := provider.Exchange(context.Background(), code, state)
+		assert.NoError(t, err)
+		_, err = provider.Exchange(context.Background(), code, state)
+		assert.ErrorIs(t, err, ErrOIDCLoginRejected)
+	})
+
+	t.Run("exchange code with wrong code verifier", func(t *testing.T) {
+		state, code := login(nil)
+		state.CodeVerifier = "wrong-code-verifier"
+		_, err := provider.Exchange(context.Background(), code, state)
+		assert.ErrorIs(t, err, ErrOIDCLoginRejected)
+	})
+
+	t.Run("reject id token with wrong nonce", func(t *testing.T) {
+		state, code := login(jwt.MapClaims{"nonce": "wrong-nonce"})
+		_, err := provider.Exchange(context.Background(), code, state)
+		assert.ErrorIs(t, err, ErrOIDCLoginRejected)
+	})
+
+	t.Run("reject id token issued to another client", func(t *testing.T) {
+		state, code := login(jwt.MapClaims{"aud": "another-client-id"})
+		_, err := provider.Exchange(context.Background(), code, state)
+		assert.ErrorIs(t, err, ErrOIDCLoginRejected)
+	})
+
+	t.Run("reject id token of another issuer", func(t *testing.T) {
+		state, code := login(jwt.MapClaims{"iss": "https://evil.example.com"})
+		_, err := provider.Exchange(context.Background(), code, state)
+		assert.ErrorIs(t, err, ErrOIDCLoginRejected)
+	})
+
+	t.Run("reject expired id token", func(t *testing.T) {
+		state, code := login(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()})
+		_, err := provider.Exchange(context.Background(), code, state)
+		assert.ErrorIs(t, err, ErrOIDCLoginRejected)
+	})
+}
+
+func TestAuthority_OIDCStateToken(t *testing.T) {
+	jwtAuth := NewTestJWTAuthenticator(longTokenTTL)
+	state, err := NewOIDCState("test-device")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, expires, err := jwtAuth.CreateOIDCStateToken(state)
+	if assert.NoError(t, err) {
+		assert.WithinDuration(t, time.Now().Add(OIDCStateTokenTTL), expires, time.Second)
+	}
+
+	t.Run("verify valid state token", func(t *testing.T) {
+		verified, err := jwtAuth.VerifyOIDCStateToken(token, state.State)
+		if assert.NoError(t, err) {
+			assert.Equal(t, state, verified)
+		}
+	})
+
+	t.Run("encrypt code verifier", func(t *testing.T) {
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+		if assert.NoError(t, err) {
+			claims := parsed.Claims.(jwt.MapClaims)
+			assert.NotEmpty(t, claims["code_verifier"])
+			assert.NotEqual(t, state.CodeVerifier, claims["code_verifier"])
+		}
+	})
+
+	t.Run("verify state token with another state", func(t *testing.T) {
+		_, err := jwtAuth.VerifyOIDCStateToken(token, "another-state")
+		assert.ErrorIs(t, err, ErrOIDCStateMismatch)
+	})
+
+	t.Run("use state token as access token", func(t *testing.T) {
+		_, err := jwtAuth.VerifyToken(token)
+		assert.ErrorIs(t, err, ErrTokenPurpose)
+	})
+}
@@ -0,0 +1,184 @@
+// Package oidctest provides a mock OpenID Connect identity provider for tests.
+package oidctest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/golang-jwt/jwt/v4"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// ClientID and ClientSecret are credentials of the client registered at the mock identity provider.
+	ClientID     = "test-client-id"
+	ClientSecret = "test-client-secret"
+
+	// keyID is ID of the key ID tokens are signed with.
+	keyID = "test-key"
+)
+
+// authorization is an authorization code issued to the client which has not been exchanged yet.
+type authorization struct {
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	claims        jwt.MapClaims
+}
+
+// Server is a mock identity provider which implements discovery, authorization code flow with PKCE and publishes
+// its keys. Users do not log in interactively, [Server.Authorize] is called instead.
+type Server struct {
+	*httptest.Server
+
+	key ed25519.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]*authorization
+}
+
+// NewServer starts a new mock identity provider. It must be closed after use.
+func NewServer() *Server {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	s := &Server{key: key, codes: make(map[string]*authorization)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/token", s.token)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Issuer returns issuer of the identity provider.
+func (s *Server) Issuer() string {
+	return s.URL
+}
+
+// Authorize simulates login of a user with the subject on the page the authorization URL points to
+// and returns the code and the state which are passed to the redirect URL. Claims are added to the ID token,
+// they override the standard claims, so that invalid tokens can be issued.
+func (s *Server) Authorize(authorizationURL string, subject string, claims jwt.MapClaims) (code string, state string, err error) {
+	u, err := url.Parse(authorizationURL)
+	if err != nil {
+		return "", "", err
+	}
+	query := u.Query()
+	if query.Get("response_type") != "code" || query.Get("client_id") != ClientID {
+		return "", "", errors.New("invalid authorization request")
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		return "", "", errors.New("authorization request has no PKCE challenge")
+	}
+
+	idTokenClaims := jwt.MapClaims{"sub": subject}
+	for k, v := range claims {
+		idTokenClaims[k] = v
+	}
+
+	code = randomString()
+	s.mu.Lock()
+	s.codes[code] = &authorization{
+		redirectURI:   query.Get("redirect_uri"),
+		codeChallenge: query.Get("code_challenge"),
+		nonce:         query.Get("nonce"),
+		claims:        idTokenClaims,
+	}
+	s.mu.Unlock()
+	return code, query.Get("state"), nil
+}
+
+func (s *Server) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 s.Issuer(),
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "EdDSA",
+			"x":   base64.RawURLEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey)),
+		}},
+	})
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != ClientID || clientSecret != ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	// codes can be exchanged only once:
+	s.mu.Lock()
+	auth, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || auth.redirectURI != r.PostForm.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(challenge[:]) != auth.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   s.Issuer(),
+		"aud":   ClientID,
+		"nonce": auth.nonce,
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"iat":   time.Now().Unix(),
+	}
+	for k, v := range auth.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(s.key)
+	if err != nil {
+		panic(err)
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		panic(err)
+	}
+}
+
+func randomString() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
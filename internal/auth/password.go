@@ -1,19 +1,59 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
+	"strings"
 )
 
+// generatedPasswordSize is the count of random bytes a generated password consists of.
+const generatedPasswordSize = 12
+
 type PasswordAuthenticator interface {
 	// HashPassword returns hash of a given password.
 	HashPassword(password string) (string, error)
 
 	// VerifyPassword returns true if a given password matches with a given hash.
+	// Hashes made by any of the supported algorithms are recognized.
 	VerifyPassword(password string, hash string) (bool, error)
+
+	// NeedsRehash returns true if a given hash was made by another algorithm or with other parameters,
+	// so that the password should be hashed again.
+	NeedsRehash(hash string) bool
 }
 
-// DefaultPasswordAuthenticator represents password hashing and validation authority.
+// ErrUnknownPasswordHash is returned when a password hash was made by an unsupported algorithm.
+var ErrUnknownPasswordHash = errors.New("unknown password hash format")
+
+// argon2idPrefix is the prefix of hashes made by Argon2id.
+const argon2idPrefix = "$argon2id$"
+
+// verifyPassword returns true if a given password matches with a given bcrypt or Argon2id hash.
+func verifyPassword(password string, hash string) (bool, error) {
+	if strings.HasPrefix(hash, argon2idPrefix) {
+		params, salt, key, err := decodeArgon2idHash(hash)
+		if err != nil {
+			return false, err
+		}
+		given := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Parallelism, uint32(len(key)))
+		return subtle.ConstantTimeCompare(given, key) == 1, nil
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// DefaultPasswordAuthenticator represents password hashing and validation authority which uses bcrypt.
 type DefaultPasswordAuthenticator struct {
 	bcryptCost int
 }
@@ -32,13 +72,129 @@ func (d *DefaultPasswordAuthenticator) HashPassword(password string) (string, er
 	return string(bytes), nil
 }
 
-// VerifyPassword returns true if a given password matches with a given hash.
+// VerifyPassword returns true if a given password matches with a given bcrypt or Argon2id hash.
 func (d *DefaultPasswordAuthenticator) VerifyPassword(password string, hash string) (bool, error) {
-	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			return false, nil
-		}
-		return false, err
+	return verifyPassword(password, hash)
+}
+
+// NeedsRehash returns true if a given hash is not a bcrypt hash of the configured cost.
+func (d *DefaultPasswordAuthenticator) NeedsRehash(hash string) bool {
+	expected := d.bcryptCost
+	if expected < bcrypt.MinCost { // bcrypt uses the default cost instead of a too low one
+		expected = bcrypt.DefaultCost
 	}
-	return true, nil
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != expected
+}
+
+// Argon2idParams are parameters of Argon2id.
+type Argon2idParams struct {
+	// Memory in KiB.
+	Memory uint32
+
+	// Count of passes over the memory.
+	Time uint32
+
+	// Count of threads.
+	Parallelism uint8
+}
+
+// Validate returns an error if the parameters can not be used by Argon2id.
+func (p Argon2idParams) Validate() error {
+	if p.Time < 1 {
+		return errors.New("argon2id time must be at least 1")
+	}
+	if p.Parallelism < 1 {
+		return errors.New("argon2id parallelism must be at least 1")
+	}
+	if p.Memory < 8*uint32(p.Parallelism) {
+		return fmt.Errorf("argon2id memory must be at least %d KiB for parallelism %d", 8*uint32(p.Parallelism), p.Parallelism)
+	}
+	return nil
+}
+
+const (
+	// argon2idSaltSize is the count of random bytes of a salt.
+	argon2idSaltSize = 16
+
+	// argon2idKeySize is the size of a derived key in bytes.
+	argon2idKeySize = 32
+)
+
+// Argon2idPasswordAuthenticator represents password hashing and validation authority which uses Argon2id.
+type Argon2idPasswordAuthenticator struct {
+	params Argon2idParams
+}
+
+// NewArgon2idPasswordAuthenticator creates a new instance of [Argon2idPasswordAuthenticator] and returns pointer to it.
+func NewArgon2idPasswordAuthenticator(params Argon2idParams) *Argon2idPasswordAuthenticator {
+	return &Argon2idPasswordAuthenticator{params: params}
+}
+
+// HashPassword returns hash of a given password in PHC string format, for example,
+// `$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>`.
+func (a *Argon2idPasswordAuthenticator) HashPassword(password string) (string, error) {
+	salt := make([]byte, argon2idSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, a.params.Time, a.params.Memory, a.params.Parallelism, argon2idKeySize)
+
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version, a.params.Memory, a.params.Time, a.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// VerifyPassword returns true if a given password matches with a given Argon2id or bcrypt hash.
+func (a *Argon2idPasswordAuthenticator) VerifyPassword(password string, hash string) (bool, error) {
+	return verifyPassword(password, hash)
+}
+
+// NeedsRehash returns true if a given hash is not an Argon2id hash made with the configured parameters.
+func (a *Argon2idPasswordAuthenticator) NeedsRehash(hash string) bool {
+	params, _, key, err := decodeArgon2idHash(hash)
+	return err != nil || params != a.params || len(key) != argon2idKeySize
+}
+
+// decodeArgon2idHash decodes parameters, salt and key from a hash made by [Argon2idPasswordAuthenticator].
+func decodeArgon2idHash(hash string) (Argon2idParams, []byte, []byte, error) {
+	var params Argon2idParams
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Parallelism); err != nil {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	// argon2 panics if time or parallelism is zero, so such hashes are rejected:
+	if err := params.Validate(); err != nil {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	return params, salt, key, nil
+}
+
+// GeneratePassword generates a new random password, for example, to reset password of a user.
+func GeneratePassword() (string, error) {
+	bytes := make([]byte, generatedPasswordSize)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
 }
@@ -3,6 +3,7 @@ package auth
 import (
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
+	"strings"
 	"testing"
 )
 
@@ -62,3 +63,106 @@ func TestAuthority_VerifyPassword(t *testing.T) {
 		assert.False(t, ok)
 	})
 }
+
+func TestGeneratePassword(t *testing.T) {
+	first, err := GeneratePassword()
+	if assert.NoError(t, err) {
+		assert.Len(t, first, 16)
+	}
+
+	second, err := GeneratePassword()
+	if assert.NoError(t, err) {
+		assert.NotEqual(t, first, second)
+	}
+}
+
+// testArgon2idParams are cheap Argon2id parameters which are used in tests.
+var testArgon2idParams = Argon2idParams{Memory: 64, Time: 1, Parallelism: 1}
+
+func TestArgon2idPasswordAuthenticator(t *testing.T) {
+	const (
+		correctPassword = "correct-password"
+		wrongPassword   = "wrong-password"
+	)
+
+	a := NewArgon2idPasswordAuthenticator(testArgon2idParams)
+	hash, err := a.HashPassword(correctPassword)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Regexp(t, `^\$argon2id\$v=19\$m=64,t=1,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`, hash)
+
+	t.Run("verify correct password", func(t *testing.T) {
+		ok, err := a.VerifyPassword(correctPassword, hash)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("verify wrong password", func(t *testing.T) {
+		ok, err := a.VerifyPassword(wrongPassword, hash)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("verify password longer than 72 bytes", func(t *testing.T) {
+		long := strings.Repeat("a", 100)
+		longHash, err := a.HashPassword(long)
+		if assert.NoError(t, err) {
+			ok, err := a.VerifyPassword(long[:72], longHash)
+			assert.NoError(t, err)
+			assert.False(t, ok)
+		}
+	})
+
+	t.Run("verify password over a malformed hash", func(t *testing.T) {
+		ok, err := a.VerifyPassword(correctPassword, "$argon2id$v=19$m=64$salt$key")
+		assert.ErrorIs(t, err, ErrUnknownPasswordHash)
+		assert.False(t, ok)
+	})
+
+	t.Run("verify password over a hash with zero parameters", func(t *testing.T) {
+		for _, params := range []string{"m=64,t=0,p=1", "m=64,t=1,p=0", "m=0,t=1,p=1"} {
+			malformed := strings.Replace(hash, "m=64,t=1,p=1", params, 1)
+			ok, err := a.VerifyPassword(correctPassword, malformed)
+			assert.ErrorIs(t, err, ErrUnknownPasswordHash, params)
+			assert.False(t, ok)
+		}
+	})
+}
+
+func TestArgon2idParams_Validate(t *testing.T) {
+	assert.NoError(t, testArgon2idParams.Validate())
+	assert.Error(t, Argon2idParams{Memory: 64, Time: 0, Parallelism: 1}.Validate())
+	assert.Error(t, Argon2idParams{Memory: 64, Time: 1, Parallelism: 0}.Validate())
+	assert.Error(t, Argon2idParams{Memory: 8, Time: 1, Parallelism: 2}.Validate())
+}
+
+func TestPasswordAuthenticator_CrossAlgorithm(t *testing.T) {
+	var (
+		bcryptAuth   = newTestPasswordAuthenticator()
+		argon2idAuth = NewArgon2idPasswordAuthenticator(testArgon2idParams)
+	)
+
+	bcryptHash, _ := bcryptAuth.HashPassword("password")
+	argon2idHash, _ := argon2idAuth.HashPassword("password")
+
+	t.Run("verify hashes of another algorithm", func(t *testing.T) {
+		ok, err := argon2idAuth.VerifyPassword("password", bcryptHash)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = bcryptAuth.VerifyPassword("password", argon2idHash)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("check if hashes need rehash", func(t *testing.T) {
+		assert.False(t, bcryptAuth.NeedsRehash(bcryptHash))
+		assert.True(t, bcryptAuth.NeedsRehash(argon2idHash))
+		assert.True(t, NewPasswordAuthenticator(bcrypt.MinCost+1).NeedsRehash(bcryptHash))
+
+		assert.False(t, argon2idAuth.NeedsRehash(argon2idHash))
+		assert.True(t, argon2idAuth.NeedsRehash(bcryptHash))
+		assert.True(t, NewArgon2idPasswordAuthenticator(Argon2idParams{Memory: 128, Time: 1, Parallelism: 1}).NeedsRehash(argon2idHash))
+	})
+}
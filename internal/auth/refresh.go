@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// refreshTokenSize is the count of random bytes a refresh token consists of.
+const refreshTokenSize = 32
+
+// NewRefreshToken generates a new random opaque refresh token.
+func NewRefreshToken() (string, error) {
+	bytes := make([]byte, refreshTokenSize)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// HashRefreshToken returns hash of a refresh token which is stored instead of the token itself.
+func HashRefreshToken(token string) string {
+	return hashToken(token)
+}
+
+// hashToken returns hash of a random token. Random tokens have enough entropy, so a fast hash function is sufficient.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,25 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewRefreshToken(t *testing.T) {
+	first, err := NewRefreshToken()
+	if assert.NoError(t, err) {
+		assert.Len(t, first, 43)
+	}
+
+	second, err := NewRefreshToken()
+	if assert.NoError(t, err) {
+		assert.NotEqual(t, first, second)
+	}
+}
+
+func TestHashRefreshToken(t *testing.T) {
+	hash := HashRefreshToken("some-refresh-token")
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, HashRefreshToken("some-refresh-token"))
+	assert.NotEqual(t, hash, HashRefreshToken("another-refresh-token"))
+}
@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// totpDigits is the count of digits of a TOTP code.
+	totpDigits = 6
+
+	// totpPeriod is the time step of TOTP codes.
+	totpPeriod = 30
+
+	// totpSkew is the count of time steps before and after the current one in which codes are accepted,
+	// it allows clocks of the server and of the client to drift.
+	totpSkew = 1
+
+	// totpSecretSize is the count of random bytes a TOTP secret consists of.
+	totpSecretSize = 20
+
+	// recoveryCodeSize is the count of characters of a recovery code without the separator.
+	recoveryCodeSize = 10
+)
+
+// totpEncoding is the encoding of TOTP secrets expected by authenticator apps.
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret generates a new random base32-encoded TOTP secret.
+func GenerateTOTPSecret() (string, error) {
+	bytes := make([]byte, totpSecretSize)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(bytes), nil
+}
+
+// TOTPURI returns an `otpauth://` URI which is used to add the secret to an authenticator app, usually as a QR code.
+func TOTPURI(issuer string, account string, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", strconv.Itoa(totpDigits))
+	query.Set("period", strconv.Itoa(totpPeriod))
+
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// totpStep returns the TOTP time step the given time belongs to.
+func totpStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+// hotp returns HOTP code (RFC 4226) of the given counter.
+func hotp(key []byte, counter int64) string {
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	// dynamic truncation:
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%modulo)
+}
+
+// decodeTOTPSecret decodes a base32-encoded TOTP secret, lowercase letters and padding are accepted.
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	return totpEncoding.DecodeString(strings.TrimRight(strings.ToUpper(secret), "="))
+}
+
+// TOTPCode returns TOTP code (RFC 6238) of the secret at the given time.
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, totpStep(t)), nil
+}
+
+// ValidateTOTP returns true and the time step of the code if the code is valid at the given time.
+// The step should be remembered to reject reuse of the same code.
+func ValidateTOTP(secret string, code string, t time.Time) (int64, bool, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return 0, false, err
+	}
+
+	current := totpStep(t)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(hotp(key, step)), []byte(code)) == 1 {
+			return step, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// IsTOTPCode returns true if the code looks like a TOTP code rather than a recovery code.
+func IsTOTPCode(code string) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// GenerateRecoveryCodes generates the given count of random one-time recovery codes
+// which are used instead of TOTP codes when the authenticator app is lost.
+func GenerateRecoveryCodes(count int) ([]string, error) {
+	codes := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		bytes := make([]byte, recoveryCodeSize*5/8)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(bytes))
+		codes = append(codes, code[:recoveryCodeSize/2]+"-"+code[recoveryCodeSize/2:])
+	}
+	return codes, nil
+}
+
+// IsRecoveryCode returns true if the code entered by a user has the format of a recovery code.
+func IsRecoveryCode(code string) bool {
+	code = NormalizeRecoveryCode(code)
+	if len(code) != recoveryCodeSize {
+		return false
+	}
+	_, err := totpEncoding.DecodeString(strings.ToUpper(code))
+	return err == nil
+}
+
+// NormalizeRecoveryCode removes separators and whitespaces from a recovery code entered by a user and lowercases it.
+func NormalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', ' ', '\t':
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+}
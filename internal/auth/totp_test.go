@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is base32 encoding of the SHA-1 secret "12345678901234567890" from RFC 6238 test vectors.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 test vectors, truncated to 6 digits:
+	testCases := []struct {
+		time int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, testCase := range testCases {
+		code, err := TOTPCode(rfc6238Secret, time.Unix(testCase.time, 0))
+		if assert.NoError(t, err) {
+			assert.Equal(t, testCase.code, code, "time=%d", testCase.time)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+
+	t.Run("validate the current code", func(t *testing.T) {
+		step, ok, err := ValidateTOTP(rfc6238Secret, "081804", now)
+		if assert.NoError(t, err) {
+			assert.True(t, ok)
+			assert.Equal(t, int64(1111111109/30), step)
+		}
+	})
+
+	t.Run("validate the code of the previous step", func(t *testing.T) {
+		_, ok, err := ValidateTOTP(rfc6238Secret, "081804", now.Add(30*time.Second))
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("validate an outdated code", func(t *testing.T) {
+		_, ok, err := ValidateTOTP(rfc6238Secret, "081804", now.Add(2*time.Minute))
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("validate a wrong code", func(t *testing.T) {
+		_, ok, err := ValidateTOTP(rfc6238Secret, "000000", now)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("validate with a malformed secret", func(t *testing.T) {
+		_, _, err := ValidateTOTP("not base32!", "081804", now)
+		assert.Error(t, err)
+	})
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if assert.NoError(t, err) {
+		assert.Len(t, secret, 32)
+		_, err := TOTPCode(secret, time.Now())
+		assert.NoError(t, err)
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("groshi", "jieggii", "JBSWY3DPEHPK3PXP")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/groshi:jieggii?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=groshi")
+	assert.Contains(t, uri, "digits=6")
+	assert.Contains(t, uri, "period=30")
+}
+
+func TestIsTOTPCode(t *testing.T) {
+	assert.True(t, IsTOTPCode("012345"))
+	assert.False(t, IsTOTPCode("01234"))
+	assert.False(t, IsTOTPCode("abcde-fghij"))
+}
+
+func TestIsRecoveryCode(t *testing.T) {
+	assert.True(t, IsRecoveryCode("abcde-fghij"))
+	assert.True(t, IsRecoveryCode(" ABCDE FGHIJ "))
+	assert.False(t, IsRecoveryCode("012345"))
+	assert.False(t, IsRecoveryCode("abcde-fghi1"))
+	assert.False(t, IsRecoveryCode("abcde-fghijk"))
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if assert.NoError(t, err) && assert.Len(t, codes, 10) {
+		for _, code := range codes {
+			assert.Len(t, code, 11)
+			assert.Equal(t, "-", code[5:6])
+			assert.Len(t, NormalizeRecoveryCode(code), 10)
+		}
+		assert.NotEqual(t, codes[0], codes[1])
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	assert.Equal(t, "abcdefghij", NormalizeRecoveryCode(" ABCDE-fghij "))
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+var _ bun.BeforeAppendModelHook = (*APIKey)(nil)
+
+// APIKey database model, represents a long-lived key which is used by automations instead of the user's password.
+type APIKey struct {
+	bun.BaseModel `bun:"table:api_keys,alias:api_key"`
+
+	ID   int64     `bun:"id,pk,autoincrement"`
+	UUID uuid.UUID `bun:"uuid,type:uuid,notnull,default:uuid_generate_v4()"`
+
+	Name string `bun:"name,notnull"`
+
+	// Beginning of the key which is shown to the user, and SHA-256 hash of the key. The key itself is not stored.
+	Prefix  string `bun:"prefix,notnull"`
+	KeyHash string `bun:"key_hash,notnull,unique"`
+
+	// Scopes granted to the key.
+	Scopes []string `bun:"scopes,array"`
+
+	Owner   User  `bun:"rel:belongs-to,join:owner_id=id"`
+	OwnerID int64 `bun:"owner_id,notnull"`
+
+	// Expiration time of the key, the key never expires if it is not set.
+	ExpiresAt time.Time `bun:"expires_at,nullzero"`
+
+	// Time when the key was used last time.
+	LastUsedAt time.Time `bun:"last_used_at,nullzero"`
+
+	CreatedAt time.Time `bun:",notnull,default:current_timestamp"`
+}
+
+func (k *APIKey) BeforeAppendModel(_ context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.InsertQuery:
+		k.CreatedAt = time.Now()
+	}
+	return nil
+}
+
+// APIKeyQuerier interface describes a type which executes database queries related to the [APIKey] model.
+type APIKeyQuerier interface {
+	CreateAPIKey(ctx context.Context, k *APIKey) error
+	SelectAPIKeyByUUID(ctx context.Context, uuid string, k *APIKey) error
+
+	// SelectAPIKeyByHash selects the API key with the given hash together with its owner.
+	SelectAPIKeyByHash(ctx context.Context, hash string, k *APIKey) error
+
+	// SelectAPIKeysByOwnerID selects API keys of the user, the most recent ones first.
+	SelectAPIKeysByOwnerID(ctx context.Context, ownerID int64, k *[]APIKey) error
+
+	// TouchAPIKey updates time when the API key was used last time.
+	TouchAPIKey(ctx context.Context, id int64) error
+
+	DeleteAPIKeyByID(ctx context.Context, id int64) error
+}
+
+func (d *DefaultDatabase) CreateAPIKey(ctx context.Context, k *APIKey) error {
+	if _, err := d.client.NewInsert().Model(k).Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectAPIKeyByUUID(ctx context.Context, uuid string, k *APIKey) error {
+	if err := d.client.NewSelect().Model(k).Where("uuid = ?", uuid).Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectAPIKeyByHash(ctx context.Context, hash string, k *APIKey) error {
+	if err := d.client.NewSelect().Model(k).Relation("Owner").Where("api_key.key_hash = ?", hash).Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectAPIKeysByOwnerID(ctx context.Context, ownerID int64, k *[]APIKey) error {
+	q := d.client.NewSelect().
+		Model(k).
+		Where("owner_id = ?", ownerID).
+		Order("created_at DESC", "id DESC")
+	if err := q.Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) TouchAPIKey(ctx context.Context, id int64) error {
+	if _, err := d.client.NewUpdate().
+		Model(sampleAPIKey).
+		Set("last_used_at = ?", time.Now()).
+		Where("id = ?", id).
+		Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) DeleteAPIKeyByID(ctx context.Context, id int64) error {
+	if _, err := d.client.NewDelete().Model(sampleAPIKey).Where("id = ?", id).Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
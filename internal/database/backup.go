@@ -0,0 +1,253 @@
+package database
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/uptrace/bun"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is the version of the database schema. It must be increased when models change
+// so that backups made with the previous version can not be restored.
+const SchemaVersion = 13
+
+// backupFormat identifies groshi backup files.
+const backupFormat = "groshi-backup"
+
+// backupBatchSize is the maximum count of rows inserted by a single query during restore.
+const backupBatchSize = 500
+
+// maxBackupLineSize is the maximum size of a line of the backup file.
+const maxBackupLineSize = 64 << 20
+
+var (
+	// ErrBackupIncompatible is returned when backup was made by an incompatible version of groshi.
+	ErrBackupIncompatible = errors.New("backup is incompatible")
+
+	// ErrBackupMalformed is returned when backup file could not be parsed.
+	ErrBackupMalformed = errors.New("backup is malformed")
+
+	// ErrDatabaseNotEmpty is returned when backup is restored into a database which already contains data.
+	ErrDatabaseNotEmpty = errors.New("database is not empty")
+)
+
+// BackupHeader is the first line of a backup file.
+type BackupHeader struct {
+	Format        string    `json:"format"`
+	SchemaVersion int       `json:"schema_version"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	// Names of the tables contained in the backup in the order they are restored in.
+	Tables []string `json:"tables"`
+}
+
+// backupRow is a line of a backup file which contains a row of a table.
+type backupRow struct {
+	Table string          `json:"table"`
+	Row   json.RawMessage `json:"row"`
+}
+
+// tables returns names of the tables of all models.
+func (d *DefaultDatabase) tables() []string {
+	tables := make([]string, 0, len(models))
+	for _, model := range models {
+		tables = append(tables, d.db.Table(reflect.TypeOf(model)).Name)
+	}
+	return tables
+}
+
+// Backup writes all rows of all tables to w as a gzip-compressed file of JSON lines. The first line is [BackupHeader],
+// each next line contains a row of a table. Rows are read in a single read-only transaction,
+// so that the backup is consistent.
+func (d *DefaultDatabase) Backup(ctx context.Context, w io.Writer) error {
+	compressor := gzip.NewWriter(w)
+	buffer := bufio.NewWriter(compressor)
+	encoder := json.NewEncoder(buffer)
+
+	header := &BackupHeader{
+		Format:        backupFormat,
+		SchemaVersion: SchemaVersion,
+		CreatedAt:     time.Now().UTC(),
+		Tables:        d.tables(),
+	}
+	if err := encoder.Encode(header); err != nil {
+		return err
+	}
+
+	txOptions := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	err := d.db.RunInTx(ctx, txOptions, func(ctx context.Context, tx bun.Tx) error {
+		for _, table := range header.Tables {
+			rows, err := tx.QueryContext(ctx, "SELECT to_jsonb(t)::text FROM ? AS t", bun.Ident(table))
+			if err != nil {
+				return err
+			}
+
+			for rows.Next() {
+				var row string
+				if err := rows.Scan(&row); err != nil {
+					_ = rows.Close()
+					return err
+				}
+				if err := encoder.Encode(&backupRow{Table: table, Row: json.RawMessage(row)}); err != nil {
+					_ = rows.Close()
+					return err
+				}
+			}
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			if err := rows.Close(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := buffer.Flush(); err != nil {
+		return err
+	}
+	return compressor.Close()
+}
+
+// Restore loads rows from the backup made by [DefaultDatabase.Backup] in a single transaction.
+// Tables must exist and be empty unless clean is true, in which case all data is deleted first.
+// Returns ErrBackupIncompatible if the backup was made with another schema version.
+func (d *DefaultDatabase) Restore(ctx context.Context, r io.Reader, clean bool) error {
+	decompressor, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBackupMalformed, err)
+	}
+	defer decompressor.Close()
+
+	scanner := bufio.NewScanner(decompressor)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBackupLineSize)
+
+	// read and validate the header:
+	header := &BackupHeader{}
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("%w: %s", ErrBackupMalformed, err)
+		}
+		return fmt.Errorf("%w: missing header", ErrBackupMalformed)
+	}
+	if err := json.Unmarshal(scanner.Bytes(), header); err != nil || header.Format != backupFormat {
+		return fmt.Errorf("%w: not a groshi backup", ErrBackupMalformed)
+	}
+	if header.SchemaVersion != SchemaVersion {
+		return fmt.Errorf("%w: schema version of the backup is %d, expected %d", ErrBackupIncompatible, header.SchemaVersion, SchemaVersion)
+	}
+	known := make(map[string]bool)
+	for _, table := range d.tables() {
+		known[table] = true
+	}
+	for _, table := range header.Tables {
+		if !known[table] {
+			return fmt.Errorf("%w: unknown table %q", ErrBackupIncompatible, table)
+		}
+	}
+
+	return d.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		tables := d.tables()
+
+		// delete existing data or check that there is no data:
+		if clean {
+			placeholders := make([]string, 0, len(tables))
+			idents := make([]interface{}, 0, len(tables))
+			for _, table := range tables {
+				placeholders = append(placeholders, "?")
+				idents = append(idents, bun.Ident(table))
+			}
+			query := "TRUNCATE " + strings.Join(placeholders, ", ") + " RESTART IDENTITY"
+			if _, err := tx.ExecContext(ctx, query, idents...); err != nil {
+				return err
+			}
+		} else {
+			for _, table := range tables {
+				exists, err := tx.NewSelect().TableExpr("?", bun.Ident(table)).Exists(ctx)
+				if err != nil {
+					return err
+				}
+				if exists {
+					return fmt.Errorf("%w: table %q contains data", ErrDatabaseNotEmpty, table)
+				}
+			}
+		}
+
+		// insert rows in batches, each batch contains rows of a single table:
+		var (
+			table string
+			batch = make([]json.RawMessage, 0, backupBatchSize)
+		)
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			rows, err := json.Marshal(batch)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx,
+				"INSERT INTO ? SELECT * FROM jsonb_populate_recordset(NULL::?, ?::jsonb)",
+				bun.Ident(table), bun.Ident(table), string(rows),
+			); err != nil {
+				return fmt.Errorf("could not restore rows of table %q: %w", table, err)
+			}
+			batch = batch[:0]
+			return nil
+		}
+
+		line := 1
+		for scanner.Scan() {
+			line++
+			row := &backupRow{}
+			if err := json.Unmarshal(scanner.Bytes(), row); err != nil || len(row.Row) == 0 {
+				return fmt.Errorf("%w: invalid row on line %d", ErrBackupMalformed, line)
+			}
+			if !known[row.Table] {
+				return fmt.Errorf("%w: unknown table %q on line %d", ErrBackupMalformed, row.Table, line)
+			}
+
+			if row.Table != table || len(batch) == backupBatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+				table = row.Table
+			}
+			batch = append(batch, row.Row)
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("%w: %s", ErrBackupMalformed, err)
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+
+		// move sequences of the auto-incremented primary keys past the restored rows:
+		for _, model := range models {
+			schema := d.db.Table(reflect.TypeOf(model))
+			for _, pk := range schema.PKs {
+				if !pk.AutoIncrement {
+					continue
+				}
+				if _, err := tx.ExecContext(ctx,
+					"SELECT setval(pg_get_serial_sequence(?, ?), coalesce((SELECT max(?) FROM ?), 0) + 1, false)",
+					schema.Name, pk.Name, bun.Ident(pk.Name), bun.Ident(schema.Name),
+				); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testDatabase connects to the PostgreSQL database provided by GROSHI_TEST_POSTGRES_* environmental variables
+// and initializes it. The test is skipped if GROSHI_TEST_POSTGRES_HOST is not set.
+// All data is deleted from the database, so it must not be used for anything else.
+func testDatabase(t *testing.T) *DefaultDatabase {
+	host := os.Getenv("GROSHI_TEST_POSTGRES_HOST")
+	if host == "" {
+		t.Skip("GROSHI_TEST_POSTGRES_HOST is not set")
+	}
+	port, err := strconv.Atoi(os.Getenv("GROSHI_TEST_POSTGRES_PORT"))
+	if err != nil {
+		port = 5432
+	}
+
+	db := New(Credentials{
+		Host:     host,
+		Port:     port,
+		User:     os.Getenv("GROSHI_TEST_POSTGRES_USER"),
+		Password: os.Getenv("GROSHI_TEST_POSTGRES_PASSWORD"),
+		Database: os.Getenv("GROSHI_TEST_POSTGRES_DATABASE"),
+	})
+	if err := db.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// gzipLines returns gzip-compressed lines.
+func gzipLines(lines ...string) []byte {
+	buffer := &bytes.Buffer{}
+	compressor := gzip.NewWriter(buffer)
+	for _, line := range lines {
+		if _, err := compressor.Write([]byte(line + "\n")); err != nil {
+			panic(err)
+		}
+	}
+	if err := compressor.Close(); err != nil {
+		panic(err)
+	}
+	return buffer.Bytes()
+}
+
+// backupHeaderLine returns the header line of a backup.
+func backupHeaderLine(header *BackupHeader) string {
+	line, err := json.Marshal(header)
+	if err != nil {
+		panic(err)
+	}
+	return string(line)
+}
+
+func TestDefaultDatabase_Restore_Header(t *testing.T) {
+	// the header is validated before connecting to the database, so the database does not have to be available:
+	db := New(Credentials{Host: "localhost", Port: 5432, User: "groshi", Database: "groshi"})
+
+	testCases := []struct {
+		name   string
+		backup []byte
+		err    error
+	}{
+		{"not compressed", []byte(`{"format":"groshi-backup"}`), ErrBackupMalformed},
+		{"missing header", gzipLines(), ErrBackupMalformed},
+		{"malformed header", gzipLines("not json"), ErrBackupMalformed},
+		{
+			"another format",
+			gzipLines(backupHeaderLine(&BackupHeader{Format: "another-backup", SchemaVersion: SchemaVersion})),
+			ErrBackupMalformed,
+		},
+		{
+			"another schema version",
+			gzipLines(backupHeaderLine(&BackupHeader{Format: backupFormat, SchemaVersion: SchemaVersion - 1})),
+			ErrBackupIncompatible,
+		},
+		{
+			"unknown table",
+			gzipLines(backupHeaderLine(&BackupHeader{Format: backupFormat, SchemaVersion: SchemaVersion, Tables: []string{"unknown"}})),
+			ErrBackupIncompatible,
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := db.Restore(context.Background(), bytes.NewReader(testCase.backup), false)
+		assert.ErrorIs(t, err, testCase.err, testCase.name)
+	}
+}
+
+func TestDefaultDatabase_BackupRestore(t *testing.T) {
+	db := testDatabase(t)
+	ctx := context.Background()
+
+	// start from an empty database by restoring an empty backup:
+	if err := db.Restore(ctx, bytes.NewReader(gzipLines(backupHeaderLine(&BackupHeader{
+		Format:        backupFormat,
+		SchemaVersion: SchemaVersion,
+		CreatedAt:     time.Now(),
+		Tables:        db.tables(),
+	}))), true); err != nil {
+		t.Fatal(err)
+	}
+
+	// create some data and back it up:
+	user := &User{UUID: uuid.New(), Username: "jieggii", Password: "hash"}
+	if err := db.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	ledger := &Ledger{}
+	if err := db.SelectOrCreatePersonalLedger(ctx, user.ID, ledger); err != nil {
+		t.Fatal(err)
+	}
+	category := &Category{UUID: uuid.New(), Name: "Food", LedgerID: ledger.ID, OwnerID: user.ID}
+	if err := db.CreateCategory(ctx, category); err != nil {
+		t.Fatal(err)
+	}
+	backup := &bytes.Buffer{}
+	if !assert.NoError(t, db.Backup(ctx, backup)) {
+		return
+	}
+
+	// the backup can not be restored into a database which contains data:
+	err := db.Restore(ctx, bytes.NewReader(backup.Bytes()), false)
+	assert.ErrorIs(t, err, ErrDatabaseNotEmpty)
+
+	// restore the backup after deleting all data:
+	if !assert.NoError(t, db.Restore(ctx, bytes.NewReader(backup.Bytes()), true)) {
+		return
+	}
+	restoredUser := &User{}
+	if assert.NoError(t, db.SelectUserByUsername(ctx, "jieggii", restoredUser)) {
+		assert.Equal(t, user.UUID, restoredUser.UUID)
+	}
+	restoredCategory := &Category{}
+	if assert.NoError(t, db.SelectCategoryByUUID(ctx, category.UUID.String(), restoredCategory)) {
+		assert.Equal(t, ledger.ID, restoredCategory.LedgerID)
+	}
+
+	// new rows get IDs after the restored ones:
+	another := &User{UUID: uuid.New(), Username: "alice", Password: "hash"}
+	if assert.NoError(t, db.CreateUser(ctx, another)) {
+		assert.Greater(t, another.ID, user.ID)
+	}
+}
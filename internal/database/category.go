@@ -15,26 +15,47 @@ type Category struct {
 
 	Name string `bun:",notnull"`
 
+	// Ledger the category belongs to.
+	Ledger   Ledger `bun:"rel:belongs-to,join:ledger_id=id"`
+	LedgerID int64  `bun:"ledger_id,notnull"`
+
+	// User who created the category.
 	Owner   User  `bun:"rel:belongs-to,join:owner_id=id"`
 	OwnerID int64 `bun:"owner_id,notnull"`
 }
 
+// CategoryStat represents aggregated statistics of transactions attributed to a category.
+type CategoryStat struct {
+	CategoryUUID uuid.UUID `bun:"category_uuid"`
+	CategoryName string    `bun:"category_name"`
+
+	// Count of transactions attributed to the category, either entirely or by a split line.
+	TransactionsCount int64 `bun:"transactions_count"`
+
+	// Sum of amounts attributed to the category converted to the base currency (the one which has rate equal to 1).
+	BaseAmount float64 `bun:"base_amount"`
+}
+
 // CategoryQuerier interface describes a type which executes database queries related to the [Category] model.
 type CategoryQuerier interface {
 	CreateCategory(ctx context.Context, c *Category) error
 	CategoryExistsByUUID(ctx context.Context, uuid string) (bool, error)
 	SelectCategoryByUUID(ctx context.Context, uuid string, c *Category) error
-	SelectCategoriesByOwnerID(ctx context.Context, ownerID int64, c *[]Category) error
+	SelectCategoriesByLedgerID(ctx context.Context, ledgerID int64, c *[]Category) error
 	UpdateCategory(ctx context.Context, c *Category) error
 	DeleteCategoryByID(ctx context.Context, id int64) error
+
+	// SelectCategoryStats returns statistics of transactions which match the given filter grouped by categories.
+	// Split transactions are attributed to categories of their split lines.
+	SelectCategoryStats(ctx context.Context, f *TransactionFilter, s *[]CategoryStat) error
 }
 
 func (d *DefaultDatabase) selectCategoryByUUIDQuery(uuid string) *bun.SelectQuery {
 	return d.client.NewSelect().Model(sampleCategory).Where("uuid = ?", uuid)
 }
 
-func (d *DefaultDatabase) selectCategoriesByOwnerIDQuery(ownerId int64) *bun.SelectQuery {
-	return d.client.NewSelect().Model(sampleCategory).Where("owner_id = ?", ownerId)
+func (d *DefaultDatabase) selectCategoriesByLedgerIDQuery(ledgerID int64) *bun.SelectQuery {
+	return d.client.NewSelect().Model(sampleCategory).Where("ledger_id = ?", ledgerID)
 }
 
 func (d *DefaultDatabase) CreateCategory(ctx context.Context, c *Category) error {
@@ -55,8 +76,8 @@ func (d *DefaultDatabase) SelectCategoryByUUID(ctx context.Context, uuid string,
 	return nil
 }
 
-func (d *DefaultDatabase) SelectCategoriesByOwnerID(ctx context.Context, ownerID int64, c *[]Category) error {
-	if err := d.selectCategoriesByOwnerIDQuery(ownerID).Scan(ctx, c); err != nil {
+func (d *DefaultDatabase) SelectCategoriesByLedgerID(ctx context.Context, ledgerID int64, c *[]Category) error {
+	if err := d.selectCategoriesByLedgerIDQuery(ledgerID).Scan(ctx, c); err != nil {
 		return err
 	}
 	return nil
@@ -70,7 +91,38 @@ func (d *DefaultDatabase) UpdateCategory(ctx context.Context, c *Category) error
 }
 
 func (d *DefaultDatabase) DeleteCategoryByID(ctx context.Context, id int64) error {
-	if _, err := d.client.NewDelete().Model(sampleCategory).Where("id = ?", id).Exec(ctx); err != nil {
+	return d.client.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		// remove the category from actions of the rules:
+		if _, err := tx.NewUpdate().
+			Model(sampleRule).
+			Set("category_id = NULL").
+			Where("category_id = ?", id).
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		if _, err := tx.NewDelete().Model(sampleCategory).Where("id = ?", id).Exec(ctx); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func (d *DefaultDatabase) SelectCategoryStats(ctx context.Context, f *TransactionFilter, s *[]CategoryStat) error {
+	q := d.client.NewSelect().
+		TableExpr("transactions AS transaction").
+		ColumnExpr("category.uuid AS category_uuid").
+		ColumnExpr("category.name AS category_name").
+		ColumnExpr("count(DISTINCT transaction.id) AS transactions_count").
+		ColumnExpr("sum(coalesce(split.amount, transaction.amount) / currency.rate) AS base_amount").
+		Join("JOIN currencies AS currency ON currency.id = transaction.currency_id").
+		Join("LEFT JOIN transaction_splits AS split ON split.transaction_id = transaction.id").
+		Join("JOIN categories AS category ON category.id = coalesce(split.category_id, transaction.category_id)").
+		GroupExpr("category.id").
+		OrderExpr("category.name ASC")
+	q = f.apply(q)
+
+	if err := q.Scan(ctx, s); err != nil {
 		return err
 	}
 	return nil
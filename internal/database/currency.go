@@ -34,6 +34,9 @@ func (c *Currency) BeforeAppendModel(_ context.Context, query bun.Query) error {
 
 type CurrencyQuerier interface {
 	SelectCurrencyByCode(ctx context.Context, code string, c *Currency) error
+
+	// SelectTransactionCurrencies selects currencies of the transactions which match the given filter ordered by code.
+	SelectTransactionCurrencies(ctx context.Context, f *TransactionFilter, c *[]Currency) error
 }
 
 func (d *DefaultDatabase) selectCurrencyByCodeQuery(code string) *bun.SelectQuery {
@@ -46,3 +49,18 @@ func (d *DefaultDatabase) SelectCurrencyByCode(ctx context.Context, code string,
 	}
 	return nil
 }
+
+func (d *DefaultDatabase) SelectTransactionCurrencies(ctx context.Context, f *TransactionFilter, c *[]Currency) error {
+	used := f.apply(d.client.NewSelect().
+		TableExpr("transactions AS transaction").
+		Column("transaction.currency_id"))
+
+	q := d.client.NewSelect().
+		Model(c).
+		Where("id IN (?)", used).
+		Order("code ASC")
+	if err := q.Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/dialect/pgdialect"
@@ -21,16 +22,80 @@ var (
 
 	// Sample of the [Transaction] database model.
 	sampleTransaction = (*Transaction)(nil)
+
+	// Sample of the [TransactionSplit] database model.
+	sampleTransactionSplit = (*TransactionSplit)(nil)
+
+	// Sample of the [Payee] database model.
+	samplePayee = (*Payee)(nil)
+
+	// Sample of the [Rule] database model.
+	sampleRule = (*Rule)(nil)
+
+	// Sample of the [ImportReport] database model.
+	sampleImportReport = (*ImportReport)(nil)
+
+	// Sample of the [Tag] database model.
+	sampleTag = (*Tag)(nil)
+
+	// Sample of the [TransactionTag] database model.
+	sampleTransactionTag = (*TransactionTag)(nil)
+
+	// Sample of the [Session] database model.
+	sampleSession = (*Session)(nil)
+
+	// Sample of the [RefreshToken] database model.
+	sampleRefreshToken = (*RefreshToken)(nil)
+
+	// Sample of the [RevokedToken] database model.
+	sampleRevokedToken = (*RevokedToken)(nil)
+
+	// Sample of the [APIKey] database model.
+	sampleAPIKey = (*APIKey)(nil)
+
+	// Sample of the [LoginFailure] database model.
+	sampleLoginFailure = (*LoginFailure)(nil)
+
+	// Sample of the [ExternalIdentity] database model.
+	sampleExternalIdentity = (*ExternalIdentity)(nil)
+
+	// Sample of the [InviteCode] database model.
+	sampleInviteCode = (*InviteCode)(nil)
+
+	// Sample of the [Ledger] database model.
+	sampleLedger = (*Ledger)(nil)
+
+	// Sample of the [LedgerMember] database model.
+	sampleLedgerMember = (*LedgerMember)(nil)
+
+	// Sample of the [ExpenseShare] database model.
+	sampleExpenseShare = (*ExpenseShare)(nil)
+
+	// Sample of the [SchemaMigration] database model.
+	sampleSchemaMigration = (*SchemaMigration)(nil)
 )
 
 var (
 	// Model samples which are used to create tables.
-	models = []any{sampleUser, sampleUser, sampleCurrency, sampleTransaction}
+	models = []any{sampleUser, sampleLedger, sampleLedgerMember, sampleCategory, sampleCurrency, samplePayee, sampleTag, sampleTransaction, sampleTransactionSplit, sampleExpenseShare, sampleTransactionTag, sampleRule, sampleImportReport, sampleSession, sampleRefreshToken, sampleRevokedToken, sampleAPIKey, sampleLoginFailure, sampleExternalIdentity, sampleInviteCode}
 
 	// PostgreSQL extensions that should be created.
 	extensions = []string{"uuid-ossp"}
 )
 
+// ErrUniqueViolation is returned by implementations of [Database] other than [DefaultDatabase]
+// when a query violates a unique constraint. Use [IsUniqueViolation] to check errors of any implementation.
+var ErrUniqueViolation = errors.New("unique constraint violation")
+
+// IsUniqueViolation returns true if the error is caused by a violation of a unique constraint.
+func IsUniqueViolation(err error) bool {
+	var pgErr pgdriver.Error
+	if errors.As(err, &pgErr) {
+		return pgErr.Field('C') == "23505"
+	}
+	return errors.Is(err, ErrUniqueViolation)
+}
+
 // Credentials represents PostgreSQL database credentials.
 type Credentials struct {
 	Host     string
@@ -44,15 +109,35 @@ type Database interface {
 	TestConnection() error
 	Init(ctx context.Context) error
 
+	// RunInTx runs fn in a database transaction, queries made using db passed to fn are executed in this transaction.
+	// The transaction is committed if fn returns nil and is rolled back otherwise.
+	RunInTx(ctx context.Context, fn func(ctx context.Context, db Database) error) error
+
 	UserQuerier
 	CategoryQuerier
 	CurrencyQuerier
 	TransactionQuerier
+	TagQuerier
+	PayeeQuerier
+	RuleQuerier
+	ImportReportQuerier
+	SessionQuerier
+	RefreshTokenQuerier
+	APIKeyQuerier
+	LoginFailureQuerier
+	ExternalIdentityQuerier
+	InviteCodeQuerier
+	LedgerQuerier
+	ExpenseQuerier
 }
 
 // DefaultDatabase is the default implementation of the [Database] interface
 type DefaultDatabase struct {
-	client *bun.DB
+	// Connection pool to the database.
+	db *bun.DB
+
+	// Client which executes queries: either the connection pool or a transaction started by [DefaultDatabase.RunInTx].
+	client bun.IDB
 }
 
 // New creates a new instance of [DefaultDatabase] and returns a pointer to it.
@@ -60,20 +145,25 @@ func New(c Credentials) *DefaultDatabase {
 	dsn := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable", c.User, c.Password, c.Host, c.Port, c.Database)
 	sqlDb := sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn)))
 	bunDb := bun.NewDB(sqlDb, pgdialect.New())
+
+	// many-to-many relation models must be registered before use:
+	bunDb.RegisterModel(sampleTransactionTag)
+
 	return &DefaultDatabase{
+		db:     bunDb,
 		client: bunDb,
 	}
 }
 
 // TestConnection tests database connection.
 func (d *DefaultDatabase) TestConnection() error {
-	if err := d.client.Ping(); err != nil {
+	if err := d.db.Ping(); err != nil {
 		return err
 	}
 	return nil
 }
 
-// Init creates all necessary tables and extensions if they do not exist.
+// Init creates all necessary tables and extensions if they do not exist and migrates tables created by previous versions.
 func (d *DefaultDatabase) Init(ctx context.Context) error {
 	// create necessary extensions if they do not exist:
 	for _, extension := range extensions {
@@ -90,5 +180,17 @@ func (d *DefaultDatabase) Init(ctx context.Context) error {
 		}
 	}
 
+	// migrate existing tables:
+	if err := d.migrate(ctx); err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// RunInTx runs fn in a database transaction. If it is called inside of another transaction, a savepoint is used.
+func (d *DefaultDatabase) RunInTx(ctx context.Context, fn func(ctx context.Context, db Database) error) error {
+	return d.client.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		return fn(ctx, &DefaultDatabase{db: d.db, client: tx})
+	})
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"github.com/uptrace/bun"
+)
+
+// ErrUserHasBalance is returned when a user who owes or is owed money by shared expenses of a ledger is deleted.
+var ErrUserHasBalance = errors.New("user has a non-zero balance in a ledger")
+
+// ExpenseShare database model, represents part of an expense shared between ledger members which the user owes
+// to the payer of the expense. Amounts of all shares of a transaction sum up to the negated transaction amount.
+type ExpenseShare struct {
+	bun.BaseModel `bun:"table:expense_shares,alias:share"`
+
+	ID int64 `bun:"id,pk,autoincrement"`
+
+	TransactionID int64 `bun:"transaction_id,notnull"`
+
+	User   User  `bun:"rel:belongs-to,join:user_id=id"`
+	UserID int64 `bun:"user_id,notnull"`
+
+	// Amount owed by the user, always positive.
+	Amount int32 `bun:"amount,notnull"`
+}
+
+// LedgerDebt represents total amount which the debtor owes to the creditor in a currency by shared expenses of a ledger.
+// UUIDs and usernames of deleted users are empty.
+type LedgerDebt struct {
+	DebtorID       int64  `bun:"debtor_id"`
+	DebtorUUID     string `bun:"debtor_uuid"`
+	DebtorUsername string `bun:"debtor_username"`
+
+	CreditorID       int64  `bun:"creditor_id"`
+	CreditorUUID     string `bun:"creditor_uuid"`
+	CreditorUsername string `bun:"creditor_username"`
+
+	CurrencyCode string `bun:"currency_code"`
+	Amount       int64  `bun:"amount"`
+}
+
+// ExpenseQuerier interface describes a type which executes database queries related to the [ExpenseShare] model.
+type ExpenseQuerier interface {
+	// SelectLedgerDebts selects debts between members of the ledger by its shared expenses and settlements.
+	// Debts are summed up for each debtor, creditor and currency, but mutual debts are not netted out.
+	SelectLedgerDebts(ctx context.Context, ledgerID int64, d *[]LedgerDebt) error
+}
+
+// userHasBalance returns true if the user owes or is owed money by shared expenses of any ledger in any currency.
+func userHasBalance(ctx context.Context, db bun.IDB, userID int64) (bool, error) {
+	return db.NewSelect().
+		TableExpr("expense_shares AS share").
+		ColumnExpr("transaction.ledger_id").
+		Join("JOIN transactions AS transaction ON transaction.id = share.transaction_id").
+		Where("share.user_id <> transaction.payer_id").
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("share.user_id = ?", userID).WhereOr("transaction.payer_id = ?", userID)
+		}).
+		GroupExpr("transaction.ledger_id, transaction.currency_id").
+		Having("sum(CASE WHEN transaction.payer_id = ? THEN share.amount ELSE -share.amount END) <> 0", userID).
+		Exists(ctx)
+}
+
+func (d *DefaultDatabase) SelectLedgerDebts(ctx context.Context, ledgerID int64, debts *[]LedgerDebt) error {
+	if err := d.client.NewSelect().
+		TableExpr("expense_shares AS share").
+		ColumnExpr("share.user_id AS debtor_id, coalesce(debtor.uuid::text, '') AS debtor_uuid, coalesce(debtor.username, '') AS debtor_username").
+		ColumnExpr("transaction.payer_id AS creditor_id, coalesce(creditor.uuid::text, '') AS creditor_uuid, coalesce(creditor.username, '') AS creditor_username").
+		ColumnExpr("currency.code AS currency_code").
+		ColumnExpr("sum(share.amount) AS amount").
+		Join("JOIN transactions AS transaction ON transaction.id = share.transaction_id").
+		Join("JOIN currencies AS currency ON currency.id = transaction.currency_id").
+		// debts of deleted users are still selected, so that balances of other members add up:
+		Join("LEFT JOIN users AS debtor ON debtor.id = share.user_id").
+		Join("LEFT JOIN users AS creditor ON creditor.id = transaction.payer_id").
+		Where("transaction.ledger_id = ?", ledgerID).
+		Where("share.user_id <> transaction.payer_id").
+		GroupExpr("share.user_id, debtor.uuid, debtor.username, transaction.payer_id, creditor.uuid, creditor.username, currency.code").
+		Scan(ctx, debts); err != nil {
+		return err
+	}
+	return nil
+}
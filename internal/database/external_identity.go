@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+var _ bun.BeforeAppendModelHook = (*ExternalIdentity)(nil)
+
+// ExternalIdentity database model, represents a link between a user and its identity at an external identity provider.
+type ExternalIdentity struct {
+	bun.BaseModel `bun:"table:external_identities,alias:external_identity"`
+
+	ID int64 `bun:"id,pk,autoincrement"`
+
+	// Issuer of the identity provider and subject which identifies the user at it.
+	Issuer  string `bun:"issuer,notnull,unique:issuer_subject"`
+	Subject string `bun:"subject,notnull,unique:issuer_subject"`
+
+	Owner   User  `bun:"rel:belongs-to,join:owner_id=id"`
+	OwnerID int64 `bun:"owner_id,notnull"`
+
+	CreatedAt time.Time `bun:",notnull,default:current_timestamp"`
+}
+
+func (i *ExternalIdentity) BeforeAppendModel(_ context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.InsertQuery:
+		i.CreatedAt = time.Now()
+	}
+	return nil
+}
+
+// ExternalIdentityQuerier interface describes a type which executes database queries related to the [ExternalIdentity] model.
+type ExternalIdentityQuerier interface {
+	CreateExternalIdentity(ctx context.Context, i *ExternalIdentity) error
+
+	// SelectExternalIdentity selects the identity with the given issuer and subject together with its owner.
+	SelectExternalIdentity(ctx context.Context, issuer string, subject string, i *ExternalIdentity) error
+}
+
+func (d *DefaultDatabase) CreateExternalIdentity(ctx context.Context, i *ExternalIdentity) error {
+	if _, err := d.client.NewInsert().Model(i).Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectExternalIdentity(ctx context.Context, issuer string, subject string, i *ExternalIdentity) error {
+	q := d.client.NewSelect().
+		Model(i).
+		Relation("Owner").
+		Where("external_identity.issuer = ?", issuer).
+		Where("external_identity.subject = ?", subject)
+	if err := q.Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+var _ bun.BeforeAppendModelHook = (*ImportReport)(nil)
+
+// ImportReport database model, represents outcome of importing a file with transactions.
+type ImportReport struct {
+	bun.BaseModel `bun:"table:import_reports,alias:report"`
+
+	ID   int64     `bun:"id,pk,autoincrement"`
+	UUID uuid.UUID `bun:"uuid,type:uuid,notnull,default:uuid_generate_v4()"`
+
+	// Name of the imported file and its format.
+	Filename string `bun:"filename,notnull"`
+	Format   string `bun:"format,notnull"`
+
+	// Count of the created transactions.
+	Imported int `bun:"imported,notnull"`
+
+	// Count of the entries which were already imported before and were skipped.
+	Skipped int `bun:"skipped,notnull"`
+
+	// Errors of the rows which could not be imported, nothing is imported if there are errors.
+	Errors []ImportReportError `bun:"errors,type:jsonb"`
+
+	Owner   User  `bun:"rel:belongs-to,join:owner_id=id"`
+	OwnerID int64 `bun:"owner_id,notnull"`
+
+	CreatedAt time.Time `bun:",notnull,default:current_timestamp"`
+}
+
+// ImportReportError represents an error of a single row of an imported file.
+type ImportReportError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (r *ImportReport) BeforeAppendModel(_ context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.InsertQuery:
+		r.CreatedAt = time.Now()
+	}
+	return nil
+}
+
+// ImportReportQuerier interface describes a type which executes database queries related to the [ImportReport] model.
+type ImportReportQuerier interface {
+	CreateImportReport(ctx context.Context, r *ImportReport) error
+	SelectImportReportByUUID(ctx context.Context, uuid string, r *ImportReport) error
+
+	// SelectImportReportsByOwnerID selects import reports of the user, the most recent ones first.
+	SelectImportReportsByOwnerID(ctx context.Context, ownerID int64, r *[]ImportReport) error
+}
+
+func (d *DefaultDatabase) CreateImportReport(ctx context.Context, r *ImportReport) error {
+	if _, err := d.client.NewInsert().Model(r).Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectImportReportByUUID(ctx context.Context, uuid string, r *ImportReport) error {
+	if err := d.client.NewSelect().Model(r).Where("uuid = ?", uuid).Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectImportReportsByOwnerID(ctx context.Context, ownerID int64, r *[]ImportReport) error {
+	q := d.client.NewSelect().
+		Model(r).
+		Where("owner_id = ?", ownerID).
+		Order("created_at DESC", "id DESC")
+	if err := q.Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
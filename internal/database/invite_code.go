@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+var _ bun.BeforeAppendModelHook = (*InviteCode)(nil)
+
+// InviteCode database model, represents a code which allows to create a new user when registration is invite-only.
+type InviteCode struct {
+	bun.BaseModel `bun:"table:invite_codes,alias:invite_code"`
+
+	ID   int64     `bun:"id,pk,autoincrement"`
+	UUID uuid.UUID `bun:"uuid,type:uuid,notnull,default:uuid_generate_v4()"`
+
+	// SHA-256 hash of the code. The code itself is not stored.
+	CodeHash string `bun:"code_hash,notnull,unique"`
+
+	// Count of users which can be created with the code and count of users which have been created with it.
+	MaxUses int `bun:"max_uses,notnull"`
+	Uses    int `bun:"uses,notnull,default:0"`
+
+	// User who created the code, users created with the code are recorded as invited by this user.
+	Creator   User  `bun:"rel:belongs-to,join:creator_id=id"`
+	CreatorID int64 `bun:"creator_id,notnull"`
+
+	ExpiresAt time.Time `bun:"expires_at,notnull"`
+	CreatedAt time.Time `bun:",notnull,default:current_timestamp"`
+}
+
+func (c *InviteCode) BeforeAppendModel(_ context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.InsertQuery:
+		c.CreatedAt = time.Now()
+	}
+	return nil
+}
+
+// InviteCodeQuerier interface describes a type which executes database queries related to the [InviteCode] model.
+type InviteCodeQuerier interface {
+	CreateInviteCode(ctx context.Context, c *InviteCode) error
+	SelectInviteCodeByUUID(ctx context.Context, uuid string, c *InviteCode) error
+	SelectInviteCodeByHash(ctx context.Context, hash string, c *InviteCode) error
+
+	// SelectInviteCodesByCreatorID selects invite codes created by the user, the most recent ones first.
+	SelectInviteCodesByCreatorID(ctx context.Context, creatorID int64, c *[]InviteCode) error
+
+	// UseInviteCode increases count of uses of the invite code.
+	// Returns false if the code has expired or has been used the maximal count of times.
+	UseInviteCode(ctx context.Context, id int64) (bool, error)
+
+	DeleteInviteCodeByID(ctx context.Context, id int64) error
+}
+
+func (d *DefaultDatabase) CreateInviteCode(ctx context.Context, c *InviteCode) error {
+	if _, err := d.client.NewInsert().Model(c).Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectInviteCodeByUUID(ctx context.Context, uuid string, c *InviteCode) error {
+	if err := d.client.NewSelect().Model(c).Where("uuid = ?", uuid).Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectInviteCodeByHash(ctx context.Context, hash string, c *InviteCode) error {
+	if err := d.client.NewSelect().Model(c).Where("code_hash = ?", hash).Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectInviteCodesByCreatorID(ctx context.Context, creatorID int64, c *[]InviteCode) error {
+	q := d.client.NewSelect().
+		Model(c).
+		Where("creator_id = ?", creatorID).
+		Order("created_at DESC", "id DESC")
+	if err := q.Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) UseInviteCode(ctx context.Context, id int64) (bool, error) {
+	q := d.client.NewUpdate().
+		Model(sampleInviteCode).
+		Set("uses = uses + 1").
+		Where("id = ?", id).
+		Where("uses < max_uses").
+		Where("expires_at > ?", time.Now())
+	return updatedOne(ctx, q)
+}
+
+func (d *DefaultDatabase) DeleteInviteCodeByID(ctx context.Context, id int64) error {
+	if _, err := d.client.NewDelete().Model(sampleInviteCode).Where("id = ?", id).Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
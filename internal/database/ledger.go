@@ -0,0 +1,274 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// Roles of ledger members, each role includes permissions of the previous ones.
+const (
+	// LedgerRoleViewer can read categories and transactions of the ledger.
+	LedgerRoleViewer = "viewer"
+
+	// LedgerRoleEditor can also create and change categories and transactions of the ledger.
+	LedgerRoleEditor = "editor"
+
+	// LedgerRoleOwner can also rename the ledger and manage its members.
+	LedgerRoleOwner = "owner"
+)
+
+// ErrLastLedgerOwner is returned when the only owner of a ledger is demoted or removed.
+var ErrLastLedgerOwner = errors.New("ledger must have at least one owner")
+
+// ledgerRoleRanks maps ledger roles to their ranks, a role with a higher rank includes permissions of lower ones.
+var ledgerRoleRanks = map[string]int{
+	LedgerRoleViewer: 1,
+	LedgerRoleEditor: 2,
+	LedgerRoleOwner:  3,
+}
+
+// LedgerRoleAllows returns true if the ledger role grants permissions of the required role.
+func LedgerRoleAllows(role string, required string) bool {
+	rank, ok := ledgerRoleRanks[role]
+	return ok && rank >= ledgerRoleRanks[required]
+}
+
+var _ bun.BeforeAppendModelHook = (*Ledger)(nil)
+
+// Ledger database model, owns categories and transactions which are shared between its members.
+type Ledger struct {
+	bun.BaseModel `bun:"table:ledgers,alias:ledger"`
+
+	ID   int64     `bun:"id,pk,autoincrement"`
+	UUID uuid.UUID `bun:"uuid,type:uuid,notnull,default:uuid_generate_v4()"`
+
+	Name string `bun:"name,notnull"`
+
+	// ID of the user whose personal ledger this is, zero for shared ledgers.
+	// Personal ledger is created for every user on demand and can not be shared.
+	PersonalUserID int64 `bun:"personal_user_id,nullzero,unique"`
+
+	CreatedAt time.Time `bun:",notnull,default:current_timestamp"`
+}
+
+func (l *Ledger) BeforeAppendModel(_ context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.InsertQuery:
+		l.CreatedAt = time.Now()
+	}
+	return nil
+}
+
+// LedgerMember database model, grants a user access to a ledger with the given role.
+type LedgerMember struct {
+	bun.BaseModel `bun:"table:ledger_members,alias:member"`
+
+	ID int64 `bun:"id,pk,autoincrement"`
+
+	Ledger   Ledger `bun:"rel:belongs-to,join:ledger_id=id"`
+	LedgerID int64  `bun:"ledger_id,notnull,unique:ledger_user"`
+
+	User   User  `bun:"rel:belongs-to,join:user_id=id"`
+	UserID int64 `bun:"user_id,notnull,unique:ledger_user"`
+
+	Role string `bun:"role,notnull"`
+
+	CreatedAt time.Time `bun:",notnull,default:current_timestamp"`
+}
+
+func (m *LedgerMember) BeforeAppendModel(_ context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.InsertQuery:
+		m.CreatedAt = time.Now()
+	}
+	return nil
+}
+
+// LedgerQuerier interface describes a type which executes database queries related to the [Ledger] and [LedgerMember] models.
+type LedgerQuerier interface {
+	// CreateLedger creates a new shared ledger and makes the user its owner.
+	CreateLedger(ctx context.Context, l *Ledger, ownerID int64) error
+
+	// SelectOrCreatePersonalLedger selects personal ledger of the user, it is created if the user does not have it yet.
+	SelectOrCreatePersonalLedger(ctx context.Context, userID int64, l *Ledger) error
+
+	SelectLedgerByUUID(ctx context.Context, uuid string, l *Ledger) error
+	UpdateLedgerName(ctx context.Context, id int64, name string) error
+
+	CreateLedgerMember(ctx context.Context, m *LedgerMember) error
+
+	// SelectLedgerMember selects membership of the user in the ledger.
+	SelectLedgerMember(ctx context.Context, ledgerID int64, userID int64, m *LedgerMember) error
+
+	// SelectLedgerMembers selects members of the ledger together with their users ordered by usernames.
+	SelectLedgerMembers(ctx context.Context, ledgerID int64, m *[]LedgerMember) error
+
+	// SelectLedgerMembershipsByUserID selects memberships of the user together with their ledgers ordered by ledger names.
+	SelectLedgerMembershipsByUserID(ctx context.Context, userID int64, m *[]LedgerMember) error
+
+	// UpdateLedgerMemberRole changes role of the member. Returns [ErrLastLedgerOwner] if the member is
+	// the only owner of its ledger and the role is not owner.
+	UpdateLedgerMemberRole(ctx context.Context, id int64, role string) error
+
+	// DeleteLedgerMemberByID removes the member from its ledger. Returns [ErrLastLedgerOwner] if the member is
+	// the only owner of its ledger.
+	DeleteLedgerMemberByID(ctx context.Context, id int64) error
+}
+
+func (d *DefaultDatabase) CreateLedger(ctx context.Context, l *Ledger, ownerID int64) error {
+	return d.client.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(l).Exec(ctx); err != nil {
+			return err
+		}
+		member := &LedgerMember{LedgerID: l.ID, UserID: ownerID, Role: LedgerRoleOwner}
+		if _, err := tx.NewInsert().Model(member).Exec(ctx); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func (d *DefaultDatabase) SelectOrCreatePersonalLedger(ctx context.Context, userID int64, l *Ledger) error {
+	err := d.client.NewSelect().Model(l).Where("personal_user_id = ?", userID).Scan(ctx)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	// the ledger may be created concurrently by another request of the user, in which case it is only selected:
+	return d.client.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		ledger := &Ledger{Name: "Personal", PersonalUserID: userID}
+		res, err := tx.NewInsert().Model(ledger).On("CONFLICT (personal_user_id) DO NOTHING").Exec(ctx)
+		if err != nil {
+			return err
+		}
+		inserted, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if inserted != 0 {
+			member := &LedgerMember{LedgerID: ledger.ID, UserID: userID, Role: LedgerRoleOwner}
+			if _, err := tx.NewInsert().Model(member).Exec(ctx); err != nil {
+				return err
+			}
+		}
+
+		return tx.NewSelect().Model(l).Where("personal_user_id = ?", userID).Scan(ctx)
+	})
+}
+
+func (d *DefaultDatabase) SelectLedgerByUUID(ctx context.Context, uuid string, l *Ledger) error {
+	if err := d.client.NewSelect().Model(l).Where("uuid = ?", uuid).Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) UpdateLedgerName(ctx context.Context, id int64, name string) error {
+	if _, err := d.client.NewUpdate().
+		Model(sampleLedger).
+		Set("name = ?", name).
+		Where("id = ?", id).
+		Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) CreateLedgerMember(ctx context.Context, m *LedgerMember) error {
+	if _, err := d.client.NewInsert().Model(m).Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectLedgerMember(ctx context.Context, ledgerID int64, userID int64, m *LedgerMember) error {
+	if err := d.client.NewSelect().
+		Model(m).
+		Where("ledger_id = ?", ledgerID).
+		Where("user_id = ?", userID).
+		Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectLedgerMembers(ctx context.Context, ledgerID int64, m *[]LedgerMember) error {
+	if err := d.client.NewSelect().
+		Model(m).
+		Relation("User").
+		Where("member.ledger_id = ?", ledgerID).
+		OrderExpr("? ASC", bun.Ident("user.username")).
+		Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectLedgerMembershipsByUserID(ctx context.Context, userID int64, m *[]LedgerMember) error {
+	if err := d.client.NewSelect().
+		Model(m).
+		Relation("Ledger").
+		Where("member.user_id = ?", userID).
+		OrderExpr("ledger.name ASC, ledger.id ASC").
+		Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkLedgerKeepsOwner returns [ErrLastLedgerOwner] if the member is the only owner of its ledger.
+// Owners of the ledger are locked until the end of the transaction, so that they can not be demoted or removed
+// concurrently.
+func checkLedgerKeepsOwner(ctx context.Context, tx bun.Tx, memberID int64) error {
+	ownerIDs := make([]int64, 0)
+	if err := tx.NewSelect().
+		Model(sampleLedgerMember).
+		Column("id").
+		Where("ledger_id = (?)", tx.NewSelect().Model(sampleLedgerMember).Column("ledger_id").Where("id = ?", memberID)).
+		Where("role = ?", LedgerRoleOwner).
+		For("UPDATE").
+		Scan(ctx, &ownerIDs); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if len(ownerIDs) == 1 && ownerIDs[0] == memberID {
+		return ErrLastLedgerOwner
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) UpdateLedgerMemberRole(ctx context.Context, id int64, role string) error {
+	return d.client.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if role != LedgerRoleOwner {
+			if err := checkLedgerKeepsOwner(ctx, tx, id); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.NewUpdate().
+			Model(sampleLedgerMember).
+			Set("role = ?", role).
+			Where("id = ?", id).
+			Exec(ctx); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func (d *DefaultDatabase) DeleteLedgerMemberByID(ctx context.Context, id int64) error {
+	return d.client.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := checkLedgerKeepsOwner(ctx, tx, id); err != nil {
+			return err
+		}
+		if _, err := tx.NewDelete().Model(sampleLedgerMember).Where("id = ?", id).Exec(ctx); err != nil {
+			return err
+		}
+		return nil
+	})
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// LoginFailure database model, represents a counter of failed login attempts of a username or an IP address.
+// It allows several replicas of groshi to share the counters.
+type LoginFailure struct {
+	bun.BaseModel `bun:"table:login_failures,alias:login_failure"`
+
+	// Key of the counter, for example, "user:jieggii" or "ip:203.0.113.7".
+	Key string `bun:"key,pk"`
+
+	Count         int       `bun:"count,notnull"`
+	LastFailureAt time.Time `bun:"last_failure_at,notnull"`
+}
+
+// LoginFailureQuerier interface describes a type which executes database queries related to the [LoginFailure] model.
+// It is implemented so that the database can be used as a login failure store of the login guard.
+type LoginFailureQuerier interface {
+	LoginFailures(ctx context.Context, key string) (int, time.Time, error)
+
+	// AddLoginFailure atomically increases the counter, see [auth.LoginFailureStore].
+	AddLoginFailure(ctx context.Context, key string, now time.Time, resetBefore time.Time) (int, error)
+
+	ResetLoginFailures(ctx context.Context, key string) error
+
+	PruneLoginFailures(ctx context.Context, before time.Time) error
+}
+
+func (d *DefaultDatabase) LoginFailures(ctx context.Context, key string) (int, time.Time, error) {
+	failure := &LoginFailure{}
+	if err := d.client.NewSelect().Model(failure).Where("key = ?", key).Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, time.Time{}, nil
+		}
+		return 0, time.Time{}, err
+	}
+	return failure.Count, failure.LastFailureAt, nil
+}
+
+func (d *DefaultDatabase) AddLoginFailure(ctx context.Context, key string, now time.Time, resetBefore time.Time) (int, error) {
+	failure := &LoginFailure{Key: key, Count: 1, LastFailureAt: now}
+	if _, err := d.client.NewInsert().
+		Model(failure).
+		On("CONFLICT (key) DO UPDATE").
+		Set("count = CASE WHEN login_failure.last_failure_at < ? THEN 1 ELSE login_failure.count + 1 END", resetBefore).
+		Set("last_failure_at = EXCLUDED.last_failure_at").
+		Returning("count").
+		Exec(ctx); err != nil {
+		return 0, err
+	}
+	return failure.Count, nil
+}
+
+func (d *DefaultDatabase) ResetLoginFailures(ctx context.Context, key string) error {
+	if _, err := d.client.NewDelete().Model(sampleLoginFailure).Where("key = ?", key).Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) PruneLoginFailures(ctx context.Context, before time.Time) error {
+	if _, err := d.client.NewDelete().Model(sampleLoginFailure).Where("last_failure_at < ?", before).Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
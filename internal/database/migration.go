@@ -0,0 +1,196 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// migrationLockID is the key of the PostgreSQL advisory lock which is held while migrations are applied,
+// so that several instances of groshi started at the same time do not migrate the database concurrently.
+const migrationLockID int64 = 0x67726f736869
+
+// SchemaMigration database model, records a migration applied to the database.
+type SchemaMigration struct {
+	bun.BaseModel `bun:"table:schema_migrations"`
+
+	Name string `bun:"name,pk"`
+
+	AppliedAt time.Time `bun:"applied_at,notnull,default:current_timestamp"`
+}
+
+// migration changes tables of databases created by previous versions of groshi.
+// Tables of new models are created by [DefaultDatabase.Init], migrations only change existing tables and their data.
+// Migrations are also applied to databases created from the current models, so they must be idempotent.
+type migration struct {
+	// Unique name of the migration, applied migrations are recorded by their names.
+	name string
+
+	// Statements executed in order to apply the migration.
+	statements []string
+}
+
+// migrations in order they are applied. New migrations must be appended to the end.
+var migrations = []migration{
+	{
+		// categories and transactions are moved from their owners to personal ledgers of the owners:
+		name: "ledgers",
+		statements: []string{
+			"ALTER TABLE categories ADD COLUMN IF NOT EXISTS ledger_id bigint",
+			"ALTER TABLE transactions ADD COLUMN IF NOT EXISTS ledger_id bigint",
+			"INSERT INTO ledgers (name, personal_user_id) SELECT 'Personal', id FROM users " +
+				"ON CONFLICT (personal_user_id) DO NOTHING",
+			"INSERT INTO ledger_members (ledger_id, user_id, role) SELECT id, personal_user_id, 'owner' FROM ledgers " +
+				"WHERE personal_user_id IS NOT NULL ON CONFLICT (ledger_id, user_id) DO NOTHING",
+			"UPDATE categories AS category SET ledger_id = ledger.id FROM ledgers AS ledger " +
+				"WHERE category.ledger_id IS NULL AND ledger.personal_user_id = category.owner_id",
+			"UPDATE transactions AS transaction SET ledger_id = ledger.id FROM ledgers AS ledger " +
+				"WHERE transaction.ledger_id IS NULL AND ledger.personal_user_id = transaction.owner_id",
+
+			// rows left by deleted users are not accessible by anyone and can not be moved to a ledger:
+			"DELETE FROM categories WHERE ledger_id IS NULL",
+			"DELETE FROM transactions WHERE ledger_id IS NULL",
+
+			"ALTER TABLE categories ALTER COLUMN ledger_id SET NOT NULL",
+			"ALTER TABLE transactions ALTER COLUMN ledger_id SET NOT NULL",
+			"CREATE INDEX IF NOT EXISTS categories_ledger_id_idx ON categories (ledger_id)",
+			"CREATE INDEX IF NOT EXISTS transactions_ledger_id_idx ON transactions (ledger_id)",
+		},
+	},
+	{
+		// transactions split across categories have no category:
+		name: "transaction_splits",
+		statements: []string{
+			"ALTER TABLE transactions ALTER COLUMN category_id DROP NOT NULL",
+		},
+	},
+	{
+		// payees are unique per owner, duplicates created concurrently are merged into the oldest payee:
+		name: "payees",
+		statements: []string{
+			"ALTER TABLE transactions ADD COLUMN IF NOT EXISTS payee_id bigint",
+			"UPDATE transactions AS transaction SET payee_id = (" +
+				"SELECT min(keep.id) FROM payees AS keep JOIN payees AS duplicate " +
+				"ON keep.owner_id = duplicate.owner_id AND keep.normalized_name = duplicate.normalized_name " +
+				"WHERE duplicate.id = transaction.payee_id" +
+				") WHERE transaction.payee_id IS NOT NULL",
+			"DELETE FROM payees AS duplicate USING payees AS keep " +
+				"WHERE keep.owner_id = duplicate.owner_id AND keep.normalized_name = duplicate.normalized_name AND keep.id < duplicate.id",
+			"CREATE UNIQUE INDEX IF NOT EXISTS payees_owner_id_normalized_name_idx ON payees (owner_id, normalized_name)",
+		},
+	},
+	{
+		// external IDs are unique per ledger, so that concurrent imports can not import the same entry twice.
+		// External IDs of duplicates imported before are cleared, the oldest transaction keeps its one:
+		name: "transaction_external_ids",
+		statements: []string{
+			"ALTER TABLE transactions ADD COLUMN IF NOT EXISTS external_id varchar",
+			"UPDATE transactions AS duplicate SET external_id = NULL FROM transactions AS keep " +
+				"WHERE keep.ledger_id = duplicate.ledger_id AND keep.external_id = duplicate.external_id AND keep.id < duplicate.id",
+			"CREATE UNIQUE INDEX IF NOT EXISTS transactions_ledger_id_external_id_idx ON transactions (ledger_id, external_id)",
+		},
+	},
+	{
+		// transactions imported from bank statements have value dates:
+		name: "transaction_value_dates",
+		statements: []string{
+			"ALTER TABLE transactions ADD COLUMN IF NOT EXISTS value_date timestamptz",
+		},
+	},
+	{
+		// tokens issued before the current token version of the user are rejected:
+		name: "user_token_versions",
+		statements: []string{
+			"ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version bigint NOT NULL DEFAULT 0",
+		},
+	},
+	{
+		// users may enable TOTP two-factor authentication:
+		name: "user_totp",
+		statements: []string{
+			"ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret varchar NOT NULL DEFAULT ''",
+			"ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled boolean NOT NULL DEFAULT false",
+			"ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step bigint NOT NULL DEFAULT 0",
+			"ALTER TABLE users ADD COLUMN IF NOT EXISTS recovery_codes varchar[]",
+		},
+	},
+	{
+		// users are identified by UUIDs and their usernames are unique. Users with duplicate usernames
+		// created concurrently before are renamed, the oldest user keeps the username.
+		// Indexes are named after the constraints PostgreSQL creates for new tables, so they are not duplicated:
+		name: "user_uuids",
+		statements: []string{
+			"ALTER TABLE users ADD COLUMN IF NOT EXISTS uuid uuid NOT NULL DEFAULT uuid_generate_v4()",
+			"CREATE UNIQUE INDEX IF NOT EXISTS users_uuid_key ON users (uuid)",
+			"UPDATE users AS duplicate SET username = duplicate.username || '-' || duplicate.id FROM users AS keep " +
+				"WHERE keep.username = duplicate.username AND keep.id < duplicate.id",
+			"CREATE UNIQUE INDEX IF NOT EXISTS users_username_key ON users (username)",
+		},
+	},
+	{
+		// users have roles and may be disabled, existing users are ordinary users:
+		name: "user_roles",
+		statements: []string{
+			"ALTER TABLE users ADD COLUMN IF NOT EXISTS role varchar NOT NULL DEFAULT 'user'",
+			"ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at timestamptz",
+		},
+	},
+	{
+		// users may be allowed to invite other users:
+		name: "user_invites",
+		statements: []string{
+			"ALTER TABLE users ADD COLUMN IF NOT EXISTS can_invite boolean NOT NULL DEFAULT false",
+			"ALTER TABLE users ADD COLUMN IF NOT EXISTS invited_by_id bigint",
+		},
+	},
+	{
+		// transactions may be shared expenses or settlements between ledger members:
+		name: "shared_expenses",
+		statements: []string{
+			"ALTER TABLE transactions ADD COLUMN IF NOT EXISTS payer_id bigint",
+			"ALTER TABLE transactions ADD COLUMN IF NOT EXISTS settlement boolean NOT NULL DEFAULT false",
+		},
+	},
+}
+
+// migrate applies migrations which have not been applied to the database yet in a single database transaction.
+func (d *DefaultDatabase) migrate(ctx context.Context) error {
+	if _, err := d.client.NewCreateTable().Model(sampleSchemaMigration).IfNotExists().Exec(ctx); err != nil {
+		return err
+	}
+
+	return d.client.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(?)", migrationLockID); err != nil {
+			return err
+		}
+
+		// fetch names of the applied migrations:
+		names := make([]string, 0)
+		if err := tx.NewSelect().Model(sampleSchemaMigration).Column("name").Scan(ctx, &names); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		applied := make(map[string]bool, len(names))
+		for _, name := range names {
+			applied[name] = true
+		}
+
+		// apply the rest of migrations:
+		for _, m := range migrations {
+			if applied[m.name] {
+				continue
+			}
+			for _, statement := range m.statements {
+				if _, err := tx.ExecContext(ctx, statement); err != nil {
+					return fmt.Errorf("could not apply migration %q: %w", m.name, err)
+				}
+			}
+			if _, err := tx.NewInsert().Model(&SchemaMigration{Name: m.name}).Exec(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,16 @@
+package database
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	names := make(map[string]bool)
+	for _, m := range migrations {
+		assert.NotEmpty(t, m.name)
+		assert.False(t, names[m.name], "migration %q is not unique", m.name)
+		assert.NotEmpty(t, m.statements, "migration %q has no statements", m.name)
+		names[m.name] = true
+	}
+}
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"strings"
+	"time"
+)
+
+var _ bun.BeforeAppendModelHook = (*Payee)(nil)
+
+// Payee database model, represents a merchant or a person money is paid to or received from.
+type Payee struct {
+	bun.BaseModel `bun:"table:payees,alias:payee"`
+
+	ID   int64     `bun:"id,pk,autoincrement"`
+	UUID uuid.UUID `bun:"uuid,type:uuid,notnull,default:uuid_generate_v4()"`
+
+	// Display name of the payee.
+	Name string `bun:"name,notnull"`
+
+	// Normalized name of the payee used to find it regardless of case and whitespace, see [NormalizePayeeName].
+	// It is unique among payees of the owner.
+	NormalizedName string `bun:"normalized_name,notnull"`
+
+	Owner   User  `bun:"rel:belongs-to,join:owner_id=id"`
+	OwnerID int64 `bun:"owner_id,notnull"`
+
+	CreatedAt time.Time `bun:",notnull,default:current_timestamp"`
+}
+
+func (p *Payee) BeforeAppendModel(_ context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.InsertQuery:
+		p.NormalizedName = NormalizePayeeName(p.Name)
+		p.CreatedAt = time.Now()
+	case *bun.UpdateQuery:
+		p.NormalizedName = NormalizePayeeName(p.Name)
+	}
+	return nil
+}
+
+// CleanPayeeName trims leading and trailing whitespace of the payee name
+// and collapses all inner whitespace sequences into single spaces.
+func CleanPayeeName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// NormalizePayeeName returns normalized form of the payee name: cleaned using [CleanPayeeName] and lower-cased.
+// Names which differ only in case and whitespace have the same normalized form.
+func NormalizePayeeName(name string) string {
+	return strings.ToLower(CleanPayeeName(name))
+}
+
+// PayeeUsage represents a payee and count of transactions which reference it.
+type PayeeUsage struct {
+	Payee `bun:",extend"`
+
+	TransactionsCount int64 `bun:"transactions_count"`
+}
+
+// PayeeStat represents aggregated statistics of transactions which reference a payee.
+type PayeeStat struct {
+	PayeeUUID uuid.UUID `bun:"payee_uuid"`
+	PayeeName string    `bun:"payee_name"`
+
+	// Count of transactions which reference the payee.
+	TransactionsCount int64 `bun:"transactions_count"`
+
+	// Sum of transaction amounts converted to the base currency (the one which has rate equal to 1).
+	BaseAmount float64 `bun:"base_amount"`
+}
+
+// PayeeQuerier interface describes a type which executes database queries related to the [Payee] model.
+type PayeeQuerier interface {
+	// SelectOrCreatePayee selects payee owned by the user whose normalized name matches the given one,
+	// creates a new payee with the given name if there is no such payee.
+	SelectOrCreatePayee(ctx context.Context, ownerID int64, name string, p *Payee) error
+	SelectPayeeByUUID(ctx context.Context, uuid string, p *Payee) error
+
+	// SelectPayeeUsages returns payees owned by the user whose normalized names start with the normalized prefix,
+	// most used payees first. At most limit payees are returned, all of them if limit is zero.
+	SelectPayeeUsages(ctx context.Context, ownerID int64, prefix string, limit int, u *[]PayeeUsage) error
+
+	// SelectPayeeStats returns statistics of transactions which match the given filter grouped by payees,
+	// payees with the largest absolute total amount first. At most limit payees are returned.
+	SelectPayeeStats(ctx context.Context, f *TransactionFilter, limit int, s *[]PayeeStat) error
+}
+
+// likePrefixReplacer escapes special characters of the LIKE pattern.
+var likePrefixReplacer = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (d *DefaultDatabase) SelectOrCreatePayee(ctx context.Context, ownerID int64, name string, p *Payee) error {
+	err := d.client.NewSelect().
+		Model(p).
+		Where("owner_id = ?", ownerID).
+		Where("normalized_name = ?", NormalizePayeeName(name)).
+		Limit(1).
+		Scan(ctx)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	// the payee may be created concurrently by another request, in which case it is only selected:
+	payee := &Payee{
+		Name:    CleanPayeeName(name),
+		OwnerID: ownerID,
+	}
+	if _, err := d.client.NewInsert().
+		Model(payee).
+		On("CONFLICT (owner_id, normalized_name) DO NOTHING").
+		Exec(ctx); err != nil {
+		return err
+	}
+	if err := d.client.NewSelect().
+		Model(p).
+		Where("owner_id = ?", ownerID).
+		Where("normalized_name = ?", NormalizePayeeName(name)).
+		Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectPayeeByUUID(ctx context.Context, uuid string, p *Payee) error {
+	if err := d.client.NewSelect().Model(p).Where("uuid = ?", uuid).Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectPayeeUsages(ctx context.Context, ownerID int64, prefix string, limit int, u *[]PayeeUsage) error {
+	q := d.client.NewSelect().
+		Model(u).
+		ColumnExpr("payee.*").
+		ColumnExpr("count(transaction.id) AS transactions_count").
+		Join("LEFT JOIN transactions AS transaction ON transaction.payee_id = payee.id").
+		Where("payee.owner_id = ?", ownerID).
+		Group("payee.id").
+		OrderExpr("transactions_count DESC, payee.name ASC").
+		Limit(limit)
+	if prefix != "" {
+		q = q.Where("payee.normalized_name LIKE ?", likePrefixReplacer.Replace(NormalizePayeeName(prefix))+"%")
+	}
+
+	if err := q.Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectPayeeStats(ctx context.Context, f *TransactionFilter, limit int, s *[]PayeeStat) error {
+	q := d.client.NewSelect().
+		TableExpr("transactions AS transaction").
+		ColumnExpr("payee.uuid AS payee_uuid").
+		ColumnExpr("payee.name AS payee_name").
+		ColumnExpr("count(transaction.id) AS transactions_count").
+		ColumnExpr("sum(transaction.amount / currency.rate) AS base_amount").
+		Join("JOIN payees AS payee ON payee.id = transaction.payee_id").
+		Join("JOIN currencies AS currency ON currency.id = transaction.currency_id").
+		GroupExpr("payee.id").
+		OrderExpr("abs(sum(transaction.amount / currency.rate)) DESC, payee.name ASC").
+		Limit(limit)
+	q = f.apply(q)
+
+	if err := q.Scan(ctx, s); err != nil {
+		return err
+	}
+	return nil
+}
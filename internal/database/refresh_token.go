@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+var _ bun.BeforeAppendModelHook = (*RefreshToken)(nil)
+
+// RefreshToken database model, represents a refresh token which can be exchanged for a new pair of tokens once.
+// Tokens issued by refreshing each other belong to the same [Session].
+type RefreshToken struct {
+	bun.BaseModel `bun:"table:refresh_tokens,alias:refresh_token"`
+
+	ID int64 `bun:"id,pk,autoincrement"`
+
+	// SHA-256 hash of the token, the token itself is not stored.
+	TokenHash string `bun:"token_hash,notnull,unique"`
+
+	// Session of the token, the whole session is revoked if a rotated token is reused.
+	Session   Session `bun:"rel:belongs-to,join:session_id=id"`
+	SessionID int64   `bun:"session_id,notnull"`
+
+	// ID (`jti` claim) and expiration time of the access token issued together with the refresh token.
+	AccessTokenID        string    `bun:"access_token_id,notnull"`
+	AccessTokenExpiresAt time.Time `bun:"access_token_expires_at,notnull"`
+
+	Owner   User  `bun:"rel:belongs-to,join:owner_id=id"`
+	OwnerID int64 `bun:"owner_id,notnull"`
+
+	ExpiresAt time.Time `bun:"expires_at,notnull"`
+
+	// Time when the token was exchanged for a new pair of tokens.
+	RotatedAt time.Time `bun:"rotated_at,nullzero"`
+
+	// Time when the token was revoked on logout or because of reuse.
+	RevokedAt time.Time `bun:"revoked_at,nullzero"`
+
+	CreatedAt time.Time `bun:",notnull,default:current_timestamp"`
+}
+
+func (t *RefreshToken) BeforeAppendModel(_ context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.InsertQuery:
+		t.CreatedAt = time.Now()
+	}
+	return nil
+}
+
+// RevokedToken database model, represents an access token which was revoked before its expiration.
+type RevokedToken struct {
+	bun.BaseModel `bun:"table:revoked_tokens,alias:revoked_token"`
+
+	// ID of the token (`jti` claim).
+	ID string `bun:"id,pk"`
+
+	// Expiration time of the token, the record is not needed after it.
+	ExpiresAt time.Time `bun:"expires_at,notnull"`
+}
+
+// RefreshTokenQuerier interface describes a type which executes database queries related to
+// the [RefreshToken] and [RevokedToken] models.
+type RefreshTokenQuerier interface {
+	CreateRefreshToken(ctx context.Context, t *RefreshToken) error
+
+	// SelectRefreshTokenByHash selects the refresh token with the given hash together with its owner and session.
+	SelectRefreshTokenByHash(ctx context.Context, hash string, t *RefreshToken) error
+
+	// RotateRefreshToken marks the token as rotated. Returns false if the token was already rotated or revoked.
+	RotateRefreshToken(ctx context.Context, id int64) (bool, error)
+
+	// DeleteExpiredTokens deletes refresh tokens and records of revoked access tokens which expired before the given time.
+	// Expired tokens are rejected anyway, so their records are not needed anymore.
+	DeleteExpiredTokens(ctx context.Context, before time.Time) error
+
+	// RevokeToken records the token with the given ID as revoked until it expires.
+	// Returns false if the token was already revoked.
+	RevokeToken(ctx context.Context, id string, expiresAt time.Time) (bool, error)
+
+	RevokedTokenQuerier
+}
+
+// RevokedTokenQuerier interface describes a type which executes database queries related to the [RevokedToken] model.
+type RevokedTokenQuerier interface {
+	// TokenRevoked returns true if the access token with the given ID was revoked.
+	TokenRevoked(ctx context.Context, id string) (bool, error)
+}
+
+func (d *DefaultDatabase) CreateRefreshToken(ctx context.Context, t *RefreshToken) error {
+	if _, err := d.client.NewInsert().Model(t).Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectRefreshTokenByHash(ctx context.Context, hash string, t *RefreshToken) error {
+	if err := d.client.NewSelect().Model(t).Relation("Owner").Relation("Session").Where("refresh_token.token_hash = ?", hash).Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) RotateRefreshToken(ctx context.Context, id int64) (bool, error) {
+	return updatedOne(ctx, d.client.NewUpdate().
+		Model(sampleRefreshToken).
+		Set("rotated_at = ?", time.Now()).
+		Where("id = ?", id).
+		Where("rotated_at IS NULL").
+		Where("revoked_at IS NULL"))
+}
+
+func (d *DefaultDatabase) DeleteExpiredTokens(ctx context.Context, before time.Time) error {
+	return d.client.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewDelete().Model(sampleRefreshToken).Where("expires_at < ?", before).Exec(ctx); err != nil {
+			return err
+		}
+		if _, err := tx.NewDelete().Model(sampleRevokedToken).Where("expires_at < ?", before).Exec(ctx); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func (d *DefaultDatabase) TokenRevoked(ctx context.Context, id string) (bool, error) {
+	exists, err := d.client.NewSelect().Model(sampleRevokedToken).Where("id = ?", id).Exists(ctx)
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+func (d *DefaultDatabase) RevokeToken(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	result, err := d.client.NewInsert().
+		Model(&RevokedToken{ID: id, ExpiresAt: expiresAt}).
+		On("CONFLICT (id) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+const (
+	// RuleMatchFieldDescription makes rule match pattern against transaction description.
+	RuleMatchFieldDescription = "description"
+
+	// RuleMatchFieldPayee makes rule match pattern against name of the transaction payee.
+	RuleMatchFieldPayee = "payee"
+)
+
+const (
+	// RuleMatchTypeSubstring makes rule match if the field contains pattern, case-insensitively.
+	RuleMatchTypeSubstring = "substring"
+
+	// RuleMatchTypeRegex makes rule match if the field matches pattern which is a regular expression.
+	RuleMatchTypeRegex = "regex"
+)
+
+var _ bun.BeforeAppendModelHook = (*Rule)(nil)
+
+// Rule database model, represents an auto-categorization rule.
+// Rule matches a transaction if all its conditions are satisfied, then its actions are applied to the transaction.
+type Rule struct {
+	bun.BaseModel `bun:"table:rules,alias:rule"`
+
+	ID   int64     `bun:"id,pk,autoincrement"`
+	UUID uuid.UUID `bun:"uuid,type:uuid,notnull,default:uuid_generate_v4()"`
+
+	Name string `bun:"name,notnull"`
+
+	// Rules are applied in ascending order of their positions.
+	Position int `bun:"position,notnull"`
+
+	// Condition: field the pattern is matched against, one of RuleMatchField* constants, optional.
+	MatchField string `bun:"match_field,nullzero"`
+
+	// Condition: how the pattern is matched, one of RuleMatchType* constants.
+	MatchType string `bun:"match_type,nullzero"`
+
+	// Condition: substring or regular expression.
+	Pattern string `bun:"pattern,nullzero"`
+
+	// Condition: inclusive amount range, each bound is optional.
+	AmountMin *int32 `bun:"amount_min"`
+	AmountMax *int32 `bun:"amount_max"`
+
+	// Condition: currency of the transaction, optional.
+	Currency   Currency `bun:"rel:belongs-to,join:currency_id=id"`
+	CurrencyID int64    `bun:"currency_id,nullzero"`
+
+	// Action: set category of the transaction, optional.
+	Category   Category `bun:"rel:belongs-to,join:category_id=id"`
+	CategoryID int64    `bun:"category_id,nullzero"`
+
+	// Action: mark the transaction with tags, optional.
+	TagIDs []int64 `bun:"tag_ids,array"`
+
+	// Action: set payee of the transaction to the payee with this name, optional.
+	PayeeName string `bun:"payee_name,nullzero"`
+
+	Owner   User  `bun:"rel:belongs-to,join:owner_id=id"`
+	OwnerID int64 `bun:"owner_id,notnull"`
+
+	CreatedAt time.Time `bun:",notnull,default:current_timestamp"`
+	UpdatedAt time.Time `bun:",notnull,default:current_timestamp"`
+}
+
+func (r *Rule) BeforeAppendModel(_ context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.InsertQuery:
+		r.CreatedAt = time.Now()
+	case *bun.UpdateQuery:
+		r.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+// RuleQuerier interface describes a type which executes database queries related to the [Rule] model.
+type RuleQuerier interface {
+	CreateRule(ctx context.Context, r *Rule) error
+	SelectRuleByUUID(ctx context.Context, uuid string, r *Rule) error
+
+	// SelectRulesByOwnerID selects rules owned by the user in the order they must be applied in.
+	SelectRulesByOwnerID(ctx context.Context, ownerID int64, r *[]Rule) error
+	UpdateRule(ctx context.Context, r *Rule) error
+	DeleteRuleByID(ctx context.Context, id int64) error
+}
+
+func (d *DefaultDatabase) selectRulesQuery(model any) *bun.SelectQuery {
+	return d.client.NewSelect().
+		Model(model).
+		Relation("Currency").
+		Relation("Category")
+}
+
+func (d *DefaultDatabase) CreateRule(ctx context.Context, r *Rule) error {
+	if _, err := d.client.NewInsert().Model(r).Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectRuleByUUID(ctx context.Context, uuid string, r *Rule) error {
+	if err := d.selectRulesQuery(r).Where("rule.uuid = ?", uuid).Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectRulesByOwnerID(ctx context.Context, ownerID int64, r *[]Rule) error {
+	q := d.selectRulesQuery(r).
+		Where("rule.owner_id = ?", ownerID).
+		Order("rule.position ASC", "rule.id ASC")
+	if err := q.Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) UpdateRule(ctx context.Context, r *Rule) error {
+	if _, err := d.client.NewUpdate().Model(r).WherePK().Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) DeleteRuleByID(ctx context.Context, id int64) error {
+	if _, err := d.client.NewDelete().Model(sampleRule).Where("id = ?", id).Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package database
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+var _ bun.BeforeAppendModelHook = (*Session)(nil)
+
+// Session database model, represents a login of a user on a device.
+// All refresh and access tokens issued since the login belong to the session.
+type Session struct {
+	bun.BaseModel `bun:"table:sessions,alias:session"`
+
+	ID   int64     `bun:"id,pk,autoincrement"`
+	UUID uuid.UUID `bun:"uuid,type:uuid,notnull,default:uuid_generate_v4()"`
+
+	// Device name provided by the client on login, and user agent and IP address of the login request.
+	DeviceName string `bun:"device_name,notnull"`
+	UserAgent  string `bun:"user_agent,notnull"`
+	IP         string `bun:"ip,notnull"`
+
+	Owner   User  `bun:"rel:belongs-to,join:owner_id=id"`
+	OwnerID int64 `bun:"owner_id,notnull"`
+
+	// Time when a token of the session was used last time.
+	LastSeenAt time.Time `bun:"last_seen_at,notnull"`
+
+	// Expiration time of the latest refresh token of the session.
+	ExpiresAt time.Time `bun:"expires_at,notnull"`
+
+	// Time when the session was revoked on logout, by the user or because of refresh token reuse.
+	RevokedAt time.Time `bun:"revoked_at,nullzero"`
+
+	CreatedAt time.Time `bun:",notnull,default:current_timestamp"`
+}
+
+func (s *Session) BeforeAppendModel(_ context.Context, query bun.Query) error {
+	switch query.(type) {
+	case *bun.InsertQuery:
+		s.CreatedAt = time.Now()
+		s.LastSeenAt = s.CreatedAt
+	}
+	return nil
+}
+
+// SessionQuerier interface describes a type which executes database queries related to the [Session] model.
+type SessionQuerier interface {
+	CreateSession(ctx context.Context, s *Session) error
+	SelectSessionByUUID(ctx context.Context, uuid string, s *Session) error
+
+	// SelectActiveSessionsByOwnerID selects sessions of the user which are neither revoked nor expired,
+	// the most recently seen ones first.
+	SelectActiveSessionsByOwnerID(ctx context.Context, ownerID int64, s *[]Session) error
+
+	// TouchSession updates time when the session was seen last time.
+	TouchSession(ctx context.Context, id int64) error
+
+	// ExtendSession updates expiration time of the session and time when it was seen last time.
+	ExtendSession(ctx context.Context, id int64, expiresAt time.Time) error
+
+	// RevokeSession revokes the session together with its refresh and access tokens.
+	RevokeSession(ctx context.Context, id int64) error
+
+	// RevokeSessionsByOwnerID revokes all sessions of the user except the session with the given ID.
+	RevokeSessionsByOwnerID(ctx context.Context, ownerID int64, exceptID int64) error
+}
+
+func (d *DefaultDatabase) CreateSession(ctx context.Context, s *Session) error {
+	if _, err := d.client.NewInsert().Model(s).Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectSessionByUUID(ctx context.Context, uuid string, s *Session) error {
+	if err := d.client.NewSelect().Model(s).Where("uuid = ?", uuid).Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectActiveSessionsByOwnerID(ctx context.Context, ownerID int64, s *[]Session) error {
+	q := d.client.NewSelect().
+		Model(s).
+		Where("owner_id = ?", ownerID).
+		Where("revoked_at IS NULL").
+		Where("expires_at > ?", time.Now()).
+		Order("last_seen_at DESC", "id DESC")
+	if err := q.Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) TouchSession(ctx context.Context, id int64) error {
+	if _, err := d.client.NewUpdate().
+		Model(sampleSession).
+		Set("last_seen_at = ?", time.Now()).
+		Where("id = ?", id).
+		Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) ExtendSession(ctx context.Context, id int64, expiresAt time.Time) error {
+	if _, err := d.client.NewUpdate().
+		Model(sampleSession).
+		Set("last_seen_at = ?", time.Now()).
+		Set("expires_at = ?", expiresAt).
+		Where("id = ?", id).
+		Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+// revokeSessions revokes sessions matching the condition together with their refresh and access tokens.
+// The condition is applied to the `sessions` table.
+func (d *DefaultDatabase) revokeSessions(ctx context.Context, condition string, args ...any) error {
+	now := time.Now()
+	sessions := d.client.NewSelect().
+		Model(sampleSession).
+		Column("id").
+		Where(condition, args...).
+		Where("revoked_at IS NULL")
+
+	// revoke access tokens of the sessions which have not expired yet:
+	if _, err := d.client.NewRaw(
+		"INSERT INTO revoked_tokens (id, expires_at) "+
+			"SELECT access_token_id, access_token_expires_at FROM refresh_tokens "+
+			"WHERE session_id IN (?) AND access_token_expires_at > ? "+
+			"ON CONFLICT DO NOTHING",
+		sessions, now,
+	).Exec(ctx); err != nil {
+		return err
+	}
+
+	// revoke refresh tokens of the sessions:
+	if _, err := d.client.NewUpdate().
+		Model(sampleRefreshToken).
+		Set("revoked_at = ?", now).
+		Where("session_id IN (?)", sessions).
+		Where("revoked_at IS NULL").
+		Exec(ctx); err != nil {
+		return err
+	}
+
+	// revoke the sessions:
+	if _, err := d.client.NewUpdate().
+		Model(sampleSession).
+		Set("revoked_at = ?", now).
+		Where(condition, args...).
+		Where("revoked_at IS NULL").
+		Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) RevokeSession(ctx context.Context, id int64) error {
+	return d.revokeSessions(ctx, "id = ?", id)
+}
+
+func (d *DefaultDatabase) RevokeSessionsByOwnerID(ctx context.Context, ownerID int64, exceptID int64) error {
+	return d.revokeSessions(ctx, "owner_id = ? AND id != ?", ownerID, exceptID)
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Tag database model.
+type Tag struct {
+	bun.BaseModel `bun:"table:tags"`
+
+	ID   int64     `bun:"id,pk,autoincrement"`
+	UUID uuid.UUID `bun:"uuid,type:uuid,notnull,default:uuid_generate_v4()"`
+
+	Name string `bun:"name,notnull"`
+
+	Owner   User  `bun:"rel:belongs-to,join:owner_id=id"`
+	OwnerID int64 `bun:"owner_id,notnull"`
+}
+
+// TransactionTag database model which links [Transaction] and [Tag] models (many-to-many relation).
+type TransactionTag struct {
+	bun.BaseModel `bun:"table:transaction_tags"`
+
+	Transaction   *Transaction `bun:"rel:belongs-to,join:transaction_id=id"`
+	TransactionID int64        `bun:"transaction_id,pk"`
+
+	Tag   *Tag  `bun:"rel:belongs-to,join:tag_id=id"`
+	TagID int64 `bun:"tag_id,pk"`
+}
+
+// TagStat represents aggregated statistics of transactions marked with a tag.
+type TagStat struct {
+	TagUUID uuid.UUID `bun:"tag_uuid"`
+	TagName string    `bun:"tag_name"`
+
+	// Count of transactions marked with the tag.
+	TransactionsCount int64 `bun:"transactions_count"`
+
+	// Sum of transaction amounts converted to the base currency (the one which has rate equal to 1).
+	BaseAmount float64 `bun:"base_amount"`
+}
+
+// TagQuerier interface describes a type which executes database queries related to the [Tag] model.
+type TagQuerier interface {
+	CreateTag(ctx context.Context, t *Tag) error
+	TagExistsByName(ctx context.Context, ownerID int64, name string) (bool, error)
+	SelectTagByUUID(ctx context.Context, uuid string, t *Tag) error
+	SelectTagsByOwnerID(ctx context.Context, ownerID int64, t *[]Tag) error
+	UpdateTag(ctx context.Context, t *Tag) error
+	DeleteTagByID(ctx context.Context, id int64) error
+
+	// SelectTagStats returns statistics of transactions which match the given filter grouped by tags.
+	SelectTagStats(ctx context.Context, f *TransactionFilter, s *[]TagStat) error
+}
+
+func (d *DefaultDatabase) selectTagByUUIDQuery(uuid string) *bun.SelectQuery {
+	return d.client.NewSelect().Model(sampleTag).Where("uuid = ?", uuid)
+}
+
+func (d *DefaultDatabase) selectTagsByOwnerIDQuery(ownerID int64) *bun.SelectQuery {
+	return d.client.NewSelect().Model(sampleTag).Where("owner_id = ?", ownerID)
+}
+
+func (d *DefaultDatabase) CreateTag(ctx context.Context, t *Tag) error {
+	if _, err := d.client.NewInsert().Model(t).Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) TagExistsByName(ctx context.Context, ownerID int64, name string) (bool, error) {
+	exists, err := d.selectTagsByOwnerIDQuery(ownerID).Where("name = ?", name).Exists(ctx)
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+func (d *DefaultDatabase) SelectTagByUUID(ctx context.Context, uuid string, t *Tag) error {
+	if err := d.selectTagByUUIDQuery(uuid).Scan(ctx, t); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectTagsByOwnerID(ctx context.Context, ownerID int64, t *[]Tag) error {
+	if err := d.selectTagsByOwnerIDQuery(ownerID).Order("name ASC").Scan(ctx, t); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) UpdateTag(ctx context.Context, t *Tag) error {
+	if _, err := d.client.NewUpdate().Model(t).WherePK().Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) DeleteTagByID(ctx context.Context, id int64) error {
+	return d.client.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		// unlink the tag from all transactions first:
+		if _, err := tx.NewDelete().Model(sampleTransactionTag).Where("tag_id = ?", id).Exec(ctx); err != nil {
+			return err
+		}
+
+		// remove the tag from actions of the rules:
+		if _, err := tx.NewUpdate().
+			Model(sampleRule).
+			Set("tag_ids = array_remove(tag_ids, ?)", id).
+			Where("? = ANY(tag_ids)", id).
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		if _, err := tx.NewDelete().Model(sampleTag).Where("id = ?", id).Exec(ctx); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func (d *DefaultDatabase) SelectTagStats(ctx context.Context, f *TransactionFilter, s *[]TagStat) error {
+	q := d.client.NewSelect().
+		TableExpr("tags AS tag").
+		ColumnExpr("tag.uuid AS tag_uuid").
+		ColumnExpr("tag.name AS tag_name").
+		ColumnExpr("count(transaction.id) AS transactions_count").
+		ColumnExpr("sum(transaction.amount / currency.rate) AS base_amount").
+		Join("JOIN transaction_tags AS transaction_tag ON transaction_tag.tag_id = tag.id").
+		Join("JOIN transactions AS transaction ON transaction.id = transaction_tag.transaction_id").
+		Join("JOIN currencies AS currency ON currency.id = transaction.currency_id").
+		GroupExpr("tag.id").
+		OrderExpr("tag.name ASC")
+	q = f.apply(q)
+
+	if err := q.Scan(ctx, s); err != nil {
+		return err
+	}
+	return nil
+}
@@ -11,7 +11,7 @@ var _ bun.BeforeAppendModelHook = (*Transaction)(nil)
 
 // Transaction database model.
 type Transaction struct {
-	bun.BaseModel `bun:"table:transactions"`
+	bun.BaseModel `bun:"table:transactions,alias:transaction"`
 
 	ID   int64     `bun:"id,pk,autoincrement"`
 	UUID uuid.UUID `bun:"uuid,type:uuid,notnull,default:uuid_generate_v4()"`
@@ -23,9 +23,39 @@ type Transaction struct {
 
 	Description string `bun:"description,nullzero"`
 
+	// Payee of the transaction, optional.
+	Payee   Payee `bun:"rel:belongs-to,join:payee_id=id"`
+	PayeeID int64 `bun:"payee_id,nullzero"`
+
+	// Category of the transaction, absent if the transaction is split across several categories.
 	Category   Category `bun:"rel:belongs-to,join:category_id=id"`
-	CategoryID int64    `bun:"category_id,notnull"`
+	CategoryID int64    `bun:"category_id,nullzero"`
+
+	// Split lines of the transaction, each of them has its own category, amount and note.
+	Splits []TransactionSplit `bun:"rel:has-many,join:id=transaction_id"`
+
+	Tags []Tag `bun:"m2m:transaction_tags,join:Transaction=Tag"`
+
+	// Identifier of the bank statement entry the transaction was imported from, used to skip already imported entries.
+	// It is unique among transactions of the ledger.
+	ExternalID string `bun:"external_id,nullzero"`
+
+	// Ledger the transaction belongs to.
+	Ledger   Ledger `bun:"rel:belongs-to,join:ledger_id=id"`
+	LedgerID int64  `bun:"ledger_id,notnull"`
+
+	// Ledger member who paid for the expense shared between members, absent if the expense is not shared.
+	Payer   User  `bun:"rel:belongs-to,join:payer_id=id"`
+	PayerID int64 `bun:"payer_id,nullzero"`
 
+	// Shares of the expense owed to the payer by members, including the share of the payer.
+	Shares []ExpenseShare `bun:"rel:has-many,join:id=transaction_id"`
+
+	// True if the transaction is a payment settling debts between ledger members rather than an expense.
+	// Such transaction is paid by the debtor and has a single share owed by the creditor.
+	Settlement bool `bun:"settlement,notnull,default:false"`
+
+	// User who created the transaction.
 	Owner   User  `bun:"rel:belongs-to,join:owner_id=id"`
 	OwnerID int64 `bun:"owner_id,notnull"`
 
@@ -33,6 +63,10 @@ type Transaction struct {
 	// UTC is always used as the timezone.
 	Timestamp time.Time `bun:",notnull"`
 
+	// Value date of the transaction (when it affected the account balance), optional.
+	// Set only for the transactions imported from bank statements which specify it.
+	ValueDate time.Time `bun:"value_date,nullzero"`
+
 	CreatedAt time.Time `bun:",notnull,default:current_timestamp"`
 	UpdatedAt time.Time `bun:",notnull,default:current_timestamp"`
 }
@@ -47,14 +81,319 @@ func (t *Transaction) BeforeAppendModel(_ context.Context, query bun.Query) erro
 	return nil
 }
 
+// TransactionSplit database model, represents a part of a [Transaction] attributed to a category.
+// Amounts of all splits of a transaction sum up to the transaction amount.
+type TransactionSplit struct {
+	bun.BaseModel `bun:"table:transaction_splits,alias:split"`
+
+	ID int64 `bun:"id,pk,autoincrement"`
+
+	TransactionID int64 `bun:"transaction_id,notnull"`
+
+	Category   Category `bun:"rel:belongs-to,join:category_id=id"`
+	CategoryID int64    `bun:"category_id,notnull"`
+
+	Amount int32  `bun:"amount,notnull"`
+	Note   string `bun:"note,nullzero"`
+}
+
+// TransactionFilter describes conditions which selected transactions must satisfy.
+// Zero values of the optional fields mean that the corresponding condition is not applied.
+type TransactionFilter struct {
+	// ID of the ledger transactions belong to, required.
+	LedgerID int64
+
+	// Select only transactions which happened at or after this moment.
+	StartTime time.Time
+
+	// Select only transactions which happened before this moment.
+	EndTime time.Time
+
+	// Select only transactions in this currency.
+	CurrencyID int64
+
+	// Select only transactions of this category or having a split line of this category.
+	CategoryID int64
+
+	// Select only transactions which reference this payee.
+	PayeeID int64
+
+	// Select only transactions marked with the given tags.
+	TagIDs []int64
+
+	// If true, transactions must be marked with all TagIDs, otherwise with at least one of them.
+	AllTags bool
+
+	// If true, settlements of debts between ledger members are not selected.
+	ExcludeSettlements bool
+}
+
+// apply adds filter conditions to the query. The query must refer to the transactions table as "transaction".
+func (f *TransactionFilter) apply(q *bun.SelectQuery) *bun.SelectQuery {
+	q = q.Where("transaction.ledger_id = ?", f.LedgerID)
+
+	if !f.StartTime.IsZero() {
+		q = q.Where("transaction.timestamp >= ?", f.StartTime)
+	}
+	if !f.EndTime.IsZero() {
+		q = q.Where("transaction.timestamp < ?", f.EndTime)
+	}
+	if f.CurrencyID != 0 {
+		q = q.Where("transaction.currency_id = ?", f.CurrencyID)
+	}
+	if f.CategoryID != 0 {
+		split := q.NewSelect().
+			Model(sampleTransactionSplit).
+			Column("transaction_id").
+			Where("category_id = ?", f.CategoryID)
+		q = q.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.
+				Where("transaction.category_id = ?", f.CategoryID).
+				WhereOr("transaction.id IN (?)", split)
+		})
+	}
+	if f.PayeeID != 0 {
+		q = q.Where("transaction.payee_id = ?", f.PayeeID)
+	}
+	if f.ExcludeSettlements {
+		q = q.Where("NOT transaction.settlement")
+	}
+	if len(f.TagIDs) != 0 {
+		tagged := q.NewSelect().
+			Model(sampleTransactionTag).
+			Column("transaction_id").
+			Where("tag_id IN (?)", bun.In(f.TagIDs)).
+			Group("transaction_id")
+		if f.AllTags {
+			tagged = tagged.Having("count(DISTINCT tag_id) = ?", len(f.TagIDs))
+		}
+		q = q.Where("transaction.id IN (?)", tagged)
+	}
+
+	return q
+}
+
+// TransactionExportRow represents a transaction flattened for export, with names of its relations instead of IDs.
+type TransactionExportRow struct {
+	UUID uuid.UUID `bun:"uuid"`
+
+	Timestamp time.Time `bun:"timestamp"`
+	ValueDate time.Time `bun:"value_date"`
+
+	Amount       int32   `bun:"amount"`
+	CurrencyCode string  `bun:"currency_code"`
+	CurrencyRate float64 `bun:"currency_rate"`
+
+	Description string `bun:"description"`
+	PayeeName   string `bun:"payee_name"`
+
+	// Name of the category of the transaction or names of the categories of its splits.
+	CategoryNames []string `bun:"category_names,array"`
+
+	// Amounts and notes of the splits of the transaction in the same order as CategoryNames, empty if it is not split.
+	SplitAmounts []int32  `bun:"split_amounts,array"`
+	SplitNotes   []string `bun:"split_notes,array"`
+
+	TagNames []string `bun:"tag_names,array"`
+}
+
 // TransactionQuerier interface describes a type which executes database queries related to the [Transaction] model.
 type TransactionQuerier interface {
-	CreateTransaction(context.Context, *Transaction) error
+	// CreateTransaction creates a new transaction together with its splits and expense shares and links it to its tags.
+	CreateTransaction(ctx context.Context, t *Transaction) error
+
+	// CreateTransactions creates all the given transactions atomically: either all of them are created or none.
+	CreateTransactions(ctx context.Context, t []*Transaction) error
+
+	SelectTransactionByUUID(ctx context.Context, uuid string, t *Transaction) error
+	SelectTransactions(ctx context.Context, f *TransactionFilter, t *[]Transaction) error
+
+	// IterateTransactionExportRows calls fn for each transaction which matches the given filter, newest first
+	// unless oldestFirst is true. Rows are read from the database one by one, iteration stops at the first error returned by fn.
+	IterateTransactionExportRows(ctx context.Context, f *TransactionFilter, oldestFirst bool, fn func(row *TransactionExportRow) error) error
+
+	// SelectTransactionExternalIDs selects those of the given external IDs which are used by transactions of the ledger.
+	SelectTransactionExternalIDs(ctx context.Context, ledgerID int64, externalIDs []string, e *[]string) error
+
+	// UpdateTransaction updates the transaction and replaces its tags with the given ones. Splits are not updated.
+	UpdateTransaction(ctx context.Context, t *Transaction) error
+}
+
+// selectTransactionsQuery returns query which selects transactions together with their relations into model.
+func (d *DefaultDatabase) selectTransactionsQuery(model any) *bun.SelectQuery {
+	return d.client.NewSelect().
+		Model(model).
+		Relation("Currency").
+		Relation("Payee").
+		Relation("Category").
+		Relation("Splits", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("split.id ASC")
+		}).
+		Relation("Splits.Category").
+		Relation("Payer").
+		Relation("Shares", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("share.id ASC")
+		}).
+		Relation("Shares.User").
+		Relation("Tags", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("tag.name ASC")
+		})
 }
 
 func (d *DefaultDatabase) CreateTransaction(ctx context.Context, t *Transaction) error {
-	if _, err := d.client.NewInsert().Model(t).Exec(ctx); err != nil {
+	return d.client.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		return insertTransaction(ctx, tx, t)
+	})
+}
+
+func (d *DefaultDatabase) CreateTransactions(ctx context.Context, t []*Transaction) error {
+	return d.client.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		for _, transaction := range t {
+			if err := insertTransaction(ctx, tx, transaction); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// insertTransaction inserts the transaction together with its splits and expense shares and links it to its tags.
+func insertTransaction(ctx context.Context, db bun.IDB, t *Transaction) error {
+	if _, err := db.NewInsert().Model(t).Exec(ctx); err != nil {
+		return err
+	}
+
+	if len(t.Splits) != 0 {
+		for i := range t.Splits {
+			t.Splits[i].TransactionID = t.ID
+		}
+		if _, err := db.NewInsert().Model(&t.Splits).Exec(ctx); err != nil {
+			return err
+		}
+	}
+
+	if len(t.Shares) != 0 {
+		for i := range t.Shares {
+			t.Shares[i].TransactionID = t.ID
+		}
+		if _, err := db.NewInsert().Model(&t.Shares).Exec(ctx); err != nil {
+			return err
+		}
+	}
+
+	return insertTransactionTags(ctx, db, t)
+}
+
+// insertTransactionTags links the transaction to its tags.
+func insertTransactionTags(ctx context.Context, db bun.IDB, t *Transaction) error {
+	if len(t.Tags) == 0 {
+		return nil
+	}
+
+	links := make([]TransactionTag, 0, len(t.Tags))
+	for _, tag := range t.Tags {
+		links = append(links, TransactionTag{TransactionID: t.ID, TagID: tag.ID})
+	}
+	if _, err := db.NewInsert().Model(&links).Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) SelectTransactionByUUID(ctx context.Context, uuid string, t *Transaction) error {
+	if err := d.selectTransactionsQuery(t).Where("transaction.uuid = ?", uuid).Scan(ctx); err != nil {
 		return err
 	}
 	return nil
 }
+
+func (d *DefaultDatabase) SelectTransactions(ctx context.Context, f *TransactionFilter, t *[]Transaction) error {
+	q := f.apply(d.selectTransactionsQuery(t)).Order("transaction.timestamp DESC", "transaction.id DESC")
+	if err := q.Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) IterateTransactionExportRows(ctx context.Context, f *TransactionFilter, oldestFirst bool, fn func(row *TransactionExportRow) error) error {
+	splits := func(column string) *bun.SelectQuery {
+		return d.client.NewSelect().
+			TableExpr("transaction_splits AS split").
+			ColumnExpr(column).
+			Join("JOIN categories AS split_category ON split_category.id = split.category_id").
+			Where("split.transaction_id = transaction.id").
+			OrderExpr("split.id ASC")
+	}
+	tags := d.client.NewSelect().
+		TableExpr("transaction_tags AS transaction_tag").
+		ColumnExpr("tag.name").
+		Join("JOIN tags AS tag ON tag.id = transaction_tag.tag_id").
+		Where("transaction_tag.transaction_id = transaction.id").
+		OrderExpr("tag.name ASC")
+
+	q := d.client.NewSelect().
+		TableExpr("transactions AS transaction").
+		ColumnExpr("transaction.uuid, transaction.timestamp, transaction.value_date, transaction.amount").
+		ColumnExpr("transaction.description").
+		ColumnExpr("currency.code AS currency_code, currency.rate AS currency_rate").
+		ColumnExpr("payee.name AS payee_name").
+		ColumnExpr("CASE WHEN transaction.category_id IS NOT NULL THEN ARRAY[category.name] ELSE ARRAY(?) END AS category_names", splits("split_category.name")).
+		ColumnExpr("ARRAY(?) AS split_amounts", splits("split.amount")).
+		ColumnExpr("ARRAY(?) AS split_notes", splits("coalesce(split.note, '')")).
+		ColumnExpr("ARRAY(?) AS tag_names", tags).
+		Join("JOIN currencies AS currency ON currency.id = transaction.currency_id").
+		Join("LEFT JOIN payees AS payee ON payee.id = transaction.payee_id").
+		Join("LEFT JOIN categories AS category ON category.id = transaction.category_id")
+	if oldestFirst {
+		q = f.apply(q).OrderExpr("transaction.timestamp ASC, transaction.id ASC")
+	} else {
+		q = f.apply(q).OrderExpr("transaction.timestamp DESC, transaction.id DESC")
+	}
+
+	rows, err := q.Rows(ctx)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := &TransactionExportRow{}
+		if err := d.db.ScanRow(ctx, rows, row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (d *DefaultDatabase) SelectTransactionExternalIDs(ctx context.Context, ledgerID int64, externalIDs []string, e *[]string) error {
+	if len(externalIDs) == 0 {
+		return nil
+	}
+
+	q := d.client.NewSelect().
+		Model(sampleTransaction).
+		Column("external_id").
+		Where("ledger_id = ?", ledgerID).
+		Where("external_id IN (?)", bun.In(externalIDs))
+	if err := q.Scan(ctx, e); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) UpdateTransaction(ctx context.Context, t *Transaction) error {
+	return d.client.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewUpdate().Model(t).WherePK().Exec(ctx); err != nil {
+			return err
+		}
+
+		// replace tags of the transaction:
+		if _, err := tx.NewDelete().Model(sampleTransactionTag).Where("transaction_id = ?", t.ID).Exec(ctx); err != nil {
+			return err
+		}
+		return insertTransactionTags(ctx, tx, t)
+	})
+}
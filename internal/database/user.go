@@ -2,7 +2,20 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"github.com/google/uuid"
 	"github.com/uptrace/bun"
+	"time"
+)
+
+// Roles of users.
+const (
+	// RoleUser is the role of ordinary users.
+	RoleUser = "user"
+
+	// RoleAdmin is the role of users who manage the instance.
+	RoleAdmin = "admin"
 )
 
 // User database model.
@@ -11,8 +24,37 @@ type User struct {
 
 	ID int64 `bun:"id,pk,autoincrement"`
 
-	Username string `bun:"username,notnull"`
+	// Public identifier of the user which never changes, it is used as the subject of tokens.
+	UUID uuid.UUID `bun:"uuid,type:uuid,notnull,unique,default:uuid_generate_v4()"`
+
+	Username string `bun:"username,notnull,unique"`
 	Password string `bun:"password,notnull"`
+
+	// Role of the user, either [RoleUser] or [RoleAdmin].
+	Role string `bun:"role,notnull,default:'user'"`
+
+	// Time when the user was disabled by an admin. Disabled users can not log in and use their tokens and API keys.
+	DisabledAt time.Time `bun:"disabled_at,nullzero"`
+
+	// CanInvite allows the user to create invite codes. Admins can always create them.
+	CanInvite bool `bun:"can_invite,notnull,default:false"`
+
+	// ID of the user who created the invite code the user was created with.
+	InvitedByID int64 `bun:"invited_by_id,nullzero"`
+
+	// Version of the user's tokens, tokens issued with another version are rejected.
+	// It is increased when the password changes.
+	TokenVersion int `bun:"token_version,notnull,default:0"`
+
+	// TOTP secret of the user. It is set on enrollment, but is required on login only after TOTPEnabled is set.
+	TOTPSecret  string `bun:"totp_secret,notnull,default:''"`
+	TOTPEnabled bool   `bun:"totp_enabled,notnull,default:false"`
+
+	// Time step of the last accepted TOTP code, codes of this and previous steps are rejected.
+	TOTPLastStep int64 `bun:"totp_last_step,notnull,default:0"`
+
+	// Hashes of unused recovery codes.
+	RecoveryCodes []string `bun:"recovery_codes,array"`
 }
 
 // UserQuerier interface describes a type which executes database queries related to the [User] model.
@@ -20,7 +62,54 @@ type UserQuerier interface {
 	CreateUser(ctx context.Context, u *User) error
 	UserExistsByUsername(ctx context.Context, username string) (bool, error)
 	SelectUserByUsername(ctx context.Context, username string, u *User) error
-	DeleteUserByUsername(ctx context.Context, username string) error
+	SelectUserByUUID(ctx context.Context, uuid string, u *User) error
+
+	// DeleteUserByID deletes the user together with its personal ledger, memberships in shared ledgers and data
+	// which is used only by the user. Categories and transactions the user created in shared ledgers stay there.
+	// Returns [ErrLastLedgerOwner] if the user is the only owner of a shared ledger
+	// and [ErrUserHasBalance] if the user has not settled up in a ledger.
+	DeleteUserByID(ctx context.Context, id int64) error
+
+	// UpdateUsername changes username of the user.
+	UpdateUsername(ctx context.Context, id int64, username string) error
+
+	// UpdateUserPasswordHash replaces password hash of the user with a new hash of the same password.
+	// Unlike [UserQuerier.ChangeUserPassword], it does not affect tokens and sessions of the user.
+	UpdateUserPasswordHash(ctx context.Context, id int64, passwordHash string) error
+
+	// ChangeUserPassword sets a new password hash of the user, increases version of the user's tokens
+	// and revokes all sessions of the user.
+	ChangeUserPassword(ctx context.Context, id int64, passwordHash string) error
+
+	// SetUserPassword sets password hash of the user who has no password, for example, created on the first OIDC login.
+	// Returns false if the user already has a password.
+	SetUserPassword(ctx context.Context, id int64, passwordHash string) (bool, error)
+
+	// UpdateUserRole changes role of the user.
+	UpdateUserRole(ctx context.Context, id int64, role string) error
+
+	// UpdateUserCanInvite allows or forbids the user to create invite codes.
+	UpdateUserCanInvite(ctx context.Context, id int64, canInvite bool) error
+
+	// DisableUser disables the user and revokes all its sessions.
+	DisableUser(ctx context.Context, id int64) error
+
+	// EnableUser enables the disabled user.
+	EnableUser(ctx context.Context, id int64) error
+
+	// SelectUsersUsage selects all users together with counts of their data, ordered by username.
+	SelectUsersUsage(ctx context.Context, u *[]UserUsage) error
+
+	// UpdateUserTOTP updates TOTP secret, TOTP status, the last used TOTP step and recovery codes of the user.
+	UpdateUserTOTP(ctx context.Context, u *User) error
+
+	// UseUserTOTPStep remembers the time step of an accepted TOTP code.
+	// Returns false if a code of this or a later step was already used.
+	UseUserTOTPStep(ctx context.Context, id int64, step int64) (bool, error)
+
+	// UseUserRecoveryCode removes the recovery code hash from the user's recovery codes.
+	// Returns false if the code was already used.
+	UseUserRecoveryCode(ctx context.Context, id int64, hash string) (bool, error)
 }
 
 func (d *DefaultDatabase) selectUserByUsernameQuery(username string) *bun.SelectQuery {
@@ -49,9 +138,261 @@ func (d *DefaultDatabase) UserExistsByUsername(ctx context.Context, username str
 	return exists, nil
 }
 
-func (d *DefaultDatabase) DeleteUserByUsername(ctx context.Context, username string) error {
-	if _, err := d.client.NewDelete().Model(sampleUser).Where("username = ?", username).Exec(ctx); err != nil {
+func (d *DefaultDatabase) SelectUserByUUID(ctx context.Context, uuid string, u *User) error {
+	if err := d.client.NewSelect().Model(u).Where("uuid = ?", uuid).Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) DeleteUserByID(ctx context.Context, id int64) error {
+	return d.client.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		// shared ledgers must keep at least one owner:
+		ownerships := make([]int64, 0)
+		if err := tx.NewSelect().
+			Model(sampleLedgerMember).
+			Column("member.id").
+			Join("JOIN ledgers AS ledger ON ledger.id = member.ledger_id").
+			Where("member.user_id = ?", id).
+			Where("member.role = ?", LedgerRoleOwner).
+			Where("ledger.personal_user_id IS NULL").
+			Scan(ctx, &ownerships); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		for _, memberID := range ownerships {
+			if err := checkLedgerKeepsOwner(ctx, tx, memberID); err != nil {
+				return err
+			}
+		}
+
+		// debts of deleted users could not be settled:
+		hasBalance, err := userHasBalance(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if hasBalance {
+			return ErrUserHasBalance
+		}
+
+		personalLedgers := tx.NewSelect().Model(sampleLedger).Column("id").Where("personal_user_id = ?", id)
+		personalTransactions := tx.NewSelect().Model(sampleTransaction).Column("id").Where("ledger_id IN (?)", personalLedgers)
+		queries := []*bun.DeleteQuery{
+			// delete the personal ledger with its transactions and categories:
+			tx.NewDelete().Model(sampleTransactionSplit).Where("transaction_id IN (?)", personalTransactions),
+			tx.NewDelete().Model(sampleTransactionTag).Where("transaction_id IN (?)", personalTransactions),
+			tx.NewDelete().Model(sampleExpenseShare).Where("transaction_id IN (?)", personalTransactions),
+			tx.NewDelete().Model(sampleTransaction).Where("ledger_id IN (?)", personalLedgers),
+			tx.NewDelete().Model(sampleCategory).Where("ledger_id IN (?)", personalLedgers),
+			tx.NewDelete().Model(sampleLedgerMember).Where("ledger_id IN (?) OR user_id = ?", personalLedgers, id),
+			tx.NewDelete().Model(sampleLedger).Where("personal_user_id = ?", id),
+
+			// tags and payees are kept while transactions in shared ledgers refer to them:
+			tx.NewDelete().Model(sampleTag).
+				Where("owner_id = ?", id).
+				Where("NOT EXISTS (?)", tx.NewSelect().Model(sampleTransactionTag).Column("tag_id").Where("transaction_tag.tag_id = tag.id")),
+			tx.NewDelete().Model(samplePayee).
+				Where("owner_id = ?", id).
+				Where("NOT EXISTS (?)", tx.NewSelect().Model(sampleTransaction).Column("id").Where("transaction.payee_id = payee.id")),
+
+			// delete data which is used only by the user:
+			tx.NewDelete().Model(sampleRule).Where("owner_id = ?", id),
+			tx.NewDelete().Model(sampleImportReport).Where("owner_id = ?", id),
+			tx.NewDelete().Model(sampleRefreshToken).Where("owner_id = ?", id),
+			tx.NewDelete().Model(sampleSession).Where("owner_id = ?", id),
+			tx.NewDelete().Model(sampleAPIKey).Where("owner_id = ?", id),
+			tx.NewDelete().Model(sampleExternalIdentity).Where("owner_id = ?", id),
+			tx.NewDelete().Model(sampleInviteCode).Where("creator_id = ?", id),
+			tx.NewDelete().Model(sampleUser).Where("id = ?", id),
+		}
+		for _, query := range queries {
+			if _, err := query.Exec(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (d *DefaultDatabase) UpdateUsername(ctx context.Context, id int64, username string) error {
+	if _, err := d.client.NewUpdate().
+		Model(sampleUser).
+		Set("username = ?", username).
+		Where("id = ?", id).
+		Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) UpdateUserPasswordHash(ctx context.Context, id int64, passwordHash string) error {
+	if _, err := d.client.NewUpdate().
+		Model(sampleUser).
+		Set("password = ?", passwordHash).
+		Where("id = ?", id).
+		Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) ChangeUserPassword(ctx context.Context, id int64, passwordHash string) error {
+	return d.client.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		db := &DefaultDatabase{db: d.db, client: tx}
+
+		result, err := db.client.NewUpdate().
+			Model(sampleUser).
+			Set("password = ?", passwordHash).
+			Set("token_version = token_version + 1").
+			Where("id = ?", id).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return sql.ErrNoRows
+		}
+
+		return db.revokeSessions(ctx, "owner_id = ?", id)
+	})
+}
+
+func (d *DefaultDatabase) SetUserPassword(ctx context.Context, id int64, passwordHash string) (bool, error) {
+	return updatedOne(ctx, d.client.NewUpdate().
+		Model(sampleUser).
+		Set("password = ?", passwordHash).
+		Where("id = ?", id).
+		Where("password = ''"))
+}
+
+func (d *DefaultDatabase) UpdateUserRole(ctx context.Context, id int64, role string) error {
+	if _, err := d.client.NewUpdate().
+		Model(sampleUser).
+		Set("role = ?", role).
+		Where("id = ?", id).
+		Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) UpdateUserCanInvite(ctx context.Context, id int64, canInvite bool) error {
+	if _, err := d.client.NewUpdate().
+		Model(sampleUser).
+		Set("can_invite = ?", canInvite).
+		Where("id = ?", id).
+		Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) DisableUser(ctx context.Context, id int64) error {
+	return d.client.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		db := &DefaultDatabase{db: d.db, client: tx}
+
+		if _, err := db.client.NewUpdate().
+			Model(sampleUser).
+			Set("disabled_at = ?", time.Now()).
+			Where("id = ?", id).
+			Where("disabled_at IS NULL").
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		return db.revokeSessions(ctx, "owner_id = ?", id)
+	})
+}
+
+func (d *DefaultDatabase) EnableUser(ctx context.Context, id int64) error {
+	if _, err := d.client.NewUpdate().
+		Model(sampleUser).
+		Set("disabled_at = NULL").
+		Where("id = ?", id).
+		Exec(ctx); err != nil {
 		return err
 	}
 	return nil
 }
+
+// UserUsage is a user together with counts of its data.
+type UserUsage struct {
+	User `bun:",extend"`
+
+	Transactions int `bun:"transactions"`
+	Categories   int `bun:"categories"`
+	Tags         int `bun:"tags"`
+	Rules        int `bun:"rules"`
+	APIKeys      int `bun:"api_keys"`
+
+	// Count of sessions which are neither revoked nor expired.
+	ActiveSessions int `bun:"active_sessions"`
+
+	// Time when a session of the user was seen last time.
+	LastSeenAt time.Time `bun:"last_seen_at,nullzero"`
+
+	// Username of the user who invited the user, it is empty if the user was not invited.
+	InvitedBy string `bun:"invited_by,nullzero"`
+}
+
+func (d *DefaultDatabase) SelectUsersUsage(ctx context.Context, u *[]UserUsage) error {
+	q := d.client.NewSelect().
+		Model(u).
+		ColumnExpr("?TableAlias.*").
+		ColumnExpr("(SELECT count(*) FROM transactions WHERE transactions.owner_id = ?TableAlias.id) AS transactions").
+		ColumnExpr("(SELECT count(*) FROM categories WHERE categories.owner_id = ?TableAlias.id) AS categories").
+		ColumnExpr("(SELECT count(*) FROM tags WHERE tags.owner_id = ?TableAlias.id) AS tags").
+		ColumnExpr("(SELECT count(*) FROM rules WHERE rules.owner_id = ?TableAlias.id) AS rules").
+		ColumnExpr("(SELECT count(*) FROM api_keys WHERE api_keys.owner_id = ?TableAlias.id) AS api_keys").
+		ColumnExpr("(SELECT count(*) FROM sessions WHERE sessions.owner_id = ?TableAlias.id AND sessions.revoked_at IS NULL AND sessions.expires_at > now()) AS active_sessions").
+		ColumnExpr("(SELECT max(sessions.last_seen_at) FROM sessions WHERE sessions.owner_id = ?TableAlias.id) AS last_seen_at").
+		ColumnExpr("(SELECT inviter.username FROM users AS inviter WHERE inviter.id = ?TableAlias.invited_by_id) AS invited_by").
+		Order("username ASC")
+	if err := q.Scan(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DefaultDatabase) UpdateUserTOTP(ctx context.Context, u *User) error {
+	if _, err := d.client.NewUpdate().
+		Model(u).
+		Column("totp_secret", "totp_enabled", "totp_last_step", "recovery_codes").
+		WherePK().
+		Exec(ctx); err != nil {
+		return err
+	}
+	return nil
+}
+
+// updatedOne executes the update query and returns true if exactly one row was updated.
+func updatedOne(ctx context.Context, q *bun.UpdateQuery) (bool, error) {
+	result, err := q.Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (d *DefaultDatabase) UseUserTOTPStep(ctx context.Context, id int64, step int64) (bool, error) {
+	return updatedOne(ctx, d.client.NewUpdate().
+		Model(sampleUser).
+		Set("totp_last_step = ?", step).
+		Where("id = ?", id).
+		Where("totp_last_step < ?", step))
+}
+
+func (d *DefaultDatabase) UseUserRecoveryCode(ctx context.Context, id int64, hash string) (bool, error) {
+	return updatedOne(ctx, d.client.NewUpdate().
+		Model(sampleUser).
+		Set("recovery_codes = array_remove(recovery_codes, ?)", hash).
+		Where("id = ?", id).
+		Where("? = ANY(recovery_codes)", hash))
+}
@@ -0,0 +1,28 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// csvWriter writes rows in [FormatCSV].
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return nil, err
+	}
+	return &csvWriter{writer: writer}, nil
+}
+
+func (c *csvWriter) Write(row *Row) error {
+	return c.writer.Write(row.cells())
+}
+
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
@@ -0,0 +1,199 @@
+// Package export writes transactions to files of various formats.
+// Rows are written one by one, so that exports of any size may be streamed without buffering them in memory.
+package export
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// FormatCSV is the comma-separated values format with a header row.
+	FormatCSV = "csv"
+
+	// FormatNDJSON is the newline delimited JSON (JSON Lines) format, each line is a JSON object.
+	FormatNDJSON = "ndjson"
+
+	// FormatXLSX is the Office Open XML spreadsheet format used by Excel.
+	FormatXLSX = "xlsx"
+
+	// FormatLedger is the plain-text accounting journal format of ledger-cli, which is also read by hledger.
+	FormatLedger = "ledger"
+
+	// FormatBeancount is the plain-text accounting format of beancount.
+	FormatBeancount = "beancount"
+)
+
+// ErrUnknownFormat is returned when export format is not supported.
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// contentTypes contains MIME types of the supported formats.
+var contentTypes = map[string]string{
+	FormatCSV:       "text/csv; charset=utf-8",
+	FormatNDJSON:    "application/x-ndjson",
+	FormatXLSX:      "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	FormatLedger:    "text/plain; charset=utf-8",
+	FormatBeancount: "text/plain; charset=utf-8",
+}
+
+// columns contains names of the columns of tabular formats.
+var columns = []string{
+	"date", "value_date", "amount", "currency", "converted_amount", "converted_currency",
+	"payee", "category", "tags", "description", "uuid",
+}
+
+// listSeparator separates multiple categories and tags in a single cell of tabular formats.
+const listSeparator = "; "
+
+// Row represents an exported transaction.
+type Row struct {
+	UUID string
+
+	// Timestamp of the transaction and its optional value date (zero if absent).
+	Timestamp time.Time
+	ValueDate time.Time
+
+	// Amount in minor units of the currency of the transaction, negative for expenses.
+	Amount   int32
+	Currency string
+
+	// Amount converted to the currency of the export in its minor units.
+	ConvertedAmount   int64
+	ConvertedCurrency string
+
+	Payee string
+
+	// Category of the transaction or categories of its splits.
+	Categories []string
+
+	// Splits of the transaction, empty if it is not split.
+	Splits []Split
+
+	Tags        []string
+	Description string
+}
+
+// Split represents a split line of an exported transaction.
+type Split struct {
+	Category string
+
+	// Amount in minor units of the currency of the transaction, negative for expenses.
+	Amount int32
+
+	Note string
+}
+
+// Declarations describe accounts and commodities which are declared at the beginning of plain-text accounting files
+// ([FormatLedger] and [FormatBeancount]), other formats do not use them.
+type Declarations struct {
+	// Names of the categories, each category is declared both as an expense and as an income account.
+	Categories []string
+
+	// Currencies used by the exported transactions.
+	Commodities []Commodity
+}
+
+// Commodity represents a currency declared as a commodity.
+type Commodity struct {
+	Code   string
+	Symbol string
+}
+
+// Writer writes rows to a file of some format.
+type Writer interface {
+	// Write writes a single row.
+	Write(row *Row) error
+
+	// Close finishes the file and flushes buffered data. It does not close the underlying writer.
+	Close() error
+}
+
+// NewWriter creates a new [Writer] which writes rows in the given format to w.
+// Declarations are required by plain-text accounting formats and may be nil for other formats.
+func NewWriter(format string, w io.Writer, declarations *Declarations) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatNDJSON:
+		return newNDJSONWriter(w), nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	case FormatLedger:
+		return newLedgerWriter(w, declarations)
+	case FormatBeancount:
+		return newBeancountWriter(w, declarations)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// IsPlainTextAccounting reports whether the given format is a plain-text accounting format which requires [Declarations].
+func IsPlainTextAccounting(format string) bool {
+	return format == FormatLedger || format == FormatBeancount
+}
+
+// ContentType returns MIME type of the given format, empty string if the format is unknown.
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// currencyExponents contains numbers of minor unit digits of ISO 4217 currencies which do not have two of them.
+var currencyExponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// CurrencyExponent returns the number of minor unit digits of the currency, two for unknown currencies.
+func CurrencyExponent(currency string) int {
+	if exponent, ok := currencyExponents[currency]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// FormatAmount formats amount in minor units of the currency as a decimal number with as many fraction digits
+// as the currency has minor unit digits, e.g. "-12.50" for EUR, "-1250" for JPY and "-1.250" for KWD.
+func FormatAmount(amount int64, currency string) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	exponent := CurrencyExponent(currency)
+	if exponent == 0 {
+		return sign + strconv.FormatInt(amount, 10)
+	}
+
+	divisor := int64(1)
+	for i := 0; i < exponent; i++ {
+		divisor *= 10
+	}
+	fraction := strconv.FormatInt(amount%divisor, 10)
+	fraction = strings.Repeat("0", exponent-len(fraction)) + fraction
+	return sign + strconv.FormatInt(amount/divisor, 10) + "." + fraction
+}
+
+// cells returns values of the row in order of [columns] for tabular formats.
+func (r *Row) cells() []string {
+	var valueDate string
+	if !r.ValueDate.IsZero() {
+		valueDate = r.ValueDate.Format(time.DateOnly)
+	}
+	return []string{
+		r.Timestamp.UTC().Format(time.RFC3339),
+		valueDate,
+		FormatAmount(int64(r.Amount), r.Currency),
+		r.Currency,
+		FormatAmount(r.ConvertedAmount, r.ConvertedCurrency),
+		r.ConvertedCurrency,
+		r.Payee,
+		strings.Join(r.Categories, listSeparator),
+		strings.Join(r.Tags, listSeparator),
+		r.Description,
+		r.UUID,
+	}
+}
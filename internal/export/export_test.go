@@ -0,0 +1,121 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRows returns rows used in tests.
+func testRows() []*Row {
+	return []*Row{
+		{
+			UUID:              "3be1ed0a-c307-49de-872e-38730200f301",
+			Timestamp:         time.Date(2026, time.March, 5, 14, 30, 0, 0, time.UTC),
+			ValueDate:         time.Date(2026, time.March, 4, 0, 0, 0, 0, time.UTC),
+			Amount:            -1250,
+			Currency:          "USD",
+			ConvertedAmount:   -1150,
+			ConvertedCurrency: "EUR",
+			Payee:             "Corner Store",
+			Categories:        []string{"Groceries", "Household"},
+			Tags:              []string{"vacation-2026"},
+			Description:       `Soap, "premium" & milk`,
+		},
+		{
+			UUID:              "02983837-7ab0-492a-90b6-285491936067",
+			Timestamp:         time.Date(2026, time.March, 1, 9, 0, 0, 0, time.UTC),
+			Amount:            250000,
+			Currency:          "EUR",
+			ConvertedAmount:   250000,
+			ConvertedCurrency: "EUR",
+		},
+	}
+}
+
+// export writes the rows in the given format and returns the result.
+func export(t *testing.T, format string, rows []*Row) []byte {
+	buffer := &bytes.Buffer{}
+	writer, err := NewWriter(format, buffer, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for _, row := range rows {
+		assert.NoError(t, writer.Write(row))
+	}
+	assert.NoError(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func TestFormatAmount(t *testing.T) {
+	testCases := []struct {
+		amount   int64
+		currency string
+		s        string
+	}{
+		{0, "EUR", "0.00"},
+		{5, "EUR", "0.05"},
+		{-5, "EUR", "-0.05"},
+		{1250, "EUR", "12.50"},
+		{-123456, "EUR", "-1234.56"},
+		{-1250, "JPY", "-1250"},
+		{0, "JPY", "0"},
+		{1250, "KWD", "1.250"},
+		{-5, "BHD", "-0.005"},
+		{1250, "XYZ", "12.50"},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.s, FormatAmount(testCase.amount, testCase.currency))
+	}
+}
+
+func TestNewWriter(t *testing.T) {
+	_, err := NewWriter("pdf", &bytes.Buffer{}, nil)
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+	assert.Empty(t, ContentType("pdf"))
+	assert.Equal(t, "application/x-ndjson", ContentType(FormatNDJSON))
+}
+
+func TestCSVWriter(t *testing.T) {
+	expected := strings.Join([]string{
+		"date,value_date,amount,currency,converted_amount,converted_currency,payee,category,tags,description,uuid",
+		`2026-03-05T14:30:00Z,2026-03-04,-12.50,USD,-11.50,EUR,Corner Store,Groceries; Household,vacation-2026,"Soap, ""premium"" & milk",3be1ed0a-c307-49de-872e-38730200f301`,
+		"2026-03-01T09:00:00Z,,2500.00,EUR,2500.00,EUR,,,,,02983837-7ab0-492a-90b6-285491936067",
+		"",
+	}, "\n")
+	assert.Equal(t, expected, string(export(t, FormatCSV, testRows())))
+
+	// header is written even if there are no rows:
+	assert.Equal(t, strings.SplitAfter(expected, "\n")[0], string(export(t, FormatCSV, nil)))
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(string(export(t, FormatNDJSON, testRows())), "\n"), "\n")
+	if !assert.Len(t, lines, 2) {
+		return
+	}
+
+	first := map[string]any{}
+	if assert.NoError(t, json.Unmarshal([]byte(lines[0]), &first)) {
+		assert.Equal(t, "2026-03-04T00:00:00Z", first["value_date"])
+		assert.Equal(t, float64(-1250), first["amount"])
+		assert.Equal(t, float64(-1150), first["converted_amount"])
+		assert.Equal(t, []any{"Groceries", "Household"}, first["categories"])
+		assert.Equal(t, "Corner Store", first["payee"])
+	}
+
+	assert.JSONEq(t, `{
+		"uuid": "02983837-7ab0-492a-90b6-285491936067",
+		"timestamp": "2026-03-01T09:00:00Z",
+		"amount": 250000,
+		"currency": "EUR",
+		"converted_amount": 250000,
+		"converted_currency": "EUR",
+		"categories": [],
+		"tags": [],
+		"description": ""
+	}`, lines[1])
+}
@@ -0,0 +1,69 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// ndjsonRow represents a row in [FormatNDJSON], amounts are in minor units as everywhere in the API.
+type ndjsonRow struct {
+	UUID              string     `json:"uuid"`
+	Timestamp         time.Time  `json:"timestamp"`
+	ValueDate         *time.Time `json:"value_date,omitempty"`
+	Amount            int32      `json:"amount"`
+	Currency          string     `json:"currency"`
+	ConvertedAmount   int64      `json:"converted_amount"`
+	ConvertedCurrency string     `json:"converted_currency"`
+	Payee             string     `json:"payee,omitempty"`
+	Categories        []string   `json:"categories"`
+	Tags              []string   `json:"tags"`
+	Description       string     `json:"description"`
+}
+
+// ndjsonWriter writes rows in [FormatNDJSON].
+type ndjsonWriter struct {
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	buffer := bufio.NewWriter(w)
+	return &ndjsonWriter{buffer: buffer, encoder: json.NewEncoder(buffer)}
+}
+
+func (n *ndjsonWriter) Write(row *Row) error {
+	var valueDate *time.Time
+	if !row.ValueDate.IsZero() {
+		valueDate = &row.ValueDate
+	}
+
+	// empty lists are written as arrays rather than nulls:
+	categories, tags := row.Categories, row.Tags
+	if categories == nil {
+		categories = []string{}
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+
+	// encoder terminates each value with a newline:
+	return n.encoder.Encode(&ndjsonRow{
+		UUID:              row.UUID,
+		Timestamp:         row.Timestamp.UTC(),
+		ValueDate:         valueDate,
+		Amount:            row.Amount,
+		Currency:          row.Currency,
+		ConvertedAmount:   row.ConvertedAmount,
+		ConvertedCurrency: row.ConvertedCurrency,
+		Payee:             row.Payee,
+		Categories:        categories,
+		Tags:              tags,
+		Description:       row.Description,
+	})
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.buffer.Flush()
+}
@@ -0,0 +1,287 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// plainTextAssetsAccount is the account which balances postings to the category accounts.
+	plainTextAssetsAccount = "Assets:Groshi"
+
+	// plainTextUncategorized is the name of the category transactions without category are attributed to.
+	plainTextUncategorized = "Uncategorized"
+
+	// plainTextOpenDate is the date accounts and commodities are declared at,
+	// it is fixed so that the output does not change between exports.
+	plainTextOpenDate = "1970-01-01"
+)
+
+// plainTextAccountComponent converts a part of category name to a component of account name which is valid both
+// in ledger and beancount: it starts with an uppercase letter or a digit and consists of letters, digits and dashes.
+func plainTextAccountComponent(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.TrimSpace(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() != 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			if b.Len() == 0 {
+				r = unicode.ToUpper(r)
+				if !unicode.IsUpper(r) && !unicode.IsDigit(r) {
+					// letters without case can not start a component:
+					b.WriteByte('X')
+				}
+			}
+			b.WriteRune(r)
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// plainTextAccount returns the account an amount attributed to the category is posted to:
+// expenses are posted to "Expenses:<category>" and incomes to "Income:<category>".
+// Colons in the category name separate subaccounts.
+func plainTextAccount(category string, amount int32) string {
+	components := []string{"Income"}
+	if amount < 0 {
+		components[0] = "Expenses"
+	}
+	for _, part := range strings.Split(category, ":") {
+		if component := plainTextAccountComponent(part); component != "" {
+			components = append(components, component)
+		}
+	}
+	if len(components) == 1 {
+		components = append(components, plainTextUncategorized)
+	}
+	return strings.Join(components, ":")
+}
+
+// plainTextAccounts returns sorted names of the accounts declared by the declarations.
+func plainTextAccounts(declarations *Declarations) []string {
+	categories := []string{plainTextUncategorized}
+	if declarations != nil {
+		categories = append(categories, declarations.Categories...)
+	}
+
+	unique := map[string]bool{plainTextAssetsAccount: true}
+	for _, category := range categories {
+		unique[plainTextAccount(category, -1)] = true
+		unique[plainTextAccount(category, 1)] = true
+	}
+
+	accounts := make([]string, 0, len(unique))
+	for account := range unique {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+	return accounts
+}
+
+// plainTextCommodities returns commodities of the declarations sorted by code.
+func plainTextCommodities(declarations *Declarations) []Commodity {
+	if declarations == nil {
+		return nil
+	}
+	commodities := append([]Commodity(nil), declarations.Commodities...)
+	sort.Slice(commodities, func(i, j int) bool {
+		return commodities[i].Code < commodities[j].Code
+	})
+	return commodities
+}
+
+// plainTextPosting represents a posting of a transaction to an account.
+type plainTextPosting struct {
+	account string
+	amount  int64
+	note    string
+}
+
+// plainTextPostings returns postings of the row: to the category accounts (one per split) and to the assets account.
+func (r *Row) plainTextPostings() []plainTextPosting {
+	postings := make([]plainTextPosting, 0, len(r.Splits)+2)
+	if len(r.Splits) != 0 {
+		for _, split := range r.Splits {
+			postings = append(postings, plainTextPosting{
+				account: plainTextAccount(split.Category, split.Amount),
+				amount:  -int64(split.Amount),
+				note:    split.Note,
+			})
+		}
+	} else {
+		var category string
+		if len(r.Categories) != 0 {
+			category = r.Categories[0]
+		}
+		postings = append(postings, plainTextPosting{
+			account: plainTextAccount(category, r.Amount),
+			amount:  -int64(r.Amount),
+		})
+	}
+	return append(postings, plainTextPosting{account: plainTextAssetsAccount, amount: int64(r.Amount)})
+}
+
+// singleLine replaces line breaks in s with spaces.
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// ledgerTag converts s to a ledger tag name which contains neither whitespace nor colons.
+func ledgerTag(s string) string {
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == ':'
+	}), "-")
+}
+
+// ledgerWriter writes rows in [FormatLedger].
+type ledgerWriter struct {
+	buffer *bufio.Writer
+}
+
+func newLedgerWriter(w io.Writer, declarations *Declarations) (*ledgerWriter, error) {
+	l := &ledgerWriter{buffer: bufio.NewWriter(w)}
+
+	for _, commodity := range plainTextCommodities(declarations) {
+		fmt.Fprintf(l.buffer, "commodity %s\n    format 1000.00 %s\n", commodity.Code, commodity.Code)
+	}
+	for _, account := range plainTextAccounts(declarations) {
+		fmt.Fprintf(l.buffer, "account %s\n", account)
+	}
+
+	// errors are reported by the buffer:
+	if _, err := l.buffer.WriteString("\n"); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Write writes the row as a cleared transaction. Payee is used as the transaction description if present,
+// description is written as a comment then. UUID, value date and tags are written as metadata comments.
+func (l *ledgerWriter) Write(row *Row) error {
+	payee, description := singleLine(row.Payee), singleLine(row.Description)
+	if payee == "" {
+		payee, description = description, ""
+	}
+	fmt.Fprintf(l.buffer, "%s *", row.Timestamp.UTC().Format(time.DateOnly))
+	if payee != "" {
+		fmt.Fprintf(l.buffer, " %s", payee)
+	}
+	l.buffer.WriteString("\n")
+
+	if description != "" {
+		fmt.Fprintf(l.buffer, "    ; %s\n", description)
+	}
+	fmt.Fprintf(l.buffer, "    ; uuid: %s\n", row.UUID)
+	if !row.ValueDate.IsZero() {
+		fmt.Fprintf(l.buffer, "    ; value_date: %s\n", row.ValueDate.Format(time.DateOnly))
+	}
+	tags := make([]string, 0, len(row.Tags))
+	for _, tag := range row.Tags {
+		if tag = ledgerTag(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) != 0 {
+		fmt.Fprintf(l.buffer, "    ; :%s:\n", strings.Join(tags, ":"))
+	}
+
+	for _, posting := range row.plainTextPostings() {
+		fmt.Fprintf(l.buffer, "    %-40s  %16s", posting.account, FormatAmount(posting.amount, row.Currency)+" "+row.Currency)
+		if note := singleLine(posting.note); note != "" {
+			fmt.Fprintf(l.buffer, "  ; %s", note)
+		}
+		l.buffer.WriteString("\n")
+	}
+
+	_, err := l.buffer.WriteString("\n")
+	return err
+}
+
+func (l *ledgerWriter) Close() error {
+	return l.buffer.Flush()
+}
+
+// beancountString quotes s as a beancount string.
+func beancountString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(singleLine(s)) + `"`
+}
+
+// beancountTag converts s to a beancount tag name which consists of letters, digits and "-", "_", "/", "." characters.
+func beancountTag(s string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_/.", r)) {
+			return r
+		}
+		return '-'
+	}, strings.TrimSpace(s)), "-")
+}
+
+// beancountWriter writes rows in [FormatBeancount].
+type beancountWriter struct {
+	buffer *bufio.Writer
+}
+
+func newBeancountWriter(w io.Writer, declarations *Declarations) (*beancountWriter, error) {
+	b := &beancountWriter{buffer: bufio.NewWriter(w)}
+
+	for _, commodity := range plainTextCommodities(declarations) {
+		fmt.Fprintf(b.buffer, "%s commodity %s\n", plainTextOpenDate, commodity.Code)
+		if commodity.Symbol != "" {
+			fmt.Fprintf(b.buffer, "  symbol: %s\n", beancountString(commodity.Symbol))
+		}
+	}
+	for _, account := range plainTextAccounts(declarations) {
+		fmt.Fprintf(b.buffer, "%s open %s\n", plainTextOpenDate, account)
+	}
+
+	// errors are reported by the buffer:
+	if _, err := b.buffer.WriteString("\n"); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Write writes the row as a completed transaction with payee and narration. UUID and value date are written
+// as metadata of the transaction, notes of the splits as metadata of the postings.
+func (b *beancountWriter) Write(row *Row) error {
+	fmt.Fprintf(b.buffer, "%s *", row.Timestamp.UTC().Format(time.DateOnly))
+	if payee := singleLine(row.Payee); payee != "" {
+		fmt.Fprintf(b.buffer, " %s", beancountString(payee))
+	}
+	fmt.Fprintf(b.buffer, " %s", beancountString(row.Description))
+	for _, tag := range row.Tags {
+		if tag = beancountTag(tag); tag != "" {
+			fmt.Fprintf(b.buffer, " #%s", tag)
+		}
+	}
+	b.buffer.WriteString("\n")
+
+	fmt.Fprintf(b.buffer, "  uuid: %s\n", beancountString(row.UUID))
+	if !row.ValueDate.IsZero() {
+		fmt.Fprintf(b.buffer, "  value_date: %s\n", row.ValueDate.Format(time.DateOnly))
+	}
+
+	for _, posting := range row.plainTextPostings() {
+		fmt.Fprintf(b.buffer, "  %-40s  %16s\n", posting.account, FormatAmount(posting.amount, row.Currency)+" "+row.Currency)
+		if note := singleLine(posting.note); note != "" {
+			fmt.Fprintf(b.buffer, "    note: %s\n", beancountString(note))
+		}
+	}
+
+	_, err := b.buffer.WriteString("\n")
+	return err
+}
+
+func (b *beancountWriter) Close() error {
+	return b.buffer.Flush()
+}
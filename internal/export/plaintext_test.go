@@ -0,0 +1,136 @@
+package export
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// testDeclarations returns declarations used in tests.
+func testDeclarations() *Declarations {
+	return &Declarations{
+		Categories:  []string{"Household", "groceries & drinks"},
+		Commodities: []Commodity{{Code: "USD", Symbol: "$"}, {Code: "EUR", Symbol: "€"}},
+	}
+}
+
+// testPlainTextRows returns rows used in plain-text accounting tests.
+func testPlainTextRows() []*Row {
+	return []*Row{
+		{
+			UUID:        "3be1ed0a-c307-49de-872e-38730200f301",
+			Timestamp:   time.Date(2026, time.March, 5, 23, 30, 0, 0, time.FixedZone("UTC-1", -3600)),
+			ValueDate:   time.Date(2026, time.March, 4, 0, 0, 0, 0, time.UTC),
+			Amount:      -1250,
+			Currency:    "USD",
+			Payee:       "Corner Store",
+			Categories:  []string{"groceries & drinks", "Household"},
+			Splits:      []Split{{Category: "groceries & drinks", Amount: -1000}, {Category: "Household", Amount: -250, Note: "Dish soap"}},
+			Tags:        []string{"summer trip"},
+			Description: `Weekly "big" shopping`,
+		},
+		{
+			UUID:      "02983837-7ab0-492a-90b6-285491936067",
+			Timestamp: time.Date(2026, time.March, 7, 9, 0, 0, 0, time.UTC),
+			Amount:    250000,
+			Currency:  "EUR",
+		},
+	}
+}
+
+// exportPlainText writes the rows in the given plain-text accounting format and returns the result.
+func exportPlainText(t *testing.T, format string, rows []*Row) string {
+	buffer := &bytes.Buffer{}
+	writer, err := NewWriter(format, buffer, testDeclarations())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for _, row := range rows {
+		assert.NoError(t, writer.Write(row))
+	}
+	assert.NoError(t, writer.Close())
+	return buffer.String()
+}
+
+func TestPlainTextAccount(t *testing.T) {
+	testCases := []struct {
+		category string
+		amount   int32
+		account  string
+	}{
+		{"Groceries", -1, "Expenses:Groceries"},
+		{"Salary", 1, "Income:Salary"},
+		{"groceries & drinks", -1, "Expenses:Groceries-drinks"},
+		{"Food: restaurants", -1, "Expenses:Food:Restaurants"},
+		{"Café", -1, "Expenses:Café"},
+		{"食品", -1, "Expenses:X食品"},
+		{"2026 trip", -1, "Expenses:2026-trip"},
+		{"", -1, "Expenses:Uncategorized"},
+		{"!!!", 1, "Income:Uncategorized"},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.account, plainTextAccount(testCase.category, testCase.amount), testCase.category)
+	}
+}
+
+func TestLedgerWriter(t *testing.T) {
+	expected := `commodity EUR
+    format 1000.00 EUR
+commodity USD
+    format 1000.00 USD
+account Assets:Groshi
+account Expenses:Groceries-drinks
+account Expenses:Household
+account Expenses:Uncategorized
+account Income:Groceries-drinks
+account Income:Household
+account Income:Uncategorized
+
+2026-03-06 * Corner Store
+    ; Weekly "big" shopping
+    ; uuid: 3be1ed0a-c307-49de-872e-38730200f301
+    ; value_date: 2026-03-04
+    ; :summer-trip:
+    Expenses:Groceries-drinks                        10.00 USD
+    Expenses:Household                                2.50 USD  ; Dish soap
+    Assets:Groshi                                   -12.50 USD
+
+2026-03-07 *
+    ; uuid: 02983837-7ab0-492a-90b6-285491936067
+    Income:Uncategorized                          -2500.00 EUR
+    Assets:Groshi                                  2500.00 EUR
+
+`
+	assert.Equal(t, expected, exportPlainText(t, FormatLedger, testPlainTextRows()))
+}
+
+func TestBeancountWriter(t *testing.T) {
+	expected := `1970-01-01 commodity EUR
+  symbol: "€"
+1970-01-01 commodity USD
+  symbol: "$"
+1970-01-01 open Assets:Groshi
+1970-01-01 open Expenses:Groceries-drinks
+1970-01-01 open Expenses:Household
+1970-01-01 open Expenses:Uncategorized
+1970-01-01 open Income:Groceries-drinks
+1970-01-01 open Income:Household
+1970-01-01 open Income:Uncategorized
+
+2026-03-06 * "Corner Store" "Weekly \"big\" shopping" #summer-trip
+  uuid: "3be1ed0a-c307-49de-872e-38730200f301"
+  value_date: 2026-03-04
+  Expenses:Groceries-drinks                        10.00 USD
+  Expenses:Household                                2.50 USD
+    note: "Dish soap"
+  Assets:Groshi                                   -12.50 USD
+
+2026-03-07 * ""
+  uuid: "02983837-7ab0-492a-90b6-285491936067"
+  Income:Uncategorized                          -2500.00 EUR
+  Assets:Groshi                                  2500.00 EUR
+
+`
+	assert.Equal(t, expected, exportPlainText(t, FormatBeancount, testPlainTextRows()))
+}
@@ -0,0 +1,210 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Static parts of the XLSX package. The workbook contains a single worksheet, strings are stored inline
+// in the cells so that no shared string table has to be built before the worksheet is written.
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`
+
+	xlsxRootRelationships = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Transactions" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`
+
+	xlsxWorkbookRelationships = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`
+
+	// cell formats are referenced by the xlsxStyle constants.
+	xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<numFmts count="2"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/><numFmt numFmtId="165" formatCode="yyyy-mm-dd"/></numFmts>` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="5">` +
+		`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+		`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+		`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+		`<xf numFmtId="2" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+		`</cellXfs>` +
+		`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+		`</styleSheet>`
+
+	xlsxWorksheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>` +
+		`<cols><col min="1" max="1" width="20" customWidth="1"/><col min="2" max="2" width="12" customWidth="1"/>` +
+		`<col min="7" max="10" width="24" customWidth="1"/><col min="11" max="11" width="38" customWidth="1"/></cols>` +
+		`<sheetData>`
+
+	xlsxWorksheetFooter = `</sheetData></worksheet>`
+)
+
+// Indexes of the cell formats defined in xlsxStyles.
+const (
+	xlsxStyleDefault = iota
+	xlsxStyleHeader
+	xlsxStyleDateTime
+	xlsxStyleDate
+	xlsxStyleAmount
+)
+
+// xlsxEpoch is the moment represented by zero serial date number in spreadsheets.
+var xlsxEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+// xlsxSerialDate converts t to serial date number: count of days since [xlsxEpoch] with fraction for time of day.
+func xlsxSerialDate(t time.Time) string {
+	t = t.UTC()
+	days := float64(t.Sub(xlsxEpoch)) / float64(24*time.Hour)
+	return strconv.FormatFloat(days, 'f', -1, 64)
+}
+
+// xlsxColumnName returns name of the column with the given zero-based index, e.g. "A" for 0 and "AA" for 26.
+func xlsxColumnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+// xlsxWriter writes rows in [FormatXLSX]. The worksheet is written to the ZIP archive while rows are written,
+// other parts of the package are written upfront.
+type xlsxWriter struct {
+	archive *zip.Writer
+	sheet   *bufio.Writer
+
+	// number of the last written row.
+	rowNumber int
+}
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	archive := zip.NewWriter(w)
+	for _, part := range []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRelationships},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRelationships},
+		{"xl/styles.xml", xlsxStyles},
+	} {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(file, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	x := &xlsxWriter{archive: archive, sheet: bufio.NewWriter(sheet)}
+	if _, err := x.sheet.WriteString(xlsxWorksheetHeader); err != nil {
+		return nil, err
+	}
+
+	// write header row:
+	x.startRow()
+	for i, column := range columns {
+		x.writeString(i, xlsxStyleHeader, column)
+	}
+	if err := x.endRow(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// startRow starts a new row.
+func (x *xlsxWriter) startRow() {
+	x.rowNumber++
+	x.sheet.WriteString(`<row r="` + strconv.Itoa(x.rowNumber) + `">`)
+}
+
+// endRow finishes the current row and returns the first error which occurred while writing it.
+func (x *xlsxWriter) endRow() error {
+	_, err := x.sheet.WriteString(`</row>`)
+	return err
+}
+
+// cellStart writes start of the cell tag with the given attributes.
+func (x *xlsxWriter) cellStart(column int, style int, attributes string) {
+	x.sheet.WriteString(`<c r="` + xlsxColumnName(column) + strconv.Itoa(x.rowNumber) + `"`)
+	if style != xlsxStyleDefault {
+		x.sheet.WriteString(` s="` + strconv.Itoa(style) + `"`)
+	}
+	x.sheet.WriteString(attributes + `>`)
+}
+
+// writeString writes an inline string cell, empty strings are skipped.
+func (x *xlsxWriter) writeString(column int, style int, value string) {
+	if value == "" {
+		return
+	}
+	x.cellStart(column, style, ` t="inlineStr"`)
+	x.sheet.WriteString(`<is><t xml:space="preserve">`)
+	// invalid XML characters are replaced by EscapeText, errors are reported by the buffer:
+	_ = xml.EscapeText(x.sheet, []byte(value))
+	x.sheet.WriteString(`</t></is></c>`)
+}
+
+// writeNumber writes a numeric cell.
+func (x *xlsxWriter) writeNumber(column int, style int, value string) {
+	x.cellStart(column, style, "")
+	x.sheet.WriteString(`<v>` + value + `</v></c>`)
+}
+
+func (x *xlsxWriter) Write(row *Row) error {
+	x.startRow()
+	x.writeNumber(0, xlsxStyleDateTime, xlsxSerialDate(row.Timestamp))
+	if !row.ValueDate.IsZero() {
+		x.writeNumber(1, xlsxStyleDate, xlsxSerialDate(row.ValueDate))
+	}
+	x.writeNumber(2, xlsxStyleAmount, FormatAmount(int64(row.Amount), row.Currency))
+	x.writeString(3, xlsxStyleDefault, row.Currency)
+	x.writeNumber(4, xlsxStyleAmount, FormatAmount(row.ConvertedAmount, row.ConvertedCurrency))
+	x.writeString(5, xlsxStyleDefault, row.ConvertedCurrency)
+	x.writeString(6, xlsxStyleDefault, row.Payee)
+	x.writeString(7, xlsxStyleDefault, strings.Join(row.Categories, listSeparator))
+	x.writeString(8, xlsxStyleDefault, strings.Join(row.Tags, listSeparator))
+	x.writeString(9, xlsxStyleDefault, row.Description)
+	x.writeString(10, xlsxStyleDefault, row.UUID)
+	return x.endRow()
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := x.sheet.WriteString(xlsxWorksheetFooter); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.archive.Close()
+}
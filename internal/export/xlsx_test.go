@@ -0,0 +1,103 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+// xlsxTestSheet represents worksheet read in tests.
+type xlsxTestSheet struct {
+	Rows []struct {
+		Number int `xml:"r,attr"`
+		Cells  []struct {
+			Ref    string `xml:"r,attr"`
+			Style  int    `xml:"s,attr"`
+			Type   string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			String string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func TestXLSXColumnName(t *testing.T) {
+	assert.Equal(t, "A", xlsxColumnName(0))
+	assert.Equal(t, "K", xlsxColumnName(10))
+	assert.Equal(t, "Z", xlsxColumnName(25))
+	assert.Equal(t, "AA", xlsxColumnName(26))
+	assert.Equal(t, "BA", xlsxColumnName(52))
+}
+
+func TestXLSXSerialDate(t *testing.T) {
+	assert.Equal(t, "25569", xlsxSerialDate(time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "46085.5", xlsxSerialDate(time.Date(2026, time.March, 4, 12, 0, 0, 0, time.UTC)))
+}
+
+func TestXLSXWriter(t *testing.T) {
+	data := export(t, FormatXLSX, testRows())
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// all parts of the package must be present and be well-formed XML:
+	parts := make(map[string][]byte)
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if !assert.NoError(t, err) {
+			return
+		}
+		content, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.NoError(t, reader.Close())
+		parts[file.Name] = content
+
+		decoder := xml.NewDecoder(bytes.NewReader(content))
+		for {
+			if _, err := decoder.Token(); err != nil {
+				assert.ErrorIs(t, err, io.EOF, file.Name)
+				break
+			}
+		}
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		assert.Contains(t, parts, name)
+	}
+
+	sheet := xlsxTestSheet{}
+	if !assert.NoError(t, xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet)) || !assert.Len(t, sheet.Rows, 3) {
+		return
+	}
+
+	header := sheet.Rows[0]
+	if assert.Len(t, header.Cells, len(columns)) {
+		assert.Equal(t, "A1", header.Cells[0].Ref)
+		assert.Equal(t, "date", header.Cells[0].String)
+		assert.Equal(t, xlsxStyleHeader, header.Cells[0].Style)
+		assert.Equal(t, "uuid", header.Cells[10].String)
+	}
+
+	first := sheet.Rows[1]
+	if assert.Len(t, first.Cells, len(columns)) {
+		assert.Equal(t, xlsxStyleDateTime, first.Cells[0].Style)
+		assert.Equal(t, "B2", first.Cells[1].Ref)
+		assert.Equal(t, "46085", first.Cells[1].Value)
+		assert.Equal(t, "-12.50", first.Cells[2].Value)
+		assert.Equal(t, xlsxStyleAmount, first.Cells[2].Style)
+		assert.Equal(t, "inlineStr", first.Cells[9].Type)
+		assert.Equal(t, `Soap, "premium" & milk`, first.Cells[9].String)
+	}
+
+	// empty cells are omitted:
+	second := sheet.Rows[2]
+	if assert.Len(t, second.Cells, 6) {
+		assert.Equal(t, 3, second.Number)
+		assert.Equal(t, "C3", second.Cells[1].Ref)
+		assert.Equal(t, "K3", second.Cells[5].Ref)
+	}
+}
@@ -0,0 +1,246 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// camtAccount represents account of a CAMT.053 statement.
+type camtAccount struct {
+	IBAN  string `xml:"Id>IBAN"`
+	Other string `xml:"Id>Othr>Id"`
+	Ccy   string `xml:"Ccy"`
+}
+
+// camtDate represents date or date-time element of a CAMT.053 entry.
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+// parse parses the date, returns zero time if the element is empty.
+func (d *camtDate) parse() (time.Time, error) {
+	switch {
+	case d.Date != "":
+		t, err := time.Parse(time.DateOnly, d.Date)
+		if err != nil {
+			return time.Time{}, ErrInvalidDate
+		}
+		return t, nil
+	case d.DateTime != "":
+		// time zone is optional in ISO 20022 date-times:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"} {
+			if t, err := time.Parse(layout, d.DateTime); err == nil {
+				return t.UTC(), nil
+			}
+		}
+		return time.Time{}, ErrInvalidDate
+	}
+	return time.Time{}, nil
+}
+
+// camtParty represents debtor or creditor of a CAMT.053 transaction, both pre-2019 and later layouts are supported.
+type camtParty struct {
+	Name      string `xml:"Nm"`
+	PartyName string `xml:"Pty>Nm"`
+}
+
+func (p *camtParty) name() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.PartyName
+}
+
+// camtEntry represents entry (Ntry element) of a CAMT.053 statement.
+type camtEntry struct {
+	Ref    string `xml:"NtryRef"`
+	Amount struct {
+		Value    string `xml:",chardata"`
+		Currency string `xml:"Ccy,attr"`
+	} `xml:"Amt"`
+	CreditDebit string `xml:"CdtDbtInd"`
+	Status      struct {
+		Value string `xml:",chardata"`
+		Code  string `xml:"Cd"`
+	} `xml:"Sts"`
+	BookingDate camtDate `xml:"BookgDt"`
+	ValueDate   camtDate `xml:"ValDt"`
+	ServicerRef string   `xml:"AcctSvcrRef"`
+	Info        string   `xml:"AddtlNtryInf"`
+
+	Transactions []struct {
+		ServicerRef string    `xml:"Refs>AcctSvcrRef"`
+		EndToEndID  string    `xml:"Refs>EndToEndId"`
+		Debtor      camtParty `xml:"RltdPties>Dbtr"`
+		Creditor    camtParty `xml:"RltdPties>Cdtr"`
+		Remittance  []string  `xml:"RmtInf>Ustrd"`
+		Info        string    `xml:"AddtlTxInf"`
+	} `xml:"NtryDtls>TxDtls"`
+}
+
+// ParseCAMT053 reads transaction entries from an ISO 20022 CAMT.053 (bank to customer statement) XML file.
+// Only booked entries are read. Counterparty of the entries is used as payee and remittance information
+// as description. Entries are identified by the references assigned by the bank or by their content
+// if they have no references. Entries which could not be parsed are reported as row errors,
+// the returned error is not nil only if the file could not be parsed at all.
+func ParseCAMT053(r io.Reader) ([]Entry, []RowError, error) {
+	decoder := xml.NewDecoder(r)
+
+	entries := make([]Entry, 0)
+	rowErrors := make([]RowError, 0)
+
+	var (
+		account camtAccount
+		found   bool
+	)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s", ErrMalformedFile, err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		line, _ := decoder.InputPos()
+
+		switch start.Name.Local {
+		case "BkToCstmrStmt":
+			found = true
+		case "Stmt":
+			// each statement has its own account:
+			account = camtAccount{}
+		case "Acct":
+			if err := decoder.DecodeElement(&account, &start); err != nil {
+				return nil, nil, fmt.Errorf("%w: %s", ErrMalformedFile, err)
+			}
+		case "Ntry":
+			ntry := &camtEntry{}
+			if err := decoder.DecodeElement(ntry, &start); err != nil {
+				return nil, nil, fmt.Errorf("%w: %s", ErrMalformedFile, err)
+			}
+			entry, ok, err := ntry.entry(&account, line)
+			if err != nil {
+				rowErrors = append(rowErrors, *err)
+				continue
+			}
+			if ok {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("%w: not a CAMT.053 statement", ErrMalformedFile)
+	}
+
+	assignContentHashes(entries)
+	return entries, rowErrors, nil
+}
+
+// entry converts CAMT.053 entry to [Entry]. Returns false if the entry is not booked and must be skipped.
+func (n *camtEntry) entry(account *camtAccount, line int) (Entry, bool, *RowError) {
+	status := strings.TrimSpace(n.Status.Value)
+	if n.Status.Code != "" {
+		status = n.Status.Code
+	}
+	if status != "" && status != "BOOK" {
+		return Entry{}, false, nil
+	}
+
+	entry := Entry{
+		Row:          line,
+		CurrencyCode: strings.ToUpper(n.Amount.Currency),
+	}
+	if entry.CurrencyCode == "" {
+		entry.CurrencyCode = strings.ToUpper(account.Ccy)
+	}
+
+	bookingDate, err := n.BookingDate.parse()
+	if err == nil && bookingDate.IsZero() {
+		err = ErrInvalidDate
+	}
+	if err != nil {
+		return Entry{}, false, &RowError{Row: line, Column: "BookgDt", Err: err}
+	}
+	entry.Timestamp = bookingDate
+
+	valueDate, err := n.ValueDate.parse()
+	if err != nil {
+		return Entry{}, false, &RowError{Row: line, Column: "ValDt", Err: err}
+	}
+	entry.ValueDate = valueDate
+
+	amount, err := ParseAmount(n.Amount.Value, '.')
+	if err != nil {
+		return Entry{}, false, &RowError{Row: line, Column: "Amt", Err: err}
+	}
+	if amount < 0 {
+		amount = -amount
+	}
+	// credit/debit indicator of reversal entries is already opposite to the one of the reversed entries:
+	switch n.CreditDebit {
+	case "CRDT":
+	case "DBIT":
+		amount = -amount
+	default:
+		return Entry{}, false, &RowError{Row: line, Column: "CdtDbtInd", Err: fmt.Errorf("unknown credit/debit indicator %q", n.CreditDebit)}
+	}
+	if amount == 0 {
+		return Entry{}, false, &RowError{Row: line, Column: "Amt", Err: fmt.Errorf("%w: amount is zero", ErrInvalidAmount)}
+	}
+	entry.Amount = amount
+
+	// counterparty and remittance information are taken from transaction details:
+	remittance := make([]string, 0)
+	var reference string
+	for _, details := range n.Transactions {
+		if entry.Payee == "" {
+			if entry.Amount < 0 {
+				entry.Payee = details.Creditor.name()
+			} else {
+				entry.Payee = details.Debtor.name()
+			}
+		}
+		for _, line := range details.Remittance {
+			if line = strings.TrimSpace(line); line != "" {
+				remittance = append(remittance, line)
+			}
+		}
+		if reference == "" && len(n.Transactions) == 1 {
+			reference = details.ServicerRef
+			if reference == "" && details.EndToEndID != "NOTPROVIDED" {
+				reference = details.EndToEndID
+			}
+		}
+	}
+	if len(remittance) == 0 && n.Info != "" {
+		remittance = append(remittance, strings.TrimSpace(n.Info))
+	}
+	entry.Description = strings.Join(remittance, " ")
+	entry.Payee = strings.TrimSpace(entry.Payee)
+
+	// entry references assigned by the bank take precedence over references of the transaction:
+	if n.ServicerRef != "" {
+		reference = n.ServicerRef
+	} else if n.Ref != "" {
+		reference = n.Ref
+	}
+	if reference != "" {
+		accountID := account.IBAN
+		if accountID == "" {
+			accountID = account.Other
+		}
+		entry.ExternalID = "camt:" + accountID + ":" + reference
+	}
+
+	return entry, true, nil
+}
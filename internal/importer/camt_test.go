@@ -0,0 +1,54 @@
+package importer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCAMT053(t *testing.T) {
+	t.Run("statement", func(t *testing.T) {
+		file, err := os.Open("testdata/statement.camt053.xml")
+		if err != nil {
+			panic(err)
+		}
+		defer file.Close()
+
+		entries, rowErrors, err := ParseCAMT053(file)
+		if !assert.NoError(t, err) || !assert.Len(t, entries, 3) {
+			return
+		}
+
+		assert.Equal(t, Entry{
+			Row:          22,
+			Timestamp:    time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC),
+			ValueDate:    time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC),
+			Amount:       -119000,
+			CurrencyCode: "EUR",
+			Description:  "Invoice 2026-017 Customer 4711",
+			Payee:        "Office Supplies GmbH",
+			ExternalID:   "camt:DE89370400440532013000:2026030512345",
+		}, entries[0])
+
+		// counterparty of incoming payments is the debtor:
+		assert.Equal(t, int32(500000), entries[1].Amount)
+		assert.Equal(t, "Customer AG", entries[1].Payee)
+		assert.Equal(t, time.Date(2026, 3, 5, 8, 30, 0, 0, time.UTC), entries[1].Timestamp)
+		assert.True(t, strings.HasPrefix(entries[1].ExternalID, "hash:"))
+
+		// additional entry information is used if there are no transaction details:
+		assert.Equal(t, "Account maintenance fee", entries[2].Description)
+
+		if assert.Len(t, rowErrors, 1) {
+			assert.Equal(t, "Amt", rowErrors[0].Column)
+			assert.ErrorIs(t, rowErrors[0].Err, ErrInvalidAmount)
+		}
+	})
+
+	t.Run("not a CAMT.053 file", func(t *testing.T) {
+		_, _, err := ParseCAMT053(strings.NewReader(`<?xml version="1.0"?><Document><BkToCstmrDbtCdtNtfctn/></Document>`))
+		assert.ErrorIs(t, err, ErrMalformedFile)
+	})
+}
@@ -0,0 +1,289 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SignExpenseNegative means that expenses have negative amounts in the statement.
+	SignExpenseNegative = "expense_negative"
+
+	// SignExpensePositive means that expenses have positive amounts in the statement, and income has negative ones.
+	SignExpensePositive = "expense_positive"
+)
+
+const defaultDateFormat = "YYYY-MM-DD"
+
+var (
+	// ErrMissingColumn is returned when a required column is not mapped or mapped column is absent in the file.
+	ErrMissingColumn = errors.New("missing column")
+
+	// ErrInvalidOptions is returned when CSV options are inconsistent.
+	ErrInvalidOptions = errors.New("invalid options")
+)
+
+// CSVColumns maps transaction fields to CSV columns. Columns are referred to by their header names
+// if the file has a header, otherwise by their numbers starting from 1. Empty values mean that the field is absent.
+type CSVColumns struct {
+	Date string `json:"date"`
+
+	// Either amount or debit and credit columns must be mapped.
+	Amount string `json:"amount"`
+
+	// Debit column contains expenses and credit column contains income, both as absolute values.
+	Debit  string `json:"debit"`
+	Credit string `json:"credit"`
+
+	Description string `json:"description"`
+	Payee       string `json:"payee"`
+	Currency    string `json:"currency"`
+	Category    string `json:"category"`
+}
+
+// CSVOptions describes format of a CSV file.
+type CSVOptions struct {
+	Columns CSVColumns
+
+	// Field delimiter, comma is used if zero.
+	Delimiter rune
+
+	// If true, the first record is a header with names of the columns.
+	Header bool
+
+	// Date format, such as "DD.MM.YYYY" (see [DateLayout]), "YYYY-MM-DD" is used if empty.
+	DateFormat string
+
+	// Decimal separator, dot is used if zero.
+	DecimalSeparator rune
+
+	// Sign convention of the amount column, one of Sign* constants, [SignExpenseNegative] is used if empty.
+	Sign string
+}
+
+// csvColumnIndexes contains indexes of the mapped columns, -1 means that the column is not mapped.
+type csvColumnIndexes struct {
+	date, amount, debit, credit, description, payee, currency, category int
+}
+
+// resolveColumns finds indexes of the mapped columns.
+func (o *CSVOptions) resolveColumns(header []string) (*csvColumnIndexes, error) {
+	resolve := func(field, column string) (int, error) {
+		if column == "" {
+			return -1, nil
+		}
+		if header != nil {
+			for i, name := range header {
+				if strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(column)) {
+					return i, nil
+				}
+			}
+			return 0, fmt.Errorf("%w: %s column %q is absent in the header", ErrMissingColumn, field, column)
+		}
+		number, err := strconv.Atoi(column)
+		if err != nil || number < 1 {
+			return 0, fmt.Errorf("%w: %s column must be referred to by its number starting from 1", ErrInvalidOptions, field)
+		}
+		return number - 1, nil
+	}
+
+	indexes := &csvColumnIndexes{}
+	for _, c := range []struct {
+		field  string
+		column string
+		index  *int
+	}{
+		{"date", o.Columns.Date, &indexes.date},
+		{"amount", o.Columns.Amount, &indexes.amount},
+		{"debit", o.Columns.Debit, &indexes.debit},
+		{"credit", o.Columns.Credit, &indexes.credit},
+		{"description", o.Columns.Description, &indexes.description},
+		{"payee", o.Columns.Payee, &indexes.payee},
+		{"currency", o.Columns.Currency, &indexes.currency},
+		{"category", o.Columns.Category, &indexes.category},
+	} {
+		index, err := resolve(c.field, c.column)
+		if err != nil {
+			return nil, err
+		}
+		*c.index = index
+	}
+
+	if indexes.date == -1 {
+		return nil, fmt.Errorf("%w: date column is not mapped", ErrMissingColumn)
+	}
+	hasAmount := indexes.amount != -1
+	hasDebitCredit := indexes.debit != -1 || indexes.credit != -1
+	if hasAmount == hasDebitCredit {
+		return nil, fmt.Errorf("%w: either amount or debit and credit columns must be mapped", ErrInvalidOptions)
+	}
+	return indexes, nil
+}
+
+// ParseCSV reads transaction entries from a CSV file. Rows which could not be parsed are reported as row errors,
+// the returned error is not nil only if the file could not be parsed at all.
+func ParseCSV(r io.Reader, opts *CSVOptions) ([]Entry, []RowError, error) {
+	dateFormat := opts.DateFormat
+	if dateFormat == "" {
+		dateFormat = defaultDateFormat
+	}
+	dateLayout := DateLayout(dateFormat)
+
+	decimalSeparator := opts.DecimalSeparator
+	if decimalSeparator == 0 {
+		decimalSeparator = '.'
+	}
+	if decimalSeparator != '.' && decimalSeparator != ',' {
+		return nil, nil, fmt.Errorf("%w: decimal separator must be either dot or comma", ErrInvalidOptions)
+	}
+
+	var invert bool
+	switch opts.Sign {
+	case "", SignExpenseNegative:
+	case SignExpensePositive:
+		invert = true
+	default:
+		return nil, nil, fmt.Errorf("%w: unknown sign convention %q", ErrInvalidOptions, opts.Sign)
+	}
+
+	reader := csv.NewReader(r)
+	if opts.Delimiter != 0 {
+		reader.Comma = opts.Delimiter
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var header []string
+	if opts.Header {
+		record, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, nil, fmt.Errorf("%w: file has no header", ErrMissingColumn)
+			}
+			return nil, nil, err
+		}
+		// strip byte order mark which spreadsheet applications like to add:
+		if len(record) != 0 {
+			record[0] = strings.TrimPrefix(record[0], "\ufeff")
+		}
+		header = record
+	}
+
+	indexes, err := opts.resolveColumns(header)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columnName := func(index int) string {
+		if header != nil {
+			return header[index]
+		}
+		return strconv.Itoa(index + 1)
+	}
+
+	entries := make([]Entry, 0)
+	rowErrors := make([]RowError, 0)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rowErrors = append(rowErrors, RowError{Row: parseErr.StartLine, Err: parseErr.Err})
+				continue
+			}
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		// skip empty lines:
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+
+		field := func(index int) string {
+			if index == -1 || index >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[index])
+		}
+		rowError := func(index int, err error) {
+			rowErrors = append(rowErrors, RowError{Row: line, Column: columnName(index), Err: err})
+		}
+
+		entry := Entry{
+			Row:          line,
+			CurrencyCode: strings.ToUpper(field(indexes.currency)),
+			Description:  field(indexes.description),
+			Payee:        field(indexes.payee),
+			Category:     field(indexes.category),
+		}
+
+		valid := true
+
+		timestamp, err := time.Parse(dateLayout, field(indexes.date))
+		if err != nil {
+			rowError(indexes.date, fmt.Errorf("%w: expected date in format %s", ErrInvalidDate, dateFormat))
+			valid = false
+		}
+		entry.Timestamp = timestamp
+
+		if indexes.amount != -1 {
+			amount, err := ParseAmount(field(indexes.amount), decimalSeparator)
+			if err != nil {
+				rowError(indexes.amount, err)
+				valid = false
+			}
+			if invert {
+				amount = -amount
+			}
+			entry.Amount = amount
+		} else {
+			// amount is credit minus debit, one of them is usually empty:
+			var amount int64
+			for _, c := range []struct {
+				index int
+				sign  int64
+			}{{indexes.debit, -1}, {indexes.credit, 1}} {
+				value := field(c.index)
+				if value == "" {
+					continue
+				}
+				parsed, err := ParseAmount(value, decimalSeparator)
+				if err != nil {
+					rowError(c.index, err)
+					valid = false
+					continue
+				}
+				if parsed < 0 {
+					parsed = -parsed
+				}
+				amount += c.sign * int64(parsed)
+			}
+			if amount > math.MaxInt32 || amount < math.MinInt32 {
+				rowErrors = append(rowErrors, RowError{Row: line, Err: ErrAmountOutOfRange})
+				valid = false
+			}
+			entry.Amount = int32(amount)
+		}
+
+		if valid && entry.Amount == 0 {
+			rowErrors = append(rowErrors, RowError{Row: line, Err: fmt.Errorf("%w: amount is zero", ErrInvalidAmount)})
+			valid = false
+		}
+
+		if valid {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, rowErrors, nil
+}
@@ -0,0 +1,133 @@
+package importer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCSV(t *testing.T) {
+	t.Run("file with header", func(t *testing.T) {
+		file, err := os.Open("testdata/statement.csv")
+		if err != nil {
+			panic(err)
+		}
+		defer file.Close()
+
+		entries, rowErrors, err := ParseCSV(file, &CSVOptions{
+			Columns: CSVColumns{
+				Date:        "Buchungstag",
+				Amount:      "Betrag",
+				Description: "Verwendungszweck",
+				Payee:       "Empfänger",
+				Currency:    "Währung",
+			},
+			Delimiter:        ';',
+			Header:           true,
+			DateFormat:       "DD.MM.YYYY",
+			DecimalSeparator: ',',
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		if assert.Len(t, entries, 2) {
+			assert.Equal(t, Entry{
+				Row:          2,
+				Timestamp:    time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+				Amount:       -1250,
+				CurrencyCode: "EUR",
+				Description:  "Einkauf",
+				Payee:        "REWE Markt",
+			}, entries[0])
+			assert.Equal(t, int32(250000), entries[1].Amount)
+		}
+
+		if assert.Len(t, rowErrors, 2) {
+			assert.Equal(t, 5, rowErrors[0].Row)
+			assert.Equal(t, "Buchungstag", rowErrors[0].Column)
+			assert.ErrorIs(t, rowErrors[0].Err, ErrInvalidDate)
+
+			assert.Equal(t, 6, rowErrors[1].Row)
+			assert.Equal(t, "Betrag", rowErrors[1].Column)
+			assert.ErrorIs(t, rowErrors[1].Err, ErrInvalidAmount)
+		}
+	})
+
+	t.Run("file without header with inverted sign", func(t *testing.T) {
+		data := "2026-03-01,Coffee,4.20\n2026-03-02,Refund,-10\n"
+		entries, rowErrors, err := ParseCSV(strings.NewReader(data), &CSVOptions{
+			Columns: CSVColumns{Date: "1", Description: "2", Amount: "3"},
+			Sign:    SignExpensePositive,
+		})
+		if assert.NoError(t, err) && assert.Len(t, entries, 2) {
+			assert.Empty(t, rowErrors)
+			assert.Equal(t, int32(-420), entries[0].Amount)
+			assert.Equal(t, "Coffee", entries[0].Description)
+			assert.Equal(t, int32(1000), entries[1].Amount)
+		}
+	})
+
+	t.Run("debit and credit columns", func(t *testing.T) {
+		data := "date,debit,credit\n2026-03-01,15.00,\n2026-03-02,,20.00\n2026-03-03,,\n"
+		entries, rowErrors, err := ParseCSV(strings.NewReader(data), &CSVOptions{
+			Columns: CSVColumns{Date: "date", Debit: "debit", Credit: "credit"},
+			Header:  true,
+		})
+		if assert.NoError(t, err) && assert.Len(t, entries, 2) {
+			assert.Equal(t, int32(-1500), entries[0].Amount)
+			assert.Equal(t, int32(2000), entries[1].Amount)
+			if assert.Len(t, rowErrors, 1) {
+				assert.Equal(t, 4, rowErrors[0].Row)
+				assert.ErrorIs(t, rowErrors[0].Err, ErrInvalidAmount)
+			}
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		testCases := []struct {
+			name string
+			data string
+			opts *CSVOptions
+			err  error
+		}{
+			{
+				"date column is not mapped",
+				"1.00\n",
+				&CSVOptions{Columns: CSVColumns{Amount: "1"}},
+				ErrMissingColumn,
+			},
+			{
+				"both amount and debit columns are mapped",
+				"2026-03-01,1.00\n",
+				&CSVOptions{Columns: CSVColumns{Date: "1", Amount: "2", Debit: "2"}},
+				ErrInvalidOptions,
+			},
+			{
+				"column is absent in the header",
+				"date,amount\n",
+				&CSVOptions{Columns: CSVColumns{Date: "date", Amount: "sum"}, Header: true},
+				ErrMissingColumn,
+			},
+			{
+				"column name is used without header",
+				"2026-03-01,1.00\n",
+				&CSVOptions{Columns: CSVColumns{Date: "date", Amount: "2"}},
+				ErrInvalidOptions,
+			},
+			{
+				"unknown sign convention",
+				"2026-03-01,1.00\n",
+				&CSVOptions{Columns: CSVColumns{Date: "1", Amount: "2"}, Sign: "reversed"},
+				ErrInvalidOptions,
+			},
+		}
+
+		for _, testCase := range testCases {
+			_, _, err := ParseCSV(strings.NewReader(testCase.data), testCase.opts)
+			assert.ErrorIs(t, err, testCase.err, testCase.name)
+		}
+	})
+}
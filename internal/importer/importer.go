@@ -0,0 +1,148 @@
+// Package importer implements parsers of bank statement files which produce transaction entries to be imported.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidAmount is returned when an amount could not be parsed.
+	ErrInvalidAmount = errors.New("invalid amount")
+
+	// ErrAmountOutOfRange is returned when an amount does not fit into the range of transaction amounts.
+	ErrAmountOutOfRange = errors.New("amount is out of range")
+
+	// ErrInvalidDate is returned when a date could not be parsed.
+	ErrInvalidDate = errors.New("invalid date")
+)
+
+// Entry represents a transaction read from a statement file.
+type Entry struct {
+	// Number of the row (line, record) the entry was read from, starting from 1.
+	Row int
+
+	// Booking date of the entry.
+	Timestamp time.Time
+
+	// Value date of the entry, zero if the statement does not specify it.
+	ValueDate time.Time
+
+	// Amount in minor units, negative for expenses and positive for income.
+	Amount int32
+
+	// Currency code, empty if the statement does not specify it.
+	CurrencyCode string
+
+	Description string
+	Payee       string
+
+	// Category name, empty if the statement does not specify it.
+	Category string
+
+	// Identifier of the entry which is used to detect already imported entries,
+	// empty if duplicates of the entry are not detected.
+	ExternalID string
+}
+
+// RowError represents an error in a single row of a statement file.
+type RowError struct {
+	// Number of the row, starting from 1.
+	Row int
+
+	// Name of the column or field which caused the error, empty if the error concerns the whole row.
+	Column string
+
+	Err error
+}
+
+func (e *RowError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("row %d: %s", e.Row, e.Err)
+	}
+	return fmt.Sprintf("row %d, column %q: %s", e.Row, e.Column, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// ParseAmount parses decimal number with at most two fractional digits and returns it in minor units.
+// Spaces, apostrophes and the separator which is not the decimal one are treated as thousands separators.
+func ParseAmount(s string, decimalSeparator rune) (int32, error) {
+	thousandsSeparator := ','
+	if decimalSeparator == ',' {
+		thousandsSeparator = '.'
+	}
+
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case thousandsSeparator, ' ', '\u00a0', '\'':
+			return -1
+		case decimalSeparator:
+			return '.'
+		}
+		return r
+	}, strings.TrimSpace(s))
+
+	negative := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		negative, s = true, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	case strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")"):
+		// accounting notation of negative numbers:
+		negative, s = true, s[1:len(s)-1]
+	}
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" && fraction == "" || len(fraction) > 2 || !isDigits(whole) || !isDigits(fraction) {
+		return 0, ErrInvalidAmount
+	}
+	for len(fraction) < 2 {
+		fraction += "0"
+	}
+
+	var minor int64
+	for _, digit := range whole + fraction {
+		minor = minor*10 + int64(digit-'0')
+		if minor > math.MaxInt32 {
+			return 0, ErrAmountOutOfRange
+		}
+	}
+	if negative {
+		minor = -minor
+	}
+	return int32(minor), nil
+}
+
+// isDigits returns true if the string consists of ASCII digits only (an empty string included).
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// dateFormatReplacer replaces tokens of human-readable date formats with elements of Go time layouts.
+var dateFormatReplacer = strings.NewReplacer(
+	"YYYY", "2006",
+	"YY", "06",
+	"MM", "01",
+	"DD", "02",
+	"hh", "15",
+	"mm", "04",
+	"ss", "05",
+)
+
+// DateLayout converts human-readable date format (e.g. "DD.MM.YYYY" or "YYYY-MM-DD hh:mm")
+// to Go time layout.
+func DateLayout(format string) string {
+	return dateFormatReplacer.Replace(format)
+}
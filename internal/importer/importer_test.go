@@ -0,0 +1,46 @@
+package importer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	testCases := []struct {
+		s                string
+		decimalSeparator rune
+		amount           int32
+		err              error
+	}{
+		{"12.5", '.', 1250, nil},
+		{"-12.50", '.', -1250, nil},
+		{"+3", '.', 300, nil},
+		{"1,234.56", '.', 123456, nil},
+		{"1.234,56", ',', 123456, nil},
+		{"-0,99", ',', -99, nil},
+		{"1 000", '.', 100000, nil},
+		{"(7.25)", '.', -725, nil},
+		{".5", '.', 50, nil},
+		{"1.234", '.', 0, ErrInvalidAmount},
+		{"abc", '.', 0, ErrInvalidAmount},
+		{"", '.', 0, ErrInvalidAmount},
+		{"-", '.', 0, ErrInvalidAmount},
+		{"99999999999", '.', 0, ErrAmountOutOfRange},
+	}
+
+	for _, testCase := range testCases {
+		amount, err := ParseAmount(testCase.s, testCase.decimalSeparator)
+		if testCase.err == nil {
+			assert.NoError(t, err, testCase.s)
+			assert.Equal(t, testCase.amount, amount, testCase.s)
+		} else {
+			assert.ErrorIs(t, err, testCase.err, testCase.s)
+		}
+	}
+}
+
+func TestDateLayout(t *testing.T) {
+	assert.Equal(t, "02.01.2006", DateLayout("DD.MM.YYYY"))
+	assert.Equal(t, "2006-01-02 15:04:05", DateLayout("YYYY-MM-DD hh:mm:ss"))
+	assert.Equal(t, "01/02/06", DateLayout("MM/DD/YY"))
+}
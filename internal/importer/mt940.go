@@ -0,0 +1,230 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// mt940StatementLine matches the first line of the statement line field (:61:): value date, optional booking date,
+// debit/credit mark, optional funds code, amount, transaction type, reference for the account owner
+// and optional reference of the bank.
+var mt940StatementLine = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?(\d+,\d{0,2})([A-Z][A-Z0-9]{3})(.*?)(?://(.*))?$`)
+
+// mt940Balance matches balance fields (:60F:, :60M:): debit/credit mark, date, currency and amount.
+var mt940Balance = regexp.MustCompile(`^[CD]\d{6}([A-Z]{3})`)
+
+// mt940Field represents a field of MT940 message.
+type mt940Field struct {
+	tag   string
+	value string
+
+	// number of the line the field starts at.
+	line int
+}
+
+// scanMT940 splits MT940 file into fields. SWIFT message blocks wrapping the statement are skipped.
+func scanMT940(r io.Reader) ([]mt940Field, error) {
+	scanner := bufio.NewScanner(r)
+
+	fields := make([]mt940Field, 0)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+
+		// skip headers and trailers of SWIFT messages:
+		if strings.HasPrefix(text, "{") || strings.HasPrefix(text, "-}") || text == "-" {
+			if i := strings.Index(text, "{4:"); i != -1 && strings.HasPrefix(text[i+3:], ":") {
+				// text block starts on the same line:
+				text = text[i+3:]
+			} else {
+				continue
+			}
+		}
+
+		if strings.HasPrefix(text, ":") {
+			if end := strings.IndexByte(text[1:], ':'); end != -1 {
+				fields = append(fields, mt940Field{
+					tag:   text[1 : end+1],
+					value: text[end+2:],
+					line:  line,
+				})
+				continue
+			}
+		}
+
+		// continuation of the previous field:
+		if len(fields) != 0 {
+			fields[len(fields)-1].value += "\n" + text
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// parseMT940Information extracts counterparty and remittance information from the information field (:86:).
+// Structured information with "?NN" subfields (used by German banks) and "/CODE/" subfields is supported,
+// otherwise the whole field is used as remittance information.
+func parseMT940Information(value string) (counterparty string, remittance string) {
+	value = strings.ReplaceAll(value, "\n", "")
+
+	switch {
+	case strings.Contains(value, "?20") || strings.Contains(value, "?32"):
+		var remittanceParts, counterpartyParts []string
+		for _, subfield := range strings.Split(value, "?")[1:] {
+			if len(subfield) < 2 {
+				continue
+			}
+			code, content := subfield[:2], strings.TrimSpace(subfield[2:])
+			switch {
+			case code >= "20" && code <= "29", code >= "60" && code <= "63":
+				remittanceParts = append(remittanceParts, content)
+			case code == "32" || code == "33":
+				counterpartyParts = append(counterpartyParts, content)
+			}
+		}
+		return strings.Join(counterpartyParts, ""), strings.Join(remittanceParts, "")
+	case strings.HasPrefix(value, "/"):
+		// subfields are separated by slashes: /CODE/content/CODE/content...
+		parts := strings.Split(value[1:], "/")
+		for i := 0; i+1 < len(parts); i += 2 {
+			switch parts[i] {
+			case "NAME":
+				counterparty = strings.TrimSpace(parts[i+1])
+			case "REMI":
+				remittance = strings.TrimSpace(parts[i+1])
+			}
+		}
+		if counterparty != "" || remittance != "" {
+			return counterparty, remittance
+		}
+	}
+	return "", strings.TrimSpace(value)
+}
+
+// ParseMT940 reads transaction entries from a SWIFT MT940 statement file. Currency of the entries is taken
+// from the opening balance of the statement. Entries are identified by the references assigned by the bank
+// or by their content if they have no references. Statement lines which could not be parsed are reported
+// as row errors, the returned error is not nil only if the file could not be parsed at all.
+func ParseMT940(r io.Reader) ([]Entry, []RowError, error) {
+	fields, err := scanMT940(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries := make([]Entry, 0)
+	rowErrors := make([]RowError, 0)
+
+	var (
+		// account and currency of the current statement:
+		accountID    string
+		currencyCode string
+
+		// entry of the previous statement line which may be followed by the information field:
+		last *Entry
+	)
+	found := false
+	for _, field := range fields {
+		if field.tag != "86" {
+			last = nil
+		}
+
+		switch field.tag {
+		case "20":
+			found = true
+		case "25":
+			accountID = strings.TrimSpace(field.value)
+		case "60F", "60M":
+			if match := mt940Balance.FindStringSubmatch(field.value); match != nil {
+				currencyCode = match[1]
+			}
+		case "61":
+			entry, err := parseMT940StatementLine(field, accountID, currencyCode)
+			if err != nil {
+				rowErrors = append(rowErrors, *err)
+				continue
+			}
+			entries = append(entries, entry)
+			last = &entries[len(entries)-1]
+		case "86":
+			if last != nil {
+				last.Payee, last.Description = parseMT940Information(field.value)
+				last = nil
+			}
+		}
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("%w: not an MT940 statement", ErrMalformedFile)
+	}
+
+	assignContentHashes(entries)
+	return entries, rowErrors, nil
+}
+
+// parseMT940StatementLine converts statement line field (:61:) to [Entry].
+func parseMT940StatementLine(field mt940Field, accountID string, currencyCode string) (Entry, *RowError) {
+	firstLine, _, _ := strings.Cut(field.value, "\n")
+	match := mt940StatementLine.FindStringSubmatch(strings.TrimSpace(firstLine))
+	if match == nil {
+		return Entry{}, &RowError{Row: field.line, Column: ":61:", Err: fmt.Errorf("%w: invalid statement line", ErrMalformedFile)}
+	}
+
+	entry := Entry{
+		Row:          field.line,
+		CurrencyCode: currencyCode,
+	}
+
+	valueDate, err := time.Parse("060102", match[1])
+	if err != nil {
+		return Entry{}, &RowError{Row: field.line, Column: "value date", Err: ErrInvalidDate}
+	}
+	entry.ValueDate = valueDate
+
+	// booking date has no year, it is the year of the value date unless the dates are on different sides of new year:
+	entry.Timestamp = valueDate
+	if match[2] != "" {
+		month, _ := strconv.Atoi(match[2][:2])
+		day, _ := strconv.Atoi(match[2][2:])
+		year := valueDate.Year()
+		switch {
+		case valueDate.Month() == time.December && month == 1:
+			year++
+		case valueDate.Month() == time.January && month == 12:
+			year--
+		}
+		bookingDate := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		if int(bookingDate.Month()) != month || bookingDate.Day() != day {
+			return Entry{}, &RowError{Row: field.line, Column: "booking date", Err: ErrInvalidDate}
+		}
+		entry.Timestamp = bookingDate
+	}
+
+	amount, err := ParseAmount(match[5], ',')
+	if err != nil {
+		return Entry{}, &RowError{Row: field.line, Column: "amount", Err: err}
+	}
+	// reversal of credit is a debit entry, reversal of debit is a credit entry:
+	if match[3] == "D" || match[3] == "RC" {
+		amount = -amount
+	}
+	if amount == 0 {
+		return Entry{}, &RowError{Row: field.line, Column: "amount", Err: fmt.Errorf("%w: amount is zero", ErrInvalidAmount)}
+	}
+	entry.Amount = amount
+
+	if bankReference := strings.TrimSpace(match[8]); bankReference != "" && bankReference != "NONREF" {
+		entry.ExternalID = "mt940:" + accountID + ":" + bankReference
+	}
+
+	return entry, nil
+}
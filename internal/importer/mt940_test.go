@@ -0,0 +1,61 @@
+package importer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMT940(t *testing.T) {
+	t.Run("statement", func(t *testing.T) {
+		file, err := os.Open("testdata/statement.mt940")
+		if err != nil {
+			panic(err)
+		}
+		defer file.Close()
+
+		entries, rowErrors, err := ParseMT940(file)
+		if !assert.NoError(t, err) || !assert.Len(t, entries, 3) {
+			return
+		}
+
+		assert.Equal(t, Entry{
+			Row:          6,
+			Timestamp:    time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC),
+			ValueDate:    time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC),
+			Amount:       -119000,
+			CurrencyCode: "EUR",
+			Description:  "EREF+INV-2026-017Invoice 2026-017Customer 4711",
+			Payee:        "Office SuppliesGmbH",
+			ExternalID:   "mt940:37040044/0532013000:2026030512345",
+		}, entries[0])
+
+		assert.Equal(t, int32(500000), entries[1].Amount)
+		assert.Equal(t, "Customer AG", entries[1].Payee)
+		assert.Equal(t, "Payment for order 88", entries[1].Description)
+		assert.True(t, strings.HasPrefix(entries[1].ExternalID, "hash:"))
+
+		assert.Equal(t, int32(-490), entries[2].Amount)
+		assert.Equal(t, "Account maintenance fee", entries[2].Description)
+
+		if assert.Len(t, rowErrors, 1) {
+			assert.Equal(t, 13, rowErrors[0].Row)
+		}
+	})
+
+	t.Run("booking date in the next year", func(t *testing.T) {
+		data := ":20:X\n:25:ACC\n:60F:C251231EUR0,00\n:61:2512310102D10,00NTRFNONREF\n"
+		entries, _, err := ParseMT940(strings.NewReader(data))
+		if assert.NoError(t, err) && assert.Len(t, entries, 1) {
+			assert.Equal(t, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), entries[0].Timestamp)
+			assert.Equal(t, time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC), entries[0].ValueDate)
+		}
+	})
+
+	t.Run("not an MT940 file", func(t *testing.T) {
+		_, _, err := ParseMT940(strings.NewReader("date,amount\n"))
+		assert.ErrorIs(t, err, ErrMalformedFile)
+	})
+}
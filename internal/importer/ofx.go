@@ -0,0 +1,220 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrMalformedFile is returned when a statement file is malformed and could not be parsed.
+var ErrMalformedFile = errors.New("malformed file")
+
+// ofxToken represents an OFX element: opening tag with an optional value or closing tag.
+type ofxToken struct {
+	name    string
+	closing bool
+	value   string
+
+	// number of the line the token starts at.
+	line int
+}
+
+// tokenizeOFX splits OFX document into tokens. Both SGML (where elements with values are usually not closed)
+// and XML versions of OFX are supported. Headers, processing instructions and comments are skipped.
+func tokenizeOFX(r io.Reader) ([]ofxToken, error) {
+	data, err := io.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	doc := string(data)
+
+	start := strings.IndexByte(doc, '<')
+	if start == -1 {
+		return nil, fmt.Errorf("%w: no OFX elements found", ErrMalformedFile)
+	}
+	line := 1 + strings.Count(doc[:start], "\n")
+	doc = doc[start:]
+
+	tokens := make([]ofxToken, 0)
+	for len(doc) != 0 {
+		end := strings.IndexByte(doc, '>')
+		if end == -1 {
+			return nil, fmt.Errorf("%w: unterminated tag at line %d", ErrMalformedFile, line)
+		}
+		tag := strings.TrimSpace(doc[1:end])
+
+		valueEnd := strings.IndexByte(doc[end+1:], '<')
+		if valueEnd == -1 {
+			valueEnd = len(doc) - end - 1
+		}
+		value := doc[end+1 : end+1+valueEnd]
+
+		if tag != "" && tag[0] != '?' && tag[0] != '!' {
+			token := ofxToken{line: line}
+			if tag[0] == '/' {
+				token.closing = true
+				token.name = strings.ToUpper(strings.TrimSpace(tag[1:]))
+			} else {
+				token.name = strings.ToUpper(strings.Fields(tag)[0])
+				token.value = html.UnescapeString(strings.TrimSpace(value))
+			}
+			tokens = append(tokens, token)
+		}
+
+		line += strings.Count(doc[:end+1+valueEnd], "\n")
+		doc = doc[end+1+valueEnd:]
+	}
+	return tokens, nil
+}
+
+// ParseOFXDate parses OFX date-time value such as "20260301", "20260301120000" or "20260301120000.000[-5:EST]".
+// Time zone is UTC unless specified.
+func ParseOFXDate(s string) (time.Time, error) {
+	location := time.UTC
+	if open := strings.IndexByte(s, '['); open != -1 {
+		zone := strings.TrimSuffix(s[open+1:], "]")
+		s = s[:open]
+
+		offset, name, _ := strings.Cut(zone, ":")
+		hours, err := strconv.ParseFloat(offset, 64)
+		if err != nil {
+			return time.Time{}, ErrInvalidDate
+		}
+		location = time.FixedZone(name, int(hours*3600))
+	}
+
+	// fractional seconds are ignored:
+	s, _, _ = strings.Cut(strings.TrimSpace(s), ".")
+
+	var layout string
+	switch len(s) {
+	case 8:
+		layout = "20060102"
+	case 12:
+		layout = "200601021504"
+	case 14:
+		layout = "20060102150405"
+	default:
+		return time.Time{}, ErrInvalidDate
+	}
+
+	t, err := time.ParseInLocation(layout, s, location)
+	if err != nil {
+		return time.Time{}, ErrInvalidDate
+	}
+	return t.UTC(), nil
+}
+
+// parseStatementAmount parses amount of a statement which uses dot or comma as the decimal separator.
+func parseStatementAmount(s string) (int32, error) {
+	decimalSeparator := '.'
+	if strings.ContainsRune(s, ',') && !strings.ContainsRune(s, '.') {
+		decimalSeparator = ','
+	}
+	return ParseAmount(s, decimalSeparator)
+}
+
+// ParseOFX reads transaction entries from an OFX (or QFX) file. Currency of the entries is taken from the statement.
+// Entries are identified by their FITIDs (together with the account ID), or by their content if they have no FITID.
+// Transactions which could not be parsed are reported as row errors, the returned error is not nil
+// only if the file could not be parsed at all.
+func ParseOFX(r io.Reader) ([]Entry, []RowError, error) {
+	tokens, err := tokenizeOFX(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries := make([]Entry, 0)
+	rowErrors := make([]RowError, 0)
+
+	var (
+		// default currency and account ID of the current statement:
+		currencyCode string
+		accountID    string
+
+		// fields of the current transaction, nil if not inside a transaction:
+		fields map[string]string
+		line   int
+	)
+
+	finishTransaction := func() {
+		entry := Entry{
+			Row:          line,
+			CurrencyCode: strings.ToUpper(currencyCode),
+			Description:  fields["MEMO"],
+			Payee:        fields["NAME"],
+		}
+		if cursym := fields["CURSYM"]; cursym != "" {
+			entry.CurrencyCode = strings.ToUpper(cursym)
+		}
+		if fitID := fields["FITID"]; fitID != "" {
+			entry.ExternalID = "ofx:" + accountID + ":" + fitID
+		}
+
+		valid := true
+
+		date := fields["DTPOSTED"]
+		if date == "" {
+			date = fields["DTUSER"]
+		}
+		timestamp, err := ParseOFXDate(date)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Row: line, Column: "DTPOSTED", Err: err})
+			valid = false
+		}
+		entry.Timestamp = timestamp
+
+		amount, err := parseStatementAmount(fields["TRNAMT"])
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Row: line, Column: "TRNAMT", Err: err})
+			valid = false
+		}
+		entry.Amount = amount
+
+		if valid && entry.Amount == 0 {
+			rowErrors = append(rowErrors, RowError{Row: line, Column: "TRNAMT", Err: fmt.Errorf("%w: amount is zero", ErrInvalidAmount)})
+			valid = false
+		}
+
+		if valid {
+			entries = append(entries, entry)
+		}
+		fields = nil
+	}
+
+	for _, token := range tokens {
+		switch {
+		case token.name == "STMTTRN" && !token.closing:
+			if fields != nil {
+				finishTransaction()
+			}
+			fields = make(map[string]string)
+			line = token.line
+		case token.name == "STMTTRN" && token.closing:
+			if fields != nil {
+				finishTransaction()
+			}
+		case token.closing || token.value == "":
+			// closing tags of other elements and opening tags of aggregates carry no data.
+		case fields != nil:
+			if _, ok := fields[token.name]; !ok {
+				fields[token.name] = token.value
+			}
+		case token.name == "CURDEF":
+			currencyCode = token.value
+		case token.name == "ACCTID":
+			accountID = token.value
+		}
+	}
+	if fields != nil {
+		finishTransaction()
+	}
+
+	assignContentHashes(entries)
+	return entries, rowErrors, nil
+}
@@ -0,0 +1,95 @@
+package importer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseOFXDate(t *testing.T) {
+	testCases := []struct {
+		s    string
+		time time.Time
+		err  error
+	}{
+		{"20260301", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), nil},
+		{"202603011230", time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC), nil},
+		{"20260301120000.000[-5:EST]", time.Date(2026, 3, 1, 17, 0, 0, 0, time.UTC), nil},
+		{"20260301120000[+5.5:IST]", time.Date(2026, 3, 1, 6, 30, 0, 0, time.UTC), nil},
+		{"2026-03-01", time.Time{}, ErrInvalidDate},
+		{"", time.Time{}, ErrInvalidDate},
+	}
+
+	for _, testCase := range testCases {
+		parsed, err := ParseOFXDate(testCase.s)
+		if testCase.err == nil {
+			assert.NoError(t, err, testCase.s)
+			assert.Equal(t, testCase.time, parsed, testCase.s)
+		} else {
+			assert.ErrorIs(t, err, testCase.err, testCase.s)
+		}
+	}
+}
+
+func TestParseOFX(t *testing.T) {
+	t.Run("SGML statement", func(t *testing.T) {
+		file, err := os.Open("testdata/statement.ofx")
+		if err != nil {
+			panic(err)
+		}
+		defer file.Close()
+
+		entries, rowErrors, err := ParseOFX(file)
+		if !assert.NoError(t, err) || !assert.Len(t, entries, 4) {
+			return
+		}
+
+		assert.Equal(t, Entry{
+			Row:          39,
+			Timestamp:    time.Date(2026, 3, 1, 17, 0, 0, 0, time.UTC),
+			Amount:       -4215,
+			CurrencyCode: "USD",
+			Description:  "POS PURCHASE",
+			Payee:        "WHOLE FOODS MARKET",
+			ExternalID:   "ofx:1234567890:2026030100001",
+		}, entries[0])
+		assert.Equal(t, int32(300000), entries[1].Amount)
+
+		// entries without FITID are identified by their content, identical entries are still distinguished:
+		assert.Equal(t, "Coffee & bagel", entries[2].Description)
+		assert.True(t, strings.HasPrefix(entries[2].ExternalID, "hash:"))
+		assert.Equal(t, entries[2].ExternalID+"#2", entries[3].ExternalID)
+
+		if assert.Len(t, rowErrors, 1) {
+			assert.Equal(t, "DTPOSTED", rowErrors[0].Column)
+			assert.ErrorIs(t, rowErrors[0].Err, ErrInvalidDate)
+		}
+	})
+
+	t.Run("XML statement", func(t *testing.T) {
+		file, err := os.Open("testdata/statement.qfx")
+		if err != nil {
+			panic(err)
+		}
+		defer file.Close()
+
+		entries, rowErrors, err := ParseOFX(file)
+		if assert.NoError(t, err) && assert.Len(t, entries, 2) {
+			assert.Empty(t, rowErrors)
+
+			assert.Equal(t, int32(-1999), entries[0].Amount)
+			assert.Equal(t, "EUR", entries[0].CurrencyCode)
+			assert.Equal(t, "SPOTIFY", entries[0].Payee)
+			assert.Equal(t, "ofx:4111XXXXXXXX1111:CC-0001", entries[0].ExternalID)
+
+			assert.Equal(t, "USD", entries[1].CurrencyCode)
+		}
+	})
+
+	t.Run("not an OFX file", func(t *testing.T) {
+		_, _, err := ParseOFX(strings.NewReader("date,amount\n"))
+		assert.ErrorIs(t, err, ErrMalformedFile)
+	})
+}
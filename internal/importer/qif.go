@@ -0,0 +1,179 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// qifTransactionTypes contains QIF account types whose records are transactions.
+var qifTransactionTypes = map[string]bool{
+	"bank":  true,
+	"cash":  true,
+	"ccard": true,
+	"oth a": true,
+	"oth l": true,
+}
+
+// QIFOptions describes format of a QIF file.
+type QIFOptions struct {
+	// If true, dates are in day-first order (DD/MM/YY), otherwise in month-first order (MM/DD/YY) used by Quicken.
+	DayFirst bool
+}
+
+// ParseQIFDate parses QIF date such as "03/01/2026", "3/1'26" or "03.01.26".
+// Years written with two digits are expanded to 1970-2069.
+func ParseQIFDate(s string, dayFirst bool) (time.Time, error) {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case '\'', '.', '-':
+			return '/'
+		case ' ':
+			return -1
+		}
+		return r
+	}, s)
+
+	parts := strings.Split(s, "/")
+	if len(parts) != 3 {
+		return time.Time{}, ErrInvalidDate
+	}
+	numbers := make([]int, 0, 3)
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return time.Time{}, ErrInvalidDate
+		}
+		numbers = append(numbers, n)
+	}
+
+	month, day, year := numbers[0], numbers[1], numbers[2]
+	if dayFirst {
+		month, day = day, month
+	}
+	if len(parts[2]) <= 2 {
+		year += 1900
+		if year < 1970 {
+			year += 100
+		}
+	}
+
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Year() != year || t.Month() != time.Month(month) || t.Day() != day {
+		return time.Time{}, ErrInvalidDate
+	}
+	return t, nil
+}
+
+// ParseQIF reads transaction entries from a QIF file. QIF files do not specify currency, so currency of
+// the entries is empty. Entries are identified by their content. Categories of the entries are taken from
+// the category field unless it refers to a transfer between accounts. Records which could not be parsed
+// are reported as row errors, the returned error is not nil only if the file could not be parsed at all.
+func ParseQIF(r io.Reader, opts *QIFOptions) ([]Entry, []RowError, error) {
+	scanner := bufio.NewScanner(r)
+
+	entries := make([]Entry, 0)
+	rowErrors := make([]RowError, 0)
+
+	var (
+		// whether records of the current section are transactions:
+		transactions bool
+
+		// fields of the current record and number of the line it starts at:
+		fields = make(map[byte]string)
+		start  int
+	)
+
+	finishRecord := func() {
+		defer func() {
+			fields = make(map[byte]string)
+			start = 0
+		}()
+		if !transactions || len(fields) == 0 {
+			return
+		}
+
+		entry := Entry{
+			Row:         start,
+			Description: fields['M'],
+			Payee:       fields['P'],
+		}
+		if category := fields['L']; !strings.HasPrefix(category, "[") {
+			entry.Category = category
+		}
+
+		valid := true
+
+		timestamp, err := ParseQIFDate(fields['D'], opts.DayFirst)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Row: start, Column: "D", Err: err})
+			valid = false
+		}
+		entry.Timestamp = timestamp
+
+		amountField := fields['T']
+		if amountField == "" {
+			amountField = fields['U']
+		}
+		amount, err := parseStatementAmount(amountField)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Row: start, Column: "T", Err: err})
+			valid = false
+		}
+		entry.Amount = amount
+
+		if valid && entry.Amount == 0 {
+			rowErrors = append(rowErrors, RowError{Row: start, Column: "T", Err: fmt.Errorf("%w: amount is zero", ErrInvalidAmount)})
+			valid = false
+		}
+
+		if valid {
+			entries = append(entries, entry)
+		}
+	}
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(text, "!"):
+			finishRecord()
+			header := strings.ToLower(strings.TrimSpace(text[1:]))
+			if strings.HasPrefix(header, "type:") {
+				transactions = qifTransactionTypes[strings.TrimSpace(strings.TrimPrefix(header, "type:"))]
+			} else if header == "account" {
+				// account list records follow:
+				transactions = false
+			}
+		case text[0] == '^':
+			finishRecord()
+		default:
+			if start == 0 {
+				start = line
+			}
+			// split lines (S, E, $) are ignored, only the first value of a field is used:
+			if _, ok := fields[text[0]]; !ok {
+				fields[text[0]] = strings.TrimSpace(text[1:])
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	// the last record may be not terminated:
+	finishRecord()
+
+	assignContentHashes(entries)
+	return entries, rowErrors, nil
+}
@@ -0,0 +1,66 @@
+package importer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseQIFDate(t *testing.T) {
+	testCases := []struct {
+		s        string
+		dayFirst bool
+		time     time.Time
+		err      error
+	}{
+		{"03/01/2026", false, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), nil},
+		{"3/1'26", false, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), nil},
+		{"3/1' 6", false, time.Date(2006, 3, 1, 0, 0, 0, 0, time.UTC), nil},
+		{"12/31/99", false, time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC), nil},
+		{"01.03.2026", true, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), nil},
+		{"13/01/2026", false, time.Time{}, ErrInvalidDate},
+		{"2026", false, time.Time{}, ErrInvalidDate},
+	}
+
+	for _, testCase := range testCases {
+		parsed, err := ParseQIFDate(testCase.s, testCase.dayFirst)
+		if testCase.err == nil {
+			assert.NoError(t, err, testCase.s)
+			assert.Equal(t, testCase.time, parsed, testCase.s)
+		} else {
+			assert.ErrorIs(t, err, testCase.err, testCase.s)
+		}
+	}
+}
+
+func TestParseQIF(t *testing.T) {
+	file, err := os.Open("testdata/statement.qif")
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	entries, rowErrors, err := ParseQIF(file, &QIFOptions{})
+	if !assert.NoError(t, err) || !assert.Len(t, entries, 3) {
+		return
+	}
+
+	assert.Equal(t, 6, entries[0].Row)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), entries[0].Timestamp)
+	assert.Equal(t, int32(-123456), entries[0].Amount)
+	assert.Equal(t, "Landlord", entries[0].Payee)
+	assert.Equal(t, "Rent March", entries[0].Description)
+	assert.Equal(t, "Housing", entries[0].Category)
+	assert.NotEmpty(t, entries[0].ExternalID)
+
+	// transfers are not categorized:
+	assert.Empty(t, entries[1].Category)
+
+	assert.Equal(t, int32(8510), entries[2].Amount)
+
+	if assert.Len(t, rowErrors, 1) {
+		assert.Equal(t, 21, rowErrors[0].Row)
+		assert.Equal(t, "D", rowErrors[0].Column)
+	}
+}
@@ -0,0 +1,69 @@
+package importer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	// FormatOFX is the Open Financial Exchange format (including Quicken QFX), both SGML (1.x) and XML (2.x) versions.
+	FormatOFX = "ofx"
+
+	// FormatQIF is the Quicken Interchange Format.
+	FormatQIF = "qif"
+
+	// FormatCAMT053 is the ISO 20022 bank to customer statement (camt.053) XML format.
+	FormatCAMT053 = "camt053"
+
+	// FormatMT940 is the SWIFT MT940 customer statement format.
+	FormatMT940 = "mt940"
+)
+
+// DetectFormat detects format of a statement file by its content, returns empty string if the format is unknown.
+func DetectFormat(data []byte) string {
+	head := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\ufeff")))
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+
+	switch {
+	case bytes.HasPrefix(head, []byte("OFXHEADER")), bytes.Contains(bytes.ToUpper(head), []byte("<OFX>")), bytes.Contains(head, []byte("<?OFX")):
+		return FormatOFX
+	case bytes.HasPrefix(head, []byte("!Type:")), bytes.HasPrefix(head, []byte("!Account")), bytes.HasPrefix(head, []byte("!Option")):
+		return FormatQIF
+	case bytes.Contains(head, []byte("camt.053")), bytes.Contains(head, []byte("<BkToCstmrStmt>")):
+		return FormatCAMT053
+	case bytes.Contains(head, []byte(":20:")) && bytes.Contains(head, []byte(":25:")):
+		return FormatMT940
+	}
+	return ""
+}
+
+// contentHash returns hash of the entry fields which identifies the entry if the statement provides no identifier.
+func (e *Entry) contentHash() string {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s\x00%d\x00%s\x00%s\x00%s",
+		e.Timestamp.Format(time.RFC3339), e.Amount, e.CurrencyCode, e.Payee, e.Description)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// assignContentHashes sets external IDs of the entries which have none to their content hashes.
+// Identical entries of the same statement get different IDs, so that they are not considered duplicates of each other.
+func assignContentHashes(entries []Entry) {
+	occurrences := make(map[string]int)
+	for i := range entries {
+		if entries[i].ExternalID != "" {
+			continue
+		}
+		hash := entries[i].contentHash()
+		occurrences[hash]++
+		entries[i].ExternalID = "hash:" + hash
+		if n := occurrences[hash]; n > 1 {
+			entries[i].ExternalID += "#" + strconv.Itoa(n)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package importer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	testCases := []struct {
+		data   string
+		format string
+	}{
+		{"OFXHEADER:100\nDATA:OFXSGML\n\n<OFX>", FormatOFX},
+		{"<?xml version=\"1.0\"?>\n<?OFX OFXHEADER=\"200\"?>\n<OFX>", FormatOFX},
+		{"\ufeff!Type:Bank\nD03/01/2026\n", FormatQIF},
+		{"<?xml version=\"1.0\"?>\n<Document xmlns=\"urn:iso:std:iso:20022:tech:xsd:camt.053.001.02\">", FormatCAMT053},
+		{"{1:F01DEUTDEFFAXXX0000000000}{4:\n:20:STARTUMS\n:25:37040044/0532013000\n", FormatMT940},
+		{"date,amount\n", ""},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.format, DetectFormat([]byte(testCase.data)), testCase.data)
+	}
+}
@@ -2,17 +2,44 @@ package middleware
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"github.com/groshi-project/groshi/internal/auth"
+	"github.com/groshi-project/groshi/internal/database"
 	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
 	"github.com/groshi-project/groshi/internal/service/handler/model"
+	"github.com/groshi-project/groshi/internal/service/handler/response"
 	"net/http"
 	"strings"
+	"time"
 )
 
-const UsernameContextKey = "username"
+// contextKey is type of context keys set by the middleware, so that they do not collide with keys of other packages.
+type contextKey string
+
+// UserContextKey is set to the *[database.User] the request is authenticated as.
+const UserContextKey contextKey = "user"
+const TokenIDContextKey contextKey = "token_id"
+const SessionIDContextKey contextKey = "session_id"
+
+// ScopesContextKey is set to the scopes of the API key the request is authenticated with.
+// It is not set for requests authenticated with JWTs, which have all scopes.
+const ScopesContextKey contextKey = "scopes"
 const authorizationHeader = "Authorization"
 
+// sessionTouchInterval is the minimal interval between updates of the time when a session was seen last time.
+const sessionTouchInterval = time.Minute
+
+// Querier describes database queries made by the JWT middleware.
+type Querier interface {
+	database.RevokedTokenQuerier
+	SelectSessionByUUID(ctx context.Context, uuid string, s *database.Session) error
+	TouchSession(ctx context.Context, id int64) error
+	SelectUserByUUID(ctx context.Context, uuid string, u *database.User) error
+	SelectAPIKeyByHash(ctx context.Context, hash string, k *database.APIKey) error
+	TouchAPIKey(ctx context.Context, id int64) error
+}
+
 var (
 	errEmptyOrMissingAuthHeader = errors.New("empty or missing authorization header")
 	errInvalidAuthHeader        = errors.New("invalid authorization header")
@@ -33,9 +60,51 @@ func tokenFromHeader(headerValue string) (string, error) {
 	return tokens[1], nil
 }
 
-// NewJWT returns new JWT middleware which extracts and verifies JWT from authorization header.
-// Additionally, sets [UsernameContextKey] context key to the authorized user's username.
-func NewJWT(authenticator auth.JWTAuthenticator) func(next http.Handler) http.Handler {
+// apiKeyContext verifies the API key and returns context containing the key owner and the key scopes.
+// Renders an error response and returns false if the key is unknown or expired.
+func apiKeyContext(w http.ResponseWriter, r *http.Request, db Querier, key string) (context.Context, bool) {
+	apiKey := &database.APIKey{}
+	if err := db.SelectAPIKeyByHash(r.Context(), auth.HashAPIKey(key), apiKey); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpresp.Render(w, httpresp.New(http.StatusUnauthorized, model.NewError("invalid api key")))
+			return nil, false
+		}
+		httpresp.Render(w, response.InternalServerError)
+		return nil, false
+	}
+	if !apiKey.ExpiresAt.IsZero() && !time.Now().Before(apiKey.ExpiresAt) {
+		httpresp.Render(w, httpresp.New(http.StatusUnauthorized, model.NewError("api key is expired")))
+		return nil, false
+	}
+	if !apiKey.Owner.DisabledAt.IsZero() {
+		httpresp.Render(w, response.UserDisabled)
+		return nil, false
+	}
+
+	// update time when the key was used last time, but not on every request:
+	if time.Since(apiKey.LastUsedAt) > sessionTouchInterval {
+		if err := db.TouchAPIKey(r.Context(), apiKey.ID); err != nil {
+			httpresp.Render(w, response.InternalServerError)
+			return nil, false
+		}
+	}
+
+	scopes := apiKey.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	ctx := context.WithValue(r.Context(), UserContextKey, &apiKey.Owner)
+	ctx = context.WithValue(ctx, ScopesContextKey, scopes)
+	return ctx, true
+}
+
+// NewJWT returns new JWT middleware which extracts and verifies JWT or API key from authorization header.
+// It rejects revoked tokens, tokens of revoked sessions, tokens issued before the user's password was changed,
+// unknown or expired API keys and requests of disabled users.
+// Additionally, sets [UserContextKey] context key to the authorized user. For JWTs it sets
+// [TokenIDContextKey] context key to the ID of the token and [SessionIDContextKey] context key to the UUID of its session,
+// for API keys it sets [ScopesContextKey] context key to the scopes of the key.
+func NewJWT(authenticator auth.JWTAuthenticator, db Querier) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// extract token from authorization header value:
@@ -53,6 +122,15 @@ func NewJWT(authenticator auth.JWTAuthenticator) func(next http.Handler) http.Ha
 				}
 			}
 
+			if auth.IsAPIKey(token) {
+				ctx, ok := apiKeyContext(w, r, db, token)
+				if !ok {
+					return
+				}
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
 			claims, err := authenticator.VerifyToken(token)
 			if err != nil {
 				// todo: is it safe to display error?
@@ -60,12 +138,80 @@ func NewJWT(authenticator auth.JWTAuthenticator) func(next http.Handler) http.Ha
 				return
 			}
 
-			username, ok := claims[auth.JWTClaimUsername]
-			if !ok {
-				panic("token does not contain username claim")
+			userID, ok := claims[auth.JWTClaimSubject].(string)
+			if !ok || userID == "" {
+				httpresp.Render(w, httpresp.New(http.StatusUnauthorized, model.NewError("token has no subject")))
+				return
+			}
+
+			// reject tokens without ID as they can not be revoked:
+			tokenID, ok := claims[auth.JWTClaimTokenID].(string)
+			if !ok || tokenID == "" {
+				httpresp.Render(w, httpresp.New(http.StatusUnauthorized, model.NewError("token has no id")))
+				return
+			}
+
+			revoked, err := db.TokenRevoked(r.Context(), tokenID)
+			if err != nil {
+				httpresp.Render(w, response.InternalServerError)
+				return
+			}
+			if revoked {
+				httpresp.Render(w, httpresp.New(http.StatusUnauthorized, model.NewError("token is revoked")))
+				return
+			}
+
+			// reject tokens of revoked and unknown sessions:
+			sessionID, ok := claims[auth.JWTClaimSessionID].(string)
+			if !ok || sessionID == "" {
+				httpresp.Render(w, httpresp.New(http.StatusUnauthorized, model.NewError("token has no session")))
+				return
+			}
+			session := &database.Session{}
+			if err := db.SelectSessionByUUID(r.Context(), sessionID, session); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					httpresp.Render(w, httpresp.New(http.StatusUnauthorized, model.NewError("session not found")))
+					return
+				}
+				httpresp.Render(w, response.InternalServerError)
+				return
+			}
+			if !session.RevokedAt.IsZero() {
+				httpresp.Render(w, httpresp.New(http.StatusUnauthorized, model.NewError("session is revoked")))
+				return
+			}
+
+			// reject tokens issued with another version of the user's tokens:
+			user := &database.User{}
+			if err := db.SelectUserByUUID(r.Context(), userID, user); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					httpresp.Render(w, httpresp.New(http.StatusUnauthorized, model.NewError("user not found")))
+					return
+				}
+				httpresp.Render(w, response.InternalServerError)
+				return
+			}
+			version, _ := claims[auth.JWTClaimVersion].(float64)
+			if int(version) != user.TokenVersion {
+				httpresp.Render(w, httpresp.New(http.StatusUnauthorized, model.NewError("token is outdated, log in again")))
+				return
+			}
+			if !user.DisabledAt.IsZero() {
+				httpresp.Render(w, response.UserDisabled)
+				return
+			}
+
+			// update time when the session was seen last time, but not on every request:
+			if time.Since(session.LastSeenAt) > sessionTouchInterval {
+				if err := db.TouchSession(r.Context(), session.ID); err != nil {
+					httpresp.Render(w, response.InternalServerError)
+					return
+				}
 			}
 
-			ctx := context.WithValue(r.Context(), UsernameContextKey, username)
+			ctx := context.WithValue(r.Context(), UserContextKey, user)
+			ctx = context.WithValue(ctx, TokenIDContextKey, tokenID)
+			ctx = context.WithValue(ctx, SessionIDContextKey, sessionID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -1,9 +1,12 @@
 package middleware
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/groshi-project/groshi/internal/auth"
+	"github.com/groshi-project/groshi/internal/database"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
@@ -52,14 +55,15 @@ func newMockJWTAuthenticator(secretKey string) *mockJWTAuthenticator {
 	}
 }
 
-func (m *mockJWTAuthenticator) CreateExpiredToken(username string) (string, time.Time, error) {
+func (m *mockJWTAuthenticator) CreateExpiredToken(userID string) (string, time.Time, error) {
 	issued := time.Now()
 	expires := time.Now().Add(-time.Hour) // token expired an hour ago
 	token := jwt.NewWithClaims(auth.JWTSigningMethod, jwt.MapClaims{
-		"username": username,
-		"exp":      expires.Unix(),
-		"iat":      issued.Unix(),
+		"sub": userID,
+		"exp": expires.Unix(),
+		"iat": issued.Unix(),
 	})
+	token.Header["typ"] = "at+jwt"
 
 	tokenString, err := token.SignedString(m.secretKey)
 	if err != nil {
@@ -69,14 +73,15 @@ func (m *mockJWTAuthenticator) CreateExpiredToken(username string) (string, time
 	return tokenString, expires, nil
 }
 
-func (m *mockJWTAuthenticator) CreateNotYetValidToken(username string) (string, time.Time, error) {
+func (m *mockJWTAuthenticator) CreateNotYetValidToken(userID string) (string, time.Time, error) {
 	issued := time.Now().Add(time.Hour) // issued in one hour from now
 	expires := time.Now().Add(2 * time.Hour)
 	token := jwt.NewWithClaims(auth.JWTSigningMethod, jwt.MapClaims{
-		"username": username,
-		"exp":      expires.Unix(),
-		"iat":      issued.Unix(),
+		"sub": userID,
+		"exp": expires.Unix(),
+		"iat": issued.Unix(),
 	})
+	token.Header["typ"] = "at+jwt"
 
 	tokenString, err := token.SignedString(m.secretKey)
 	if err != nil {
@@ -86,6 +91,75 @@ func (m *mockJWTAuthenticator) CreateNotYetValidToken(username string) (string,
 	return tokenString, expires, nil
 }
 
+type mockQuerier struct {
+	revokedTokens map[string]bool
+
+	sessions map[string]*database.Session
+
+	users map[string]*database.User
+
+	apiKeys map[string]*database.APIKey
+}
+
+func (m *mockQuerier) SelectUserByUUID(ctx context.Context, uuid string, u *database.User) error {
+	user, ok := m.users[uuid]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	*u = *user
+	return nil
+}
+
+func (m *mockQuerier) TokenRevoked(ctx context.Context, id string) (bool, error) {
+	return m.revokedTokens[id], nil
+}
+
+func (m *mockQuerier) SelectSessionByUUID(ctx context.Context, uuid string, s *database.Session) error {
+	session, ok := m.sessions[uuid]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	*s = *session
+	return nil
+}
+
+func (m *mockQuerier) TouchSession(ctx context.Context, id int64) error {
+	for _, session := range m.sessions {
+		if session.ID == id {
+			session.LastSeenAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (m *mockQuerier) SelectAPIKeyByHash(ctx context.Context, hash string, k *database.APIKey) error {
+	apiKey, ok := m.apiKeys[hash]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	*k = *apiKey
+	return nil
+}
+
+func (m *mockQuerier) TouchAPIKey(ctx context.Context, id int64) error {
+	for _, apiKey := range m.apiKeys {
+		if apiKey.ID == id {
+			apiKey.LastUsedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (m *mockJWTAuthenticator) CreateTokenWithoutID(userID string) (string, error) {
+	token := jwt.NewWithClaims(auth.JWTSigningMethod, jwt.MapClaims{
+		"sub": userID,
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"iat": time.Now().Unix(),
+	})
+	token.Header["typ"] = "at+jwt"
+	return token.SignedString(m.secretKey)
+}
+
 // testRequest creates a new [httptest.Recorder] and makes a test request to handler using it,
 // then returns pointer to this recorder.
 func testRequest(setAuthHeader bool, authHeader string, handler http.Handler) *httptest.ResponseRecorder {
@@ -101,40 +175,126 @@ func testRequest(setAuthHeader bool, authHeader string, handler http.Handler) *h
 
 func TestNewJWT(t *testing.T) {
 	const (
-		testUsername  = "test-username"
-		testSecretKey = "test-secret-key"
+		testUserID         = "test-user-id"
+		testDisabledUserID = "test-disabled-user-id"
+		testUsername       = "test-username"
+		testSecretKey      = "test-secret-key"
+		testTokenID        = "test-token-id"
+		testRevokedTokenID = "test-revoked-token-id"
+
+		testSessionID        = "test-session-id"
+		testRevokedSessionID = "test-revoked-session-id"
 	)
 
 	var (
-		// Test handler which checks if username context value is equal to testUsername.
+		// Test handler which checks if user context value is the test user.
 		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			username, ok := r.Context().Value(UsernameContextKey).(string)
+			user, ok := r.Context().Value(UserContextKey).(*database.User)
 			if !ok {
-				panic("username context key is missing")
+				panic("user context key is missing")
 			}
-			assert.Equal(t, testUsername, username)
+			assert.Equal(t, testUsername, user.Username)
+			assert.Equal(t, testTokenID, r.Context().Value(TokenIDContextKey))
+			assert.Equal(t, testSessionID, r.Context().Value(SessionIDContextKey))
 		})
 
 		// JWT Authenticator used to issue and validate JWTs.
 		jwtAuth = newMockJWTAuthenticator(testSecretKey)
 
+		// Querier which reports testRevokedTokenID and testRevokedSessionID as revoked.
+		db = &mockQuerier{
+			revokedTokens: map[string]bool{testRevokedTokenID: true},
+			sessions: map[string]*database.Session{
+				testSessionID:        {ID: 1},
+				testRevokedSessionID: {ID: 2, RevokedAt: time.Now()},
+			},
+			users: map[string]*database.User{
+				testUserID: {ID: 1, Username: testUsername, TokenVersion: 1},
+			},
+		}
+
 		// Middleware which validates JWT.
-		middleware = NewJWT(jwtAuth)
+		middleware = NewJWT(jwtAuth, db)
 	)
 
 	t.Run("call the handler with valid token", func(t *testing.T) {
 		// create a new valid token for the test user:
-		token, _, err := jwtAuth.CreateToken(testUsername)
+		token, _, err := jwtAuth.CreateToken(&auth.TokenClaims{UserID: testUserID, TokenID: testTokenID, SessionID: testSessionID, Version: 1})
 		if err != nil {
 			panic(err)
 		}
 		rec := testRequest(true, fmt.Sprintf("Bearer %s", token), middleware(handler))
 		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotZero(t, db.sessions[testSessionID].LastSeenAt)
+	})
+
+	t.Run("call the handler with token of an outdated version", func(t *testing.T) {
+		token, _, err := jwtAuth.CreateToken(&auth.TokenClaims{UserID: testUserID, TokenID: testTokenID, SessionID: testSessionID, Version: 0})
+		if err != nil {
+			panic(err)
+		}
+		rec := testRequest(true, fmt.Sprintf("Bearer %s", token), middleware(handler))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("call the handler with token of a disabled user", func(t *testing.T) {
+		db.users[testDisabledUserID] = &database.User{ID: 2, Username: testUsername, TokenVersion: 1, DisabledAt: time.Now()}
+		token, _, err := jwtAuth.CreateToken(&auth.TokenClaims{UserID: testDisabledUserID, TokenID: testTokenID, SessionID: testSessionID, Version: 1})
+		if err != nil {
+			panic(err)
+		}
+		rec := testRequest(true, fmt.Sprintf("Bearer %s", token), middleware(handler))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("call the handler with token of an unknown user", func(t *testing.T) {
+		token, _, err := jwtAuth.CreateToken(&auth.TokenClaims{UserID: "unknown-user-id", TokenID: testTokenID, SessionID: testSessionID, Version: 1})
+		if err != nil {
+			panic(err)
+		}
+		rec := testRequest(true, fmt.Sprintf("Bearer %s", token), middleware(handler))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("call the handler with token of a revoked session", func(t *testing.T) {
+		token, _, err := jwtAuth.CreateToken(&auth.TokenClaims{UserID: testUserID, TokenID: testTokenID, SessionID: testRevokedSessionID})
+		if err != nil {
+			panic(err)
+		}
+		rec := testRequest(true, fmt.Sprintf("Bearer %s", token), middleware(handler))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("call the handler with token of an unknown session", func(t *testing.T) {
+		token, _, err := jwtAuth.CreateToken(&auth.TokenClaims{UserID: testUserID, TokenID: testTokenID, SessionID: "unknown-session-id"})
+		if err != nil {
+			panic(err)
+		}
+		rec := testRequest(true, fmt.Sprintf("Bearer %s", token), middleware(handler))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("call the handler with revoked token", func(t *testing.T) {
+		token, _, err := jwtAuth.CreateToken(&auth.TokenClaims{UserID: testUserID, TokenID: testRevokedTokenID, SessionID: testSessionID})
+		if err != nil {
+			panic(err)
+		}
+		rec := testRequest(true, fmt.Sprintf("Bearer %s", token), middleware(handler))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("call the handler with token without id", func(t *testing.T) {
+		token, err := jwtAuth.CreateTokenWithoutID(testUserID)
+		if err != nil {
+			panic(err)
+		}
+		rec := testRequest(true, fmt.Sprintf("Bearer %s", token), middleware(handler))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
 	})
 
 	t.Run("call the handler with expired token", func(t *testing.T) {
 		// create a new expired token for the test user:
-		token, _, err := jwtAuth.CreateExpiredToken(testUsername)
+		token, _, err := jwtAuth.CreateExpiredToken(testUserID)
 		if err != nil {
 			panic(err)
 		}
@@ -143,7 +303,7 @@ func TestNewJWT(t *testing.T) {
 	})
 
 	t.Run("call the handler with token which is not yet valid", func(t *testing.T) {
-		token, _, err := jwtAuth.CreateNotYetValidToken(testUsername)
+		token, _, err := jwtAuth.CreateNotYetValidToken(testUserID)
 		if err != nil {
 			panic(err)
 		}
@@ -171,3 +331,71 @@ func TestNewJWT(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, rec.Code)
 	})
 }
+
+func TestNewJWTWithAPIKey(t *testing.T) {
+	const (
+		testUsername      = "test-username"
+		testKey           = "groshi_test-key"
+		testExpiredKey    = "groshi_test-expired-key"
+		testUnknownKey    = "groshi_test-unknown-key"
+		testNoScopesKey   = "groshi_test-no-scopes-key"
+		testDisabledKey   = "groshi_test-disabled-key"
+		testSecretKey     = "test-secret-key"
+		testRequiredScope = auth.ScopeStatsRead
+	)
+
+	var (
+		owner = database.User{ID: 1, Username: testUsername}
+
+		// Test handler which checks if user context value is the owner of the key.
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := r.Context().Value(UserContextKey).(*database.User)
+			if assert.True(t, ok) {
+				assert.Equal(t, testUsername, user.Username)
+			}
+			assert.Nil(t, r.Context().Value(SessionIDContextKey))
+		})
+
+		db = &mockQuerier{
+			apiKeys: map[string]*database.APIKey{
+				auth.HashAPIKey(testKey):         {ID: 1, Owner: owner, Scopes: []string{testRequiredScope}},
+				auth.HashAPIKey(testExpiredKey):  {ID: 2, Owner: owner, Scopes: []string{testRequiredScope}, ExpiresAt: time.Now().Add(-time.Minute)},
+				auth.HashAPIKey(testNoScopesKey): {ID: 3, Owner: owner},
+				auth.HashAPIKey(testDisabledKey): {ID: 4, Owner: database.User{ID: 2, DisabledAt: time.Now()}, Scopes: []string{testRequiredScope}},
+			},
+		}
+
+		middleware = NewJWT(newMockJWTAuthenticator(testSecretKey), db)
+	)
+
+	t.Run("call the handler with valid api key", func(t *testing.T) {
+		rec := testRequest(true, "Bearer "+testKey, middleware(RequireScope(testRequiredScope)(handler)))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotZero(t, db.apiKeys[auth.HashAPIKey(testKey)].LastUsedAt)
+	})
+
+	t.Run("call the handler with api key without the required scope", func(t *testing.T) {
+		rec := testRequest(true, "Bearer "+testNoScopesKey, middleware(RequireScope(testRequiredScope)(handler)))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("call the handler which requires full access with api key", func(t *testing.T) {
+		rec := testRequest(true, "Bearer "+testKey, middleware(RequireFullAccess(handler)))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("call the handler with expired api key", func(t *testing.T) {
+		rec := testRequest(true, "Bearer "+testExpiredKey, middleware(handler))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("call the handler with api key of a disabled user", func(t *testing.T) {
+		rec := testRequest(true, "Bearer "+testDisabledKey, middleware(handler))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("call the handler with unknown api key", func(t *testing.T) {
+		rec := testRequest(true, "Bearer "+testUnknownKey, middleware(handler))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseTrustedProxies parses IP addresses and CIDR prefixes of trusted proxies.
+func ParseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		if strings.Contains(value, "/") {
+			prefix, err := netip.ParsePrefix(value)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return prefixes, nil
+}
+
+// NewRealIP returns new middleware which sets remote address of requests coming from trusted proxies to the address
+// of the client from `X-Forwarded-For` or `X-Real-IP` headers. Remote addresses of other requests are kept,
+// so that clients can not spoof their addresses by sending these headers directly.
+func NewRealIP(trustedProxies []netip.Prefix) func(next http.Handler) http.Handler {
+	trusted := func(value string) bool {
+		addr, err := netip.ParseAddr(strings.TrimSpace(value))
+		if err != nil {
+			return false
+		}
+		for _, prefix := range trustedProxies {
+			if prefix.Contains(addr.Unmap()) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+			if trusted(host) {
+				if ip := forwardedIP(r, trusted); ip != "" {
+					r.RemoteAddr = ip
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedIP returns address of the client which the request is forwarded for. The rightmost address
+// of `X-Forwarded-For` which is not a trusted proxy is used, as addresses to the left of it may be set by the client.
+// `X-Real-IP` is used if `X-Forwarded-For` is not set. Empty string is returned if neither of the headers is valid.
+func forwardedIP(r *http.Request, trusted func(string) bool) string {
+	if header := r.Header.Values("X-Forwarded-For"); len(header) != 0 {
+		addrs := strings.Split(strings.Join(header, ","), ",")
+		for i := len(addrs) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(addrs[i]))
+			if err != nil {
+				return ""
+			}
+			if i == 0 || !trusted(addr.String()) {
+				return addr.String()
+			}
+		}
+	}
+
+	if addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return addr.String()
+	}
+	return ""
+}
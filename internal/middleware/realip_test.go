@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewRealIP(t *testing.T) {
+	trustedProxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	testCases := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		{"direct request", "203.0.113.7:1234", nil, "203.0.113.7:1234"},
+		{"spoofed header of a direct request", "203.0.113.7:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "203.0.113.7:1234"},
+		{"request from a trusted proxy", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "198.51.100.1"},
+		{"request through several trusted proxies", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "198.51.100.1, 192.0.2.1"}, "198.51.100.1"},
+		{"spoofed header forwarded by a trusted proxy", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "198.51.100.9, 198.51.100.1"}, "198.51.100.1"},
+		{"real ip header of a trusted proxy", "192.0.2.1:1234", map[string]string{"X-Real-IP": "198.51.100.1"}, "198.51.100.1"},
+		{"malformed header of a trusted proxy", "10.1.2.3:1234", map[string]string{"X-Forwarded-For": "unknown"}, "10.1.2.3:1234"},
+	}
+
+	for _, testCase := range testCases {
+		var remoteAddr string
+		handler := NewRealIP(trustedProxies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			remoteAddr = r.RemoteAddr
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = testCase.remoteAddr
+		for key, value := range testCase.headers {
+			req.Header.Set(key, value)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, testCase.expected, remoteAddr, testCase.name)
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	_, err := ParseTrustedProxies([]string{"not-an-address"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
+	"github.com/groshi-project/groshi/internal/service/handler/model"
+	"github.com/groshi-project/groshi/internal/service/handler/response"
+	"net/http"
+)
+
+// RequireRole returns new middleware which rejects requests of users who do not have the role.
+// It must be used after the [NewJWT] middleware.
+func RequireRole(role string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := r.Context().Value(UserContextKey).(*database.User)
+			if !ok || user == nil {
+				httpresp.Render(w, response.InternalServerError)
+				return
+			}
+			if user.Role != role {
+				httpresp.Render(w, httpresp.New(http.StatusForbidden, model.NewError(fmt.Sprintf("this route requires %s role", role))))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
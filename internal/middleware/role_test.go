@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"context"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireRole(t *testing.T) {
+	var (
+		handler    = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		middleware = RequireRole(database.RoleAdmin)(handler)
+	)
+
+	// request returns response to a request made by the user:
+	request := func(user *database.User) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		middleware.ServeHTTP(rec, req.WithContext(context.WithValue(req.Context(), UserContextKey, user)))
+		return rec
+	}
+
+	t.Run("call the handler as a user with the role", func(t *testing.T) {
+		rec := request(&database.User{Role: database.RoleAdmin})
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("call the handler as a user without the role", func(t *testing.T) {
+		rec := request(&database.User{Role: database.RoleUser})
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("call the handler without user context value", func(t *testing.T) {
+		rec := request(nil)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	})
+}
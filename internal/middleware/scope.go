@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
+	"github.com/groshi-project/groshi/internal/service/handler/model"
+	"net/http"
+)
+
+// HasScope returns true if the request authenticated by the [NewJWT] middleware has the scope.
+// Requests authenticated with JWTs have all scopes.
+func HasScope(r *http.Request, scope string) bool {
+	scopes, ok := r.Context().Value(ScopesContextKey).([]string)
+	if !ok {
+		return true
+	}
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireScope returns new middleware which rejects requests which do not have the scope.
+// It must be used after the [NewJWT] middleware.
+func RequireScope(scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasScope(r, scope) {
+				httpresp.Render(w, httpresp.New(http.StatusForbidden, model.NewError(fmt.Sprintf("api key does not have %s scope", scope))))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireFullAccess is middleware which rejects requests authenticated with API keys.
+// It protects routes which manage the account itself. It must be used after the [NewJWT] middleware.
+func RequireFullAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(ScopesContextKey).([]string); ok {
+			httpresp.Render(w, httpresp.New(http.StatusForbidden, model.NewError("this route is not available for api keys")))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
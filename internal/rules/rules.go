@@ -0,0 +1,197 @@
+// Package rules implements engine which applies auto-categorization rules to transactions.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"github.com/groshi-project/groshi/internal/database"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrNoConditions is returned when a rule has no conditions.
+	ErrNoConditions = errors.New("rule has no conditions")
+
+	// ErrNoActions is returned when a rule has no actions.
+	ErrNoActions = errors.New("rule has no actions")
+
+	// ErrInvalidMatchField is returned when a rule has unknown match field.
+	ErrInvalidMatchField = errors.New("invalid match field")
+
+	// ErrInvalidMatchType is returned when a rule has unknown match type.
+	ErrInvalidMatchType = errors.New("invalid match type")
+
+	// ErrInvalidAmountRange is returned when lower amount bound of a rule is greater than its upper bound.
+	ErrInvalidAmountRange = errors.New("invalid amount range")
+)
+
+// Input represents transaction fields rules are matched against.
+type Input struct {
+	Description string
+	Payee       string
+	Amount      int32
+	CurrencyID  int64
+}
+
+// Result represents outcome of applying rules to a transaction.
+// Zero values mean that no rule has set the corresponding field.
+type Result struct {
+	// ID of the category set by the first matched rule which sets category.
+	CategoryID int64
+
+	// IDs of the tags added by all matched rules, without duplicates, in order of addition.
+	TagIDs []int64
+
+	// Payee name set by the first matched rule which renames payee.
+	PayeeName string
+
+	// IDs of all matched rules in order they were applied.
+	RuleIDs []int64
+}
+
+// Matched returns true if at least one rule has matched.
+func (r *Result) Matched() bool {
+	return len(r.RuleIDs) != 0
+}
+
+// compiledRule represents a rule prepared for matching.
+type compiledRule struct {
+	rule *database.Rule
+
+	// regular expression the rule's field must match, nil if the rule has no pattern.
+	pattern *regexp.Regexp
+}
+
+// compile prepares the rule for matching.
+func compile(rule *database.Rule) (*compiledRule, error) {
+	hasPattern := rule.MatchField != "" || rule.MatchType != "" || rule.Pattern != ""
+
+	compiled := &compiledRule{rule: rule}
+	if !hasPattern {
+		return compiled, nil
+	}
+
+	switch rule.MatchField {
+	case database.RuleMatchFieldDescription, database.RuleMatchFieldPayee:
+	default:
+		return nil, ErrInvalidMatchField
+	}
+
+	var expr string
+	switch rule.MatchType {
+	case database.RuleMatchTypeSubstring:
+		expr = "(?i)" + regexp.QuoteMeta(rule.Pattern)
+	case database.RuleMatchTypeRegex:
+		expr = rule.Pattern
+	default:
+		return nil, ErrInvalidMatchType
+	}
+
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	compiled.pattern = pattern
+
+	return compiled, nil
+}
+
+// matches returns true if all conditions of the rule are satisfied by the input.
+func (c *compiledRule) matches(in *Input) bool {
+	if c.pattern != nil {
+		field := in.Description
+		if c.rule.MatchField == database.RuleMatchFieldPayee {
+			field = in.Payee
+		}
+		if strings.TrimSpace(field) == "" || !c.pattern.MatchString(field) {
+			return false
+		}
+	}
+	if c.rule.AmountMin != nil && in.Amount < *c.rule.AmountMin {
+		return false
+	}
+	if c.rule.AmountMax != nil && in.Amount > *c.rule.AmountMax {
+		return false
+	}
+	if c.rule.CurrencyID != 0 && in.CurrencyID != c.rule.CurrencyID {
+		return false
+	}
+	return true
+}
+
+// Validate checks if the rule is valid: it has at least one condition and action,
+// known match field and type, valid pattern and amount range.
+func Validate(rule *database.Rule) error {
+	hasConditions := rule.MatchField != "" || rule.MatchType != "" || rule.Pattern != "" ||
+		rule.AmountMin != nil || rule.AmountMax != nil || rule.CurrencyID != 0
+	if !hasConditions {
+		return ErrNoConditions
+	}
+	if rule.CategoryID == 0 && len(rule.TagIDs) == 0 && rule.PayeeName == "" {
+		return ErrNoActions
+	}
+	if rule.AmountMin != nil && rule.AmountMax != nil && *rule.AmountMin > *rule.AmountMax {
+		return ErrInvalidAmountRange
+	}
+
+	_, err := compile(rule)
+	return err
+}
+
+// Engine applies an ordered list of rules to transactions.
+type Engine struct {
+	rules []*compiledRule
+}
+
+// New creates a new instance of [Engine] which applies the given rules in the given order
+// and returns pointer to it. Returns an error if any rule has invalid match field, match type or pattern.
+// Rules are not required to pass [Validate]: for example, a rule may lose its only action
+// when the category it sets is deleted, such rule is kept but does nothing.
+func New(rules []database.Rule) (*Engine, error) {
+	engine := &Engine{rules: make([]*compiledRule, 0, len(rules))}
+	for i := range rules {
+		compiled, err := compile(&rules[i])
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rules[i].Name, err)
+		}
+		engine.rules = append(engine.rules, compiled)
+	}
+	return engine, nil
+}
+
+// Apply matches all rules against the input in order and returns combined result of their actions.
+// Category and payee are set by the first matched rule which sets them, tags of all matched rules are combined.
+func (e *Engine) Apply(in Input) *Result {
+	result := &Result{}
+	for _, compiled := range e.rules {
+		if !compiled.matches(&in) {
+			continue
+		}
+		rule := compiled.rule
+		result.RuleIDs = append(result.RuleIDs, rule.ID)
+
+		if result.CategoryID == 0 {
+			result.CategoryID = rule.CategoryID
+		}
+		if result.PayeeName == "" {
+			result.PayeeName = rule.PayeeName
+		}
+		for _, tagID := range rule.TagIDs {
+			if !containsID(result.TagIDs, tagID) {
+				result.TagIDs = append(result.TagIDs, tagID)
+			}
+		}
+	}
+	return result
+}
+
+// containsID returns true if the slice contains the given ID.
+func containsID(ids []int64, id int64) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package rules
+
+import (
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func amount(a int32) *int32 {
+	return &a
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name string
+		rule database.Rule
+		err  error
+	}{
+		{
+			"valid substring rule",
+			database.Rule{MatchField: "description", MatchType: "substring", Pattern: "rewe", CategoryID: 1},
+			nil,
+		},
+		{
+			"valid amount rule",
+			database.Rule{AmountMin: amount(-1000), AmountMax: amount(0), TagIDs: []int64{1}},
+			nil,
+		},
+		{
+			"rule without conditions",
+			database.Rule{CategoryID: 1},
+			ErrNoConditions,
+		},
+		{
+			"rule without actions",
+			database.Rule{MatchField: "payee", MatchType: "substring", Pattern: "rewe"},
+			ErrNoActions,
+		},
+		{
+			"rule with unknown match field",
+			database.Rule{MatchField: "note", MatchType: "substring", Pattern: "rewe", CategoryID: 1},
+			ErrInvalidMatchField,
+		},
+		{
+			"rule with unknown match type",
+			database.Rule{MatchField: "payee", MatchType: "glob", Pattern: "rewe*", CategoryID: 1},
+			ErrInvalidMatchType,
+		},
+		{
+			"rule with inverted amount range",
+			database.Rule{AmountMin: amount(10), AmountMax: amount(-10), CategoryID: 1},
+			ErrInvalidAmountRange,
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := Validate(&testCase.rule)
+		if testCase.err == nil {
+			assert.NoError(t, err, testCase.name)
+		} else {
+			assert.ErrorIs(t, err, testCase.err, testCase.name)
+		}
+	}
+
+	t.Run("rule with invalid regular expression", func(t *testing.T) {
+		err := Validate(&database.Rule{MatchField: "payee", MatchType: "regex", Pattern: "(", CategoryID: 1})
+		assert.Error(t, err)
+	})
+}
+
+func TestEngine_Apply(t *testing.T) {
+	engine, err := New([]database.Rule{
+		{ID: 1, Name: "groceries", MatchField: "payee", MatchType: "substring", Pattern: "REWE", CategoryID: 10, TagIDs: []int64{100}},
+		{ID: 2, Name: "big purchases", AmountMax: amount(-10000), TagIDs: []int64{101, 100}},
+		{ID: 3, Name: "amazon", MatchField: "description", MatchType: "regex", Pattern: `^AMZN\s+Mktp`, CategoryID: 11, PayeeName: "Amazon"},
+		{ID: 4, Name: "dollars", CurrencyID: 2, CategoryID: 12},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	t.Run("no rules match", func(t *testing.T) {
+		result := engine.Apply(Input{Description: "Rent", Amount: -500, CurrencyID: 1})
+		assert.False(t, result.Matched())
+		assert.Zero(t, result.CategoryID)
+		assert.Empty(t, result.TagIDs)
+		assert.Empty(t, result.PayeeName)
+	})
+
+	t.Run("substring matches case-insensitively", func(t *testing.T) {
+		result := engine.Apply(Input{Payee: "Rewe Markt GmbH", Amount: -500, CurrencyID: 1})
+		assert.Equal(t, []int64{1}, result.RuleIDs)
+		assert.Equal(t, int64(10), result.CategoryID)
+		assert.Equal(t, []int64{100}, result.TagIDs)
+	})
+
+	t.Run("first category wins and tags are combined", func(t *testing.T) {
+		result := engine.Apply(Input{Payee: "REWE", Amount: -25000, CurrencyID: 2})
+		assert.Equal(t, []int64{1, 2, 4}, result.RuleIDs)
+		assert.Equal(t, int64(10), result.CategoryID)
+		assert.Equal(t, []int64{100, 101}, result.TagIDs)
+	})
+
+	t.Run("regex matches and renames payee", func(t *testing.T) {
+		result := engine.Apply(Input{Description: "AMZN Mktp DE 123", Amount: -500, CurrencyID: 1})
+		assert.Equal(t, []int64{3}, result.RuleIDs)
+		assert.Equal(t, int64(11), result.CategoryID)
+		assert.Equal(t, "Amazon", result.PayeeName)
+	})
+
+	t.Run("pattern does not match empty field", func(t *testing.T) {
+		empty, err := New([]database.Rule{
+			{ID: 1, MatchField: "payee", MatchType: "regex", Pattern: ".*", CategoryID: 1},
+		})
+		if assert.NoError(t, err) {
+			assert.False(t, empty.Apply(Input{Description: "something"}).Matched())
+		}
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Run("rule without actions is tolerated", func(t *testing.T) {
+		engine, err := New([]database.Rule{
+			{ID: 1, Name: "orphaned", CurrencyID: 1},
+		})
+		if assert.NoError(t, err) {
+			result := engine.Apply(Input{CurrencyID: 1})
+			assert.True(t, result.Matched())
+			assert.Zero(t, result.CategoryID)
+		}
+	})
+
+	t.Run("rule with invalid match type is rejected", func(t *testing.T) {
+		_, err := New([]database.Rule{
+			{Name: "valid", CurrencyID: 1, CategoryID: 1},
+			{Name: "invalid", MatchField: "payee", MatchType: "glob", Pattern: "*", CategoryID: 1},
+		})
+		assert.ErrorIs(t, err, ErrInvalidMatchType)
+	})
+}
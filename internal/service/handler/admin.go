@@ -0,0 +1,312 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/groshi-project/groshi/internal/auth"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
+	"github.com/groshi-project/groshi/internal/service/handler/response"
+	"net/http"
+	"time"
+)
+
+type adminUsersGetResponseItem struct {
+	UUID     string `json:"uuid" example:"3f0c9a52-6a1e-4d1b-9b1e-5a2f7c8d9e0f"`
+	Username string `json:"username" example:"jieggii"`
+	Role     string `json:"role" example:"user" enums:"user,admin"`
+
+	// True if the user is allowed to create invite codes.
+	CanInvite bool `json:"can_invite" example:"false"`
+
+	// Username of the user who invited the user, it is omitted if the user was not invited.
+	InvitedBy string `json:"invited_by,omitempty" example:"jieggii"`
+
+	// Time when the user was disabled, it is omitted if the user is enabled.
+	DisabledAt *time.Time `json:"disabled_at,omitempty" example:"2026-03-22T08:14:02Z"`
+
+	// Counts of the user's data.
+	Transactions   int `json:"transactions" example:"1024"`
+	Categories     int `json:"categories" example:"12"`
+	Tags           int `json:"tags" example:"8"`
+	Rules          int `json:"rules" example:"3"`
+	APIKeys        int `json:"api_keys" example:"1"`
+	ActiveSessions int `json:"active_sessions" example:"2"`
+
+	// Time when a session of the user was seen last time, it is omitted if the user has never logged in.
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty" example:"2026-03-22T08:14:02Z"`
+}
+
+type adminUsersGetResponse []adminUsersGetResponseItem
+
+// AdminUsersGet returns all users together with counts of their data.
+//
+//	@Summary		Fetch users
+//	@Description	Returns all users ordered by username together with counts of their data. Available only for admins
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	adminUsersGetResponse	"Successful operation"
+//	@Failure		403	{object}	model.Error				"The current user is not an admin"
+//	@Failure		500	{object}	model.Error				"Internal server error"
+//	@Security		Bearer
+//	@Router			/admin/users [get]
+func (h *Handler) AdminUsersGet(w http.ResponseWriter, r *http.Request) {
+	// fetch users from the database:
+	users := make([]database.UserUsage, 0)
+	if err := h.database.SelectUsersUsage(r.Context(), &users); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	resp := make(adminUsersGetResponse, 0, len(users))
+	for _, user := range users {
+		resp = append(resp, adminUsersGetResponseItem{
+			UUID:           user.UUID.String(),
+			Username:       user.Username,
+			Role:           user.Role,
+			CanInvite:      user.CanInvite,
+			InvitedBy:      user.InvitedBy,
+			DisabledAt:     optionalTime(user.DisabledAt),
+			Transactions:   user.Transactions,
+			Categories:     user.Categories,
+			Tags:           user.Tags,
+			Rules:          user.Rules,
+			APIKeys:        user.APIKeys,
+			ActiveSessions: user.ActiveSessions,
+			LastSeenAt:     optionalTime(user.LastSeenAt),
+		})
+	}
+	httpresp.Render(w, httpresp.NewOK(&resp))
+}
+
+// userByUUID fetches the user with UUID from the `uuid` URL param.
+// Renders an error response and returns false if the user could not be fetched.
+func (h *Handler) userByUUID(w http.ResponseWriter, r *http.Request) (*database.User, bool) {
+	user := &database.User{}
+	if err := h.database.SelectUserByUUID(r.Context(), chi.URLParam(r, "uuid"), user); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpresp.Render(w, response.UserNotFound)
+			return nil, false
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return nil, false
+	}
+	return user, true
+}
+
+// AdminUsersDisable disables a user.
+//
+//	@Summary		Disable a user
+//	@Description	Disables a user and revokes all its sessions. Disabled users can not log in and use their tokens and API keys. Admins can not disable themselves. Available only for admins
+//	@Tags			admin
+//	@Param			uuid	path	string	true	"User UUID"
+//	@Success		204		"Successful operation"
+//	@Failure		403		{object}	model.Error	"The current user is not an admin"
+//	@Failure		404		{object}	model.Error	"User not found"
+//	@Failure		409		{object}	model.Error	"The user is the current user"
+//	@Failure		500		{object}	model.Error	"Internal server error"
+//	@Security		Bearer
+//	@Router			/admin/users/{uuid}/disable [post]
+func (h *Handler) AdminUsersDisable(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user and the user to disable:
+	current, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	user, ok := h.userByUUID(w, r)
+	if !ok {
+		return
+	}
+	if user.ID == current.ID {
+		httpresp.Render(w, response.CannotDisableCurrentUser)
+		return
+	}
+
+	// disable the user:
+	if err := h.database.DisableUser(r.Context(), user.ID); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AdminUsersEnable enables a disabled user.
+//
+//	@Summary		Enable a user
+//	@Description	Enables a disabled user, so that it can log in again. Available only for admins
+//	@Tags			admin
+//	@Param			uuid	path	string	true	"User UUID"
+//	@Success		204		"Successful operation"
+//	@Failure		403		{object}	model.Error	"The current user is not an admin"
+//	@Failure		404		{object}	model.Error	"User not found"
+//	@Failure		500		{object}	model.Error	"Internal server error"
+//	@Security		Bearer
+//	@Router			/admin/users/{uuid}/enable [post]
+func (h *Handler) AdminUsersEnable(w http.ResponseWriter, r *http.Request) {
+	// fetch the user to enable:
+	user, ok := h.userByUUID(w, r)
+	if !ok {
+		return
+	}
+
+	// enable the user:
+	if err := h.database.EnableUser(r.Context(), user.ID); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type adminUsersPermissionsUpdateParams struct {
+	// Allows the user to create invite codes.
+	CanInvite bool `json:"can_invite" example:"true"`
+}
+
+// AdminUsersPermissionsUpdate changes permissions of a user.
+//
+//	@Summary		Change permissions of a user
+//	@Description	Allows or forbids a user to create invite codes. Admins can always create them. Available only for admins
+//	@Tags			admin
+//	@Accept			json
+//	@Param			uuid		path	string								true	"User UUID"
+//	@Param			permissions	body	adminUsersPermissionsUpdateParams	true	"Permissions"
+//	@Success		204			"Successful operation"
+//	@Failure		400			{object}	model.Error	"Invalid request body format"
+//	@Failure		403			{object}	model.Error	"The current user is not an admin"
+//	@Failure		404			{object}	model.Error	"User not found"
+//	@Failure		500			{object}	model.Error	"Internal server error"
+//	@Security		Bearer
+//	@Router			/admin/users/{uuid}/permissions [put]
+func (h *Handler) AdminUsersPermissionsUpdate(w http.ResponseWriter, r *http.Request) {
+	// decode request params:
+	params := &adminUsersPermissionsUpdateParams{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return
+	}
+
+	// fetch the user:
+	user, ok := h.userByUUID(w, r)
+	if !ok {
+		return
+	}
+
+	// update permissions of the user:
+	if err := h.database.UpdateUserCanInvite(r.Context(), user.ID, params.CanInvite); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type adminUsersPasswordResetResponse struct {
+	// New random password of the user, it must be passed to the user.
+	Password string `json:"password" example:"h7Kq2mZx9VbN4tLp"`
+}
+
+// AdminUsersPasswordReset sets a new random password of a user.
+//
+//	@Summary		Reset password of a user
+//	@Description	Sets a new random password of a user, revokes all its sessions and returns the password. Available only for admins
+//	@Tags			admin
+//	@Produce		json
+//	@Param			uuid	path		string							true	"User UUID"
+//	@Success		200		{object}	adminUsersPasswordResetResponse	"Successful operation"
+//	@Failure		403		{object}	model.Error						"The current user is not an admin"
+//	@Failure		404		{object}	model.Error						"User not found"
+//	@Failure		500		{object}	model.Error						"Internal server error"
+//	@Security		Bearer
+//	@Router			/admin/users/{uuid}/password-reset [post]
+func (h *Handler) AdminUsersPasswordReset(w http.ResponseWriter, r *http.Request) {
+	// fetch the user:
+	user, ok := h.userByUUID(w, r)
+	if !ok {
+		return
+	}
+
+	// set a new random password:
+	password, err := auth.GeneratePassword()
+	if err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+	passwordHash, err := h.passwordAuth.HashPassword(password)
+	if err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+	if err := h.database.ChangeUserPassword(r.Context(), user.ID, passwordHash); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	resp := &adminUsersPasswordResetResponse{Password: password}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
+
+type adminJobsGetResponseItem struct {
+	Name string `json:"name" example:"prune-expired-tokens"`
+
+	// Interval between runs of the job in seconds.
+	Interval int64 `json:"interval" example:"86400"`
+
+	Running  bool `json:"running" example:"false"`
+	Runs     int  `json:"runs" example:"7"`
+	Failures int  `json:"failures" example:"1"`
+
+	LastStartedAt  *time.Time `json:"last_started_at,omitempty" example:"2026-03-22T08:14:02Z"`
+	LastFinishedAt *time.Time `json:"last_finished_at,omitempty" example:"2026-03-22T08:14:03Z"`
+
+	// Error of the last run, it is empty if the last run succeeded.
+	LastError string `json:"last_error,omitempty" example:"could not fetch rates"`
+}
+
+type adminJobsGetResponse []adminJobsGetResponseItem
+
+// AdminJobsGet returns statuses of the service jobs.
+//
+//	@Summary		Fetch job statuses
+//	@Description	Returns statuses of the jobs which are run by the service periodically. Available only for admins
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	adminJobsGetResponse	"Successful operation"
+//	@Failure		403	{object}	model.Error				"The current user is not an admin"
+//	@Security		Bearer
+//	@Router			/admin/jobs [get]
+func (h *Handler) AdminJobsGet(w http.ResponseWriter, r *http.Request) {
+	statuses := h.jobs.Statuses()
+
+	// respond:
+	resp := make(adminJobsGetResponse, 0, len(statuses))
+	for _, status := range statuses {
+		resp = append(resp, adminJobsGetResponseItem{
+			Name:           status.Name,
+			Interval:       int64(status.Interval.Seconds()),
+			Running:        status.Running,
+			Runs:           status.Runs,
+			Failures:       status.Failures,
+			LastStartedAt:  optionalTime(status.LastStartedAt),
+			LastFinishedAt: optionalTime(status.LastFinishedAt),
+			LastError:      status.LastError,
+		})
+	}
+	httpresp.Render(w, httpresp.NewOK(&resp))
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// testAdminContext creates an admin and a logged-in test user and returns context of the admin.
+func testAdminContext(handler *Handler) context.Context {
+	ctx := context.Background()
+	testLogin(ctx, handler)
+	if err := handler.database.CreateUser(ctx, &database.User{
+		Username: "test-admin",
+		Password: "hash(test-admin-password)",
+		Role:     database.RoleAdmin,
+	}); err != nil {
+		panic(err)
+	}
+	return testUserContext(ctx, handler, "test-admin")
+}
+
+func TestHandler_AdminUsersGet(t *testing.T) {
+	var (
+		handler = newTestHandler()
+		ctx     = testAdminContext(handler)
+		mockDb  = handler.database.(*mockDatabase)
+	)
+	mockDb.tags = append(mockDb.tags, &database.Tag{Name: "test-tag", OwnerID: mockDb.users[0].ID})
+
+	rec := testGetRequest(ctx, "/admin/users", handler.AdminUsersGet)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		resp := adminUsersGetResponse{}
+		if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp)) && assert.Len(t, resp, 2) {
+			admin, user := resp[0], resp[1]
+			assert.Equal(t, "test-admin", admin.Username)
+			assert.Equal(t, database.RoleAdmin, admin.Role)
+			assert.Zero(t, admin.ActiveSessions)
+			assert.Nil(t, admin.LastSeenAt)
+
+			assert.Equal(t, "test-username", user.Username)
+			assert.Equal(t, database.RoleUser, user.Role)
+			assert.Nil(t, user.DisabledAt)
+			assert.Equal(t, 1, user.Tags)
+			assert.Equal(t, 1, user.ActiveSessions)
+			assert.NotNil(t, user.LastSeenAt)
+		}
+	}
+}
+
+func TestHandler_AdminUsersDisable(t *testing.T) {
+	t.Run("disable a user", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testAdminContext(handler)
+			user    = handler.database.(*mockDatabase).users[0]
+		)
+
+		rec := testRequest(withURLParam(ctx, "uuid", user.UUID.String()), nil, handler.AdminUsersDisable)
+		if assert.Equal(t, http.StatusNoContent, rec.Code) {
+			assert.False(t, user.DisabledAt.IsZero())
+			assert.False(t, handler.database.(*mockDatabase).sessions[0].RevokedAt.IsZero())
+		}
+
+		// the disabled user can not log in:
+		params := &authLoginParams{Username: "test-username", Password: "test-password"}
+		rec = testRequest(context.Background(), params, handler.AuthLogin)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		// the user can log in again after it is enabled:
+		rec = testRequest(withURLParam(ctx, "uuid", user.UUID.String()), nil, handler.AdminUsersEnable)
+		if assert.Equal(t, http.StatusNoContent, rec.Code) {
+			assert.True(t, user.DisabledAt.IsZero())
+		}
+		rec = testRequest(context.Background(), params, handler.AuthLogin)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("disable the current user", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testAdminContext(handler)
+			admin   = handler.database.(*mockDatabase).users[1]
+		)
+
+		rec := testRequest(withURLParam(ctx, "uuid", admin.UUID.String()), nil, handler.AdminUsersDisable)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.True(t, admin.DisabledAt.IsZero())
+	})
+
+	t.Run("disable a non-existent user", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testAdminContext(handler)
+		)
+
+		rec := testRequest(withURLParam(ctx, "uuid", "c8a8a0d4-7c0e-4b7e-8a62-5d5f0d1b7a9e"), nil, handler.AdminUsersDisable)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestHandler_AdminUsersPermissionsUpdate(t *testing.T) {
+	var (
+		handler = newTestHandler()
+		ctx     = testAdminContext(handler)
+		user    = handler.database.(*mockDatabase).users[0]
+	)
+
+	rec := testRequest(withURLParam(ctx, "uuid", user.UUID.String()), &adminUsersPermissionsUpdateParams{CanInvite: true}, handler.AdminUsersPermissionsUpdate)
+	if assert.Equal(t, http.StatusNoContent, rec.Code) {
+		assert.True(t, user.CanInvite)
+	}
+}
+
+func TestHandler_AdminUsersPasswordReset(t *testing.T) {
+	var (
+		handler = newTestHandler()
+		ctx     = testAdminContext(handler)
+		user    = handler.database.(*mockDatabase).users[0]
+	)
+
+	rec := testRequest(withURLParam(ctx, "uuid", user.UUID.String()), nil, handler.AdminUsersPasswordReset)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		resp := &adminUsersPasswordResetResponse{}
+		if assert.NoError(t, json.NewDecoder(rec.Body).Decode(resp)) {
+			assert.Equal(t, "hash("+resp.Password+")", user.Password)
+			assert.Equal(t, 1, user.TokenVersion)
+			assert.False(t, handler.database.(*mockDatabase).sessions[0].RevokedAt.IsZero())
+		}
+	}
+}
+
+func TestHandler_AdminJobsGet(t *testing.T) {
+	var (
+		handler = newTestHandler()
+		ctx     = testAdminContext(handler)
+		done    = make(chan struct{})
+	)
+	jobCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handler.jobs.Start(jobCtx, "test-job", time.Hour, func() error {
+		close(done)
+		return nil
+	})
+	<-done
+
+	rec := testGetRequest(ctx, "/admin/jobs", handler.AdminJobsGet)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		resp := adminJobsGetResponse{}
+		if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp)) && assert.Len(t, resp, 1) {
+			assert.Equal(t, "test-job", resp[0].Name)
+			assert.Equal(t, int64(3600), resp[0].Interval)
+			assert.NotNil(t, resp[0].LastStartedAt)
+		}
+	}
+}
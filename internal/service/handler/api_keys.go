@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/groshi-project/groshi/internal/auth"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
+	"github.com/groshi-project/groshi/internal/service/handler/model"
+	"github.com/groshi-project/groshi/internal/service/handler/response"
+	"net/http"
+	"time"
+)
+
+type apiKeysCreateParams struct {
+	Name string `json:"name" example:"receipt-scanner" validate:"required,max=128"`
+
+	// Scopes granted to the key, see the description of the route for the list of scopes.
+	Scopes []string `json:"scopes" example:"transactions:write,stats:read" validate:"required,min=1,dive,required"`
+
+	// Optional expiration time of the key, the key never expires if it is not set.
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2027-03-20T12:57:38Z"`
+}
+
+type apiKeysCreateResponse struct {
+	UUID string `json:"uuid" example:"7c1e2a4b-9d3f-4e5a-b6c7-8d9e0f1a2b3c"`
+
+	// The key itself, it is shown only once.
+	Key string `json:"key" example:"groshi_P3lq0xK7w9dV2mN8bR4tY6uZ1cH5jF0aE3sG7kL9oQ2"`
+
+	Name      string     `json:"name" example:"receipt-scanner"`
+	Scopes    []string   `json:"scopes" example:"transactions:write,stats:read"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2027-03-20T12:57:38Z"`
+}
+
+// APIKeysCreate creates a new API key of the current user.
+//
+//	@Summary		Create an API key
+//	@Description	Creates a new API key with the given scopes and returns it. The key is shown only once. API keys are passed in the `Authorization` header as `Bearer <key>` and give access only to the routes allowed by their scopes: `categories:read`, `categories:write`, `tags:read`, `tags:write`, `payees:read`, `rules:read`, `rules:write`, `transactions:read`, `transactions:write`, `stats:read` and `ledgers:read`. `/user` routes and changes of ledgers are not available for API keys
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			key	body		apiKeysCreateParams		true	"API key params"
+//	@Success		200	{object}	apiKeysCreateResponse	"Successful operation"
+//	@Failure		400	{object}	model.Error				"Invalid request body format, invalid request params, unknown scope or expiration time in the past"
+//	@Failure		404	{object}	model.Error				"User not found"
+//	@Failure		500	{object}	model.Error				"Internal server error"
+//	@Security		Bearer
+//	@Router			/user/api-keys [post]
+func (h *Handler) APIKeysCreate(w http.ResponseWriter, r *http.Request) {
+	// decode request params:
+	params := &apiKeysCreateParams{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return
+	}
+
+	// validate request params:
+	if err := h.paramsValidate.Struct(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return
+	}
+	scopes := make([]string, 0, len(params.Scopes))
+	seen := make(map[string]bool)
+	for _, scope := range params.Scopes {
+		if !auth.ValidScope(scope) {
+			httpresp.Render(w, httpresp.New(http.StatusBadRequest, model.NewError(fmt.Sprintf("unknown scope %q", scope))))
+			return
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	if params.ExpiresAt != nil && !params.ExpiresAt.After(h.now()) {
+		httpresp.Render(w, httpresp.New(http.StatusBadRequest, model.NewError("expiration time must be in the future")))
+		return
+	}
+
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// generate and store the key:
+	key, err := auth.NewAPIKey()
+	if err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+	apiKey := &database.APIKey{
+		Name:    params.Name,
+		Prefix:  auth.APIKeyDisplayPrefix(key),
+		KeyHash: auth.HashAPIKey(key),
+		Scopes:  scopes,
+		OwnerID: user.ID,
+	}
+	if params.ExpiresAt != nil {
+		apiKey.ExpiresAt = *params.ExpiresAt
+	}
+	if err := h.database.CreateAPIKey(r.Context(), apiKey); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	resp := &apiKeysCreateResponse{
+		UUID:      apiKey.UUID.String(),
+		Key:       key,
+		Name:      apiKey.Name,
+		Scopes:    apiKey.Scopes,
+		ExpiresAt: params.ExpiresAt,
+	}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
+
+type apiKeysGetResponseItem struct {
+	UUID string `json:"uuid" example:"7c1e2a4b-9d3f-4e5a-b6c7-8d9e0f1a2b3c"`
+	Name string `json:"name" example:"receipt-scanner"`
+
+	// Beginning of the key which helps to recognize it.
+	Prefix string   `json:"prefix" example:"groshi_P3lq0x"`
+	Scopes []string `json:"scopes" example:"transactions:write,stats:read"`
+
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" example:"2027-03-20T12:57:38Z"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" example:"2026-03-22T08:14:02Z"`
+	CreatedAt  time.Time  `json:"created_at" example:"2026-03-20T12:57:38Z"`
+}
+
+type apiKeysGetResponse []apiKeysGetResponseItem
+
+// optionalTime returns pointer to t or nil if t is zero.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// APIKeysGet returns API keys of the current user.
+//
+//	@Summary		Fetch API keys
+//	@Description	Returns API keys of the current user, the most recent ones first. Keys themselves are not returned
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	apiKeysGetResponse	"Successful operation"
+//	@Failure		404	{object}	model.Error			"User not found"
+//	@Failure		500	{object}	model.Error			"Internal server error"
+//	@Security		Bearer
+//	@Router			/user/api-keys [get]
+func (h *Handler) APIKeysGet(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch API keys of the user from the database:
+	apiKeys := make([]database.APIKey, 0)
+	if err := h.database.SelectAPIKeysByOwnerID(r.Context(), user.ID, &apiKeys); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+
+	// respond:
+	resp := make(apiKeysGetResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		resp = append(resp, apiKeysGetResponseItem{
+			UUID:       apiKey.UUID.String(),
+			Name:       apiKey.Name,
+			Prefix:     apiKey.Prefix,
+			Scopes:     apiKey.Scopes,
+			ExpiresAt:  optionalTime(apiKey.ExpiresAt),
+			LastUsedAt: optionalTime(apiKey.LastUsedAt),
+			CreatedAt:  apiKey.CreatedAt,
+		})
+	}
+	httpresp.Render(w, httpresp.NewOK(&resp))
+}
+
+type apiKeysDeleteResponse struct {
+	UUID string `json:"uuid" example:"7c1e2a4b-9d3f-4e5a-b6c7-8d9e0f1a2b3c"`
+}
+
+// APIKeysDelete deletes an API key of the current user.
+//
+//	@Summary		Delete an API key
+//	@Description	Deletes an API key of the current user, so that it can not be used anymore, and returns its UUID
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			uuid	path		string					true	"API key UUID"
+//	@Success		200		{object}	apiKeysDeleteResponse	"Successful operation"
+//	@Failure		403		{object}	model.Error				"Access to the API key is forbidden"
+//	@Failure		404		{object}	model.Error				"User or API key not found"
+//	@Failure		500		{object}	model.Error				"Internal server error"
+//	@Security		Bearer
+//	@Router			/user/api-keys/{uuid} [delete]
+func (h *Handler) APIKeysDelete(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch the API key and check if it belongs to the current user:
+	apiKey := &database.APIKey{}
+	if err := h.database.SelectAPIKeyByUUID(r.Context(), chi.URLParam(r, "uuid"), apiKey); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpresp.Render(w, response.APIKeyNotFound)
+			return
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+	if apiKey.OwnerID != user.ID {
+		httpresp.Render(w, response.APIKeyForbidden)
+		return
+	}
+
+	// delete the API key:
+	if err := h.database.DeleteAPIKeyByID(r.Context(), apiKey.ID); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	resp := &apiKeysDeleteResponse{UUID: apiKey.UUID.String()}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/groshi-project/groshi/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// testAPIKeysContext logs in as a test user and returns context of the user.
+func testAPIKeysContext(handler *Handler) context.Context {
+	ctx := context.Background()
+	testLogin(ctx, handler)
+	return testUserContext(ctx, handler, "test-username")
+}
+
+func TestHandler_APIKeysCreate(t *testing.T) {
+	t.Run("create an api key", func(t *testing.T) {
+		var (
+			handler   = newTestHandler()
+			ctx       = testAPIKeysContext(handler)
+			expiresAt = time.Now().Add(time.Hour).UTC()
+		)
+
+		params := &apiKeysCreateParams{
+			Name:      "receipt-scanner",
+			Scopes:    []string{auth.ScopeTransactionsWrite, auth.ScopeStatsRead, auth.ScopeStatsRead},
+			ExpiresAt: &expiresAt,
+		}
+		rec := testRequest(ctx, params, handler.APIKeysCreate)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			resp := &apiKeysCreateResponse{}
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(resp)) {
+				assert.True(t, auth.IsAPIKey(resp.Key))
+				assert.Equal(t, []string{auth.ScopeTransactionsWrite, auth.ScopeStatsRead}, resp.Scopes)
+
+				apiKey := handler.database.(*mockDatabase).apiKeys[0]
+				assert.Equal(t, auth.HashAPIKey(resp.Key), apiKey.KeyHash)
+				assert.NotContains(t, apiKey.KeyHash, resp.Key)
+				assert.Equal(t, auth.APIKeyDisplayPrefix(resp.Key), apiKey.Prefix)
+				assert.True(t, expiresAt.Equal(apiKey.ExpiresAt))
+			}
+		}
+	})
+
+	t.Run("create an api key with unknown scope", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testAPIKeysContext(handler)
+		)
+
+		rec := testRequest(ctx, &apiKeysCreateParams{Name: "bot", Scopes: []string{"user:delete"}}, handler.APIKeysCreate)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Empty(t, handler.database.(*mockDatabase).apiKeys)
+	})
+
+	t.Run("create an api key without scopes", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testAPIKeysContext(handler)
+		)
+
+		rec := testRequest(ctx, &apiKeysCreateParams{Name: "bot"}, handler.APIKeysCreate)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("create an api key which is already expired", func(t *testing.T) {
+		var (
+			handler   = newTestHandler()
+			ctx       = testAPIKeysContext(handler)
+			expiresAt = time.Now().Add(-time.Hour)
+		)
+
+		params := &apiKeysCreateParams{Name: "bot", Scopes: []string{auth.ScopeStatsRead}, ExpiresAt: &expiresAt}
+		rec := testRequest(ctx, params, handler.APIKeysCreate)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestHandler_APIKeysGet(t *testing.T) {
+	var (
+		handler = newTestHandler()
+		ctx     = testAPIKeysContext(handler)
+	)
+
+	rec := testRequest(ctx, &apiKeysCreateParams{Name: "bot", Scopes: []string{auth.ScopeStatsRead}}, handler.APIKeysCreate)
+	if !assert.Equal(t, http.StatusOK, rec.Code) {
+		return
+	}
+
+	rec = testGetRequest(ctx, "/user/api-keys", handler.APIKeysGet)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		resp := apiKeysGetResponse{}
+		if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp)) && assert.Len(t, resp, 1) {
+			assert.Equal(t, "bot", resp[0].Name)
+			assert.Nil(t, resp[0].ExpiresAt)
+			assert.Nil(t, resp[0].LastUsedAt)
+		}
+	}
+}
+
+func TestHandler_APIKeysDelete(t *testing.T) {
+	t.Run("delete an api key", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testAPIKeysContext(handler)
+		)
+
+		testRequest(ctx, &apiKeysCreateParams{Name: "bot", Scopes: []string{auth.ScopeStatsRead}}, handler.APIKeysCreate)
+		apiKey := handler.database.(*mockDatabase).apiKeys[0]
+
+		rec := testRequest(withURLParam(ctx, "uuid", apiKey.UUID.String()), nil, handler.APIKeysDelete)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, handler.database.(*mockDatabase).apiKeys)
+	})
+
+	t.Run("delete an api key of another user", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testAPIKeysContext(handler)
+		)
+
+		testRequest(ctx, &apiKeysCreateParams{Name: "bot", Scopes: []string{auth.ScopeStatsRead}}, handler.APIKeysCreate)
+		apiKey := handler.database.(*mockDatabase).apiKeys[0]
+		apiKey.OwnerID = -1
+
+		rec := testRequest(withURLParam(ctx, "uuid", apiKey.UUID.String()), nil, handler.APIKeysDelete)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Len(t, handler.database.(*mockDatabase).apiKeys, 1)
+	})
+
+	t.Run("delete a non-existent api key", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testAPIKeysContext(handler)
+		)
+
+		rec := testRequest(withURLParam(ctx, "uuid", "c8a8a0d4-7c0e-4b7e-8a62-5d5f0d1b7a9e"), nil, handler.APIKeysDelete)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -3,10 +3,13 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"github.com/groshi-project/groshi/internal/auth"
 	"github.com/groshi-project/groshi/internal/database"
+	"github.com/groshi-project/groshi/internal/middleware"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"testing"
+	"time"
 )
 
 func TestHandler_AuthLogin(t *testing.T) {
@@ -36,17 +39,51 @@ func TestHandler_AuthLogin(t *testing.T) {
 		}
 		rec := testRequest(ctx, params, handler.AuthLogin)
 		if assert.Equal(t, http.StatusOK, rec.Code) {
-			resp := &authLoginResponse{}
+			resp := &authTokensResponse{}
 			err := json.NewDecoder(rec.Body).Decode(resp)
 			if assert.NoError(t, err) {
 				if assert.NotEmpty(t, resp) {
 					assert.NotEmpty(t, resp.Token)
 					assert.NotEmpty(t, resp.Expires)
+					assert.NotEmpty(t, resp.RefreshToken)
+					assert.NotEmpty(t, resp.RefreshTokenExpires)
 				}
 			}
 		}
 	})
 
+	t.Run("log in as an existing user with outdated password hash", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = context.Background()
+			user    = &database.User{Username: testUsername, Password: "old-hash(test-password)"}
+		)
+		if err := handler.database.CreateUser(ctx, user); err != nil {
+			panic(err)
+		}
+
+		rec := testRequest(ctx, &authLoginParams{Username: testUsername, Password: testPassword}, handler.AuthLogin)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			assert.Equal(t, testPasswordHash, user.Password)
+			assert.Zero(t, user.TokenVersion)
+		}
+	})
+
+	t.Run("log in as an existing user with outdated password hash and wrong password", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = context.Background()
+			user    = &database.User{Username: testUsername, Password: "old-hash(test-password)"}
+		)
+		if err := handler.database.CreateUser(ctx, user); err != nil {
+			panic(err)
+		}
+
+		rec := testRequest(ctx, &authLoginParams{Username: testUsername, Password: "wrong-password"}, handler.AuthLogin)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "old-hash(test-password)", user.Password)
+	})
+
 	t.Run("log in as an existing user with wrong password", func(t *testing.T) {
 		var (
 			handler = newTestHandler()
@@ -85,3 +122,144 @@ func TestHandler_AuthLogin(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 	})
 }
+
+// testLogin logs in as a new test user and returns issued tokens.
+func testLogin(ctx context.Context, handler *Handler) *authTokensResponse {
+	if err := handler.database.CreateUser(ctx, &database.User{
+		Username: "test-username",
+		Password: "hash(test-password)",
+	}); err != nil {
+		panic(err)
+	}
+
+	params := &authLoginParams{Username: "test-username", Password: "test-password", DeviceName: "test-device"}
+	rec := testRequest(ctx, params, handler.AuthLogin)
+	resp := &authTokensResponse{}
+	if err := json.NewDecoder(rec.Body).Decode(resp); err != nil {
+		panic(err)
+	}
+	return resp
+}
+
+func TestHandler_AuthRefresh(t *testing.T) {
+	t.Run("refresh tokens", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = context.Background()
+			login   = testLogin(ctx, handler)
+		)
+
+		rec := testRequest(ctx, &authRefreshParams{RefreshToken: login.RefreshToken}, handler.AuthRefresh)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			resp := &authTokensResponse{}
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(resp)) {
+				assert.NotEmpty(t, resp.Token)
+				assert.NotEmpty(t, resp.RefreshToken)
+				assert.NotEqual(t, login.RefreshToken, resp.RefreshToken)
+
+				// both refresh tokens belong to the session created on login:
+				mockDb := handler.database.(*mockDatabase)
+				if assert.Len(t, mockDb.sessions, 1) && assert.Len(t, mockDb.refreshTokens, 2) {
+					assert.Equal(t, mockDb.sessions[0].ID, mockDb.refreshTokens[1].SessionID)
+				}
+			}
+		}
+	})
+
+	t.Run("reuse a rotated refresh token", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = context.Background()
+			login   = testLogin(ctx, handler)
+		)
+
+		rec := testRequest(ctx, &authRefreshParams{RefreshToken: login.RefreshToken}, handler.AuthRefresh)
+		if !assert.Equal(t, http.StatusOK, rec.Code) {
+			return
+		}
+		refreshed := &authTokensResponse{}
+		if err := json.NewDecoder(rec.Body).Decode(refreshed); err != nil {
+			panic(err)
+		}
+
+		// reuse the first refresh token:
+		rec = testRequest(ctx, &authRefreshParams{RefreshToken: login.RefreshToken}, handler.AuthRefresh)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		// the whole family must be revoked:
+		rec = testRequest(ctx, &authRefreshParams{RefreshToken: refreshed.RefreshToken}, handler.AuthRefresh)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		for _, refreshToken := range handler.database.(*mockDatabase).refreshTokens {
+			revoked, _ := handler.database.TokenRevoked(ctx, refreshToken.AccessTokenID)
+			assert.True(t, revoked)
+		}
+		assert.False(t, handler.database.(*mockDatabase).sessions[0].RevokedAt.IsZero())
+	})
+
+	t.Run("use an expired refresh token", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = context.Background()
+			login   = testLogin(ctx, handler)
+		)
+		handler.database.(*mockDatabase).refreshTokens[0].ExpiresAt = time.Now().Add(-time.Minute)
+
+		rec := testRequest(ctx, &authRefreshParams{RefreshToken: login.RefreshToken}, handler.AuthRefresh)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("use an unknown refresh token", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = context.Background()
+		)
+
+		rec := testRequest(ctx, &authRefreshParams{RefreshToken: "unknown-refresh-token"}, handler.AuthRefresh)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("call the handler with no params", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = context.Background()
+		)
+
+		rec := testRequest(ctx, nil, handler.AuthRefresh)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestHandler_AuthLogout(t *testing.T) {
+	var (
+		handler   = newTestHandler()
+		ctx       = context.Background()
+		login     = testLogin(ctx, handler)
+		mockDb    = handler.database.(*mockDatabase)
+		tokenID   = mockDb.refreshTokens[0].AccessTokenID
+		sessionID = mockDb.sessions[0].UUID.String()
+	)
+
+	rec := testRequest(context.WithValue(ctx, middleware.SessionIDContextKey, sessionID), nil, handler.AuthLogout)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	// the session, the access token and the refresh token must be revoked:
+	assert.False(t, mockDb.sessions[0].RevokedAt.IsZero())
+	revoked, err := handler.database.TokenRevoked(ctx, tokenID)
+	if assert.NoError(t, err) {
+		assert.True(t, revoked)
+	}
+	rec = testRequest(ctx, &authRefreshParams{RefreshToken: login.RefreshToken}, handler.AuthRefresh)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestHandler_AuthJWKS(t *testing.T) {
+	handler := newTestHandler()
+
+	rec := testGetRequest(context.Background(), "/.well-known/jwks.json", handler.AuthJWKS)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		resp := &auth.JWKSet{}
+		if assert.NoError(t, json.NewDecoder(rec.Body).Decode(resp)) && assert.Len(t, resp.Keys, 1) {
+			assert.Equal(t, "test-key-id", resp.Keys[0].KeyID)
+		}
+	}
+}
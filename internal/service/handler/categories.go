@@ -6,7 +6,6 @@ import (
 	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/groshi-project/groshi/internal/database"
-	"github.com/groshi-project/groshi/internal/middleware"
 	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
 	"github.com/groshi-project/groshi/internal/service/handler/response"
 	"net/http"
@@ -23,14 +22,16 @@ type categoriesCreateResponse struct {
 // CategoriesCreate creates a new category and returns its UUID.
 //
 //	@Summary		Create a new category
-//	@Description	Creates a new category and returns its UUID
+//	@Description	Creates a new category in the ledger and returns its UUID
 //	@Tags			categories
 //	@Accept			json
 //	@Produce		json
+//	@Param			ledger	query		string						false	"Ledger UUID, the personal ledger of user is used if it is not set"
 //	@Param			user	body		categoriesCreateParams		true	"Category name"
 //	@Success		200		{object}	categoriesCreateResponse	"Successful operation"
 //	@Failure		400		{object}	model.Error					"Invalid request body format or invalid request params"
-//	@Failure		404		{object}	model.Error					"User not found"
+//	@Failure		403		{object}	model.Error					"Access to the ledger is forbidden or user is its viewer"
+//	@Failure		404		{object}	model.Error					"User or ledger not found"
 //	@Failure		500		{object}	model.Error					"Internal server error"
 //	@Security		Bearer
 //	@Router			/categories [post]
@@ -48,29 +49,23 @@ func (h *Handler) CategoriesCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// extract current user's username from context:
-	username, ok := r.Context().Value(middleware.UsernameContextKey).(string)
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
 	if !ok {
-		h.internalServerErrorLogger.Println(errMissingUsernameContextValue)
-		httpresp.Render(w, response.InternalServerError)
+		return
 	}
 
-	// fetch the current user from the database:
-	user := &database.User{}
-	if err := h.database.SelectUserByUsername(r.Context(), username, user); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			httpresp.Render(w, response.UserNotFound)
-			return
-		}
-		h.internalServerErrorLogger.Println(err)
-		httpresp.Render(w, response.InternalServerError)
+	// fetch the ledger and check if the current user can edit it:
+	ledger, ok := h.requestLedger(w, r, user, database.LedgerRoleEditor)
+	if !ok {
 		return
 	}
 
-	// create a new category owned by the current user:
+	// create a new category in the ledger:
 	category := &database.Category{
-		Name:    params.Name,
-		OwnerID: user.ID,
+		Name:     params.Name,
+		LedgerID: ledger.ID,
+		OwnerID:  user.ID,
 	}
 	if err := h.database.CreateCategory(r.Context(), category); err != nil {
 		h.internalServerErrorLogger.Println(err)
@@ -92,43 +87,37 @@ type categoriesGetResponseItem struct {
 
 type categoriesGetResponse []categoriesGetResponseItem
 
-// CategoriesGet returns all categories created by user.
+// CategoriesGet returns all categories of the ledger.
 //
 //	@Summary		Fetch all categories
-//	@Description	Returns all categories created by user.
+//	@Description	Returns all categories of the ledger.
 //	@Tags			categories
 //	@Accept			json
 //	@Produce		json
-//	@Success		200	{object}	categoriesGetResponse	"Successful operation"
-//	@Failure		404	{object}	model.Error				"User not found"
-//	@Failure		500	{object}	model.Error				"Internal server error"
+//	@Param			ledger	query		string					false	"Ledger UUID, the personal ledger of user is used if it is not set"
+//	@Success		200		{object}	categoriesGetResponse	"Successful operation"
+//	@Failure		403		{object}	model.Error				"Access to the ledger is forbidden"
+//	@Failure		404		{object}	model.Error				"User or ledger not found"
+//	@Failure		500		{object}	model.Error				"Internal server error"
 //	@Security		Bearer
 //	@Router			/categories [get]
 func (h *Handler) CategoriesGet(w http.ResponseWriter, r *http.Request) {
-	// extract current user's username from context
-	username, ok := r.Context().Value(middleware.UsernameContextKey).(string)
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
 	if !ok {
-		h.internalServerErrorLogger.Println(errMissingUsernameContextValue)
-		httpresp.Render(w, response.InternalServerError)
 		return
 	}
 
-	// fetch current user from the database:
-	user := &database.User{}
-	if err := h.database.SelectUserByUsername(r.Context(), username, user); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			httpresp.Render(w, response.UserNotFound)
-			return
-		}
-		h.internalServerErrorLogger.Println(err)
-		httpresp.Render(w, response.InternalServerError)
+	// fetch the ledger and check if the current user can read it:
+	ledger, ok := h.requestLedger(w, r, user, database.LedgerRoleViewer)
+	if !ok {
 		return
 	}
 
-	// fetch categories that belong to this user from the database:
+	// fetch categories that belong to the ledger from the database:
 	// todo: should slice of pointers be used instead of a slice of objects?
 	categories := make([]database.Category, 0)
-	if err := h.database.SelectCategoriesByOwnerID(r.Context(), user.ID, &categories); err != nil {
+	if err := h.database.SelectCategoriesByLedgerID(r.Context(), ledger.ID, &categories); err != nil {
 		if !errors.Is(err, sql.ErrNoRows) {
 			h.internalServerErrorLogger.Println(err)
 			httpresp.Render(w, response.InternalServerError)
@@ -147,6 +136,63 @@ func (h *Handler) CategoriesGet(w http.ResponseWriter, r *http.Request) {
 	httpresp.Render(w, httpresp.NewOK(&resp))
 }
 
+// categoryByUUID fetches category with the given UUID.
+// Renders an error response and returns false if the category could not be fetched.
+func (h *Handler) categoryByUUID(w http.ResponseWriter, r *http.Request, uuid string) (*database.Category, bool) {
+	category := &database.Category{}
+	if err := h.database.SelectCategoryByUUID(r.Context(), uuid, category); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpresp.Render(w, response.CategoryNotFound)
+			return nil, false
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return nil, false
+	}
+	return category, true
+}
+
+// ledgerCategory fetches category with the given UUID and checks that it belongs to the ledger.
+// Renders an error response and returns false if the category could not be fetched or belongs to another ledger.
+func (h *Handler) ledgerCategory(w http.ResponseWriter, r *http.Request, uuid string, ledger *database.Ledger) (*database.Category, bool) {
+	category, ok := h.categoryByUUID(w, r, uuid)
+	if !ok {
+		return nil, false
+	}
+
+	if category.LedgerID != ledger.ID {
+		httpresp.Render(w, response.CategoryForbidden)
+		return nil, false
+	}
+
+	return category, true
+}
+
+// accessibleCategory fetches category with the given UUID and checks that the user has the required role in its ledger.
+// Renders an error response and returns false if the category could not be fetched or is not accessible.
+func (h *Handler) accessibleCategory(w http.ResponseWriter, r *http.Request, uuid string, user *database.User, required string) (*database.Category, bool) {
+	category, ok := h.categoryByUUID(w, r, uuid)
+	if !ok {
+		return nil, false
+	}
+
+	if _, ok := h.ledgerMember(w, r, category.LedgerID, user, required, response.CategoryForbidden); !ok {
+		return nil, false
+	}
+
+	return category, true
+}
+
+// containsCategory returns true if the categories contain the category with the given ID.
+func containsCategory(categories []database.Category, categoryID int64) bool {
+	for _, category := range categories {
+		if category.ID == categoryID {
+			return true
+		}
+	}
+	return false
+}
+
 type categoriesUpdateParams struct {
 	Name string `json:"name" example:"Food" validate:"required"`
 }
@@ -172,36 +218,15 @@ func (h *Handler) CategoriesUpdate(w http.ResponseWriter, r *http.Request) {
 	// parse URL params:
 	uuid := chi.URLParam(r, "uuid")
 
-	// fetch category:
-	category := &database.Category{}
-	if err := h.database.SelectCategoryByUUID(r.Context(), uuid, category); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			httpresp.Render(w, response.CategoryNotFound)
-			return
-		}
-		h.internalServerErrorLogger.Println(err)
-		httpresp.Render(w, response.InternalServerError)
-		return
-	}
-
-	// extract current user's username from context:
-	username := r.Context().Value("username").(string)
-
-	// fetch current user:
-	user := &database.User{}
-	if err := h.database.SelectUserByUsername(r.Context(), username, user); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			httpresp.Render(w, response.UserNotFound)
-			return
-		}
-		h.internalServerErrorLogger.Println(err)
-		httpresp.Render(w, response.InternalServerError)
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
 		return
 	}
 
-	// check if the category owned by the current user:
-	if category.OwnerID != user.ID {
-		httpresp.Render(w, response.CategoryForbidden)
+	// fetch the category and check if the current user can edit its ledger:
+	category, ok := h.accessibleCategory(w, r, uuid, user, database.LedgerRoleEditor)
+	if !ok {
 		return
 	}
 
@@ -228,36 +253,15 @@ func (h *Handler) CategoriesDelete(w http.ResponseWriter, r *http.Request) {
 	// parse URL params:
 	uuid := chi.URLParam(r, "uuid")
 
-	// fetch the given category from the database:
-	category := &database.Category{}
-	if err := h.database.SelectCategoryByUUID(r.Context(), uuid, category); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			httpresp.Render(w, response.CategoryNotFound)
-			return
-		}
-		h.internalServerErrorLogger.Println(err)
-		httpresp.Render(w, response.InternalServerError)
-		return
-	}
-
-	// extract current user's username from context
-	username := r.Context().Value("username").(string)
-
-	// fetch current user from the database:
-	user := &database.User{}
-	if err := h.database.SelectUserByUsername(r.Context(), username, user); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			httpresp.Render(w, response.UserNotFound)
-			return
-		}
-		h.internalServerErrorLogger.Println(err)
-		httpresp.Render(w, response.InternalServerError)
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
 		return
 	}
 
-	// check if the category belongs to the user:
-	if category.OwnerID != user.ID {
-		httpresp.Render(w, response.CategoryForbidden)
+	// fetch the category and check if the current user can edit its ledger:
+	category, ok := h.accessibleCategory(w, r, uuid, user, database.LedgerRoleEditor)
+	if !ok {
 		return
 	}
 
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"github.com/google/uuid"
 	"github.com/groshi-project/groshi/internal/database"
-	"github.com/groshi-project/groshi/internal/middleware"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"testing"
@@ -32,7 +31,7 @@ func TestHandler_CategoriesCreate(t *testing.T) {
 			panic(err)
 		}
 
-		ctx = context.WithValue(context.Background(), middleware.UsernameContextKey, testUsername)
+		ctx = testUserContext(context.Background(), handler, testUsername)
 		params := &categoriesCreateParams{
 			Name: testCategoryName,
 		}
@@ -59,30 +58,17 @@ func TestHandler_CategoriesCreate(t *testing.T) {
 		}
 	})
 
-	t.Run("create a new category owned by a non-existed user", func(t *testing.T) {
-		var (
-			handler = newTestHandler()
-			ctx     = context.WithValue(context.Background(), middleware.UsernameContextKey, "i-dont-exist")
-		)
-
-		params := &categoriesCreateParams{
-			Name: testCategoryName,
-		}
-		rec := testRequest(ctx, params, handler.CategoriesCreate)
-		assert.Equal(t, http.StatusNotFound, rec.Code)
-	})
-
 	t.Run("call the handler with no params", func(t *testing.T) {
 		var (
 			handler = newTestHandler()
-			ctx     = context.WithValue(context.Background(), middleware.UsernameContextKey, testUsername)
+			ctx     = testUserContext(context.Background(), handler, testUsername)
 		)
 
 		rec := testRequest(ctx, nil, handler.CategoriesCreate)
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 	})
 
-	t.Run("call the handler without username context value", func(t *testing.T) {
+	t.Run("call the handler without user context value", func(t *testing.T) {
 		var (
 			handler = newTestHandler()
 			ctx     = context.Background()
@@ -105,11 +91,12 @@ func TestHandler_CategoriesGet(t *testing.T) {
 	t.Run("get categories owned by existent user, when there are few categories", func(t *testing.T) {
 		var (
 			handler    = newTestHandler()
-			ctx        = context.WithValue(context.Background(), middleware.UsernameContextKey, testUsername)
+			ctx        = testUserContext(context.Background(), handler, testUsername)
 			categories = []*database.Category{
 				{UUID: uuid.New(), Name: "Food", OwnerID: testUserID},
 				{UUID: uuid.New(), Name: "Transport", OwnerID: testUserID},
 				{UUID: uuid.New(), Name: "Sports", OwnerID: testUserID},
+				{UUID: uuid.New(), Name: "Shared", OwnerID: testUserID},
 			}
 		)
 
@@ -121,8 +108,13 @@ func TestHandler_CategoriesGet(t *testing.T) {
 			panic(err)
 		}
 
-		// create categories owned by the test user:
-		for _, category := range categories {
+		// create categories in the personal ledger of the test user, the last one is in another ledger:
+		ledgerID := testPersonalLedgerID(handler.database.(*mockDatabase), testUserID)
+		for i, category := range categories {
+			category.LedgerID = ledgerID
+			if i == len(categories)-1 {
+				category.LedgerID = ledgerID + 1
+			}
 			if err := handler.database.CreateCategory(ctx, category); err != nil {
 				panic(err)
 			}
@@ -133,7 +125,7 @@ func TestHandler_CategoriesGet(t *testing.T) {
 			body := &categoriesGetResponse{}
 			err := json.NewDecoder(rec.Body).Decode(body)
 			if assert.NoError(t, err) {
-				assert.Len(t, *body, len(categories))
+				assert.Len(t, *body, len(categories)-1)
 			}
 		}
 
@@ -142,7 +134,7 @@ func TestHandler_CategoriesGet(t *testing.T) {
 	t.Run("get categories owned by existent user, when there are no categories", func(t *testing.T) {
 		var (
 			handler = newTestHandler()
-			ctx     = context.WithValue(context.Background(), middleware.UsernameContextKey, testUsername)
+			ctx     = testUserContext(context.Background(), handler, testUsername)
 		)
 
 		// create a test user:
@@ -162,13 +154,4 @@ func TestHandler_CategoriesGet(t *testing.T) {
 			}
 		}
 	})
-
-	t.Run("get categories owned by non-existent user", func(t *testing.T) {
-		var (
-			handler = newTestHandler()
-			ctx     = context.WithValue(context.Background(), middleware.UsernameContextKey, "i-dont-exist")
-		)
-		rec := testRequest(ctx, nil, handler.CategoriesGet)
-		assert.Equal(t, http.StatusNotFound, rec.Code)
-	})
 }
@@ -0,0 +1,356 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
+	"github.com/groshi-project/groshi/internal/service/handler/response"
+	"github.com/groshi-project/groshi/internal/settleup"
+	"net/http"
+	"sort"
+	"time"
+)
+
+type transactionsCreateSharingMemberParams struct {
+	Username string `json:"username" example:"jieggii" validate:"required"`
+
+	// Number of shares of the member, used only by the `shares` method.
+	Shares int32 `json:"shares" example:"2" validate:"min=0"`
+
+	// Exact amount owed by the member, used only by the `exact` method.
+	Amount int32 `json:"amount" example:"1250" validate:"min=0"`
+}
+
+type transactionsCreateSharingParams struct {
+	// Username of the ledger member who paid for the expense, the current user if not set.
+	Payer string `json:"payer" example:"jieggii"`
+
+	Method  string                                  `json:"method" example:"equal" validate:"required,oneof=equal shares exact"`
+	Members []transactionsCreateSharingMemberParams `json:"members" validate:"required,min=1,unique=Username,dive"`
+}
+
+// expenseShares splits the expense with the amount between ledger members according to the sharing params
+// and returns ID of the payer and shares of the members. Zero payer ID and no shares are returned if sharing is nil.
+// Renders an error response and returns false if the expense can not be shared this way.
+func (h *Handler) expenseShares(w http.ResponseWriter, r *http.Request, amount int32, sharing *transactionsCreateSharingParams, ledger *database.Ledger, user *database.User) (int64, []database.ExpenseShare, bool) {
+	if sharing == nil {
+		return 0, nil, true
+	}
+	if amount >= 0 {
+		httpresp.Render(w, response.ExpenseNotShareable)
+		return 0, nil, false
+	}
+
+	// fetch the payer and check if it is a member of the ledger:
+	payerID := user.ID
+	if sharing.Payer != "" {
+		payer, ok := h.ledgerMemberByUsername(w, r, ledger, sharing.Payer)
+		if !ok {
+			return 0, nil, false
+		}
+		payerID = payer.UserID
+	}
+
+	// fetch members sharing the expense and check if they are members of the ledger:
+	memberIDs := make([]int64, 0, len(sharing.Members))
+	weights := make([]int64, 0, len(sharing.Members))
+	for _, params := range sharing.Members {
+		member, ok := h.ledgerMemberByUsername(w, r, ledger, params.Username)
+		if !ok {
+			return 0, nil, false
+		}
+		memberIDs = append(memberIDs, member.UserID)
+
+		switch sharing.Method {
+		case settleup.MethodShares:
+			weights = append(weights, int64(params.Shares))
+		case settleup.MethodExact:
+			weights = append(weights, int64(params.Amount))
+		default:
+			weights = append(weights, 0)
+		}
+	}
+
+	// split the expense:
+	amounts, err := settleup.Split(-int64(amount), sharing.Method, weights)
+	if err != nil {
+		httpresp.Render(w, response.InvalidExpenseShares)
+		return 0, nil, false
+	}
+	shares := make([]database.ExpenseShare, 0, len(amounts))
+	for i, shareAmount := range amounts {
+		if shareAmount == 0 {
+			continue
+		}
+		shares = append(shares, database.ExpenseShare{
+			UserID: memberIDs[i],
+			Amount: int32(shareAmount),
+		})
+	}
+
+	return payerID, shares, true
+}
+
+type ledgerDebtItem struct {
+	// UUID and username of the member who owes money, empty if the user has been deleted.
+	FromUUID string `json:"from_uuid" example:"2c1a8e3b-6f4d-4b7a-9c2e-1d3f5a7b9c0e"`
+	From     string `json:"from" example:"jieggii"`
+
+	// UUID and username of the member who is owed money, empty if the user has been deleted.
+	ToUUID string `json:"to_uuid" example:"7d9e1f3a-5b2c-4e6d-8f0a-3c5e7a9b1d2f"`
+	To     string `json:"to" example:"alice"`
+
+	Currency string `json:"currency" example:"EUR"`
+	Amount   int64  `json:"amount" example:"1250"`
+}
+
+// ledgerDebts fetches debts between members of the ledger from the `uuid` URL param, which the current user
+// must be a member of, and transforms them by fn separately for each currency.
+// Renders an error response and returns false if the debts could not be fetched.
+func (h *Handler) ledgerDebts(w http.ResponseWriter, r *http.Request, fn func(debts []settleup.Debt) []settleup.Debt) ([]ledgerDebtItem, bool) {
+	// fetch the current user and the ledger:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return nil, false
+	}
+	ledger, _, ok := h.ledgerByUUID(w, r, chi.URLParam(r, "uuid"), user, database.LedgerRoleViewer)
+	if !ok {
+		return nil, false
+	}
+
+	// fetch debts of the ledger members:
+	debts := make([]database.LedgerDebt, 0)
+	if err := h.database.SelectLedgerDebts(r.Context(), ledger.ID, &debts); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return nil, false
+	}
+
+	// group debts by currencies:
+	uuids := make(map[int64]string)
+	usernames := make(map[int64]string)
+	debtsByCurrency := make(map[string][]settleup.Debt)
+	for _, debt := range debts {
+		uuids[debt.DebtorID] = debt.DebtorUUID
+		uuids[debt.CreditorID] = debt.CreditorUUID
+		usernames[debt.DebtorID] = debt.DebtorUsername
+		usernames[debt.CreditorID] = debt.CreditorUsername
+		debtsByCurrency[debt.CurrencyCode] = append(debtsByCurrency[debt.CurrencyCode], settleup.Debt{
+			DebtorID:   debt.DebtorID,
+			CreditorID: debt.CreditorID,
+			Amount:     debt.Amount,
+		})
+	}
+	currencies := make([]string, 0, len(debtsByCurrency))
+	for currency := range debtsByCurrency {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	items := make([]ledgerDebtItem, 0)
+	for _, currency := range currencies {
+		for _, debt := range fn(debtsByCurrency[currency]) {
+			items = append(items, ledgerDebtItem{
+				FromUUID: uuids[debt.DebtorID],
+				From:     usernames[debt.DebtorID],
+				ToUUID:   uuids[debt.CreditorID],
+				To:       usernames[debt.CreditorID],
+				Currency: currency,
+				Amount:   debt.Amount,
+			})
+		}
+	}
+	return items, true
+}
+
+// ledgerMemberSettled checks if the member neither owes nor is owed money by shared expenses of the ledger
+// in any currency. Renders an error response and returns false if the member has a non-zero balance
+// or the balance could not be fetched.
+func (h *Handler) ledgerMemberSettled(w http.ResponseWriter, r *http.Request, ledger *database.Ledger, member *database.LedgerMember) bool {
+	debts := make([]database.LedgerDebt, 0)
+	if err := h.database.SelectLedgerDebts(r.Context(), ledger.ID, &debts); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return false
+	}
+
+	balances := make(map[string]int64)
+	for _, debt := range debts {
+		switch member.UserID {
+		case debt.DebtorID:
+			balances[debt.CurrencyCode] -= debt.Amount
+		case debt.CreditorID:
+			balances[debt.CurrencyCode] += debt.Amount
+		}
+	}
+	for _, balance := range balances {
+		if balance != 0 {
+			httpresp.Render(w, response.LedgerMemberHasBalance)
+			return false
+		}
+	}
+	return true
+}
+
+type ledgerBalancesGetResponse []ledgerDebtItem
+
+// LedgerBalancesGet returns balances between each pair of ledger members.
+//
+//	@Summary		Fetch balances between ledger members
+//	@Description	Returns how much each member of the ledger owes to other members by shared expenses and settlements.
+//	@Description	Mutual debts are netted out, so for each pair of members and currency at most one of them owes the other
+//	@Tags			ledgers
+//	@Produce		json
+//	@Param			uuid	path		string						true	"Ledger UUID"
+//	@Success		200		{object}	ledgerBalancesGetResponse	"Successful operation"
+//	@Failure		403		{object}	model.Error					"Access to the ledger is forbidden"
+//	@Failure		404		{object}	model.Error					"Ledger not found"
+//	@Failure		500		{object}	model.Error					"Internal server error"
+//	@Security		Bearer
+//	@Router			/ledgers/{uuid}/balances [get]
+func (h *Handler) LedgerBalancesGet(w http.ResponseWriter, r *http.Request) {
+	items, ok := h.ledgerDebts(w, r, settleup.Net)
+	if !ok {
+		return
+	}
+
+	// respond:
+	resp := ledgerBalancesGetResponse(items)
+	httpresp.Render(w, httpresp.NewOK(&resp))
+}
+
+type ledgerSettleUpGetResponse []ledgerDebtItem
+
+// LedgerSettleUpGet returns a settle-up plan of the ledger.
+//
+//	@Summary		Fetch a settle-up plan
+//	@Description	Returns payments between ledger members after which nobody owes anyone anything, separately for each currency.
+//	@Description	The plan has as few payments as possible: at most one fewer than there are members with non-zero balances
+//	@Tags			ledgers
+//	@Produce		json
+//	@Param			uuid	path		string						true	"Ledger UUID"
+//	@Success		200		{object}	ledgerSettleUpGetResponse	"Successful operation"
+//	@Failure		403		{object}	model.Error					"Access to the ledger is forbidden"
+//	@Failure		404		{object}	model.Error					"Ledger not found"
+//	@Failure		500		{object}	model.Error					"Internal server error"
+//	@Security		Bearer
+//	@Router			/ledgers/{uuid}/settle-up [get]
+func (h *Handler) LedgerSettleUpGet(w http.ResponseWriter, r *http.Request) {
+	items, ok := h.ledgerDebts(w, r, settleup.Plan)
+	if !ok {
+		return
+	}
+
+	// respond:
+	resp := ledgerSettleUpGetResponse(items)
+	httpresp.Render(w, httpresp.NewOK(&resp))
+}
+
+type ledgerSettlementsCreateParams struct {
+	// UUID of the ledger member the current user pays to, as returned in balances and settle-up plans.
+	ToUUID string `json:"to_uuid" example:"7d9e1f3a-5b2c-4e6d-8f0a-3c5e7a9b1d2f" validate:"required,uuid"`
+
+	Amount       int32  `json:"amount" example:"1250" validate:"required,min=1"`
+	CurrencyCode string `json:"currency" example:"EUR" validate:"required"`
+
+	Timestamp time.Time `json:"timestamp" example:"2026-03-20T12:57:38Z" validate:"required"`
+
+	Description string `json:"description" example:"Paid back for the trip"`
+}
+
+// LedgerSettlementsCreate records a payment of the current user to another ledger member and returns UUID of its transaction.
+//
+//	@Summary		Record a settlement
+//	@Description	Records a payment of the current user to another ledger member, which reduces debt of the current user to that member.
+//	@Description	The payment is recorded as a settlement transaction of the ledger, which is not counted in statistics
+//	@Tags			ledgers
+//	@Accept			json
+//	@Produce		json
+//	@Param			uuid		path		string							true	"Ledger UUID"
+//	@Param			settlement	body		ledgerSettlementsCreateParams	true	"Settlement"
+//	@Success		200			{object}	transactionsCreateResponse		"Successful operation"
+//	@Failure		400			{object}	model.Error						"Invalid request body format or invalid request params"
+//	@Failure		403			{object}	model.Error						"Access to the ledger is forbidden or user is a viewer of the ledger"
+//	@Failure		404			{object}	model.Error						"Ledger, member or currency not found"
+//	@Failure		500			{object}	model.Error						"Internal server error"
+//	@Security		Bearer
+//	@Router			/ledgers/{uuid}/settlements [post]
+func (h *Handler) LedgerSettlementsCreate(w http.ResponseWriter, r *http.Request) {
+	// decode request params:
+	params := &ledgerSettlementsCreateParams{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return
+	}
+
+	// validate request params:
+	if err := h.paramsValidate.Struct(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return
+	}
+
+	// fetch the current user and the ledger:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	ledger, _, ok := h.ledgerByUUID(w, r, chi.URLParam(r, "uuid"), user, database.LedgerRoleEditor)
+	if !ok {
+		return
+	}
+
+	// fetch the member the payment is made to:
+	creditor, ok := h.ledgerMemberByUserUUID(w, r, ledger, params.ToUUID)
+	if !ok {
+		return
+	}
+	if creditor.UserID == user.ID {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return
+	}
+
+	// fetch provided currency:
+	currency := &database.Currency{}
+	if err := h.database.SelectCurrencyByCode(r.Context(), params.CurrencyCode, currency); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpresp.Render(w, response.CurrencyNotFound)
+			return
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// record the payment as a transaction paid by the current user which is owed entirely by the creditor:
+	transaction := &database.Transaction{
+		Amount:     -params.Amount,
+		CurrencyID: currency.ID,
+
+		Description: params.Description,
+
+		LedgerID: ledger.ID,
+		OwnerID:  user.ID,
+
+		PayerID: user.ID,
+		Shares: []database.ExpenseShare{
+			{UserID: creditor.UserID, Amount: params.Amount},
+		},
+		Settlement: true,
+
+		Timestamp: params.Timestamp.UTC(),
+	}
+	if err := h.database.CreateTransaction(r.Context(), transaction); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	resp := &transactionsCreateResponse{
+		UUID: transaction.UUID.String(),
+	}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler_Expenses(t *testing.T) {
+	const (
+		aliceID    int64 = 1
+		bobID      int64 = 2
+		carolID    int64 = 3
+		strangerID int64 = 4
+	)
+
+	userUUIDs := map[string]uuid.UUID{"alice": uuid.New(), "bob": uuid.New(), "carol": uuid.New(), "stranger": uuid.New()}
+
+	// newEnv creates a test handler with a ledger shared by alice, bob and carol, and a stranger who is not its member.
+	newEnv := func() (*Handler, *mockDatabase, *database.Ledger) {
+		handler := newTestHandler()
+		db := handler.database.(*mockDatabase)
+		for id, username := range map[int64]string{aliceID: "alice", bobID: "bob", carolID: "carol", strangerID: "stranger"} {
+			if err := db.CreateUser(context.Background(), &database.User{ID: id, UUID: userUUIDs[username], Username: username}); err != nil {
+				panic(err)
+			}
+		}
+		db.currencies = append(db.currencies, &database.Currency{ID: 1, Code: "EUR", Rate: 1})
+		ledger := testSharedLedger(db, aliceID, map[int64]string{bobID: database.LedgerRoleEditor, carolID: database.LedgerRoleViewer})
+		return handler, db, ledger
+	}
+
+	// createExpense makes a request to create a transaction in the ledger on behalf of the user.
+	createExpense := func(handler *Handler, ledger *database.Ledger, username string, params *transactionsCreateParams) *httptest.ResponseRecorder {
+		body, err := json.Marshal(params)
+		if err != nil {
+			panic(err)
+		}
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/transactions?ledger="+ledger.UUID.String(), bytes.NewReader(body))
+		handler.TransactionsCreate(rec, req.WithContext(testUserContext(context.Background(), handler, username)))
+		return rec
+	}
+
+	// debt returns a debt item of the test users in EUR.
+	debt := func(from string, to string, amount int64) ledgerDebtItem {
+		return ledgerDebtItem{
+			FromUUID: userUUIDs[from].String(),
+			From:     from,
+			ToUUID:   userUUIDs[to].String(),
+			To:       to,
+			Currency: "EUR",
+			Amount:   amount,
+		}
+	}
+
+	// ledgerDebts makes a request to the handler returning debts of the ledger on behalf of the user.
+	ledgerDebts := func(t *testing.T, handler *Handler, ledger *database.Ledger, username string, handlerFunc http.HandlerFunc) []ledgerDebtItem {
+		ctx := withURLParam(testUserContext(context.Background(), handler, username), "uuid", ledger.UUID.String())
+		rec := testGetRequest(ctx, "/", handlerFunc)
+		items := make([]ledgerDebtItem, 0)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&items))
+		}
+		return items
+	}
+
+	t.Run("share an expense equally and settle up", func(t *testing.T) {
+		handler, db, ledger := newEnv()
+
+		// alice pays 10.00 for everyone:
+		rec := createExpense(handler, ledger, "alice", &transactionsCreateParams{
+			Amount:       -1000,
+			CurrencyCode: "EUR",
+			Timestamp:    time.Now(),
+			Sharing: &transactionsCreateSharingParams{
+				Method:  "equal",
+				Members: []transactionsCreateSharingMemberParams{{Username: "alice"}, {Username: "bob"}, {Username: "carol"}},
+			},
+		})
+		if !assert.Equal(t, http.StatusOK, rec.Code) || !assert.Len(t, db.transactions, 1) {
+			return
+		}
+		transaction := db.transactions[0]
+		assert.Equal(t, aliceID, transaction.PayerID)
+		assert.Equal(t, []database.ExpenseShare{
+			{UserID: aliceID, Amount: 334},
+			{UserID: bobID, Amount: 333},
+			{UserID: carolID, Amount: 333},
+		}, transaction.Shares)
+
+		// bob pays 6.00 for himself and carol, carol's share is twice as large:
+		rec = createExpense(handler, ledger, "bob", &transactionsCreateParams{
+			Amount:       -600,
+			CurrencyCode: "EUR",
+			Timestamp:    time.Now(),
+			Sharing: &transactionsCreateSharingParams{
+				Method:  "shares",
+				Members: []transactionsCreateSharingMemberParams{{Username: "bob", Shares: 1}, {Username: "carol", Shares: 2}},
+			},
+		})
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		assert.Equal(t, []ledgerDebtItem{
+			debt("bob", "alice", 333),
+			debt("carol", "alice", 333),
+			debt("carol", "bob", 400),
+		}, ledgerDebts(t, handler, ledger, "carol", handler.LedgerBalancesGet))
+
+		// bob is owed more than he owes, so only carol has to pay:
+		plan := ledgerDebts(t, handler, ledger, "carol", handler.LedgerSettleUpGet)
+		assert.Equal(t, []ledgerDebtItem{
+			debt("carol", "alice", 666),
+			debt("carol", "bob", 67),
+		}, plan)
+
+		// bob pays back alice:
+		ctx := withURLParam(testUserContext(context.Background(), handler, "bob"), "uuid", ledger.UUID.String())
+		rec = testRequest(ctx, &ledgerSettlementsCreateParams{
+			ToUUID:       userUUIDs["alice"].String(),
+			Amount:       333,
+			CurrencyCode: "EUR",
+			Timestamp:    time.Now(),
+		}, handler.LedgerSettlementsCreate)
+		if assert.Equal(t, http.StatusOK, rec.Code) && assert.Len(t, db.transactions, 3) {
+			assert.True(t, db.transactions[2].Settlement)
+			assert.Equal(t, int32(-333), db.transactions[2].Amount)
+		}
+
+		assert.Equal(t, []ledgerDebtItem{
+			debt("carol", "alice", 333),
+			debt("carol", "bob", 400),
+		}, ledgerDebts(t, handler, ledger, "alice", handler.LedgerBalancesGet))
+	})
+
+	t.Run("share an expense by exact amounts paid by another member", func(t *testing.T) {
+		handler, db, ledger := newEnv()
+
+		rec := createExpense(handler, ledger, "alice", &transactionsCreateParams{
+			Amount:       -1000,
+			CurrencyCode: "EUR",
+			Timestamp:    time.Now(),
+			Sharing: &transactionsCreateSharingParams{
+				Payer:   "carol",
+				Method:  "exact",
+				Members: []transactionsCreateSharingMemberParams{{Username: "alice", Amount: 700}, {Username: "bob", Amount: 300}},
+			},
+		})
+		if assert.Equal(t, http.StatusOK, rec.Code) && assert.Len(t, db.transactions, 1) {
+			assert.Equal(t, carolID, db.transactions[0].PayerID)
+		}
+	})
+
+	t.Run("share an expense incorrectly", func(t *testing.T) {
+		testCases := []struct {
+			name   string
+			amount int32
+			params *transactionsCreateSharingParams
+			code   int
+		}{
+			{
+				"income",
+				1000,
+				&transactionsCreateSharingParams{Method: "equal", Members: []transactionsCreateSharingMemberParams{{Username: "bob"}}},
+				http.StatusBadRequest,
+			},
+			{
+				"exact amounts mismatch",
+				-1000,
+				&transactionsCreateSharingParams{Method: "exact", Members: []transactionsCreateSharingMemberParams{{Username: "bob", Amount: 900}}},
+				http.StatusBadRequest,
+			},
+			{
+				"duplicate members",
+				-1000,
+				&transactionsCreateSharingParams{Method: "equal", Members: []transactionsCreateSharingMemberParams{{Username: "bob"}, {Username: "bob"}}},
+				http.StatusBadRequest,
+			},
+			{
+				"unknown method",
+				-1000,
+				&transactionsCreateSharingParams{Method: "percent", Members: []transactionsCreateSharingMemberParams{{Username: "bob"}}},
+				http.StatusBadRequest,
+			},
+			{
+				"member of another ledger",
+				-1000,
+				&transactionsCreateSharingParams{Method: "equal", Members: []transactionsCreateSharingMemberParams{{Username: "stranger"}}},
+				http.StatusNotFound,
+			},
+		}
+
+		for _, testCase := range testCases {
+			handler, db, ledger := newEnv()
+			rec := createExpense(handler, ledger, "alice", &transactionsCreateParams{
+				Amount:       testCase.amount,
+				CurrencyCode: "EUR",
+				Timestamp:    time.Now(),
+				Sharing:      testCase.params,
+			})
+			assert.Equal(t, testCase.code, rec.Code, testCase.name)
+			assert.Empty(t, db.transactions, testCase.name)
+		}
+	})
+
+	t.Run("record a settlement without being an editor", func(t *testing.T) {
+		handler, db, ledger := newEnv()
+
+		ctx := withURLParam(testUserContext(context.Background(), handler, "carol"), "uuid", ledger.UUID.String())
+		rec := testRequest(ctx, &ledgerSettlementsCreateParams{
+			ToUUID:       userUUIDs["alice"].String(),
+			Amount:       100,
+			CurrencyCode: "EUR",
+			Timestamp:    time.Now(),
+		}, handler.LedgerSettlementsCreate)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, db.transactions)
+	})
+
+	t.Run("remove a member who has not settled up", func(t *testing.T) {
+		handler, _, ledger := newEnv()
+
+		// alice pays 10.00 for herself and bob:
+		rec := createExpense(handler, ledger, "alice", &transactionsCreateParams{
+			Amount:       -1000,
+			CurrencyCode: "EUR",
+			Timestamp:    time.Now(),
+			Sharing: &transactionsCreateSharingParams{
+				Method:  "equal",
+				Members: []transactionsCreateSharingMemberParams{{Username: "alice"}, {Username: "bob"}},
+			},
+		})
+		if !assert.Equal(t, http.StatusOK, rec.Code) {
+			return
+		}
+
+		// bob can not be removed while he owes alice:
+		ownerCtx := withURLParam(testUserContext(context.Background(), handler, "alice"), "uuid", ledger.UUID.String())
+		rec = testRequest(withURLParam(ownerCtx, "username", "bob"), nil, handler.LedgerMembersDelete)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+
+		// bob pays back alice and leaves the ledger:
+		ctx := withURLParam(testUserContext(context.Background(), handler, "bob"), "uuid", ledger.UUID.String())
+		rec = testRequest(ctx, &ledgerSettlementsCreateParams{
+			ToUUID:       userUUIDs["alice"].String(),
+			Amount:       500,
+			CurrencyCode: "EUR",
+			Timestamp:    time.Now(),
+		}, handler.LedgerSettlementsCreate)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		rec = testRequest(withURLParam(ctx, "username", "bob"), nil, handler.LedgerMembersDelete)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	})
+
+	t.Run("fetch balances of a ledger without being its member", func(t *testing.T) {
+		handler, _, ledger := newEnv()
+
+		ctx := withURLParam(testUserContext(context.Background(), handler, "stranger"), "uuid", ledger.UUID.String())
+		rec := testGetRequest(ctx, "/", handler.LedgerBalancesGet)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/groshi-project/groshi/internal/export"
+	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
+	"github.com/groshi-project/groshi/internal/service/handler/response"
+	"net/http"
+	"time"
+)
+
+// newExportRow creates a new instance of [export.Row] from a transaction export row,
+// amount of the transaction is converted to the given currency.
+func newExportRow(row *database.TransactionExportRow, currency *database.Currency) *export.Row {
+	var splits []export.Split
+	for i, amount := range row.SplitAmounts {
+		splits = append(splits, export.Split{
+			Category: row.CategoryNames[i],
+			Amount:   amount,
+			Note:     row.SplitNotes[i],
+		})
+	}
+
+	return &export.Row{
+		UUID:              row.UUID.String(),
+		Timestamp:         row.Timestamp,
+		ValueDate:         row.ValueDate,
+		Amount:            row.Amount,
+		Currency:          row.CurrencyCode,
+		ConvertedAmount:   convertBaseAmount(float64(row.Amount)/row.CurrencyRate, currency),
+		ConvertedCurrency: currency.Code,
+		Payee:             row.PayeeName,
+		Categories:        row.CategoryNames,
+		Splits:            splits,
+		Tags:              row.TagNames,
+		Description:       row.Description,
+	}
+}
+
+// exportDeclarations fetches categories of the ledger and currencies of the transactions matching the filter.
+// Renders an error response and returns false if they could not be fetched.
+func (h *Handler) exportDeclarations(w http.ResponseWriter, r *http.Request, filter *database.TransactionFilter) (*export.Declarations, bool) {
+	categories := make([]database.Category, 0)
+	if err := h.database.SelectCategoriesByLedgerID(r.Context(), filter.LedgerID, &categories); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return nil, false
+	}
+
+	currencies := make([]database.Currency, 0)
+	if err := h.database.SelectTransactionCurrencies(r.Context(), filter, &currencies); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return nil, false
+	}
+
+	declarations := &export.Declarations{}
+	for _, category := range categories {
+		declarations.Categories = append(declarations.Categories, category.Name)
+	}
+	for _, currency := range currencies {
+		declarations.Commodities = append(declarations.Commodities, export.Commodity{Code: currency.Code, Symbol: currency.Symbol})
+	}
+	return declarations, true
+}
+
+// Export streams transactions of user as a file.
+//
+//	@Summary		Export transactions
+//	@Description	Returns a file with transactions owned by user which match the given filters, newest first.
+//	@Description	Each row contains category (or categories of the splits), currency, amount converted to the given currency and tags.
+//	@Description	Amounts are decimal numbers in CSV and XLSX files and are in minor units in NDJSON files.
+//	@Description	Plain-text accounting formats (`ledger`, which is also read by hledger, and `beancount`) list transactions oldest first,
+//	@Description	categories are rendered as expense and income accounts balanced by the `Assets:Groshi` account
+//	@Description	and currencies of the transactions are declared as commodities.
+//	@Description	Transactions are filtered the same way as in the transaction listing.
+//	@Tags			export
+//	@Produce		text/csv
+//	@Produce		application/x-ndjson
+//	@Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Produce		plain
+//	@Param			format		query		string		false	"Format of the file: `csv` (default), `ndjson`, `xlsx`, `ledger` or `beancount`"
+//	@Param			in			query		string		true	"Code of the currency amounts will be converted to"
+//	@Param			ledger		query		string		false	"Ledger UUID, the personal ledger of user is used if it is not set"
+//	@Param			start_time	query		string		false	"Export transactions which happened at or after this moment (RFC 3339)"
+//	@Param			end_time	query		string		false	"Export transactions which happened before this moment (RFC 3339)"
+//	@Param			currency	query		string		false	"Export only transactions in this currency"
+//	@Param			category	query		string		false	"Category UUID"
+//	@Param			payee		query		string		false	"Payee UUID"
+//	@Param			tag			query		[]string	false	"Tag UUID, may be repeated"
+//	@Param			tag_mode	query		string		false	"Whether transactions must have `any` (default) or `all` of the given tags"
+//	@Success		200			{file}		file		"Successful operation"
+//	@Failure		400			{object}	model.Error	"Invalid request params"
+//	@Failure		403			{object}	model.Error	"Access to the ledger, the category, payee or a tag is forbidden"
+//	@Failure		404			{object}	model.Error	"User, ledger, currency, category, payee or tag not found"
+//	@Failure		500			{object}	model.Error	"Internal server error"
+//	@Security		Bearer
+//	@Router			/export [get]
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// check if the format is supported:
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+	contentType := export.ContentType(format)
+	if contentType == "" {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return
+	}
+
+	// fetch the currency amounts will be converted to:
+	currency, ok := h.statsCurrency(w, r)
+	if !ok {
+		return
+	}
+
+	// build transaction filter from request params:
+	filter, ok := h.transactionFilter(w, r, user)
+	if !ok {
+		return
+	}
+
+	// fetch accounts and commodities to declare in plain-text accounting files:
+	var declarations *export.Declarations
+	if export.IsPlainTextAccounting(format) {
+		declarations, ok = h.exportDeclarations(w, r, filter)
+		if !ok {
+			return
+		}
+	}
+
+	// stream the file, response status can not be changed after this point:
+	filename := fmt.Sprintf("transactions-%s.%s", time.Now().UTC().Format(time.DateOnly), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	writer, err := export.NewWriter(format, w, declarations)
+	if err == nil {
+		// plain-text accounting journals are chronological:
+		oldestFirst := export.IsPlainTextAccounting(format)
+		err = h.database.IterateTransactionExportRows(r.Context(), filter, oldestFirst, func(row *database.TransactionExportRow) error {
+			return writer.Write(newExportRow(row, currency))
+		})
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		// abort the connection so that the client does not take the truncated file for a complete one:
+		h.internalServerErrorLogger.Println(err)
+		panic(http.ErrAbortHandler)
+	}
+}
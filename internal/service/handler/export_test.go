@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"context"
+	"encoding/csv"
+	"github.com/google/uuid"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_Export(t *testing.T) {
+	const (
+		testUserID   int64 = 11
+		testUsername       = "test-username"
+	)
+
+	var (
+		handler = newTestHandler()
+		db      = handler.database.(*mockDatabase)
+		ctx     = testUserContext(context.Background(), handler, testUsername)
+	)
+
+	// create a test user, a category, currencies and transactions:
+	if err := db.CreateUser(ctx, &database.User{ID: testUserID, Username: testUsername}); err != nil {
+		panic(err)
+	}
+	ledgerID := testPersonalLedgerID(db, testUserID)
+	groceries := &database.Category{ID: 1, UUID: uuid.New(), Name: "Groceries", LedgerID: ledgerID, OwnerID: testUserID}
+	if err := db.CreateCategory(ctx, groceries); err != nil {
+		panic(err)
+	}
+	db.currencies = append(db.currencies,
+		&database.Currency{ID: 1, Code: "EUR", Rate: 1},
+		&database.Currency{ID: 2, Code: "USD", Rate: 1.25},
+	)
+	for _, transaction := range []*database.Transaction{
+		{
+			Amount: -2500, CurrencyID: 2, CategoryID: groceries.ID, Description: "Weekly shopping",
+			Payee: database.Payee{Name: "Corner Store"}, Tags: []database.Tag{{Name: "food"}, {Name: "home"}},
+			LedgerID: ledgerID, OwnerID: testUserID, Timestamp: time.Date(2026, time.March, 5, 14, 30, 0, 0, time.UTC),
+		},
+		{Amount: 100, CurrencyID: 1, LedgerID: testPersonalLedgerID(db, testUserID+1), OwnerID: testUserID + 1, Timestamp: time.Now()},
+	} {
+		if err := db.CreateTransaction(ctx, transaction); err != nil {
+			panic(err)
+		}
+	}
+
+	t.Run("export CSV", func(t *testing.T) {
+		rec := testGetRequest(ctx, "/export?in=EUR", handler.Export)
+		if !assert.Equal(t, http.StatusOK, rec.Code) {
+			return
+		}
+		assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Header().Get("Content-Disposition"), ".csv\"")
+
+		records, err := csv.NewReader(rec.Body).ReadAll()
+		if assert.NoError(t, err) && assert.Len(t, records, 2) {
+			assert.Equal(t, []string{
+				"2026-03-05T14:30:00Z", "", "-25.00", "USD", "-20.00", "EUR",
+				"Corner Store", "Groceries", "food; home", "Weekly shopping", db.transactions[0].UUID.String(),
+			}, records[1])
+		}
+	})
+
+	t.Run("export NDJSON", func(t *testing.T) {
+		rec := testGetRequest(ctx, "/export?in=USD&format=ndjson", handler.Export)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+			lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+			if assert.Len(t, lines, 1) {
+				assert.Contains(t, lines[0], `"converted_amount":-2500,"converted_currency":"USD"`)
+			}
+		}
+	})
+
+	t.Run("export XLSX", func(t *testing.T) {
+		rec := testGetRequest(ctx, "/export?in=EUR&format=xlsx", handler.Export)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", rec.Header().Get("Content-Type"))
+			assert.True(t, strings.HasPrefix(rec.Body.String(), "PK"))
+		}
+	})
+
+	t.Run("export beancount", func(t *testing.T) {
+		rec := testGetRequest(ctx, "/export?in=EUR&format=beancount", handler.Export)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			body := rec.Body.String()
+			assert.Contains(t, body, "1970-01-01 commodity USD\n")
+			assert.NotContains(t, body, "commodity EUR")
+			assert.Contains(t, body, "1970-01-01 open Expenses:Groceries\n")
+			assert.Contains(t, body, `2026-03-05 * "Corner Store" "Weekly shopping" #food #home`)
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		rec := testGetRequest(ctx, "/export?in=EUR&format=pdf", handler.Export)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("missing currency", func(t *testing.T) {
+		rec := testGetRequest(ctx, "/export", handler.Export)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
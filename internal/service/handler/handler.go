@@ -1,14 +1,24 @@
 package handler
 
 import (
+	"database/sql"
 	"errors"
 	"github.com/go-playground/validator/v10"
 	"github.com/groshi-project/groshi/internal/auth"
 	"github.com/groshi-project/groshi/internal/database"
+	"github.com/groshi-project/groshi/internal/middleware"
+	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
+	"github.com/groshi-project/groshi/internal/service/handler/response"
+	"github.com/groshi-project/groshi/internal/service/job"
 	"log"
+	"net/http"
+	"time"
 )
 
-var errMissingUsernameContextValue = errors.New("missing username context value")
+var (
+	errMissingUserContextValue      = errors.New("missing user context value")
+	errMissingSessionIDContextValue = errors.New("missing session id context value")
+)
 
 // Handler represents dependencies for HTTP handler functions.
 type Handler struct {
@@ -21,20 +31,82 @@ type Handler struct {
 	// Password authenticator used to hash and validate passwords.
 	passwordAuth auth.PasswordAuthenticator
 
+	// Login guard used to protect login from brute-force attacks.
+	loginGuard *auth.LoginGuard
+
+	// OpenID Connect identity provider users can log in with, it is nil if OIDC login is disabled.
+	oidc *auth.OIDCProvider
+
+	// Service jobs, their statuses are shown to admins.
+	jobs *job.Job
+
+	// Registration mode which defines who can create a new user, one of auth.Registration* constants.
+	registrationMode string
+
+	// Duration of a refresh token validity.
+	refreshTokenTTL time.Duration
+
 	// Logger used to log internal server errors.
 	internalServerErrorLogger *log.Logger
 
 	// Validator settings for validating incoming request params.
 	paramsValidate *validator.Validate
+
+	// Clock used to validate time-based codes, it is replaced in tests.
+	now func() time.Time
 }
 
 // New creates a new instance of [Handler] and returns pointer to it.
-func New(database database.Database, jwtAuth auth.JWTAuthenticator, passwordAuth auth.PasswordAuthenticator, internalServerErrorLogger *log.Logger) *Handler {
+func New(database database.Database, jwtAuth auth.JWTAuthenticator, passwordAuth auth.PasswordAuthenticator, loginGuard *auth.LoginGuard, oidc *auth.OIDCProvider, jobs *job.Job, registrationMode string, refreshTokenTTL time.Duration, internalServerErrorLogger *log.Logger) *Handler {
 	return &Handler{
 		database:                  database,
 		JWTAuth:                   jwtAuth,
 		passwordAuth:              passwordAuth,
+		loginGuard:                loginGuard,
+		oidc:                      oidc,
+		jobs:                      jobs,
+		registrationMode:          registrationMode,
+		refreshTokenTTL:           refreshTokenTTL,
 		internalServerErrorLogger: internalServerErrorLogger,
 		paramsValidate:            validator.New(),
+		now:                       time.Now,
+	}
+}
+
+// currentUser returns the current user which is stored in the request context by the JWT middleware.
+// Renders an error response and returns false if the request context does not contain the user.
+func (h *Handler) currentUser(w http.ResponseWriter, r *http.Request) (*database.User, bool) {
+	user, ok := r.Context().Value(middleware.UserContextKey).(*database.User)
+	if !ok || user == nil {
+		h.internalServerErrorLogger.Println(errMissingUserContextValue)
+		httpresp.Render(w, response.InternalServerError)
+		return nil, false
+	}
+	return user, true
+}
+
+// currentSession fetches the current session using its UUID stored in the request context.
+// Renders an error response and returns false if the session could not be fetched.
+func (h *Handler) currentSession(w http.ResponseWriter, r *http.Request) (*database.Session, bool) {
+	// extract current session's UUID from context:
+	sessionID, ok := r.Context().Value(middleware.SessionIDContextKey).(string)
+	if !ok {
+		h.internalServerErrorLogger.Println(errMissingSessionIDContextValue)
+		httpresp.Render(w, response.InternalServerError)
+		return nil, false
+	}
+
+	// fetch the current session from the database:
+	session := &database.Session{}
+	if err := h.database.SelectSessionByUUID(r.Context(), sessionID, session); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpresp.Render(w, response.SessionNotFound)
+			return nil, false
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return nil, false
 	}
+
+	return session, true
 }
@@ -0,0 +1,699 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/groshi-project/groshi/internal/importer"
+	"github.com/groshi-project/groshi/internal/rules"
+	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
+	"github.com/groshi-project/groshi/internal/service/handler/model"
+	"github.com/groshi-project/groshi/internal/service/handler/response"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxImportFileSize is the maximum size of an uploaded statement file in bytes.
+const maxImportFileSize = 32 << 20
+
+// importFile reads options and statement file from a multipart form: options are decoded
+// from the "options" field and validated, the file is taken from the "file" field.
+// Renders an error response and returns false if the form could not be read.
+func (h *Handler) importFile(w http.ResponseWriter, r *http.Request, options any) (multipart.File, string, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize)
+	if err := r.ParseMultipartForm(maxImportFileSize); err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return nil, "", false
+	}
+
+	// decode and validate options:
+	if rawOptions := r.FormValue("options"); rawOptions != "" {
+		if err := json.Unmarshal([]byte(rawOptions), options); err != nil {
+			httpresp.Render(w, response.InvalidRequestBodyFormat)
+			return nil, "", false
+		}
+	}
+	if err := h.paramsValidate.Struct(options); err != nil {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return nil, "", false
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		httpresp.Render(w, response.ImportFileMissing)
+		return nil, "", false
+	}
+	return file, header.Filename, true
+}
+
+type importRowError struct {
+	// Number of the row (line or record) in the file, starting from 1.
+	Row int `json:"row" example:"12"`
+
+	// Column or field which caused the error, absent if the error concerns the whole row.
+	Column string `json:"column,omitempty" example:"amount"`
+
+	Message string `json:"message" example:"invalid amount"`
+}
+
+type importItem struct {
+	// Number of the row (line or record) in the file, starting from 1.
+	Row int `json:"row" example:"2"`
+
+	// UUID of the created transaction, absent in dry-run mode.
+	UUID string `json:"uuid,omitempty" example:"3be1ed0a-c307-49de-872e-38730200f301"`
+
+	Amount   int32  `json:"amount" example:"-1250"`
+	Currency string `json:"currency" example:"EUR"`
+
+	Description string   `json:"description" example:"REWE SAGT DANKE 1234"`
+	Payee       string   `json:"payee,omitempty" example:"REWE"`
+	Category    string   `json:"category,omitempty" example:"Groceries"`
+	Tags        []string `json:"tags,omitempty" example:"food"`
+
+	Timestamp time.Time  `json:"timestamp" example:"2024-03-20T00:00:00Z"`
+	ValueDate *time.Time `json:"value_date,omitempty" example:"2024-03-19T00:00:00Z"`
+}
+
+type importResponse struct {
+	DryRun bool `json:"dry_run" example:"true"`
+
+	// UUID of the import report, absent in dry-run mode.
+	ReportUUID string `json:"report,omitempty" example:"9a7c5e1b-3d2f-4b6a-8e0c-1f2d3c4b5a69"`
+
+	// Count of the created transactions, always zero in dry-run mode.
+	Imported int `json:"imported" example:"0"`
+
+	// Errors of the rows which could not be imported. Nothing is imported if there are errors.
+	Errors []importRowError `json:"errors"`
+
+	// Rows of the entries which were already imported before and are skipped.
+	Skipped []int `json:"skipped"`
+
+	// Transactions which are (or would be in dry-run mode) created.
+	Transactions []importItem `json:"transactions"`
+}
+
+// importSource describes the imported file.
+type importSource struct {
+	filename string
+	format   string
+
+	// currency of the entries which do not specify it.
+	defaultCurrencyCode string
+}
+
+// importEntries skips already imported entries, resolves currencies and categories of the entries, applies rules
+// of the user to the entries without category and, unless in dry-run mode, creates transactions atomically
+// and saves the import report. Nothing is created if there are row errors. Renders the import report.
+func (h *Handler) importEntries(w http.ResponseWriter, r *http.Request, user *database.User, source *importSource, entries []importer.Entry, rowErrors []importer.RowError, dryRun bool) {
+	resp := &importResponse{
+		DryRun:       dryRun,
+		Errors:       make([]importRowError, 0),
+		Skipped:      make([]int, 0),
+		Transactions: make([]importItem, 0, len(entries)),
+	}
+	for _, rowError := range rowErrors {
+		resp.Errors = append(resp.Errors, importRowError{
+			Row:     rowError.Row,
+			Column:  rowError.Column,
+			Message: rowError.Err.Error(),
+		})
+	}
+
+	// fetch the ledger and check if the current user can edit it:
+	ledger, ok := h.requestLedger(w, r, user, database.LedgerRoleEditor)
+	if !ok {
+		return
+	}
+
+	// fetch categories of the ledger and tags of the current user:
+	categories := make([]database.Category, 0)
+	if err := h.database.SelectCategoriesByLedgerID(r.Context(), ledger.ID, &categories); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+	categoriesByName := make(map[string]database.Category, len(categories))
+	categoriesByID := make(map[int64]database.Category, len(categories))
+	for _, category := range categories {
+		categoriesByName[strings.ToLower(category.Name)] = category
+		categoriesByID[category.ID] = category
+	}
+
+	tags := make([]database.Tag, 0)
+	if err := h.database.SelectTagsByOwnerID(r.Context(), user.ID, &tags); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+	tagsByID := make(map[int64]database.Tag, len(tags))
+	for _, tag := range tags {
+		tagsByID[tag.ID] = tag
+	}
+
+	// fetch rules of the current user:
+	engine, _, err := h.rulesEngine(r.Context(), user)
+	if err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// find external IDs of the entries which were already imported:
+	externalIDs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.ExternalID != "" {
+			externalIDs = append(externalIDs, entry.ExternalID)
+		}
+	}
+	existingExternalIDs := make([]string, 0)
+	if err := h.database.SelectTransactionExternalIDs(r.Context(), ledger.ID, externalIDs, &existingExternalIDs); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+	seenExternalIDs := make(map[string]bool, len(entries))
+	for _, externalID := range existingExternalIDs {
+		seenExternalIDs[externalID] = true
+	}
+
+	// currencies fetched by their codes, nil values mean that there is no currency with such code:
+	currencies := make(map[string]*database.Currency)
+
+	transactions := make([]*database.Transaction, 0, len(entries))
+	payeeNames := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		// skip entries which were already imported or occur in the file more than once:
+		if entry.ExternalID != "" {
+			if seenExternalIDs[entry.ExternalID] {
+				resp.Skipped = append(resp.Skipped, entry.Row)
+				continue
+			}
+			seenExternalIDs[entry.ExternalID] = true
+		}
+
+		// fetch currency of the entry:
+		currencyCode := entry.CurrencyCode
+		if currencyCode == "" {
+			currencyCode = strings.ToUpper(source.defaultCurrencyCode)
+		}
+		if currencyCode == "" {
+			resp.Errors = append(resp.Errors, importRowError{Row: entry.Row, Column: "currency", Message: "currency is not specified"})
+			continue
+		}
+		currency, ok := currencies[currencyCode]
+		if !ok {
+			currency = &database.Currency{}
+			if err := h.database.SelectCurrencyByCode(r.Context(), currencyCode, currency); err != nil {
+				if !errors.Is(err, sql.ErrNoRows) {
+					h.internalServerErrorLogger.Println(err)
+					httpresp.Render(w, response.InternalServerError)
+					return
+				}
+				currency = nil
+			}
+			currencies[currencyCode] = currency
+		}
+		if currency == nil {
+			resp.Errors = append(resp.Errors, importRowError{Row: entry.Row, Column: "currency", Message: fmt.Sprintf("unknown currency %q", currencyCode)})
+			continue
+		}
+
+		transaction := &database.Transaction{
+			Amount:      entry.Amount,
+			CurrencyID:  currency.ID,
+			Description: entry.Description,
+			ExternalID:  entry.ExternalID,
+			LedgerID:    ledger.ID,
+			OwnerID:     user.ID,
+			Timestamp:   entry.Timestamp.UTC(),
+			ValueDate:   entry.ValueDate.UTC(),
+		}
+		payeeName := database.CleanPayeeName(entry.Payee)
+
+		// find category of the entry by its name or apply rules if category is not specified:
+		if entry.Category != "" {
+			category, ok := categoriesByName[strings.ToLower(entry.Category)]
+			if !ok {
+				resp.Errors = append(resp.Errors, importRowError{Row: entry.Row, Column: "category", Message: fmt.Sprintf("category %q not found", entry.Category)})
+				continue
+			}
+			transaction.CategoryID = category.ID
+		} else {
+			result := engine.Apply(rules.Input{
+				Description: entry.Description,
+				Payee:       payeeName,
+				Amount:      entry.Amount,
+				CurrencyID:  currency.ID,
+			})
+			// rules may refer to categories of other ledgers, such categories are not applied:
+			if _, ok := categoriesByID[result.CategoryID]; ok {
+				transaction.CategoryID = result.CategoryID
+			}
+			for _, tagID := range result.TagIDs {
+				if tag, ok := tagsByID[tagID]; ok {
+					transaction.Tags = append(transaction.Tags, tag)
+				}
+			}
+			if result.PayeeName != "" {
+				payeeName = result.PayeeName
+			}
+		}
+
+		item := importItem{
+			Row:         entry.Row,
+			Amount:      transaction.Amount,
+			Currency:    currency.Code,
+			Description: transaction.Description,
+			Payee:       payeeName,
+			Category:    categoriesByID[transaction.CategoryID].Name,
+			Timestamp:   transaction.Timestamp,
+		}
+		if !transaction.ValueDate.IsZero() {
+			item.ValueDate = &transaction.ValueDate
+		}
+		for _, tag := range transaction.Tags {
+			item.Tags = append(item.Tags, tag.Name)
+		}
+
+		transactions = append(transactions, transaction)
+		payeeNames = append(payeeNames, payeeName)
+		resp.Transactions = append(resp.Transactions, item)
+	}
+
+	sort.SliceStable(resp.Errors, func(i, j int) bool {
+		return resp.Errors[i].Row < resp.Errors[j].Row
+	})
+
+	if dryRun {
+		httpresp.Render(w, httpresp.NewOK(resp))
+		return
+	}
+	if len(resp.Errors) != 0 {
+		if !h.saveImportReport(w, r, user, source, resp) {
+			return
+		}
+		httpresp.Render(w, httpresp.New(http.StatusUnprocessableEntity, resp))
+		return
+	}
+
+	// fetch payees of the transactions or create new ones and create all transactions atomically,
+	// so that payees are not left behind if the transactions could not be created:
+	err = h.database.RunInTx(r.Context(), func(ctx context.Context, db database.Database) error {
+		payees := make(map[string]int64)
+		for i, transaction := range transactions {
+			if payeeNames[i] == "" {
+				continue
+			}
+			normalizedName := database.NormalizePayeeName(payeeNames[i])
+			payeeID, ok := payees[normalizedName]
+			if !ok {
+				payee := &database.Payee{}
+				if err := db.SelectOrCreatePayee(ctx, user.ID, payeeNames[i], payee); err != nil {
+					return err
+				}
+				payeeID = payee.ID
+				payees[normalizedName] = payeeID
+			}
+			transaction.PayeeID = payeeID
+		}
+		return db.CreateTransactions(ctx, transactions)
+	})
+	if err != nil {
+		// some entries may be imported concurrently by another request:
+		if database.IsUniqueViolation(err) {
+			httpresp.Render(w, response.ImportConflict)
+			return
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	for i, transaction := range transactions {
+		resp.Transactions[i].UUID = transaction.UUID.String()
+	}
+	resp.Imported = len(transactions)
+	if !h.saveImportReport(w, r, user, source, resp) {
+		return
+	}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
+
+// saveImportReport saves report of importing the file and sets its UUID in the response.
+// Renders an error response and returns false if the report could not be saved.
+func (h *Handler) saveImportReport(w http.ResponseWriter, r *http.Request, user *database.User, source *importSource, resp *importResponse) bool {
+	report := &database.ImportReport{
+		Filename: source.filename,
+		Format:   source.format,
+		Imported: resp.Imported,
+		Skipped:  len(resp.Skipped),
+		Errors:   make([]database.ImportReportError, 0, len(resp.Errors)),
+		OwnerID:  user.ID,
+	}
+	for _, rowError := range resp.Errors {
+		report.Errors = append(report.Errors, database.ImportReportError{
+			Row:     rowError.Row,
+			Column:  rowError.Column,
+			Message: rowError.Message,
+		})
+	}
+
+	if err := h.database.CreateImportReport(r.Context(), report); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return false
+	}
+	resp.ReportUUID = report.UUID.String()
+	return true
+}
+
+type importCSVOptions struct {
+	// Mapping of transaction fields to columns: columns are referred to by their header names
+	// if the file has a header, otherwise by their numbers starting from 1.
+	// Date and either amount or debit and credit columns are required.
+	Columns importer.CSVColumns `json:"columns"`
+
+	// Field delimiter, comma by default.
+	Delimiter string `json:"delimiter" example:";" validate:"omitempty,len=1"`
+
+	// Whether the first line of the file is a header.
+	Header bool `json:"header" example:"true"`
+
+	// Date format composed of YYYY, YY, MM, DD, hh, mm and ss, "YYYY-MM-DD" by default.
+	DateFormat string `json:"date_format" example:"DD.MM.YYYY"`
+
+	// Decimal separator, dot or comma, dot by default.
+	DecimalSeparator string `json:"decimal_separator" example:"," validate:"omitempty,len=1"`
+
+	// Sign convention of the amount column: expenses are negative (default) or positive.
+	Sign string `json:"sign" example:"expense_negative" validate:"omitempty,oneof=expense_negative expense_positive"`
+
+	// Currency of the transactions if the currency column is not mapped or empty.
+	Currency string `json:"currency" example:"EUR"`
+
+	// Only validate the file and report transactions which would be created.
+	DryRun bool `json:"dry_run" example:"true"`
+}
+
+// firstRune returns the first rune of the string or zero if the string is empty.
+func firstRune(s string) rune {
+	for _, r := range s {
+		return r
+	}
+	return 0
+}
+
+// ImportCSV imports transactions from a CSV file.
+//
+//	@Summary		Import transactions from a CSV file
+//	@Description	Imports transactions from a CSV file (e.g. a bank statement or a spreadsheet export) using the given mapping of columns.
+//	@Description	Transactions without category are categorized by the rules of user.
+//	@Description	In dry-run mode the file is only validated and transactions which would be created are reported.
+//	@Description	Otherwise, all transactions are created atomically: nothing is imported if any row has errors.
+//	@Description	Import report of the file is saved in both cases.
+//	@Tags			import
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			ledger	query		string				false	"Ledger UUID, the personal ledger of user is used if it is not set"
+//	@Param			file	formData	file				true	"CSV file"
+//	@Param			options	formData	string				true	"Import options, JSON-encoded importCSVOptions"
+//	@Success		200		{object}	importResponse		"Successful operation"
+//	@Failure		400		{object}	model.Error			"Invalid request body format, invalid options or missing file"
+//	@Failure		403		{object}	model.Error			"Access to the ledger is forbidden or user is its viewer"
+//	@Failure		404		{object}	model.Error			"User or ledger not found"
+//	@Failure		409		{object}	model.Error			"Some entries were imported concurrently, nothing is imported"
+//	@Failure		422		{object}	importResponse		"Some rows have errors, nothing is imported"
+//	@Failure		500		{object}	model.Error			"Internal server error"
+//	@Security		Bearer
+//	@Router			/import/csv [post]
+func (h *Handler) ImportCSV(w http.ResponseWriter, r *http.Request) {
+	// read options and the file:
+	options := &importCSVOptions{}
+	file, filename, ok := h.importFile(w, r, options)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// parse the file:
+	entries, rowErrors, err := importer.ParseCSV(file, &importer.CSVOptions{
+		Columns:          options.Columns,
+		Delimiter:        firstRune(options.Delimiter),
+		Header:           options.Header,
+		DateFormat:       options.DateFormat,
+		DecimalSeparator: firstRune(options.DecimalSeparator),
+		Sign:             options.Sign,
+	})
+	if err != nil {
+		httpresp.Render(w, httpresp.New(http.StatusBadRequest, model.NewError(fmt.Sprintf("unable to parse file: %s", err))))
+		return
+	}
+
+	source := &importSource{
+		filename:            filename,
+		format:              "csv",
+		defaultCurrencyCode: options.Currency,
+	}
+	h.importEntries(w, r, user, source, entries, rowErrors, options.DryRun)
+}
+
+type importStatementOptions struct {
+	// Format of the file: ofx (also used for QFX files), qif, camt053 or mt940, detected by content if empty.
+	Format string `json:"format" example:"ofx" validate:"omitempty,oneof=ofx qif camt053 mt940"`
+
+	// Currency of the transactions if the statement does not specify it (QIF statements never do).
+	Currency string `json:"currency" example:"USD"`
+
+	// Whether dates of a QIF statement are in day-first order (DD/MM/YY) instead of month-first one (MM/DD/YY).
+	DayFirst bool `json:"day_first" example:"false"`
+
+	// Only validate the file and report transactions which would be created.
+	DryRun bool `json:"dry_run" example:"true"`
+}
+
+// ImportStatement imports transactions from a bank statement file.
+//
+//	@Summary		Import transactions from a bank statement
+//	@Description	Imports transactions from an OFX (QFX), QIF, ISO 20022 CAMT.053 or SWIFT MT940 bank statement file.
+//	@Description	Currency of the transactions is taken from the statement or from options if the statement does not specify it.
+//	@Description	Entries which were already imported (identified by their bank references such as FITIDs or, if they have none, by their content) are skipped.
+//	@Description	Transactions without category are categorized by the rules of user.
+//	@Description	In dry-run mode the file is only validated and transactions which would be created are reported.
+//	@Description	Otherwise, all transactions are created atomically: nothing is imported if any entry has errors.
+//	@Description	Import report of the file is saved in both cases.
+//	@Tags			import
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			ledger	query		string				false	"Ledger UUID, the personal ledger of user is used if it is not set"
+//	@Param			file	formData	file				true	"Statement file"
+//	@Param			options	formData	string				false	"Import options, JSON-encoded importStatementOptions"
+//	@Success		200		{object}	importResponse		"Successful operation"
+//	@Failure		400		{object}	model.Error			"Invalid request body format, invalid options, missing file, unknown or malformed file format"
+//	@Failure		403		{object}	model.Error			"Access to the ledger is forbidden or user is its viewer"
+//	@Failure		404		{object}	model.Error			"User or ledger not found"
+//	@Failure		409		{object}	model.Error			"Some entries were imported concurrently, nothing is imported"
+//	@Failure		422		{object}	importResponse		"Some entries have errors, nothing is imported"
+//	@Failure		500		{object}	model.Error			"Internal server error"
+//	@Security		Bearer
+//	@Router			/import/statement [post]
+func (h *Handler) ImportStatement(w http.ResponseWriter, r *http.Request) {
+	// read options and the file:
+	options := &importStatementOptions{}
+	file, filename, ok := h.importFile(w, r, options)
+	if !ok {
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return
+	}
+
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// parse the file:
+	format := options.Format
+	if format == "" {
+		format = importer.DetectFormat(data)
+	}
+
+	var (
+		entries   []importer.Entry
+		rowErrors []importer.RowError
+	)
+	switch format {
+	case importer.FormatOFX:
+		entries, rowErrors, err = importer.ParseOFX(bytes.NewReader(data))
+	case importer.FormatQIF:
+		entries, rowErrors, err = importer.ParseQIF(bytes.NewReader(data), &importer.QIFOptions{DayFirst: options.DayFirst})
+	case importer.FormatCAMT053:
+		entries, rowErrors, err = importer.ParseCAMT053(bytes.NewReader(data))
+	case importer.FormatMT940:
+		entries, rowErrors, err = importer.ParseMT940(bytes.NewReader(data))
+	default:
+		httpresp.Render(w, response.ImportFormatUnknown)
+		return
+	}
+	if err != nil {
+		httpresp.Render(w, httpresp.New(http.StatusBadRequest, model.NewError(fmt.Sprintf("unable to parse file: %s", err))))
+		return
+	}
+
+	source := &importSource{
+		filename:            filename,
+		format:              format,
+		defaultCurrencyCode: options.Currency,
+	}
+	h.importEntries(w, r, user, source, entries, rowErrors, options.DryRun)
+}
+
+type importReportItem struct {
+	UUID     string `json:"uuid" example:"9a7c5e1b-3d2f-4b6a-8e0c-1f2d3c4b5a69"`
+	Filename string `json:"filename" example:"statement-2026-03.xml"`
+	Format   string `json:"format" example:"camt053"`
+
+	// Count of the created transactions.
+	Imported int `json:"imported" example:"42"`
+
+	// Count of the entries which were already imported before and were skipped.
+	Skipped int `json:"skipped" example:"3"`
+
+	// Errors of the rows which could not be imported, nothing is imported if there are errors.
+	Errors []importRowError `json:"errors"`
+
+	CreatedAt time.Time `json:"created_at" example:"2024-03-20T12:57:38Z"`
+}
+
+// newImportReportItem creates a new instance of [importReportItem] from an import report model.
+func newImportReportItem(r *database.ImportReport) importReportItem {
+	item := importReportItem{
+		UUID:      r.UUID.String(),
+		Filename:  r.Filename,
+		Format:    r.Format,
+		Imported:  r.Imported,
+		Skipped:   r.Skipped,
+		Errors:    make([]importRowError, 0, len(r.Errors)),
+		CreatedAt: r.CreatedAt,
+	}
+	for _, rowError := range r.Errors {
+		item.Errors = append(item.Errors, importRowError{
+			Row:     rowError.Row,
+			Column:  rowError.Column,
+			Message: rowError.Message,
+		})
+	}
+	return item
+}
+
+type importReportsGetResponse []importReportItem
+
+// ImportReportsGet returns reports of all imports made by user.
+//
+//	@Summary		Fetch import reports
+//	@Description	Returns reports of all imports made by user, the most recent ones first
+//	@Tags			import
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	importReportsGetResponse	"Successful operation"
+//	@Failure		404	{object}	model.Error					"User not found"
+//	@Failure		500	{object}	model.Error					"Internal server error"
+//	@Security		Bearer
+//	@Router			/import/reports [get]
+func (h *Handler) ImportReportsGet(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch import reports of the current user:
+	reports := make([]database.ImportReport, 0)
+	if err := h.database.SelectImportReportsByOwnerID(r.Context(), user.ID, &reports); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+
+	// respond:
+	resp := make(importReportsGetResponse, 0, len(reports))
+	for i := range reports {
+		resp = append(resp, newImportReportItem(&reports[i]))
+	}
+	httpresp.Render(w, httpresp.NewOK(&resp))
+}
+
+type importReportsGetOneResponse importReportItem
+
+// ImportReportsGetOne returns a single import report.
+//
+//	@Summary		Fetch an import report
+//	@Description	Returns an import report of user
+//	@Tags			import
+//	@Accept			json
+//	@Produce		json
+//	@Param			uuid	path		string						true	"Import report UUID"
+//	@Success		200		{object}	importReportsGetOneResponse	"Successful operation"
+//	@Failure		403		{object}	model.Error					"Access to the import report is forbidden"
+//	@Failure		404		{object}	model.Error					"User or import report not found"
+//	@Failure		500		{object}	model.Error					"Internal server error"
+//	@Security		Bearer
+//	@Router			/import/reports/{uuid} [get]
+func (h *Handler) ImportReportsGetOne(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch the import report and check if it belongs to the current user:
+	report := &database.ImportReport{}
+	if err := h.database.SelectImportReportByUUID(r.Context(), chi.URLParam(r, "uuid"), report); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpresp.Render(w, response.ImportReportNotFound)
+			return
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+	if report.OwnerID != user.ID {
+		httpresp.Render(w, response.ImportReportForbidden)
+		return
+	}
+
+	// respond:
+	resp := importReportsGetOneResponse(newImportReportItem(report))
+	httpresp.Render(w, httpresp.NewOK(&resp))
+}
@@ -0,0 +1,303 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/groshi-project/groshi/internal/importer"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_ImportCSV(t *testing.T) {
+	const (
+		testUserID   int64 = 8
+		testUsername       = "test-username"
+	)
+
+	var (
+		handler = newTestHandler()
+		db      = handler.database.(*mockDatabase)
+		ctx     = testUserContext(context.Background(), handler, testUsername)
+	)
+
+	// create a test user, a category, a rule and currencies:
+	if err := db.CreateUser(ctx, &database.User{ID: testUserID, Username: testUsername}); err != nil {
+		panic(err)
+	}
+	ledgerID := testPersonalLedgerID(db, testUserID)
+	groceries := &database.Category{ID: 1, UUID: uuid.New(), Name: "Groceries", LedgerID: ledgerID, OwnerID: testUserID}
+	if err := db.CreateCategory(ctx, groceries); err != nil {
+		panic(err)
+	}
+	salary := &database.Category{ID: 2, UUID: uuid.New(), Name: "Salary", LedgerID: ledgerID, OwnerID: testUserID}
+	if err := db.CreateCategory(ctx, salary); err != nil {
+		panic(err)
+	}
+	if err := db.CreateRule(ctx, &database.Rule{
+		Name: "Supermarkets", MatchField: "payee", MatchType: "substring", Pattern: "rewe", CategoryID: groceries.ID, OwnerID: testUserID,
+	}); err != nil {
+		panic(err)
+	}
+	db.currencies = append(db.currencies,
+		&database.Currency{ID: 1, Code: "EUR", Rate: 1},
+		&database.Currency{ID: 2, Code: "USD", Rate: 1.1},
+	)
+
+	options := &importCSVOptions{
+		Columns: importer.CSVColumns{
+			Date:        "date",
+			Amount:      "amount",
+			Payee:       "payee",
+			Description: "memo",
+			Currency:    "currency",
+			Category:    "category",
+		},
+		Delimiter:        ";",
+		Header:           true,
+		DateFormat:       "DD.MM.YYYY",
+		DecimalSeparator: ",",
+		Currency:         "EUR",
+	}
+
+	t.Run("preview file with errors", func(t *testing.T) {
+		file := []byte("date;amount;payee;memo;currency;category\n" +
+			"01.03.2026;-12,50;REWE Markt;;;\n" +
+			"02.03.2026;2500,00;ACME;March;;salary\n" +
+			"03.03.2026;-1,00;Unknown;;GBP;\n" +
+			"04.03.2026;-3,00;Kiosk;;;Snacks\n" +
+			"2026-03-05;-1,00;Kiosk;;;\n")
+
+		options := *options
+		options.DryRun = true
+		rec := testUploadRequest(ctx, &options, "statement.csv", file, handler.ImportCSV)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			resp := importResponse{}
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp)) {
+				assert.True(t, resp.DryRun)
+				assert.Zero(t, resp.Imported)
+				if assert.Len(t, resp.Transactions, 2) {
+					assert.Equal(t, "Groceries", resp.Transactions[0].Category)
+					assert.Equal(t, "EUR", resp.Transactions[0].Currency)
+					assert.Equal(t, "Salary", resp.Transactions[1].Category)
+				}
+				if assert.Len(t, resp.Errors, 3) {
+					assert.Equal(t, 4, resp.Errors[0].Row)
+					assert.Equal(t, "currency", resp.Errors[0].Column)
+					assert.Equal(t, 5, resp.Errors[1].Row)
+					assert.Equal(t, "category", resp.Errors[1].Column)
+					assert.Equal(t, 6, resp.Errors[2].Row)
+					assert.Equal(t, "date", resp.Errors[2].Column)
+				}
+			}
+		}
+		assert.Empty(t, db.transactions)
+	})
+
+	t.Run("nothing is imported if there are errors", func(t *testing.T) {
+		file := []byte("date;amount;payee;memo;currency;category\n" +
+			"01.03.2026;-12,50;REWE Markt;;;\n" +
+			"02.03.2026;abc;ACME;;;\n")
+
+		rec := testUploadRequest(ctx, options, "statement.csv", file, handler.ImportCSV)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Empty(t, db.transactions)
+	})
+
+	t.Run("import file", func(t *testing.T) {
+		file := []byte("date;amount;payee;memo;currency;category\n" +
+			"01.03.2026;-12,50;REWE Markt;;;\n" +
+			"02.03.2026;2.500,00;ACME;March;USD;Salary\n")
+
+		rec := testUploadRequest(ctx, options, "statement.csv", file, handler.ImportCSV)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			resp := importResponse{}
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp)) {
+				assert.Equal(t, 2, resp.Imported)
+				assert.Empty(t, resp.Errors)
+			}
+			if assert.Len(t, db.transactions, 2) {
+				assert.Equal(t, int32(-1250), db.transactions[0].Amount)
+				assert.Equal(t, groceries.ID, db.transactions[0].CategoryID)
+				assert.NotZero(t, db.transactions[0].PayeeID)
+				assert.Equal(t, int32(250000), db.transactions[1].Amount)
+				assert.Equal(t, int64(2), db.transactions[1].CurrencyID)
+			}
+		}
+	})
+
+	t.Run("invalid column mapping", func(t *testing.T) {
+		options := *options
+		options.Columns.Amount = "sum"
+		rec := testUploadRequest(ctx, &options, "statement.csv", []byte("date;amount\n"), handler.ImportCSV)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestHandler_ImportStatement(t *testing.T) {
+	const (
+		testUserID   int64 = 9
+		testUsername       = "test-username"
+	)
+
+	var (
+		handler = newTestHandler()
+		db      = handler.database.(*mockDatabase)
+		ctx     = testUserContext(context.Background(), handler, testUsername)
+	)
+
+	// create a test user and currencies:
+	if err := db.CreateUser(ctx, &database.User{ID: testUserID, Username: testUsername}); err != nil {
+		panic(err)
+	}
+	db.currencies = append(db.currencies,
+		&database.Currency{ID: 1, Code: "EUR", Rate: 1},
+		&database.Currency{ID: 2, Code: "USD", Rate: 1.1},
+	)
+
+	ofx := []byte(`OFXHEADER:100
+DATA:OFXSGML
+
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>USD
+<BANKACCTFROM><ACCTID>42</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><DTPOSTED>20260301<TRNAMT>-42.15<FITID>1<NAME>WHOLE FOODS</STMTTRN>
+<STMTTRN><DTPOSTED>20260302<TRNAMT>3000.00<FITID>2<NAME>ACME</STMTTRN>
+<STMTTRN><DTPOSTED>20260302<TRNAMT>3000.00<FITID>2<NAME>ACME</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>
+`)
+
+	t.Run("import OFX statement", func(t *testing.T) {
+		rec := testUploadRequest(ctx, &importStatementOptions{}, "statement.ofx", ofx, handler.ImportStatement)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			resp := importResponse{}
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp)) {
+				assert.Equal(t, 2, resp.Imported)
+				assert.Len(t, resp.Skipped, 1)
+			}
+			if assert.Len(t, db.transactions, 2) {
+				assert.Equal(t, int64(2), db.transactions[0].CurrencyID)
+				assert.Equal(t, "ofx:42:1", db.transactions[0].ExternalID)
+			}
+		}
+	})
+
+	t.Run("already imported entries are skipped", func(t *testing.T) {
+		rec := testUploadRequest(ctx, &importStatementOptions{}, "statement.ofx", ofx, handler.ImportStatement)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			resp := importResponse{}
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp)) {
+				assert.Zero(t, resp.Imported)
+				assert.Len(t, resp.Skipped, 3)
+			}
+			assert.Len(t, db.transactions, 2)
+		}
+	})
+
+	t.Run("import QIF statement", func(t *testing.T) {
+		qif := []byte("!Type:Bank\nD01.03.2026\nT-12.50\nPBakery\n^\nD02.03.2026\nT-3.00\nPKiosk\n^\n")
+
+		rec := testUploadRequest(ctx, &importStatementOptions{}, "statement.qif", qif, handler.ImportStatement)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code, "currency must be specified")
+
+		options := &importStatementOptions{Currency: "EUR", DayFirst: true}
+		for i := 0; i < 2; i++ {
+			rec := testUploadRequest(ctx, options, "statement.qif", qif, handler.ImportStatement)
+			assert.Equal(t, http.StatusOK, rec.Code)
+		}
+		if assert.Len(t, db.transactions, 4) {
+			assert.Equal(t, int64(1), db.transactions[2].CurrencyID)
+			assert.Equal(t, 1, db.transactions[2].Timestamp.Day())
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		rec := testUploadRequest(ctx, &importStatementOptions{}, "statement.txt", []byte("hello"), handler.ImportStatement)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestHandler_ImportReports(t *testing.T) {
+	const (
+		testUserID   int64 = 10
+		testUsername       = "test-username"
+	)
+
+	var (
+		handler = newTestHandler()
+		db      = handler.database.(*mockDatabase)
+		ctx     = testUserContext(context.Background(), handler, testUsername)
+	)
+
+	// create a test user and a currency:
+	if err := db.CreateUser(ctx, &database.User{ID: testUserID, Username: testUsername}); err != nil {
+		panic(err)
+	}
+	db.currencies = append(db.currencies, &database.Currency{ID: 1, Code: "EUR", Rate: 1})
+
+	camt := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+<BkToCstmrStmt><Stmt>
+<Acct><Id><IBAN>DE89370400440532013000</IBAN></Id><Ccy>EUR</Ccy></Acct>
+<Ntry><Amt Ccy="EUR">1190.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>BOOK</Sts>
+<BookgDt><Dt>2026-03-05</Dt></BookgDt><ValDt><Dt>2026-03-04</Dt></ValDt><AcctSvcrRef>1</AcctSvcrRef>
+<NtryDtls><TxDtls><RltdPties><Cdtr><Nm>Office Supplies GmbH</Nm></Cdtr></RltdPties><RmtInf><Ustrd>Invoice 17</Ustrd></RmtInf></TxDtls></NtryDtls>
+</Ntry>
+</Stmt></BkToCstmrStmt>
+</Document>
+`)
+	mt940 := []byte(":20:STARTUMS\n:25:37040044/0532013000\n:60F:C260304EUR10000,00\n" +
+		":61:2603050305CR5000,00NTRFNONREF//2\n:86:/NAME/Customer AG/REMI/Order 88/\n" +
+		":61:2603050305DRabcNTRFNONREF\n")
+
+	t.Run("import CAMT.053 statement", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			rec := testUploadRequest(ctx, &importStatementOptions{}, "march.xml", camt, handler.ImportStatement)
+			assert.Equal(t, http.StatusOK, rec.Code)
+		}
+		if assert.Len(t, db.transactions, 1) {
+			transaction := db.transactions[0]
+			assert.Equal(t, int32(-119000), transaction.Amount)
+			assert.Equal(t, "Invoice 17", transaction.Description)
+			assert.Equal(t, 4, transaction.ValueDate.Day())
+		}
+	})
+
+	t.Run("import MT940 statement with errors", func(t *testing.T) {
+		rec := testUploadRequest(ctx, &importStatementOptions{}, "march.sta", mt940, handler.ImportStatement)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Len(t, db.transactions, 1)
+	})
+
+	t.Run("get import reports", func(t *testing.T) {
+		rec := testGetRequest(ctx, "/import/reports", handler.ImportReportsGet)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			resp := importReportsGetResponse{}
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp)) && assert.Len(t, resp, 3) {
+				assert.Equal(t, "march.sta", resp[0].Filename)
+				assert.Equal(t, "mt940", resp[0].Format)
+				assert.Zero(t, resp[0].Imported)
+				assert.Len(t, resp[0].Errors, 1)
+
+				assert.Equal(t, "camt053", resp[1].Format)
+				assert.Zero(t, resp[1].Imported)
+				assert.Equal(t, 1, resp[1].Skipped)
+
+				assert.Equal(t, 1, resp[2].Imported)
+			}
+		}
+	})
+
+	t.Run("get import report of another user", func(t *testing.T) {
+		report := &database.ImportReport{Filename: "foreign.csv", Format: "csv", OwnerID: testUserID + 1}
+		if err := db.CreateImportReport(ctx, report); err != nil {
+			panic(err)
+		}
+		rec := testGetRequest(withURLParam(ctx, "uuid", report.UUID.String()), "/", handler.ImportReportsGetOne)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
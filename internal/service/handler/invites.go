@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/groshi-project/groshi/internal/auth"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
+	"github.com/groshi-project/groshi/internal/service/handler/model"
+	"github.com/groshi-project/groshi/internal/service/handler/response"
+	"net/http"
+	"time"
+)
+
+// defaultInviteCodeTTL is the time-to-live of invite codes created without expiration time.
+const defaultInviteCodeTTL = 7 * 24 * time.Hour
+
+// canInvite returns true if the user is allowed to create invite codes.
+func canInvite(user *database.User) bool {
+	return user.Role == database.RoleAdmin || user.CanInvite
+}
+
+type invitesCreateParams struct {
+	// Count of users which can be created with the code, 1 if it is not set.
+	MaxUses int `json:"max_uses,omitempty" example:"1" validate:"min=0,max=1000"`
+
+	// Optional expiration time of the code, the code expires in 7 days if it is not set.
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2026-03-27T12:57:38Z"`
+}
+
+type invitesCreateResponse struct {
+	UUID string `json:"uuid" example:"5d3b9c1e-7a2f-4e6d-8b0c-1f2e3d4c5b6a"`
+
+	// The code itself, it is shown only once.
+	Code string `json:"code" example:"mfrggzdfmztwq2lknnwg23tp"`
+
+	MaxUses   int       `json:"max_uses" example:"1"`
+	ExpiresAt time.Time `json:"expires_at" example:"2026-03-27T12:57:38Z"`
+}
+
+// InvitesCreate creates a new invite code.
+//
+//	@Summary		Create an invite code
+//	@Description	Creates a new invite code which can be used to create the given count of users until it expires and returns it. The code is shown only once. Available for admins and users who are allowed to invite
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			invite	body		invitesCreateParams		true	"Invite code params"
+//	@Success		200		{object}	invitesCreateResponse	"Successful operation"
+//	@Failure		400		{object}	model.Error				"Invalid request body format, invalid request params or expiration time in the past"
+//	@Failure		403		{object}	model.Error				"The current user is not allowed to create invite codes"
+//	@Failure		500		{object}	model.Error				"Internal server error"
+//	@Security		Bearer
+//	@Router			/user/invites [post]
+func (h *Handler) InvitesCreate(w http.ResponseWriter, r *http.Request) {
+	// decode request params:
+	params := &invitesCreateParams{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return
+	}
+
+	// validate request params:
+	if err := h.paramsValidate.Struct(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return
+	}
+	if params.MaxUses == 0 {
+		params.MaxUses = 1
+	}
+	expiresAt := h.now().Add(defaultInviteCodeTTL)
+	if params.ExpiresAt != nil {
+		if !params.ExpiresAt.After(h.now()) {
+			httpresp.Render(w, httpresp.New(http.StatusBadRequest, model.NewError("expiration time must be in the future")))
+			return
+		}
+		expiresAt = *params.ExpiresAt
+	}
+
+	// fetch the current user and check if it is allowed to invite:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if !canInvite(user) {
+		httpresp.Render(w, response.InviteCodesForbidden)
+		return
+	}
+
+	// generate and store the code:
+	code, err := auth.NewInviteCode()
+	if err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+	inviteCode := &database.InviteCode{
+		CodeHash:  auth.HashInviteCode(code),
+		MaxUses:   params.MaxUses,
+		CreatorID: user.ID,
+		ExpiresAt: expiresAt,
+	}
+	if err := h.database.CreateInviteCode(r.Context(), inviteCode); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	resp := &invitesCreateResponse{
+		UUID:      inviteCode.UUID.String(),
+		Code:      code,
+		MaxUses:   inviteCode.MaxUses,
+		ExpiresAt: inviteCode.ExpiresAt,
+	}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
+
+type invitesGetResponseItem struct {
+	UUID      string    `json:"uuid" example:"5d3b9c1e-7a2f-4e6d-8b0c-1f2e3d4c5b6a"`
+	MaxUses   int       `json:"max_uses" example:"5"`
+	Uses      int       `json:"uses" example:"2"`
+	ExpiresAt time.Time `json:"expires_at" example:"2026-03-27T12:57:38Z"`
+	CreatedAt time.Time `json:"created_at" example:"2026-03-20T12:57:38Z"`
+}
+
+type invitesGetResponse []invitesGetResponseItem
+
+// InvitesGet returns invite codes created by the current user.
+//
+//	@Summary		Fetch invite codes
+//	@Description	Returns invite codes created by the current user, the most recent ones first. Codes themselves are not returned
+//	@Tags			user
+//	@Produce		json
+//	@Success		200	{object}	invitesGetResponse	"Successful operation"
+//	@Failure		500	{object}	model.Error			"Internal server error"
+//	@Security		Bearer
+//	@Router			/user/invites [get]
+func (h *Handler) InvitesGet(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch invite codes of the user from the database:
+	inviteCodes := make([]database.InviteCode, 0)
+	if err := h.database.SelectInviteCodesByCreatorID(r.Context(), user.ID, &inviteCodes); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+
+	// respond:
+	resp := make(invitesGetResponse, 0, len(inviteCodes))
+	for _, inviteCode := range inviteCodes {
+		resp = append(resp, invitesGetResponseItem{
+			UUID:      inviteCode.UUID.String(),
+			MaxUses:   inviteCode.MaxUses,
+			Uses:      inviteCode.Uses,
+			ExpiresAt: inviteCode.ExpiresAt,
+			CreatedAt: inviteCode.CreatedAt,
+		})
+	}
+	httpresp.Render(w, httpresp.NewOK(&resp))
+}
+
+type invitesDeleteResponse struct {
+	UUID string `json:"uuid" example:"5d3b9c1e-7a2f-4e6d-8b0c-1f2e3d4c5b6a"`
+}
+
+// InvitesDelete deletes an invite code created by the current user.
+//
+//	@Summary		Delete an invite code
+//	@Description	Deletes an invite code created by the current user, so that it can not be used anymore, and returns its UUID. Users created with the code are not affected
+//	@Tags			user
+//	@Produce		json
+//	@Param			uuid	path		string					true	"Invite code UUID"
+//	@Success		200		{object}	invitesDeleteResponse	"Successful operation"
+//	@Failure		403		{object}	model.Error				"Access to the invite code is forbidden"
+//	@Failure		404		{object}	model.Error				"Invite code not found"
+//	@Failure		500		{object}	model.Error				"Internal server error"
+//	@Security		Bearer
+//	@Router			/user/invites/{uuid} [delete]
+func (h *Handler) InvitesDelete(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch the invite code and check if it was created by the current user:
+	inviteCode := &database.InviteCode{}
+	if err := h.database.SelectInviteCodeByUUID(r.Context(), chi.URLParam(r, "uuid"), inviteCode); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpresp.Render(w, response.InviteCodeNotFound)
+			return
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+	if inviteCode.CreatorID != user.ID {
+		httpresp.Render(w, response.InviteCodeForbidden)
+		return
+	}
+
+	// delete the invite code:
+	if err := h.database.DeleteInviteCodeByID(r.Context(), inviteCode.ID); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	resp := &invitesDeleteResponse{UUID: inviteCode.UUID.String()}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
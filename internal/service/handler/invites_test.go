@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/groshi-project/groshi/internal/auth"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// testInviteCode creates an invite code of the first user of the mock database and returns the code.
+func testInviteCode(handler *Handler, maxUses int, expiresAt time.Time) string {
+	mockDb := handler.database.(*mockDatabase)
+	code, err := auth.NewInviteCode()
+	if err != nil {
+		panic(err)
+	}
+	mockDb.inviteCodes = append(mockDb.inviteCodes, &database.InviteCode{
+		ID:        int64(len(mockDb.inviteCodes) + 1),
+		CodeHash:  auth.HashInviteCode(code),
+		MaxUses:   maxUses,
+		CreatorID: mockDb.users[0].ID,
+		ExpiresAt: expiresAt,
+	})
+	return code
+}
+
+func TestHandler_InvitesCreate(t *testing.T) {
+	t.Run("create an invite code as a user allowed to invite", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testUserContext(context.Background(), handler, "test-username")
+			mockDb  = handler.database.(*mockDatabase)
+		)
+		testLogin(context.Background(), handler)
+		mockDb.users[0].CanInvite = true
+
+		rec := testRequest(ctx, &invitesCreateParams{MaxUses: 3}, handler.InvitesCreate)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			resp := &invitesCreateResponse{}
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(resp)) && assert.Len(t, mockDb.inviteCodes, 1) {
+				inviteCode := mockDb.inviteCodes[0]
+				assert.Equal(t, auth.HashInviteCode(resp.Code), inviteCode.CodeHash)
+				assert.Equal(t, 3, inviteCode.MaxUses)
+				assert.Equal(t, mockDb.users[0].ID, inviteCode.CreatorID)
+				assert.WithinDuration(t, time.Now().Add(defaultInviteCodeTTL), inviteCode.ExpiresAt, time.Minute)
+			}
+		}
+	})
+
+	t.Run("create a single-use invite code as an admin", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testAdminContext(handler)
+			mockDb  = handler.database.(*mockDatabase)
+		)
+
+		rec := testRequest(ctx, &invitesCreateParams{}, handler.InvitesCreate)
+		if assert.Equal(t, http.StatusOK, rec.Code) && assert.Len(t, mockDb.inviteCodes, 1) {
+			assert.Equal(t, 1, mockDb.inviteCodes[0].MaxUses)
+		}
+	})
+
+	t.Run("create an invite code as a user not allowed to invite", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testUserContext(context.Background(), handler, "test-username")
+		)
+		testLogin(context.Background(), handler)
+
+		rec := testRequest(ctx, &invitesCreateParams{}, handler.InvitesCreate)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, handler.database.(*mockDatabase).inviteCodes)
+	})
+
+	t.Run("create an invite code with expiration time in the past", func(t *testing.T) {
+		var (
+			handler   = newTestHandler()
+			ctx       = testAdminContext(handler)
+			expiresAt = time.Now().Add(-time.Hour)
+		)
+
+		rec := testRequest(ctx, &invitesCreateParams{ExpiresAt: &expiresAt}, handler.InvitesCreate)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestHandler_InvitesGet(t *testing.T) {
+	var (
+		handler = newTestHandler()
+		ctx     = testUserContext(context.Background(), handler, "test-username")
+	)
+	testLogin(context.Background(), handler)
+	testInviteCode(handler, 2, time.Now().Add(time.Hour))
+
+	rec := testGetRequest(ctx, "/user/invites", handler.InvitesGet)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		resp := invitesGetResponse{}
+		if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp)) && assert.Len(t, resp, 1) {
+			assert.Equal(t, 2, resp[0].MaxUses)
+			assert.Zero(t, resp[0].Uses)
+		}
+	}
+}
+
+func TestHandler_InvitesDelete(t *testing.T) {
+	t.Run("delete an invite code", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testUserContext(context.Background(), handler, "test-username")
+			mockDb  = handler.database.(*mockDatabase)
+		)
+		testLogin(context.Background(), handler)
+		testInviteCode(handler, 1, time.Now().Add(time.Hour))
+
+		rec := testRequest(withURLParam(ctx, "uuid", mockDb.inviteCodes[0].UUID.String()), nil, handler.InvitesDelete)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, mockDb.inviteCodes)
+	})
+
+	t.Run("delete an invite code of another user", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testAdminContext(handler)
+			mockDb  = handler.database.(*mockDatabase)
+		)
+		testInviteCode(handler, 1, time.Now().Add(time.Hour)) // created by "test-username"
+
+		rec := testRequest(withURLParam(ctx, "uuid", mockDb.inviteCodes[0].UUID.String()), nil, handler.InvitesDelete)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Len(t, mockDb.inviteCodes, 1)
+	})
+}
@@ -0,0 +1,547 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
+	"github.com/groshi-project/groshi/internal/service/handler/response"
+	"net/http"
+	"time"
+)
+
+// ledgerMember fetches membership of the user in the ledger and checks that its role grants permissions of the required role.
+// Renders the forbidden response if the user is not a member of the ledger or [response.LedgerRoleInsufficient]
+// if the role is not sufficient and returns false.
+func (h *Handler) ledgerMember(w http.ResponseWriter, r *http.Request, ledgerID int64, user *database.User, required string, forbidden *httpresp.Response) (*database.LedgerMember, bool) {
+	member := &database.LedgerMember{}
+	if err := h.database.SelectLedgerMember(r.Context(), ledgerID, user.ID, member); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpresp.Render(w, forbidden)
+			return nil, false
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return nil, false
+	}
+
+	if !database.LedgerRoleAllows(member.Role, required) {
+		httpresp.Render(w, response.LedgerRoleInsufficient)
+		return nil, false
+	}
+
+	return member, true
+}
+
+// ledgerByUUID fetches ledger with the given UUID and checks that the user has the required role in it.
+// Renders an error response and returns false if the ledger could not be fetched or is not accessible.
+func (h *Handler) ledgerByUUID(w http.ResponseWriter, r *http.Request, uuid string, user *database.User, required string) (*database.Ledger, *database.LedgerMember, bool) {
+	ledger := &database.Ledger{}
+	if err := h.database.SelectLedgerByUUID(r.Context(), uuid, ledger); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpresp.Render(w, response.LedgerNotFound)
+			return nil, nil, false
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return nil, nil, false
+	}
+
+	member, ok := h.ledgerMember(w, r, ledger.ID, user, required, response.LedgerForbidden)
+	if !ok {
+		return nil, nil, false
+	}
+	return ledger, member, true
+}
+
+// requestLedger fetches the ledger with UUID from the `ledger` URL query param, or the personal ledger of the user
+// if the param is not set, and checks that the user has the required role in it.
+// Renders an error response and returns false if the ledger could not be fetched or is not accessible.
+func (h *Handler) requestLedger(w http.ResponseWriter, r *http.Request, user *database.User, required string) (*database.Ledger, bool) {
+	if ledgerUUID := r.URL.Query().Get("ledger"); ledgerUUID != "" {
+		ledger, _, ok := h.ledgerByUUID(w, r, ledgerUUID, user, required)
+		return ledger, ok
+	}
+
+	// the user is always the owner of the personal ledger:
+	ledger := &database.Ledger{}
+	if err := h.database.SelectOrCreatePersonalLedger(r.Context(), user.ID, ledger); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return nil, false
+	}
+	return ledger, true
+}
+
+type ledgersCreateParams struct {
+	Name string `json:"name" example:"Household" validate:"required,max=128"`
+}
+
+type ledgersCreateResponse struct {
+	UUID string `json:"uuid" example:"6e2b1f0a-3c4d-4e5f-8a9b-0c1d2e3f4a5b"`
+}
+
+// LedgersCreate creates a new shared ledger and returns its UUID.
+//
+//	@Summary		Create a new ledger
+//	@Description	Creates a new ledger, which can be shared with other users, and returns its UUID. The current user becomes its owner
+//	@Tags			ledgers
+//	@Accept			json
+//	@Produce		json
+//	@Param			ledger	body		ledgersCreateParams		true	"Ledger name"
+//	@Success		200		{object}	ledgersCreateResponse	"Successful operation"
+//	@Failure		400		{object}	model.Error				"Invalid request body format or invalid request params"
+//	@Failure		500		{object}	model.Error				"Internal server error"
+//	@Security		Bearer
+//	@Router			/ledgers [post]
+func (h *Handler) LedgersCreate(w http.ResponseWriter, r *http.Request) {
+	// decode request params:
+	params := &ledgersCreateParams{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return
+	}
+
+	// validate request params:
+	if err := h.paramsValidate.Struct(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return
+	}
+
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// create a new ledger owned by the current user:
+	ledger := &database.Ledger{Name: params.Name}
+	if err := h.database.CreateLedger(r.Context(), ledger, user.ID); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	resp := &ledgersCreateResponse{UUID: ledger.UUID.String()}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
+
+type ledgersGetResponseItem struct {
+	UUID string `json:"uuid" example:"6e2b1f0a-3c4d-4e5f-8a9b-0c1d2e3f4a5b"`
+	Name string `json:"name" example:"Household"`
+
+	// True for the personal ledger of the user, which is used when no ledger is specified.
+	Personal bool `json:"personal" example:"false"`
+
+	// Role of the current user in the ledger.
+	Role string `json:"role" example:"editor" enums:"viewer,editor,owner"`
+}
+
+type ledgersGetResponse []ledgersGetResponseItem
+
+// LedgersGet returns ledgers the current user is a member of.
+//
+//	@Summary		Fetch ledgers
+//	@Description	Returns ledgers the current user is a member of, including the personal one, ordered by names
+//	@Tags			ledgers
+//	@Produce		json
+//	@Success		200	{object}	ledgersGetResponse	"Successful operation"
+//	@Failure		500	{object}	model.Error			"Internal server error"
+//	@Security		Bearer
+//	@Router			/ledgers [get]
+func (h *Handler) LedgersGet(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// make sure the personal ledger of the user exists:
+	if err := h.database.SelectOrCreatePersonalLedger(r.Context(), user.ID, &database.Ledger{}); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// fetch memberships of the user:
+	memberships := make([]database.LedgerMember, 0)
+	if err := h.database.SelectLedgerMembershipsByUserID(r.Context(), user.ID, &memberships); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+
+	// respond:
+	resp := make(ledgersGetResponse, 0, len(memberships))
+	for _, membership := range memberships {
+		resp = append(resp, ledgersGetResponseItem{
+			UUID:     membership.Ledger.UUID.String(),
+			Name:     membership.Ledger.Name,
+			Personal: membership.Ledger.PersonalUserID != 0,
+			Role:     membership.Role,
+		})
+	}
+	httpresp.Render(w, httpresp.NewOK(&resp))
+}
+
+type ledgersUpdateParams struct {
+	Name string `json:"name" example:"Household" validate:"required,max=128"`
+}
+
+// LedgersUpdate renames a ledger.
+//
+//	@Summary		Rename a ledger
+//	@Description	Renames a ledger. Available only for owners of the ledger
+//	@Tags			ledgers
+//	@Accept			json
+//	@Param			uuid	path	string				true	"Ledger UUID"
+//	@Param			ledger	body	ledgersUpdateParams	true	"Ledger name"
+//	@Success		204		"Successful operation"
+//	@Failure		400		{object}	model.Error	"Invalid request body format or invalid request params"
+//	@Failure		403		{object}	model.Error	"Access to the ledger is forbidden or the current user is not its owner"
+//	@Failure		404		{object}	model.Error	"Ledger not found"
+//	@Failure		500		{object}	model.Error	"Internal server error"
+//	@Security		Bearer
+//	@Router			/ledgers/{uuid} [put]
+func (h *Handler) LedgersUpdate(w http.ResponseWriter, r *http.Request) {
+	// decode request params:
+	params := &ledgersUpdateParams{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return
+	}
+
+	// validate request params:
+	if err := h.paramsValidate.Struct(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return
+	}
+
+	// fetch the current user and the ledger:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	ledger, _, ok := h.ledgerByUUID(w, r, chi.URLParam(r, "uuid"), user, database.LedgerRoleOwner)
+	if !ok {
+		return
+	}
+
+	// rename the ledger:
+	if err := h.database.UpdateLedgerName(r.Context(), ledger.ID, params.Name); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type ledgerMembersGetResponseItem struct {
+	UUID     string    `json:"uuid" example:"2c1a8e3b-6f4d-4b7a-9c2e-1d3f5a7b9c0e"`
+	Username string    `json:"username" example:"jieggii"`
+	Role     string    `json:"role" example:"editor" enums:"viewer,editor,owner"`
+	AddedAt  time.Time `json:"added_at" example:"2026-03-20T12:57:38Z"`
+}
+
+type ledgerMembersGetResponse []ledgerMembersGetResponseItem
+
+// LedgerMembersGet returns members of a ledger.
+//
+//	@Summary		Fetch ledger members
+//	@Description	Returns members of a ledger ordered by usernames. Available for all members of the ledger
+//	@Tags			ledgers
+//	@Produce		json
+//	@Param			uuid	path		string						true	"Ledger UUID"
+//	@Success		200		{object}	ledgerMembersGetResponse	"Successful operation"
+//	@Failure		403		{object}	model.Error					"Access to the ledger is forbidden"
+//	@Failure		404		{object}	model.Error					"Ledger not found"
+//	@Failure		500		{object}	model.Error					"Internal server error"
+//	@Security		Bearer
+//	@Router			/ledgers/{uuid}/members [get]
+func (h *Handler) LedgerMembersGet(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user and the ledger:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	ledger, _, ok := h.ledgerByUUID(w, r, chi.URLParam(r, "uuid"), user, database.LedgerRoleViewer)
+	if !ok {
+		return
+	}
+
+	// fetch members of the ledger:
+	members := make([]database.LedgerMember, 0)
+	if err := h.database.SelectLedgerMembers(r.Context(), ledger.ID, &members); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+
+	// respond:
+	resp := make(ledgerMembersGetResponse, 0, len(members))
+	for _, member := range members {
+		resp = append(resp, ledgerMembersGetResponseItem{
+			UUID:     member.User.UUID.String(),
+			Username: member.User.Username,
+			Role:     member.Role,
+			AddedAt:  member.CreatedAt,
+		})
+	}
+	httpresp.Render(w, httpresp.NewOK(&resp))
+}
+
+type ledgerMembersAddParams struct {
+	Username string `json:"username" example:"jieggii" validate:"required"`
+	Role     string `json:"role" example:"editor" validate:"required,oneof=viewer editor owner"`
+}
+
+// LedgerMembersAdd adds a user to a ledger.
+//
+//	@Summary		Add a ledger member
+//	@Description	Gives the user with the given username access to a ledger with the given role.
+//	@Description	Viewers can read categories and transactions of the ledger, editors can also change them, owners can also rename the ledger and manage its members.
+//	@Description	Personal ledgers can not be shared. Available only for owners of the ledger
+//	@Tags			ledgers
+//	@Accept			json
+//	@Param			uuid	path	string					true	"Ledger UUID"
+//	@Param			member	body	ledgerMembersAddParams	true	"Username and role"
+//	@Success		204		"Successful operation"
+//	@Failure		400		{object}	model.Error	"Invalid request body format or invalid request params"
+//	@Failure		403		{object}	model.Error	"Access to the ledger is forbidden or the current user is not its owner"
+//	@Failure		404		{object}	model.Error	"Ledger or user not found"
+//	@Failure		409		{object}	model.Error	"The ledger is personal or the user is already its member"
+//	@Failure		500		{object}	model.Error	"Internal server error"
+//	@Security		Bearer
+//	@Router			/ledgers/{uuid}/members [post]
+func (h *Handler) LedgerMembersAdd(w http.ResponseWriter, r *http.Request) {
+	// decode request params:
+	params := &ledgerMembersAddParams{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return
+	}
+
+	// validate request params:
+	if err := h.paramsValidate.Struct(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return
+	}
+
+	// fetch the current user and the ledger:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	ledger, _, ok := h.ledgerByUUID(w, r, chi.URLParam(r, "uuid"), user, database.LedgerRoleOwner)
+	if !ok {
+		return
+	}
+	if ledger.PersonalUserID != 0 {
+		httpresp.Render(w, response.PersonalLedgerNotShareable)
+		return
+	}
+
+	// fetch the user to add:
+	invitee := &database.User{}
+	if err := h.database.SelectUserByUsername(r.Context(), params.Username, invitee); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpresp.Render(w, response.UserNotFound)
+			return
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// add the user unless it is already a member:
+	err := h.database.SelectLedgerMember(r.Context(), ledger.ID, invitee.ID, &database.LedgerMember{})
+	switch {
+	case err == nil:
+		httpresp.Render(w, response.LedgerMemberExists)
+		return
+	case !errors.Is(err, sql.ErrNoRows):
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+	member := &database.LedgerMember{
+		LedgerID: ledger.ID,
+		UserID:   invitee.ID,
+		Role:     params.Role,
+	}
+	if err := h.database.CreateLedgerMember(r.Context(), member); err != nil {
+		// the user may be added concurrently by another request:
+		if database.IsUniqueViolation(err) {
+			httpresp.Render(w, response.LedgerMemberExists)
+			return
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ledgerMemberByUsername fetches membership in the ledger of the user with the username.
+// Renders an error response and returns false if the membership could not be fetched.
+func (h *Handler) ledgerMemberByUsername(w http.ResponseWriter, r *http.Request, ledger *database.Ledger, username string) (*database.LedgerMember, bool) {
+	user := &database.User{}
+	if err := h.database.SelectUserByUsername(r.Context(), username, user); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpresp.Render(w, response.LedgerMemberNotFound)
+			return nil, false
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return nil, false
+	}
+	return h.ledgerMember(w, r, ledger.ID, user, database.LedgerRoleViewer, response.LedgerMemberNotFound)
+}
+
+// ledgerMemberByUserUUID fetches membership in the ledger of the user with the UUID.
+// Renders an error response and returns false if the membership could not be fetched.
+func (h *Handler) ledgerMemberByUserUUID(w http.ResponseWriter, r *http.Request, ledger *database.Ledger, userUUID string) (*database.LedgerMember, bool) {
+	user := &database.User{}
+	if err := h.database.SelectUserByUUID(r.Context(), userUUID, user); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpresp.Render(w, response.LedgerMemberNotFound)
+			return nil, false
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return nil, false
+	}
+	return h.ledgerMember(w, r, ledger.ID, user, database.LedgerRoleViewer, response.LedgerMemberNotFound)
+}
+
+type ledgerMembersUpdateParams struct {
+	Role string `json:"role" example:"viewer" validate:"required,oneof=viewer editor owner"`
+}
+
+// LedgerMembersUpdate changes role of a ledger member.
+//
+//	@Summary		Change role of a ledger member
+//	@Description	Changes role of a ledger member. The last owner of the ledger can not be demoted. Available only for owners of the ledger
+//	@Tags			ledgers
+//	@Accept			json
+//	@Param			uuid		path	string						true	"Ledger UUID"
+//	@Param			username	path	string						true	"Username of the member"
+//	@Param			member		body	ledgerMembersUpdateParams	true	"Role"
+//	@Success		204			"Successful operation"
+//	@Failure		400			{object}	model.Error	"Invalid request body format or invalid request params"
+//	@Failure		403			{object}	model.Error	"Access to the ledger is forbidden or the current user is not its owner"
+//	@Failure		404			{object}	model.Error	"Ledger or member not found"
+//	@Failure		409			{object}	model.Error	"The member is the last owner of the ledger"
+//	@Failure		500			{object}	model.Error	"Internal server error"
+//	@Security		Bearer
+//	@Router			/ledgers/{uuid}/members/{username} [put]
+func (h *Handler) LedgerMembersUpdate(w http.ResponseWriter, r *http.Request) {
+	// decode request params:
+	params := &ledgerMembersUpdateParams{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return
+	}
+
+	// validate request params:
+	if err := h.paramsValidate.Struct(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return
+	}
+
+	// fetch the current user, the ledger and the member:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	ledger, _, ok := h.ledgerByUUID(w, r, chi.URLParam(r, "uuid"), user, database.LedgerRoleOwner)
+	if !ok {
+		return
+	}
+	member, ok := h.ledgerMemberByUsername(w, r, ledger, chi.URLParam(r, "username"))
+	if !ok {
+		return
+	}
+
+	// change role of the member unless it is the last owner of the ledger:
+	if err := h.database.UpdateLedgerMemberRole(r.Context(), member.ID, params.Role); err != nil {
+		if errors.Is(err, database.ErrLastLedgerOwner) {
+			httpresp.Render(w, response.LastLedgerOwner)
+			return
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LedgerMembersDelete removes a member from a ledger.
+//
+//	@Summary		Remove a ledger member
+//	@Description	Removes a member from a ledger, categories and transactions created by the member stay in the ledger.
+//	@Description	Owners can remove any member, other members can only leave the ledger by removing themselves. The last owner of the ledger can not be removed.
+//	@Description	Members who owe or are owed money by shared expenses of the ledger must settle up before they are removed
+//	@Tags			ledgers
+//	@Param			uuid		path	string	true	"Ledger UUID"
+//	@Param			username	path	string	true	"Username of the member"
+//	@Success		204			"Successful operation"
+//	@Failure		403			{object}	model.Error	"Access to the ledger is forbidden or the current user is not its owner"
+//	@Failure		404			{object}	model.Error	"Ledger or member not found"
+//	@Failure		409			{object}	model.Error	"The member is the last owner of the ledger or has a non-zero balance"
+//	@Failure		500			{object}	model.Error	"Internal server error"
+//	@Security		Bearer
+//	@Router			/ledgers/{uuid}/members/{username} [delete]
+func (h *Handler) LedgerMembersDelete(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user, the ledger and the member:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	ledger, current, ok := h.ledgerByUUID(w, r, chi.URLParam(r, "uuid"), user, database.LedgerRoleViewer)
+	if !ok {
+		return
+	}
+	member, ok := h.ledgerMemberByUsername(w, r, ledger, chi.URLParam(r, "username"))
+	if !ok {
+		return
+	}
+
+	// only owners can remove other members:
+	if member.ID != current.ID && !database.LedgerRoleAllows(current.Role, database.LedgerRoleOwner) {
+		httpresp.Render(w, response.LedgerRoleInsufficient)
+		return
+	}
+
+	// debts of removed members could not be settled, so the member must settle up first:
+	if !h.ledgerMemberSettled(w, r, ledger, member) {
+		return
+	}
+
+	// remove the member unless it is the last owner of the ledger:
+	if err := h.database.DeleteLedgerMemberByID(r.Context(), member.ID); err != nil {
+		if errors.Is(err, database.ErrLastLedgerOwner) {
+			httpresp.Render(w, response.LastLedgerOwner)
+			return
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testPersonalLedgerID returns ID of the personal ledger of the user, the ledger is created if it does not exist.
+func testPersonalLedgerID(db *mockDatabase, userID int64) int64 {
+	ledger := &database.Ledger{}
+	if err := db.SelectOrCreatePersonalLedger(context.Background(), userID, ledger); err != nil {
+		panic(err)
+	}
+	return ledger.ID
+}
+
+// testSharedLedger creates a shared ledger owned by the first user and adds other users to it with the given roles.
+func testSharedLedger(db *mockDatabase, ownerID int64, roles map[int64]string) *database.Ledger {
+	ctx := context.Background()
+	ledger := &database.Ledger{Name: "Household"}
+	if err := db.CreateLedger(ctx, ledger, ownerID); err != nil {
+		panic(err)
+	}
+	for userID, role := range roles {
+		if err := db.CreateLedgerMember(ctx, &database.LedgerMember{LedgerID: ledger.ID, UserID: userID, Role: role}); err != nil {
+			panic(err)
+		}
+	}
+	return ledger
+}
+
+func TestHandler_Ledgers(t *testing.T) {
+	const (
+		ownerID       int64 = 1
+		ownerUsername       = "owner"
+		guestID       int64 = 2
+		guestUsername       = "guest"
+	)
+
+	newEnv := func() (*Handler, *mockDatabase) {
+		handler := newTestHandler()
+		db := handler.database.(*mockDatabase)
+		for id, username := range map[int64]string{ownerID: ownerUsername, guestID: guestUsername} {
+			if err := db.CreateUser(context.Background(), &database.User{ID: id, Username: username}); err != nil {
+				panic(err)
+			}
+		}
+		return handler, db
+	}
+
+	t.Run("create a ledger and list it together with the personal one", func(t *testing.T) {
+		handler, _ := newEnv()
+		ctx := testUserContext(context.Background(), handler, ownerUsername)
+
+		rec := testRequest(ctx, &ledgersCreateParams{Name: "Household"}, handler.LedgersCreate)
+		if !assert.Equal(t, http.StatusOK, rec.Code) {
+			return
+		}
+		created := &ledgersCreateResponse{}
+		if !assert.NoError(t, json.NewDecoder(rec.Body).Decode(created)) {
+			return
+		}
+
+		rec = testGetRequest(ctx, "/ledgers", handler.LedgersGet)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			resp := ledgersGetResponse{}
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp)) && assert.Len(t, resp, 2) {
+				assert.Equal(t, ledgersGetResponseItem{UUID: created.UUID, Name: "Household", Role: database.LedgerRoleOwner}, resp[0])
+				assert.Equal(t, "Personal", resp[1].Name)
+				assert.True(t, resp[1].Personal)
+				assert.Equal(t, database.LedgerRoleOwner, resp[1].Role)
+			}
+		}
+	})
+
+	t.Run("add a member to a shared ledger", func(t *testing.T) {
+		handler, db := newEnv()
+		ledger := testSharedLedger(db, ownerID, nil)
+
+		ctx := withURLParam(testUserContext(context.Background(), handler, ownerUsername), "uuid", ledger.UUID.String())
+		params := &ledgerMembersAddParams{Username: guestUsername, Role: database.LedgerRoleViewer}
+		rec := testRequest(ctx, params, handler.LedgerMembersAdd)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+
+		// adding the same user again is a conflict:
+		rec = testRequest(ctx, params, handler.LedgerMembersAdd)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+
+		rec = testGetRequest(ctx, "/", handler.LedgerMembersGet)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			resp := ledgerMembersGetResponse{}
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp)) && assert.Len(t, resp, 2) {
+				assert.Equal(t, guestUsername, resp[0].Username)
+				assert.Equal(t, database.LedgerRoleViewer, resp[0].Role)
+				assert.Equal(t, ownerUsername, resp[1].Username)
+			}
+		}
+	})
+
+	t.Run("add a member to the personal ledger", func(t *testing.T) {
+		handler, db := newEnv()
+		ledger := &database.Ledger{}
+		if err := db.SelectOrCreatePersonalLedger(context.Background(), ownerID, ledger); err != nil {
+			panic(err)
+		}
+
+		ctx := withURLParam(testUserContext(context.Background(), handler, ownerUsername), "uuid", ledger.UUID.String())
+		rec := testRequest(ctx, &ledgerMembersAddParams{Username: guestUsername, Role: database.LedgerRoleViewer}, handler.LedgerMembersAdd)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("manage members without being an owner", func(t *testing.T) {
+		handler, db := newEnv()
+		ledger := testSharedLedger(db, ownerID, map[int64]string{guestID: database.LedgerRoleEditor})
+
+		ctx := withURLParam(testUserContext(context.Background(), handler, guestUsername), "uuid", ledger.UUID.String())
+		rec := testRequest(ctx, &ledgersUpdateParams{Name: "Renamed"}, handler.LedgersUpdate)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		ctx = withURLParam(ctx, "username", ownerUsername)
+		rec = testRequest(ctx, nil, handler.LedgerMembersDelete)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("access a ledger without being its member", func(t *testing.T) {
+		handler, db := newEnv()
+		ledger := testSharedLedger(db, ownerID, nil)
+
+		ctx := withURLParam(testUserContext(context.Background(), handler, guestUsername), "uuid", ledger.UUID.String())
+		rec := testGetRequest(ctx, "/", handler.LedgerMembersGet)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("viewer reads transactions of a shared ledger but can not create them", func(t *testing.T) {
+		handler, db := newEnv()
+		ledger := testSharedLedger(db, ownerID, map[int64]string{guestID: database.LedgerRoleViewer})
+		db.currencies = append(db.currencies, &database.Currency{ID: 1, Code: "USD", Rate: 1})
+		if err := db.CreateTransaction(context.Background(), &database.Transaction{
+			Amount:     -1500,
+			CurrencyID: 1,
+			Timestamp:  time.Now(),
+			LedgerID:   ledger.ID,
+			OwnerID:    ownerID,
+		}); err != nil {
+			panic(err)
+		}
+
+		ctx := testUserContext(context.Background(), handler, guestUsername)
+		rec := testGetRequest(ctx, "/transactions?ledger="+ledger.UUID.String(), handler.TransactionsGet)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			resp := transactionsGetResponse{}
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp)) {
+				assert.Len(t, resp, 1)
+			}
+		}
+
+		// transactions of the shared ledger are not visible in the personal one:
+		rec = testGetRequest(ctx, "/transactions", handler.TransactionsGet)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			resp := transactionsGetResponse{}
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp)) {
+				assert.Len(t, resp, 0)
+			}
+		}
+
+		body, err := json.Marshal(&transactionsCreateParams{Amount: -500, CurrencyCode: "USD", Timestamp: time.Now()})
+		if err != nil {
+			panic(err)
+		}
+		rec = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/transactions?ledger="+ledger.UUID.String(), bytes.NewReader(body))
+		handler.TransactionsCreate(rec, req.WithContext(ctx))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("demote and remove the last owner", func(t *testing.T) {
+		handler, db := newEnv()
+		ledger := testSharedLedger(db, ownerID, map[int64]string{guestID: database.LedgerRoleEditor})
+
+		ctx := withURLParam(testUserContext(context.Background(), handler, ownerUsername), "uuid", ledger.UUID.String())
+		ctx = withURLParam(ctx, "username", ownerUsername)
+		rec := testRequest(ctx, &ledgerMembersUpdateParams{Role: database.LedgerRoleEditor}, handler.LedgerMembersUpdate)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		rec = testRequest(ctx, nil, handler.LedgerMembersDelete)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+
+		// once another owner exists, the first one can leave:
+		guestCtx := withURLParam(testUserContext(context.Background(), handler, ownerUsername), "uuid", ledger.UUID.String())
+		guestCtx = withURLParam(guestCtx, "username", guestUsername)
+		rec = testRequest(guestCtx, &ledgerMembersUpdateParams{Role: database.LedgerRoleOwner}, handler.LedgerMembersUpdate)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		rec = testRequest(ctx, nil, handler.LedgerMembersDelete)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+
+		err := db.SelectLedgerMember(context.Background(), ledger.ID, ownerID, &database.LedgerMember{})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
+	"github.com/groshi-project/groshi/internal/service/handler/response"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// renderTooManyLoginAttempts renders [response.TooManyLoginAttempts] with `Retry-After` header.
+func renderTooManyLoginAttempts(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	httpresp.Render(w, response.TooManyLoginAttempts)
+}
+
+// loginAllowed checks if a login attempt of the username from the client IP address is allowed.
+// Renders an error response and returns false if it is not.
+func (h *Handler) loginAllowed(w http.ResponseWriter, r *http.Request, username string) bool {
+	retryAfter, err := h.loginGuard.Check(r.Context(), username, clientIP(r))
+	if err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return false
+	}
+	if retryAfter > 0 {
+		renderTooManyLoginAttempts(w, retryAfter)
+		return false
+	}
+	return true
+}
+
+// loginFailed registers a failed login attempt of the username from the client IP address and renders the failure response,
+// or [response.TooManyLoginAttempts] if the failure blocks next attempts.
+func (h *Handler) loginFailed(w http.ResponseWriter, r *http.Request, username string, failure *httpresp.Response) {
+	retryAfter, err := h.loginGuard.Fail(r.Context(), username, clientIP(r))
+	if err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+	if retryAfter > 0 {
+		renderTooManyLoginAttempts(w, retryAfter)
+		return
+	}
+	httpresp.Render(w, failure)
+}
+
+// loginSucceeded resets failed login attempts of the username.
+// Renders an error response and returns false if it fails.
+func (h *Handler) loginSucceeded(w http.ResponseWriter, r *http.Request, username string) bool {
+	if err := h.loginGuard.Succeed(r.Context(), username); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestHandler_AuthLogin_LoginGuard(t *testing.T) {
+	const (
+		testUsername = "test-username"
+		testPassword = "test-password"
+	)
+
+	// newHandler creates a new test handler with a test user.
+	newHandler := func() *Handler {
+		handler := newTestHandler()
+		if err := handler.database.CreateUser(context.Background(), &database.User{
+			Username: testUsername,
+			Password: "hash(test-password)",
+		}); err != nil {
+			panic(err)
+		}
+		return handler
+	}
+
+	t.Run("delay attempts after failures", func(t *testing.T) {
+		handler := newHandler()
+		ctx := context.Background()
+
+		for i := 0; i < handler.loginGuard.Config().FreeAttempts-1; i++ {
+			rec := testRequest(ctx, &authLoginParams{Username: testUsername, Password: "wrong-password"}, handler.AuthLogin)
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		}
+
+		// the failure which starts the delay is reported as too many attempts:
+		rec := testRequest(ctx, &authLoginParams{Username: testUsername, Password: "wrong-password"}, handler.AuthLogin)
+		if assert.Equal(t, http.StatusTooManyRequests, rec.Code) {
+			retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+			if assert.NoError(t, err) {
+				assert.Positive(t, retryAfter)
+			}
+		}
+
+		// even the correct password is rejected until the delay passes:
+		rec = testRequest(ctx, &authLoginParams{Username: testUsername, Password: testPassword}, handler.AuthLogin)
+		if assert.Equal(t, http.StatusTooManyRequests, rec.Code) {
+			retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+			if assert.NoError(t, err) {
+				assert.Positive(t, retryAfter)
+			}
+		}
+	})
+
+	t.Run("count failures of unknown users", func(t *testing.T) {
+		handler := newHandler()
+		ctx := context.Background()
+
+		for i := 0; i < handler.loginGuard.Config().FreeAttempts; i++ {
+			testRequest(ctx, &authLoginParams{Username: "unknown-user", Password: testPassword}, handler.AuthLogin)
+		}
+		rec := testRequest(ctx, &authLoginParams{Username: "unknown-user", Password: testPassword}, handler.AuthLogin)
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	})
+
+	t.Run("reset failures after successful login", func(t *testing.T) {
+		handler := newHandler()
+		ctx := context.Background()
+
+		for i := 0; i < handler.loginGuard.Config().FreeAttempts-1; i++ {
+			testRequest(ctx, &authLoginParams{Username: testUsername, Password: "wrong-password"}, handler.AuthLogin)
+		}
+		rec := testRequest(ctx, &authLoginParams{Username: testUsername, Password: testPassword}, handler.AuthLogin)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		count, _, err := handler.database.LoginFailures(ctx, "user:"+testUsername)
+		if assert.NoError(t, err) {
+			assert.Zero(t, count)
+		}
+	})
+
+	t.Run("unlock the user", func(t *testing.T) {
+		handler := newHandler()
+		ctx := context.Background()
+
+		for i := 0; i < handler.loginGuard.Config().FreeAttempts; i++ {
+			testRequest(ctx, &authLoginParams{Username: testUsername, Password: "wrong-password"}, handler.AuthLogin)
+		}
+		assert.NoError(t, handler.loginGuard.Unlock(ctx, testUsername))
+
+		// failures of the IP address are still counted, so the user is checked from another address:
+		retryAfter, err := handler.loginGuard.Check(ctx, testUsername, "198.51.100.1")
+		if assert.NoError(t, err) {
+			assert.Zero(t, retryAfter)
+		}
+	})
+}
@@ -5,14 +5,24 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/groshi-project/groshi/internal/auth"
 	"github.com/groshi-project/groshi/internal/database"
+	"github.com/groshi-project/groshi/internal/middleware"
+	"github.com/groshi-project/groshi/internal/service/job"
 	"io"
 	"log"
 	"math/rand"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -32,37 +42,127 @@ func (m *mockPasswordAuthenticator) VerifyPassword(password string, hash string)
 		return false, err
 	}
 
-	if givenPasswordHash == hash {
+	if givenPasswordHash == hash || fmt.Sprintf("old-hash(%s)", password) == hash {
 		return true, nil
 	}
 
 	return false, nil
 }
 
+// NeedsRehash reports hashes with "old-hash" prefix as outdated.
+func (m *mockPasswordAuthenticator) NeedsRehash(hash string) bool {
+	return strings.HasPrefix(hash, "old-hash(")
+}
+
 type mockJWTAuthenticator struct{}
 
 func newMockJWTAuthenticator() *mockJWTAuthenticator {
 	return &mockJWTAuthenticator{}
 }
 
-func (m *mockJWTAuthenticator) CreateToken(username string) (token string, expires time.Time, err error) {
-	return "token", time.Now(), nil
+func (m *mockJWTAuthenticator) CreateToken(claims *auth.TokenClaims) (token string, expires time.Time, err error) {
+	return "token", time.Now().Add(time.Hour), nil
 }
 
 func (m *mockJWTAuthenticator) VerifyToken(token string) (jwt.MapClaims, error) {
 	return nil, nil
 }
 
+func (m *mockJWTAuthenticator) CreateChallengeToken(claims *auth.ChallengeClaims) (string, time.Time, error) {
+	token := fmt.Sprintf("challenge:%s:%s:%d:%s", claims.UserID, claims.TokenID, claims.Version, claims.DeviceName)
+	return token, time.Now().Add(auth.ChallengeTokenTTL), nil
+}
+
+func (m *mockJWTAuthenticator) VerifyChallengeToken(token string) (*auth.ChallengeClaims, error) {
+	parts := strings.Split(token, ":")
+	if len(parts) != 5 || parts[0] != "challenge" {
+		return nil, jwt.ErrTokenMalformed
+	}
+	version, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return nil, jwt.ErrTokenMalformed
+	}
+	return &auth.ChallengeClaims{UserID: parts[1], TokenID: parts[2], Version: version, DeviceName: parts[4]}, nil
+}
+
+func (m *mockJWTAuthenticator) CreateOIDCStateToken(state *auth.OIDCState) (string, time.Time, error) {
+	token := strings.Join([]string{"oidc", state.State, state.Nonce, state.CodeVerifier, state.DeviceName}, "|")
+	return token, time.Now().Add(auth.OIDCStateTokenTTL), nil
+}
+
+func (m *mockJWTAuthenticator) VerifyOIDCStateToken(token string, state string) (*auth.OIDCState, error) {
+	parts := strings.Split(token, "|")
+	if len(parts) != 5 || parts[0] != "oidc" {
+		return nil, jwt.ErrTokenMalformed
+	}
+	if parts[1] != state {
+		return nil, auth.ErrOIDCStateMismatch
+	}
+	return &auth.OIDCState{State: parts[1], Nonce: parts[2], CodeVerifier: parts[3], DeviceName: parts[4]}, nil
+}
+
+func (m *mockJWTAuthenticator) JWKS() *auth.JWKSet {
+	return &auth.JWKSet{Keys: []auth.JWK{{KeyType: "OKP", KeyID: "test-key-id", Use: "sig", Algorithm: "EdDSA", Curve: "Ed25519", X: "test-x"}}}
+}
+
 type mockDatabase struct {
 	users []*database.User
 
 	categories []*database.Category
+
+	currencies []*database.Currency
+
+	tags []*database.Tag
+
+	payees []*database.Payee
+
+	transactions []*database.Transaction
+
+	rules []*database.Rule
+
+	importReports []*database.ImportReport
+
+	sessions []*database.Session
+
+	refreshTokens []*database.RefreshToken
+
+	revokedTokens map[string]bool
+
+	apiKeys []*database.APIKey
+
+	externalIdentities []*database.ExternalIdentity
+
+	inviteCodes []*database.InviteCode
+
+	ledgers []*database.Ledger
+
+	ledgerMembers []*database.LedgerMember
+
+	// counters of failed login attempts are stored in memory.
+	*auth.MemoryLoginFailureStore
 }
 
 func newMockDatabase() *mockDatabase {
 	return &mockDatabase{
-		users:      make([]*database.User, 0),
-		categories: make([]*database.Category, 0),
+		users:         make([]*database.User, 0),
+		categories:    make([]*database.Category, 0),
+		currencies:    make([]*database.Currency, 0),
+		tags:          make([]*database.Tag, 0),
+		payees:        make([]*database.Payee, 0),
+		transactions:  make([]*database.Transaction, 0),
+		rules:         make([]*database.Rule, 0),
+		importReports: make([]*database.ImportReport, 0),
+		sessions:      make([]*database.Session, 0),
+		refreshTokens: make([]*database.RefreshToken, 0),
+		revokedTokens: make(map[string]bool),
+		apiKeys:       make([]*database.APIKey, 0),
+
+		externalIdentities: make([]*database.ExternalIdentity, 0),
+		inviteCodes:        make([]*database.InviteCode, 0),
+		ledgers:            make([]*database.Ledger, 0),
+		ledgerMembers:      make([]*database.LedgerMember, 0),
+
+		MemoryLoginFailureStore: auth.NewMemoryLoginFailureStore(),
 	}
 }
 
@@ -74,10 +174,20 @@ func (m *mockDatabase) Init(ctx context.Context) error {
 	panic("implement me")
 }
 
+func (m *mockDatabase) RunInTx(ctx context.Context, fn func(ctx context.Context, db database.Database) error) error {
+	return fn(ctx, m)
+}
+
 func (m *mockDatabase) CreateUser(ctx context.Context, u *database.User) error {
 	if u.ID == 0 {
 		u.ID = int64(rand.Intn(9999) + 1)
 	}
+	if u.UUID == uuid.Nil {
+		u.UUID = uuid.New()
+	}
+	if u.Role == "" {
+		u.Role = database.RoleUser
+	}
 	m.users = append(m.users, u)
 	return nil
 }
@@ -101,10 +211,20 @@ func (m *mockDatabase) SelectUserByUsername(ctx context.Context, username string
 	return sql.ErrNoRows
 }
 
-func (m *mockDatabase) DeleteUserByUsername(ctx context.Context, username string) error {
+func (m *mockDatabase) SelectUserByUUID(ctx context.Context, uuid string, u *database.User) error {
+	for _, user := range m.users {
+		if uuid == user.UUID.String() {
+			*u = *user
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) DeleteUserByID(ctx context.Context, id int64) error {
 	userIndex := -1
 	for i, user := range m.users {
-		if username == user.Username {
+		if id == user.ID {
 			userIndex = i
 			break
 		}
@@ -113,12 +233,268 @@ func (m *mockDatabase) DeleteUserByUsername(ctx context.Context, username string
 		return nil
 	}
 
+	// shared ledgers must keep at least one owner:
+	personalLedgerIDs := make(map[int64]bool)
+	for _, ledger := range m.ledgers {
+		if ledger.PersonalUserID == id {
+			personalLedgerIDs[ledger.ID] = true
+		}
+	}
+	for _, member := range m.ledgerMembers {
+		if member.UserID == id && !personalLedgerIDs[member.LedgerID] {
+			if err := m.checkLedgerKeepsOwner(member); err != nil {
+				return err
+			}
+		}
+	}
+
+	// debts of deleted users could not be settled:
+	type key struct {
+		ledgerID, currencyID int64
+	}
+	balances := make(map[key]int64)
+	for _, transaction := range m.transactions {
+		for _, share := range transaction.Shares {
+			if share.UserID == transaction.PayerID {
+				continue
+			}
+			switch id {
+			case share.UserID:
+				balances[key{transaction.LedgerID, transaction.CurrencyID}] -= int64(share.Amount)
+			case transaction.PayerID:
+				balances[key{transaction.LedgerID, transaction.CurrencyID}] += int64(share.Amount)
+			}
+		}
+	}
+	for _, balance := range balances {
+		if balance != 0 {
+			return database.ErrUserHasBalance
+		}
+	}
+
+	// delete the personal ledger and memberships of the user:
+	ledgerMembers := make([]*database.LedgerMember, 0, len(m.ledgerMembers))
+	for _, member := range m.ledgerMembers {
+		if member.UserID != id && !personalLedgerIDs[member.LedgerID] {
+			ledgerMembers = append(ledgerMembers, member)
+		}
+	}
+	m.ledgerMembers = ledgerMembers
+	ledgers := make([]*database.Ledger, 0, len(m.ledgers))
+	for _, ledger := range m.ledgers {
+		if !personalLedgerIDs[ledger.ID] {
+			ledgers = append(ledgers, ledger)
+		}
+	}
+	m.ledgers = ledgers
+	transactions := make([]*database.Transaction, 0, len(m.transactions))
+	for _, transaction := range m.transactions {
+		if !personalLedgerIDs[transaction.LedgerID] {
+			transactions = append(transactions, transaction)
+		}
+	}
+	m.transactions = transactions
+	categories := make([]*database.Category, 0, len(m.categories))
+	for _, category := range m.categories {
+		if !personalLedgerIDs[category.LedgerID] {
+			categories = append(categories, category)
+		}
+	}
+	m.categories = categories
+
 	m.users[userIndex] = m.users[len(m.users)-1]
 	m.users = m.users[:len(m.users)-1]
 
 	return nil
 }
 
+func (m *mockDatabase) UpdateUsername(ctx context.Context, id int64, username string) error {
+	for _, user := range m.users {
+		if user.Username == username && user.ID != id {
+			return database.ErrUniqueViolation
+		}
+	}
+	for _, user := range m.users {
+		if user.ID == id {
+			user.Username = username
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) UpdateUserPasswordHash(ctx context.Context, id int64, passwordHash string) error {
+	for _, user := range m.users {
+		if user.ID == id {
+			user.Password = passwordHash
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) ChangeUserPassword(ctx context.Context, id int64, passwordHash string) error {
+	for _, user := range m.users {
+		if user.ID == id {
+			user.Password = passwordHash
+			user.TokenVersion++
+			m.revokeSessions(func(session *database.Session) bool {
+				return session.OwnerID == id
+			})
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) SetUserPassword(ctx context.Context, id int64, passwordHash string) (bool, error) {
+	for _, user := range m.users {
+		if user.ID == id {
+			if user.Password != "" {
+				return false, nil
+			}
+			user.Password = passwordHash
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockDatabase) UpdateUserRole(ctx context.Context, id int64, role string) error {
+	for _, user := range m.users {
+		if user.ID == id {
+			user.Role = role
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) UpdateUserCanInvite(ctx context.Context, id int64, canInvite bool) error {
+	for _, user := range m.users {
+		if user.ID == id {
+			user.CanInvite = canInvite
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) DisableUser(ctx context.Context, id int64) error {
+	for _, user := range m.users {
+		if user.ID == id {
+			if user.DisabledAt.IsZero() {
+				user.DisabledAt = time.Now()
+			}
+			m.revokeSessions(func(session *database.Session) bool {
+				return session.OwnerID == id
+			})
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) EnableUser(ctx context.Context, id int64) error {
+	for _, user := range m.users {
+		if user.ID == id {
+			user.DisabledAt = time.Time{}
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) SelectUsersUsage(ctx context.Context, u *[]database.UserUsage) error {
+	for _, user := range m.users {
+		usage := database.UserUsage{User: *user}
+		for _, transaction := range m.transactions {
+			if transaction.OwnerID == user.ID {
+				usage.Transactions++
+			}
+		}
+		for _, category := range m.categories {
+			if category.OwnerID == user.ID {
+				usage.Categories++
+			}
+		}
+		for _, tag := range m.tags {
+			if tag.OwnerID == user.ID {
+				usage.Tags++
+			}
+		}
+		for _, rule := range m.rules {
+			if rule.OwnerID == user.ID {
+				usage.Rules++
+			}
+		}
+		for _, apiKey := range m.apiKeys {
+			if apiKey.OwnerID == user.ID {
+				usage.APIKeys++
+			}
+		}
+		for _, session := range m.sessions {
+			if session.OwnerID != user.ID {
+				continue
+			}
+			if session.RevokedAt.IsZero() && session.ExpiresAt.After(time.Now()) {
+				usage.ActiveSessions++
+			}
+			if session.LastSeenAt.After(usage.LastSeenAt) {
+				usage.LastSeenAt = session.LastSeenAt
+			}
+		}
+		for _, inviter := range m.users {
+			if inviter.ID == user.InvitedByID {
+				usage.InvitedBy = inviter.Username
+			}
+		}
+		*u = append(*u, usage)
+	}
+	sort.Slice(*u, func(i, j int) bool {
+		return (*u)[i].Username < (*u)[j].Username
+	})
+	return nil
+}
+
+func (m *mockDatabase) UpdateUserTOTP(ctx context.Context, u *database.User) error {
+	for _, user := range m.users {
+		if user.ID == u.ID {
+			user.TOTPSecret = u.TOTPSecret
+			user.TOTPEnabled = u.TOTPEnabled
+			user.TOTPLastStep = u.TOTPLastStep
+			user.RecoveryCodes = u.RecoveryCodes
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *mockDatabase) UseUserTOTPStep(ctx context.Context, id int64, step int64) (bool, error) {
+	for _, user := range m.users {
+		if user.ID == id && user.TOTPLastStep < step {
+			user.TOTPLastStep = step
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockDatabase) UseUserRecoveryCode(ctx context.Context, id int64, hash string) (bool, error) {
+	for _, user := range m.users {
+		if user.ID != id {
+			continue
+		}
+		for i, recoveryCode := range user.RecoveryCodes {
+			if recoveryCode == hash {
+				user.RecoveryCodes = append(user.RecoveryCodes[:i:i], user.RecoveryCodes[i+1:]...)
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
 func (m *mockDatabase) CreateCategory(ctx context.Context, c *database.Category) error {
 	if c.UUID.String() == "" {
 		panic("empty uuid")
@@ -133,6 +509,9 @@ func (m *mockDatabase) CreateCategory(ctx context.Context, c *database.Category)
 		panic(fmt.Errorf("category with UUID %s already exists", c.UUID.String()))
 	}
 
+	if c.ID == 0 {
+		c.ID = int64(rand.Intn(9999) + 1)
+	}
 	m.categories = append(m.categories, c)
 	return nil
 }
@@ -156,10 +535,10 @@ func (m *mockDatabase) SelectCategoryByUUID(ctx context.Context, uuid string, c
 	return sql.ErrNoRows
 }
 
-func (m *mockDatabase) SelectCategoriesByOwnerID(ctx context.Context, ownerID int64, c *[]database.Category) error {
+func (m *mockDatabase) SelectCategoriesByLedgerID(ctx context.Context, ledgerID int64, c *[]database.Category) error {
 	found := false
 	for _, category := range m.categories {
-		if category.OwnerID == ownerID {
+		if category.LedgerID == ledgerID {
 			found = true
 			*c = append(*c, *category)
 		}
@@ -179,32 +558,943 @@ func (m *mockDatabase) DeleteCategoryByID(ctx context.Context, id int64) error {
 	panic("implement me")
 }
 
-func (m *mockDatabase) SelectCurrencyByCode(ctx context.Context, code string, c *database.Currency) error {
+func (m *mockDatabase) SelectCategoryStats(ctx context.Context, f *database.TransactionFilter, s *[]database.CategoryStat) error {
 	panic("implement me")
 }
 
+func (m *mockDatabase) SelectCurrencyByCode(ctx context.Context, code string, c *database.Currency) error {
+	for _, currency := range m.currencies {
+		if currency.Code == code {
+			*c = *currency
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) SelectTransactionCurrencies(ctx context.Context, f *database.TransactionFilter, c *[]database.Currency) error {
+	for _, currency := range m.currencies {
+		for _, transaction := range m.transactions {
+			if transaction.LedgerID == f.LedgerID && transaction.CurrencyID == currency.ID {
+				*c = append(*c, *currency)
+				break
+			}
+		}
+	}
+	sort.Slice(*c, func(i, j int) bool {
+		return (*c)[i].Code < (*c)[j].Code
+	})
+	return nil
+}
+
 func (m *mockDatabase) CreateTransaction(ctx context.Context, transaction *database.Transaction) error {
-	panic("implement me")
+	if transaction.ID == 0 {
+		transaction.ID = int64(rand.Intn(9999) + 1)
+	}
+	if transaction.UUID == uuid.Nil {
+		transaction.UUID = uuid.New()
+	}
+	m.transactions = append(m.transactions, transaction)
+	return nil
 }
 
-func newTestHandler() *Handler {
-	return New(
-		newMockDatabase(),
-		newMockJWTAuthenticator(),
-		newMockPasswordAuthenticator(),
-		log.New(io.Discard, "", 0),
-	)
+func (m *mockDatabase) CreateTransactions(ctx context.Context, t []*database.Transaction) error {
+	for _, transaction := range t {
+		if err := m.CreateTransaction(ctx, transaction); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func testRequest(ctx context.Context, params any, handlerFunc http.HandlerFunc) *httptest.ResponseRecorder {
-	rec := httptest.NewRecorder()
-	body, err := json.Marshal(params)
-	if err != nil {
-		panic(err)
+func (m *mockDatabase) SelectTransactionByUUID(ctx context.Context, uuid string, t *database.Transaction) error {
+	for _, transaction := range m.transactions {
+		if transaction.UUID.String() == uuid {
+			*t = *transaction
+			return nil
+		}
 	}
+	return sql.ErrNoRows
+}
 
-	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
-	handlerFunc(rec, req.WithContext(ctx))
+func (m *mockDatabase) SelectTransactions(ctx context.Context, f *database.TransactionFilter, t *[]database.Transaction) error {
+	for _, transaction := range m.transactions {
+		if transaction.LedgerID == f.LedgerID && !(f.ExcludeSettlements && transaction.Settlement) {
+			*t = append(*t, *transaction)
+		}
+	}
+	return nil
+}
 
-	return rec
+func (m *mockDatabase) IterateTransactionExportRows(ctx context.Context, f *database.TransactionFilter, oldestFirst bool, fn func(row *database.TransactionExportRow) error) error {
+	for _, transaction := range m.transactions {
+		if transaction.LedgerID != f.LedgerID {
+			continue
+		}
+
+		row := &database.TransactionExportRow{
+			UUID:        transaction.UUID,
+			Timestamp:   transaction.Timestamp,
+			ValueDate:   transaction.ValueDate,
+			Amount:      transaction.Amount,
+			Description: transaction.Description,
+			PayeeName:   transaction.Payee.Name,
+		}
+		for _, currency := range m.currencies {
+			if currency.ID == transaction.CurrencyID {
+				row.CurrencyCode, row.CurrencyRate = currency.Code, currency.Rate
+			}
+		}
+		for _, category := range m.categories {
+			if category.ID == transaction.CategoryID {
+				row.CategoryNames = append(row.CategoryNames, category.Name)
+			}
+		}
+		for _, tag := range transaction.Tags {
+			row.TagNames = append(row.TagNames, tag.Name)
+		}
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mockDatabase) SelectTransactionExternalIDs(ctx context.Context, ledgerID int64, externalIDs []string, e *[]string) error {
+	for _, transaction := range m.transactions {
+		if transaction.LedgerID != ledgerID || transaction.ExternalID == "" {
+			continue
+		}
+		for _, externalID := range externalIDs {
+			if transaction.ExternalID == externalID {
+				*e = append(*e, externalID)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (m *mockDatabase) UpdateTransaction(ctx context.Context, t *database.Transaction) error {
+	for _, transaction := range m.transactions {
+		if transaction.ID == t.ID {
+			*transaction = *t
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *mockDatabase) CreateTag(ctx context.Context, t *database.Tag) error {
+	if t.ID == 0 {
+		t.ID = int64(rand.Intn(9999) + 1)
+	}
+	if t.UUID == uuid.Nil {
+		t.UUID = uuid.New()
+	}
+	m.tags = append(m.tags, t)
+	return nil
+}
+
+func (m *mockDatabase) TagExistsByName(ctx context.Context, ownerID int64, name string) (bool, error) {
+	for _, tag := range m.tags {
+		if tag.OwnerID == ownerID && tag.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockDatabase) SelectTagByUUID(ctx context.Context, uuid string, t *database.Tag) error {
+	for _, tag := range m.tags {
+		if tag.UUID.String() == uuid {
+			*t = *tag
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) SelectTagsByOwnerID(ctx context.Context, ownerID int64, t *[]database.Tag) error {
+	for _, tag := range m.tags {
+		if tag.OwnerID == ownerID {
+			*t = append(*t, *tag)
+		}
+	}
+	return nil
+}
+
+func (m *mockDatabase) UpdateTag(ctx context.Context, t *database.Tag) error {
+	for _, tag := range m.tags {
+		if tag.ID == t.ID {
+			*tag = *t
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *mockDatabase) DeleteTagByID(ctx context.Context, id int64) error {
+	for i, tag := range m.tags {
+		if tag.ID == id {
+			m.tags = append(m.tags[:i], m.tags[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *mockDatabase) SelectTagStats(ctx context.Context, f *database.TransactionFilter, s *[]database.TagStat) error {
+	panic("implement me")
+}
+
+func (m *mockDatabase) SelectOrCreatePayee(ctx context.Context, ownerID int64, name string, p *database.Payee) error {
+	for _, payee := range m.payees {
+		if payee.OwnerID == ownerID && payee.NormalizedName == database.NormalizePayeeName(name) {
+			*p = *payee
+			return nil
+		}
+	}
+
+	payee := &database.Payee{
+		ID:             int64(rand.Intn(9999) + 1),
+		UUID:           uuid.New(),
+		Name:           database.CleanPayeeName(name),
+		NormalizedName: database.NormalizePayeeName(name),
+		OwnerID:        ownerID,
+	}
+	m.payees = append(m.payees, payee)
+	*p = *payee
+	return nil
+}
+
+func (m *mockDatabase) SelectPayeeByUUID(ctx context.Context, uuid string, p *database.Payee) error {
+	for _, payee := range m.payees {
+		if payee.UUID.String() == uuid {
+			*p = *payee
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) SelectPayeeUsages(ctx context.Context, ownerID int64, prefix string, limit int, u *[]database.PayeeUsage) error {
+	for _, payee := range m.payees {
+		if payee.OwnerID != ownerID || !strings.HasPrefix(payee.NormalizedName, database.NormalizePayeeName(prefix)) {
+			continue
+		}
+
+		usage := database.PayeeUsage{Payee: *payee}
+		for _, transaction := range m.transactions {
+			if transaction.PayeeID == payee.ID {
+				usage.TransactionsCount++
+			}
+		}
+		*u = append(*u, usage)
+	}
+
+	sort.Slice(*u, func(i, j int) bool {
+		return (*u)[i].TransactionsCount > (*u)[j].TransactionsCount
+	})
+	if limit != 0 && len(*u) > limit {
+		*u = (*u)[:limit]
+	}
+	return nil
+}
+
+func (m *mockDatabase) SelectPayeeStats(ctx context.Context, f *database.TransactionFilter, limit int, s *[]database.PayeeStat) error {
+	panic("implement me")
+}
+
+func (m *mockDatabase) CreateRule(ctx context.Context, r *database.Rule) error {
+	if r.ID == 0 {
+		r.ID = int64(rand.Intn(9999) + 1)
+	}
+	if r.UUID == uuid.Nil {
+		r.UUID = uuid.New()
+	}
+	m.rules = append(m.rules, r)
+	return nil
+}
+
+func (m *mockDatabase) SelectRuleByUUID(ctx context.Context, uuid string, r *database.Rule) error {
+	for _, rule := range m.rules {
+		if rule.UUID.String() == uuid {
+			*r = *rule
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) SelectRulesByOwnerID(ctx context.Context, ownerID int64, r *[]database.Rule) error {
+	for _, rule := range m.rules {
+		if rule.OwnerID == ownerID {
+			*r = append(*r, *rule)
+		}
+	}
+	sort.SliceStable(*r, func(i, j int) bool {
+		return (*r)[i].Position < (*r)[j].Position
+	})
+	return nil
+}
+
+func (m *mockDatabase) UpdateRule(ctx context.Context, r *database.Rule) error {
+	for _, rule := range m.rules {
+		if rule.ID == r.ID {
+			*rule = *r
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *mockDatabase) DeleteRuleByID(ctx context.Context, id int64) error {
+	for i, rule := range m.rules {
+		if rule.ID == id {
+			m.rules = append(m.rules[:i], m.rules[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *mockDatabase) CreateImportReport(ctx context.Context, r *database.ImportReport) error {
+	if r.ID == 0 {
+		r.ID = int64(rand.Intn(9999) + 1)
+	}
+	if r.UUID == uuid.Nil {
+		r.UUID = uuid.New()
+	}
+	m.importReports = append(m.importReports, r)
+	return nil
+}
+
+func (m *mockDatabase) SelectImportReportByUUID(ctx context.Context, uuid string, r *database.ImportReport) error {
+	for _, report := range m.importReports {
+		if report.UUID.String() == uuid {
+			*r = *report
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) SelectImportReportsByOwnerID(ctx context.Context, ownerID int64, r *[]database.ImportReport) error {
+	for i := len(m.importReports) - 1; i >= 0; i-- {
+		if m.importReports[i].OwnerID == ownerID {
+			*r = append(*r, *m.importReports[i])
+		}
+	}
+	return nil
+}
+
+func (m *mockDatabase) CreateRefreshToken(ctx context.Context, t *database.RefreshToken) error {
+	if t.ID == 0 {
+		t.ID = int64(rand.Intn(9999) + 1)
+	}
+	m.refreshTokens = append(m.refreshTokens, t)
+	return nil
+}
+
+func (m *mockDatabase) SelectRefreshTokenByHash(ctx context.Context, hash string, t *database.RefreshToken) error {
+	for _, refreshToken := range m.refreshTokens {
+		if refreshToken.TokenHash == hash {
+			*t = *refreshToken
+			for _, user := range m.users {
+				if user.ID == t.OwnerID {
+					t.Owner = *user
+				}
+			}
+			for _, session := range m.sessions {
+				if session.ID == t.SessionID {
+					t.Session = *session
+				}
+			}
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) RotateRefreshToken(ctx context.Context, id int64) (bool, error) {
+	for _, refreshToken := range m.refreshTokens {
+		if refreshToken.ID == id {
+			if !refreshToken.RotatedAt.IsZero() || !refreshToken.RevokedAt.IsZero() {
+				return false, nil
+			}
+			refreshToken.RotatedAt = time.Now()
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockDatabase) DeleteExpiredTokens(ctx context.Context, before time.Time) error {
+	refreshTokens := make([]*database.RefreshToken, 0, len(m.refreshTokens))
+	for _, refreshToken := range m.refreshTokens {
+		if !refreshToken.ExpiresAt.Before(before) {
+			refreshTokens = append(refreshTokens, refreshToken)
+		}
+	}
+	m.refreshTokens = refreshTokens
+	return nil
+}
+
+func (m *mockDatabase) TokenRevoked(ctx context.Context, id string) (bool, error) {
+	return m.revokedTokens[id], nil
+}
+
+func (m *mockDatabase) RevokeToken(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	if m.revokedTokens[id] {
+		return false, nil
+	}
+	m.revokedTokens[id] = true
+	return true, nil
+}
+
+func (m *mockDatabase) CreateSession(ctx context.Context, s *database.Session) error {
+	if s.ID == 0 {
+		s.ID = int64(rand.Intn(9999) + 1)
+	}
+	if s.UUID == uuid.Nil {
+		s.UUID = uuid.New()
+	}
+	s.CreatedAt = time.Now()
+	s.LastSeenAt = s.CreatedAt
+	m.sessions = append(m.sessions, s)
+	return nil
+}
+
+func (m *mockDatabase) SelectSessionByUUID(ctx context.Context, uuid string, s *database.Session) error {
+	for _, session := range m.sessions {
+		if session.UUID.String() == uuid {
+			*s = *session
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) SelectActiveSessionsByOwnerID(ctx context.Context, ownerID int64, s *[]database.Session) error {
+	for _, session := range m.sessions {
+		if session.OwnerID == ownerID && session.RevokedAt.IsZero() && session.ExpiresAt.After(time.Now()) {
+			*s = append(*s, *session)
+		}
+	}
+	return nil
+}
+
+func (m *mockDatabase) TouchSession(ctx context.Context, id int64) error {
+	for _, session := range m.sessions {
+		if session.ID == id {
+			session.LastSeenAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (m *mockDatabase) ExtendSession(ctx context.Context, id int64, expiresAt time.Time) error {
+	for _, session := range m.sessions {
+		if session.ID == id {
+			session.LastSeenAt = time.Now()
+			session.ExpiresAt = expiresAt
+		}
+	}
+	return nil
+}
+
+// revokeSessions revokes sessions matching the condition together with their refresh and access tokens.
+func (m *mockDatabase) revokeSessions(condition func(session *database.Session) bool) {
+	now := time.Now()
+	for _, session := range m.sessions {
+		if !condition(session) || !session.RevokedAt.IsZero() {
+			continue
+		}
+		for _, refreshToken := range m.refreshTokens {
+			if refreshToken.SessionID != session.ID {
+				continue
+			}
+			if refreshToken.AccessTokenExpiresAt.After(now) {
+				m.revokedTokens[refreshToken.AccessTokenID] = true
+			}
+			if refreshToken.RevokedAt.IsZero() {
+				refreshToken.RevokedAt = now
+			}
+		}
+		session.RevokedAt = now
+	}
+}
+
+func (m *mockDatabase) RevokeSession(ctx context.Context, id int64) error {
+	m.revokeSessions(func(session *database.Session) bool {
+		return session.ID == id
+	})
+	return nil
+}
+
+func (m *mockDatabase) RevokeSessionsByOwnerID(ctx context.Context, ownerID int64, exceptID int64) error {
+	m.revokeSessions(func(session *database.Session) bool {
+		return session.OwnerID == ownerID && session.ID != exceptID
+	})
+	return nil
+}
+
+func (m *mockDatabase) CreateAPIKey(ctx context.Context, k *database.APIKey) error {
+	if k.ID == 0 {
+		k.ID = int64(rand.Intn(9999) + 1)
+	}
+	if k.UUID == uuid.Nil {
+		k.UUID = uuid.New()
+	}
+	k.CreatedAt = time.Now()
+	m.apiKeys = append(m.apiKeys, k)
+	return nil
+}
+
+func (m *mockDatabase) SelectAPIKeyByUUID(ctx context.Context, uuid string, k *database.APIKey) error {
+	for _, apiKey := range m.apiKeys {
+		if apiKey.UUID.String() == uuid {
+			*k = *apiKey
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) SelectAPIKeyByHash(ctx context.Context, hash string, k *database.APIKey) error {
+	for _, apiKey := range m.apiKeys {
+		if apiKey.KeyHash == hash {
+			*k = *apiKey
+			for _, user := range m.users {
+				if user.ID == apiKey.OwnerID {
+					k.Owner = *user
+				}
+			}
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) SelectAPIKeysByOwnerID(ctx context.Context, ownerID int64, k *[]database.APIKey) error {
+	for _, apiKey := range m.apiKeys {
+		if apiKey.OwnerID == ownerID {
+			*k = append(*k, *apiKey)
+		}
+	}
+	return nil
+}
+
+func (m *mockDatabase) TouchAPIKey(ctx context.Context, id int64) error {
+	for _, apiKey := range m.apiKeys {
+		if apiKey.ID == id {
+			apiKey.LastUsedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (m *mockDatabase) DeleteAPIKeyByID(ctx context.Context, id int64) error {
+	for i, apiKey := range m.apiKeys {
+		if apiKey.ID == id {
+			m.apiKeys = append(m.apiKeys[:i], m.apiKeys[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *mockDatabase) CreateExternalIdentity(ctx context.Context, i *database.ExternalIdentity) error {
+	for _, identity := range m.externalIdentities {
+		if identity.Issuer == i.Issuer && identity.Subject == i.Subject {
+			return errors.New("duplicate external identity")
+		}
+	}
+	i.ID = int64(rand.Intn(9999) + 1)
+	m.externalIdentities = append(m.externalIdentities, i)
+	return nil
+}
+
+func (m *mockDatabase) SelectExternalIdentity(ctx context.Context, issuer string, subject string, i *database.ExternalIdentity) error {
+	for _, identity := range m.externalIdentities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			*i = *identity
+			for _, user := range m.users {
+				if user.ID == identity.OwnerID {
+					i.Owner = *user
+				}
+			}
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) CreateInviteCode(ctx context.Context, c *database.InviteCode) error {
+	if c.ID == 0 {
+		c.ID = int64(rand.Intn(9999) + 1)
+	}
+	if c.UUID == uuid.Nil {
+		c.UUID = uuid.New()
+	}
+	c.CreatedAt = time.Now()
+	m.inviteCodes = append(m.inviteCodes, c)
+	return nil
+}
+
+func (m *mockDatabase) SelectInviteCodeByUUID(ctx context.Context, uuid string, c *database.InviteCode) error {
+	for _, inviteCode := range m.inviteCodes {
+		if inviteCode.UUID.String() == uuid {
+			*c = *inviteCode
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) SelectInviteCodeByHash(ctx context.Context, hash string, c *database.InviteCode) error {
+	for _, inviteCode := range m.inviteCodes {
+		if inviteCode.CodeHash == hash {
+			*c = *inviteCode
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) SelectInviteCodesByCreatorID(ctx context.Context, creatorID int64, c *[]database.InviteCode) error {
+	for i := len(m.inviteCodes) - 1; i >= 0; i-- {
+		if m.inviteCodes[i].CreatorID == creatorID {
+			*c = append(*c, *m.inviteCodes[i])
+		}
+	}
+	return nil
+}
+
+func (m *mockDatabase) UseInviteCode(ctx context.Context, id int64) (bool, error) {
+	for _, inviteCode := range m.inviteCodes {
+		if inviteCode.ID == id {
+			if inviteCode.Uses >= inviteCode.MaxUses || !inviteCode.ExpiresAt.After(time.Now()) {
+				return false, nil
+			}
+			inviteCode.Uses++
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockDatabase) DeleteInviteCodeByID(ctx context.Context, id int64) error {
+	for i, inviteCode := range m.inviteCodes {
+		if inviteCode.ID == id {
+			m.inviteCodes = append(m.inviteCodes[:i], m.inviteCodes[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func newTestHandler() *Handler {
+	db := newMockDatabase()
+	return New(
+		db,
+		newMockJWTAuthenticator(),
+		newMockPasswordAuthenticator(),
+		auth.NewLoginGuard(auth.NewMemoryLoginFailureStore(), auth.DefaultLoginGuardConfig()),
+		nil,
+		job.New(db, nil),
+		auth.RegistrationOpen,
+		time.Hour,
+		log.New(io.Discard, "", 0),
+	)
+}
+
+func testRequest(ctx context.Context, params any, handlerFunc http.HandlerFunc) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	body, err := json.Marshal(params)
+	if err != nil {
+		panic(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	handlerFunc(rec, req.WithContext(ctx))
+
+	return rec
+}
+
+// testGetRequest creates a new [httptest.Recorder] and makes a test GET request to the target using it,
+// then returns pointer to this recorder.
+func testGetRequest(ctx context.Context, target string, handlerFunc http.HandlerFunc) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	handlerFunc(rec, req.WithContext(ctx))
+
+	return rec
+}
+
+// withURLParam returns a copy of ctx with chi URL param key set to value.
+func withURLParam(ctx context.Context, key string, value string) context.Context {
+	routeCtx, ok := ctx.Value(chi.RouteCtxKey).(*chi.Context)
+	if !ok {
+		routeCtx = chi.NewRouteContext()
+		ctx = context.WithValue(ctx, chi.RouteCtxKey, routeCtx)
+	}
+	routeCtx.URLParams.Add(key, value)
+	return ctx
+}
+
+// testUploadRequest creates a new [httptest.Recorder] and makes a test multipart POST request
+// with JSON-encoded options and the file using it, then returns pointer to this recorder.
+func testUploadRequest(ctx context.Context, options any, filename string, file []byte, handlerFunc http.HandlerFunc) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	encodedOptions, err := json.Marshal(options)
+	if err != nil {
+		panic(err)
+	}
+	if err := writer.WriteField("options", string(encodedOptions)); err != nil {
+		panic(err)
+	}
+
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		panic(err)
+	}
+	if _, err := part.Write(file); err != nil {
+		panic(err)
+	}
+	if err := writer.Close(); err != nil {
+		panic(err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	handlerFunc(rec, req.WithContext(ctx))
+
+	return rec
+}
+
+// userContext is a context which resolves [middleware.UserContextKey] to the user of the mock database with the username
+// at the moment of the request, so that the context can be created before the user.
+type userContext struct {
+	context.Context
+	db       *mockDatabase
+	username string
+}
+
+func (c *userContext) Value(key any) any {
+	if key != middleware.UserContextKey {
+		return c.Context.Value(key)
+	}
+	for _, user := range c.db.users {
+		if user.Username == c.username {
+			return user
+		}
+	}
+	return nil
+}
+
+// testUserContext returns context of the user with the username as if the request was authenticated by the JWT middleware.
+func testUserContext(ctx context.Context, handler *Handler, username string) context.Context {
+	return &userContext{Context: ctx, db: handler.database.(*mockDatabase), username: username}
+}
+
+func (m *mockDatabase) CreateLedger(ctx context.Context, l *database.Ledger, ownerID int64) error {
+	if l.ID == 0 {
+		l.ID = int64(rand.Intn(9999) + 1)
+	}
+	if l.UUID == uuid.Nil {
+		l.UUID = uuid.New()
+	}
+	m.ledgers = append(m.ledgers, l)
+	return m.CreateLedgerMember(ctx, &database.LedgerMember{LedgerID: l.ID, UserID: ownerID, Role: database.LedgerRoleOwner})
+}
+
+func (m *mockDatabase) SelectOrCreatePersonalLedger(ctx context.Context, userID int64, l *database.Ledger) error {
+	for _, ledger := range m.ledgers {
+		if ledger.PersonalUserID == userID {
+			*l = *ledger
+			return nil
+		}
+	}
+
+	ledger := &database.Ledger{Name: "Personal", PersonalUserID: userID}
+	if err := m.CreateLedger(ctx, ledger, userID); err != nil {
+		return err
+	}
+	*l = *ledger
+	return nil
+}
+
+func (m *mockDatabase) SelectLedgerByUUID(ctx context.Context, uuid string, l *database.Ledger) error {
+	for _, ledger := range m.ledgers {
+		if ledger.UUID.String() == uuid {
+			*l = *ledger
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) UpdateLedgerName(ctx context.Context, id int64, name string) error {
+	for _, ledger := range m.ledgers {
+		if ledger.ID == id {
+			ledger.Name = name
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *mockDatabase) CreateLedgerMember(ctx context.Context, member *database.LedgerMember) error {
+	for _, other := range m.ledgerMembers {
+		if other.LedgerID == member.LedgerID && other.UserID == member.UserID {
+			return database.ErrUniqueViolation
+		}
+	}
+	if member.ID == 0 {
+		member.ID = int64(rand.Intn(9999) + 1)
+	}
+	member.CreatedAt = time.Now()
+	m.ledgerMembers = append(m.ledgerMembers, member)
+	return nil
+}
+
+func (m *mockDatabase) SelectLedgerMember(ctx context.Context, ledgerID int64, userID int64, member *database.LedgerMember) error {
+	for _, ledgerMember := range m.ledgerMembers {
+		if ledgerMember.LedgerID == ledgerID && ledgerMember.UserID == userID {
+			*member = *ledgerMember
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockDatabase) SelectLedgerMembers(ctx context.Context, ledgerID int64, members *[]database.LedgerMember) error {
+	for _, ledgerMember := range m.ledgerMembers {
+		if ledgerMember.LedgerID != ledgerID {
+			continue
+		}
+		member := *ledgerMember
+		for _, user := range m.users {
+			if user.ID == member.UserID {
+				member.User = *user
+			}
+		}
+		*members = append(*members, member)
+	}
+	sort.Slice(*members, func(i, j int) bool {
+		return (*members)[i].User.Username < (*members)[j].User.Username
+	})
+	return nil
+}
+
+func (m *mockDatabase) SelectLedgerMembershipsByUserID(ctx context.Context, userID int64, members *[]database.LedgerMember) error {
+	for _, ledgerMember := range m.ledgerMembers {
+		if ledgerMember.UserID != userID {
+			continue
+		}
+		member := *ledgerMember
+		for _, ledger := range m.ledgers {
+			if ledger.ID == member.LedgerID {
+				member.Ledger = *ledger
+			}
+		}
+		*members = append(*members, member)
+	}
+	sort.Slice(*members, func(i, j int) bool {
+		return (*members)[i].Ledger.Name < (*members)[j].Ledger.Name
+	})
+	return nil
+}
+
+// checkLedgerKeepsOwner returns [database.ErrLastLedgerOwner] if the member is the only owner of its ledger.
+func (m *mockDatabase) checkLedgerKeepsOwner(member *database.LedgerMember) error {
+	if member.Role != database.LedgerRoleOwner {
+		return nil
+	}
+	for _, other := range m.ledgerMembers {
+		if other.ID != member.ID && other.LedgerID == member.LedgerID && other.Role == database.LedgerRoleOwner {
+			return nil
+		}
+	}
+	return database.ErrLastLedgerOwner
+}
+
+func (m *mockDatabase) UpdateLedgerMemberRole(ctx context.Context, id int64, role string) error {
+	for _, member := range m.ledgerMembers {
+		if member.ID == id {
+			if role != database.LedgerRoleOwner {
+				if err := m.checkLedgerKeepsOwner(member); err != nil {
+					return err
+				}
+			}
+			member.Role = role
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *mockDatabase) DeleteLedgerMemberByID(ctx context.Context, id int64) error {
+	for i, member := range m.ledgerMembers {
+		if member.ID == id {
+			if err := m.checkLedgerKeepsOwner(member); err != nil {
+				return err
+			}
+			m.ledgerMembers = append(m.ledgerMembers[:i], m.ledgerMembers[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (m *mockDatabase) SelectLedgerDebts(ctx context.Context, ledgerID int64, debts *[]database.LedgerDebt) error {
+	uuids := make(map[int64]string)
+	usernames := make(map[int64]string)
+	for _, user := range m.users {
+		uuids[user.ID] = user.UUID.String()
+		usernames[user.ID] = user.Username
+	}
+	currencyCodes := make(map[int64]string)
+	for _, currency := range m.currencies {
+		currencyCodes[currency.ID] = currency.Code
+	}
+
+	type key struct {
+		debtorID, creditorID int64
+		currencyCode         string
+	}
+	indexes := make(map[key]int)
+	for _, transaction := range m.transactions {
+		if transaction.LedgerID != ledgerID {
+			continue
+		}
+		for _, share := range transaction.Shares {
+			if share.UserID == transaction.PayerID {
+				continue
+			}
+			k := key{share.UserID, transaction.PayerID, currencyCodes[transaction.CurrencyID]}
+			i, ok := indexes[k]
+			if !ok {
+				i = len(*debts)
+				indexes[k] = i
+				*debts = append(*debts, database.LedgerDebt{
+					DebtorID:         k.debtorID,
+					DebtorUUID:       uuids[k.debtorID],
+					DebtorUsername:   usernames[k.debtorID],
+					CreditorID:       k.creditorID,
+					CreditorUUID:     uuids[k.creditorID],
+					CreditorUsername: usernames[k.creditorID],
+					CurrencyCode:     k.currencyCode,
+				})
+			}
+			(*debts)[i].Amount += int64(share.Amount)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,271 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/groshi-project/groshi/internal/auth"
+	"github.com/groshi-project/groshi/internal/auth/oidctest"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+// testOIDCHandler returns a new test handler which uses the mock identity provider.
+func testOIDCHandler(t *testing.T, server *oidctest.Server, createUsers bool) *Handler {
+	provider, err := auth.NewOIDCProvider(context.Background(), auth.OIDCConfig{
+		Issuer:       server.Issuer(),
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  "https://app.example.com/oidc/callback",
+		CreateUsers:  createUsers,
+	}, http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := newTestHandler()
+	handler.oidc = provider
+	return handler
+}
+
+// testOIDCCallbackParams starts OIDC login, logs in as the subject at the mock identity provider
+// and returns params of the callback.
+func testOIDCCallbackParams(handler *Handler, server *oidctest.Server, subject string, claims jwt.MapClaims) *authOIDCCallbackParams {
+	rec := testRequest(context.Background(), &authOIDCLoginParams{DeviceName: "test-device"}, handler.AuthOIDCLogin)
+	resp := &authOIDCLoginResponse{}
+	if err := json.NewDecoder(rec.Body).Decode(resp); err != nil {
+		panic(err)
+	}
+
+	code, state, err := server.Authorize(resp.AuthorizationURL, subject, claims)
+	if err != nil {
+		panic(err)
+	}
+	return &authOIDCCallbackParams{Code: code, State: state, StateToken: resp.StateToken}
+}
+
+// racingDatabase simulates a request which commits concurrently with the first transaction run by the handler,
+// so that the transaction fails with a unique violation.
+type racingDatabase struct {
+	*mockDatabase
+
+	// race is called instead of the first transaction.
+	race func(m *mockDatabase)
+}
+
+func (d *racingDatabase) RunInTx(ctx context.Context, fn func(ctx context.Context, db database.Database) error) error {
+	if d.race != nil {
+		d.race(d.mockDatabase)
+		d.race = nil
+		return database.ErrUniqueViolation
+	}
+	return d.mockDatabase.RunInTx(ctx, fn)
+}
+
+func TestHandler_AuthOIDCLogin(t *testing.T) {
+	t.Run("start login", func(t *testing.T) {
+		server := oidctest.NewServer()
+		defer server.Close()
+		handler := testOIDCHandler(t, server, false)
+
+		rec := testRequest(context.Background(), &authOIDCLoginParams{DeviceName: "test-device"}, handler.AuthOIDCLogin)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			resp := &authOIDCLoginResponse{}
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(resp)) {
+				assert.Contains(t, resp.AuthorizationURL, server.URL+"/authorize?")
+				assert.NotEmpty(t, resp.StateToken)
+			}
+		}
+	})
+
+	t.Run("start login when oidc is not configured", func(t *testing.T) {
+		handler := newTestHandler()
+		rec := testRequest(context.Background(), &authOIDCLoginParams{}, handler.AuthOIDCLogin)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestHandler_AuthOIDCCallback(t *testing.T) {
+	server := oidctest.NewServer()
+	defer server.Close()
+
+	t.Run("create a new user on the first login", func(t *testing.T) {
+		var (
+			handler = testOIDCHandler(t, server, true)
+			mockDb  = handler.database.(*mockDatabase)
+		)
+		testLogin(context.Background(), handler) // takes username "test-username"
+
+		params := testOIDCCallbackParams(handler, server, "test-subject", jwt.MapClaims{"preferred_username": "test-username"})
+		rec := testRequest(context.Background(), params, handler.AuthOIDCCallback)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			if assert.Len(t, mockDb.users, 2) {
+				user := mockDb.users[1]
+				assert.Equal(t, "test-username-2", user.Username)
+				assert.Empty(t, user.Password)
+				if assert.Len(t, mockDb.externalIdentities, 1) {
+					assert.Equal(t, user.ID, mockDb.externalIdentities[0].OwnerID)
+					assert.Equal(t, "test-subject", mockDb.externalIdentities[0].Subject)
+				}
+			}
+		}
+
+		// the created user can not log in with a password:
+		rec = testRequest(context.Background(), &authLoginParams{Username: "test-username-2", Password: ""}, handler.AuthLogin)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		rec = testRequest(context.Background(), &authLoginParams{Username: "test-username-2", Password: "hash()"}, handler.AuthLogin)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("create a new user when the username is taken concurrently", func(t *testing.T) {
+		var (
+			handler = testOIDCHandler(t, server, true)
+			mockDb  = handler.database.(*mockDatabase)
+		)
+		handler.database = &racingDatabase{mockDatabase: mockDb, race: func(m *mockDatabase) {
+			m.users = append(m.users, &database.User{ID: 1, Username: "test-username"})
+		}}
+
+		params := testOIDCCallbackParams(handler, server, "test-subject", jwt.MapClaims{"preferred_username": "test-username"})
+		rec := testRequest(context.Background(), params, handler.AuthOIDCCallback)
+		if assert.Equal(t, http.StatusOK, rec.Code) && assert.Len(t, mockDb.users, 2) {
+			assert.Equal(t, "test-username-2", mockDb.users[1].Username)
+		}
+	})
+
+	t.Run("log in when the identity is linked concurrently", func(t *testing.T) {
+		var (
+			handler = testOIDCHandler(t, server, true)
+			mockDb  = handler.database.(*mockDatabase)
+		)
+		handler.database = &racingDatabase{mockDatabase: mockDb, race: func(m *mockDatabase) {
+			m.users = append(m.users, &database.User{ID: 1, Username: "test-username"})
+			m.externalIdentities = append(m.externalIdentities, &database.ExternalIdentity{
+				Issuer:  server.Issuer(),
+				Subject: "test-subject",
+				OwnerID: 1,
+			})
+		}}
+
+		params := testOIDCCallbackParams(handler, server, "test-subject", jwt.MapClaims{"preferred_username": "test-username"})
+		rec := testRequest(context.Background(), params, handler.AuthOIDCCallback)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			assert.Len(t, mockDb.users, 1)
+			assert.Len(t, mockDb.externalIdentities, 1)
+		}
+	})
+
+	t.Run("log in as a linked user", func(t *testing.T) {
+		var (
+			handler = testOIDCHandler(t, server, false)
+			mockDb  = handler.database.(*mockDatabase)
+		)
+		testLogin(context.Background(), handler)
+		mockDb.externalIdentities = append(mockDb.externalIdentities, &database.ExternalIdentity{
+			Issuer:  server.Issuer(),
+			Subject: "test-subject",
+			OwnerID: mockDb.users[0].ID,
+		})
+
+		params := testOIDCCallbackParams(handler, server, "test-subject", nil)
+		rec := testRequest(context.Background(), params, handler.AuthOIDCCallback)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			assert.Len(t, mockDb.users, 1)
+			assert.Equal(t, "test-device", mockDb.sessions[len(mockDb.sessions)-1].DeviceName)
+		}
+	})
+
+	t.Run("log in as a linked user with two-factor authentication", func(t *testing.T) {
+		var (
+			handler = testOIDCHandler(t, server, false)
+			mockDb  = handler.database.(*mockDatabase)
+		)
+		testLogin(context.Background(), handler)
+		mockDb.users[0].TOTPEnabled = true
+		mockDb.externalIdentities = append(mockDb.externalIdentities, &database.ExternalIdentity{
+			Issuer:  server.Issuer(),
+			Subject: "test-subject",
+			OwnerID: mockDb.users[0].ID,
+		})
+
+		params := testOIDCCallbackParams(handler, server, "test-subject", nil)
+		rec := testRequest(context.Background(), params, handler.AuthOIDCCallback)
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+	})
+
+	t.Run("log in with identity which is not linked", func(t *testing.T) {
+		var (
+			handler = testOIDCHandler(t, server, false)
+			mockDb  = handler.database.(*mockDatabase)
+		)
+
+		params := testOIDCCallbackParams(handler, server, "test-subject", nil)
+		rec := testRequest(context.Background(), params, handler.AuthOIDCCallback)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, mockDb.users)
+	})
+
+	t.Run("log in with another state", func(t *testing.T) {
+		handler := testOIDCHandler(t, server, true)
+
+		params := testOIDCCallbackParams(handler, server, "test-subject", nil)
+		params.State = "another-state"
+		rec := testRequest(context.Background(), params, handler.AuthOIDCCallback)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("log in with id token issued to another client", func(t *testing.T) {
+		handler := testOIDCHandler(t, server, true)
+
+		params := testOIDCCallbackParams(handler, server, "test-subject", jwt.MapClaims{"aud": "another-client-id"})
+		rec := testRequest(context.Background(), params, handler.AuthOIDCCallback)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
+
+func TestHandler_UserOIDCLink(t *testing.T) {
+	server := oidctest.NewServer()
+	defer server.Close()
+
+	t.Run("link identity", func(t *testing.T) {
+		var (
+			handler = testOIDCHandler(t, server, false)
+			mockDb  = handler.database.(*mockDatabase)
+		)
+		testLogin(context.Background(), handler)
+		ctx := testUserContext(context.Background(), handler, "test-username")
+
+		params := testOIDCCallbackParams(handler, server, "test-subject", nil)
+		rec := testRequest(ctx, params, handler.UserOIDCLink)
+		if assert.Equal(t, http.StatusNoContent, rec.Code) {
+			if assert.Len(t, mockDb.externalIdentities, 1) {
+				assert.Equal(t, mockDb.users[0].ID, mockDb.externalIdentities[0].OwnerID)
+			}
+		}
+
+		// the user can log in through the identity provider now:
+		params = testOIDCCallbackParams(handler, server, "test-subject", nil)
+		rec = testRequest(context.Background(), params, handler.AuthOIDCCallback)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("link identity which is linked to another user", func(t *testing.T) {
+		var (
+			handler = testOIDCHandler(t, server, false)
+			mockDb  = handler.database.(*mockDatabase)
+		)
+		testLogin(context.Background(), handler)
+		mockDb.externalIdentities = append(mockDb.externalIdentities, &database.ExternalIdentity{
+			Issuer:  server.Issuer(),
+			Subject: "test-subject",
+			OwnerID: mockDb.users[0].ID + 1,
+		})
+		ctx := testUserContext(context.Background(), handler, "test-username")
+
+		params := testOIDCCallbackParams(handler, server, "test-subject", nil)
+		rec := testRequest(ctx, params, handler.UserOIDCLink)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
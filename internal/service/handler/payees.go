@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
+	"github.com/groshi-project/groshi/internal/service/handler/response"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// defaultPayeesLimit is the default maximum number of payees returned by payee listings.
+	defaultPayeesLimit = 10
+
+	// maxPayeesLimit is the maximum number of payees which can be requested from payee listings.
+	maxPayeesLimit = 100
+)
+
+// payeesLimit parses the `limit` URL query param.
+// Renders an error response and returns false if the param is invalid.
+func payeesLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return defaultPayeesLimit, true
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxPayeesLimit {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return 0, false
+	}
+	return limit, true
+}
+
+// ownedPayee fetches payee with the given UUID and checks that it belongs to the user.
+// Renders an error response and returns false if the payee could not be fetched or is not owned by the user.
+func (h *Handler) ownedPayee(w http.ResponseWriter, r *http.Request, uuid string, user *database.User) (*database.Payee, bool) {
+	payee := &database.Payee{}
+	if err := h.database.SelectPayeeByUUID(r.Context(), uuid, payee); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpresp.Render(w, response.PayeeNotFound)
+			return nil, false
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return nil, false
+	}
+
+	if payee.OwnerID != user.ID {
+		httpresp.Render(w, response.PayeeForbidden)
+		return nil, false
+	}
+
+	return payee, true
+}
+
+type payeesGetResponseItem struct {
+	UUID              string `json:"uuid" example:"b5a1f7c4-3f3e-4a58-9a0e-3c2f5e7d8a91"`
+	Name              string `json:"name" example:"Corner Store"`
+	TransactionsCount int64  `json:"transactions_count" example:"27"`
+}
+
+type payeesGetResponse []payeesGetResponseItem
+
+// PayeesGet returns payees of user for auto-completion.
+//
+//	@Summary		Fetch payees
+//	@Description	Returns payees of user whose names start with the given prefix (case and whitespace insensitive),
+//	@Description	most used payees first.
+//	@Tags			payees
+//	@Accept			json
+//	@Produce		json
+//	@Param			prefix	query		string				false	"Payee name prefix"
+//	@Param			limit	query		int					false	"Maximum number of payees to return (1-100, default 10)"
+//	@Success		200		{object}	payeesGetResponse	"Successful operation"
+//	@Failure		400		{object}	model.Error			"Invalid request params"
+//	@Failure		404		{object}	model.Error			"User not found"
+//	@Failure		500		{object}	model.Error			"Internal server error"
+//	@Security		Bearer
+//	@Router			/payees [get]
+func (h *Handler) PayeesGet(w http.ResponseWriter, r *http.Request) {
+	// parse request params:
+	limit, ok := payeesLimit(w, r)
+	if !ok {
+		return
+	}
+	prefix := r.URL.Query().Get("prefix")
+
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch payees matching the prefix:
+	usages := make([]database.PayeeUsage, 0)
+	if err := h.database.SelectPayeeUsages(r.Context(), user.ID, prefix, limit, &usages); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+
+	// respond:
+	resp := make(payeesGetResponse, 0, len(usages))
+	for _, usage := range usages {
+		resp = append(resp, payeesGetResponseItem{
+			UUID:              usage.UUID.String(),
+			Name:              usage.Name,
+			TransactionsCount: usage.TransactionsCount,
+		})
+	}
+	httpresp.Render(w, httpresp.NewOK(&resp))
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHandler_PayeesGet(t *testing.T) {
+	const (
+		testUserID   int64 = 5
+		testUsername       = "test-username"
+	)
+
+	var (
+		handler = newTestHandler()
+		db      = handler.database.(*mockDatabase)
+		ctx     = testUserContext(context.Background(), handler, testUsername)
+	)
+
+	// create a test user, a category and a currency:
+	if err := db.CreateUser(ctx, &database.User{ID: testUserID, Username: testUsername}); err != nil {
+		panic(err)
+	}
+	category := &database.Category{UUID: uuid.New(), Name: "Groceries", LedgerID: testPersonalLedgerID(db, testUserID), OwnerID: testUserID}
+	if err := db.CreateCategory(ctx, category); err != nil {
+		panic(err)
+	}
+	db.currencies = append(db.currencies, &database.Currency{ID: 1, Code: "EUR", Rate: 1})
+
+	// create transactions with payees given in different forms:
+	for _, payeeName := range []string{"Corner Store", "  corner   STORE ", "Cornelius", "Bakery"} {
+		params := &transactionsCreateParams{
+			Amount:       -100,
+			CurrencyCode: "EUR",
+			Timestamp:    time.Now(),
+			Payee:        payeeName,
+			CategoryUUID: category.UUID.String(),
+		}
+		if rec := testRequest(ctx, params, handler.TransactionsCreate); rec.Code != http.StatusOK {
+			panic(rec.Body.String())
+		}
+	}
+
+	t.Run("payees are created once per normalized name", func(t *testing.T) {
+		assert.Len(t, db.payees, 3)
+	})
+
+	t.Run("get payees by prefix", func(t *testing.T) {
+		rec := testGetRequest(ctx, "/payees?prefix=CORN", handler.PayeesGet)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			resp := payeesGetResponse{}
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp)) && assert.Len(t, resp, 2) {
+				assert.Equal(t, "Corner Store", resp[0].Name)
+				assert.Equal(t, int64(2), resp[0].TransactionsCount)
+				assert.Equal(t, "Cornelius", resp[1].Name)
+				assert.Equal(t, int64(1), resp[1].TransactionsCount)
+			}
+		}
+	})
+
+	t.Run("get payees with invalid limit", func(t *testing.T) {
+		rec := testGetRequest(ctx, "/payees?limit=0", handler.PayeesGet)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
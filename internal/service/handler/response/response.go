@@ -27,6 +27,11 @@ var UserNotFound = httpresp.New(
 	model.NewError("user not found"),
 )
 
+var UserAlreadyExists = httpresp.New(
+	http.StatusConflict,
+	model.NewError("user already exists"),
+)
+
 var CategoryNotFound = httpresp.New(
 	http.StatusNotFound,
 	model.NewError("category not found"),
@@ -42,7 +47,277 @@ var InvalidCredentials = httpresp.New(
 	model.NewError("invalid credentials"),
 )
 
+var TooManyLoginAttempts = httpresp.New(
+	http.StatusTooManyRequests,
+	model.NewError("too many failed login attempts, try again later"),
+)
+
+var WrongPassword = httpresp.New(
+	http.StatusForbidden,
+	model.NewError("wrong current password"),
+)
+
+var PasswordAlreadySet = httpresp.New(
+	http.StatusConflict,
+	model.NewError("password is already set, change it using the current password"),
+)
+
+var InvalidChallengeToken = httpresp.New(
+	http.StatusUnauthorized,
+	model.NewError("invalid or expired challenge token"),
+)
+
+var InvalidTwoFactorCode = httpresp.New(
+	http.StatusUnauthorized,
+	model.NewError("invalid two-factor authentication code"),
+)
+
+var TwoFactorAlreadyEnabled = httpresp.New(
+	http.StatusConflict,
+	model.NewError("two-factor authentication is already enabled"),
+)
+
+var TwoFactorNotEnabled = httpresp.New(
+	http.StatusConflict,
+	model.NewError("two-factor authentication is not enabled"),
+)
+
+var TwoFactorNotEnrolled = httpresp.New(
+	http.StatusConflict,
+	model.NewError("two-factor authentication enrollment is not started"),
+)
+
+var InvalidRefreshToken = httpresp.New(
+	http.StatusUnauthorized,
+	model.NewError("invalid, expired or revoked refresh token"),
+)
+
+var RefreshTokenReused = httpresp.New(
+	http.StatusUnauthorized,
+	model.NewError("refresh token was already used, all tokens of this login were revoked"),
+)
+
+var SessionNotFound = httpresp.New(
+	http.StatusNotFound,
+	model.NewError("session not found"),
+)
+
+var SessionForbidden = httpresp.New(
+	http.StatusForbidden,
+	model.NewError("you have no access to this session"),
+)
+
+var APIKeyNotFound = httpresp.New(
+	http.StatusNotFound,
+	model.NewError("api key not found"),
+)
+
+var APIKeyForbidden = httpresp.New(
+	http.StatusForbidden,
+	model.NewError("you have no access to this api key"),
+)
+
 var CurrencyNotFound = httpresp.New(
 	http.StatusNotFound,
 	model.NewError("currency not found"),
 )
+
+var TagNotFound = httpresp.New(
+	http.StatusNotFound,
+	model.NewError("tag not found"),
+)
+
+var TagForbidden = httpresp.New(
+	http.StatusForbidden,
+	model.NewError("you have no access to this tag"),
+)
+
+var TagAlreadyExists = httpresp.New(
+	http.StatusConflict,
+	model.NewError("tag with such name already exists"),
+)
+
+var TransactionNotFound = httpresp.New(
+	http.StatusNotFound,
+	model.NewError("transaction not found"),
+)
+
+var TransactionForbidden = httpresp.New(
+	http.StatusForbidden,
+	model.NewError("you have no access to this transaction"),
+)
+
+var SplitsAmountMismatch = httpresp.New(
+	http.StatusBadRequest,
+	model.NewError("amounts of the splits do not sum up to the transaction amount"),
+)
+
+var PayeeNotFound = httpresp.New(
+	http.StatusNotFound,
+	model.NewError("payee not found"),
+)
+
+var PayeeForbidden = httpresp.New(
+	http.StatusForbidden,
+	model.NewError("you have no access to this payee"),
+)
+
+var RuleNotFound = httpresp.New(
+	http.StatusNotFound,
+	model.NewError("rule not found"),
+)
+
+var RuleForbidden = httpresp.New(
+	http.StatusForbidden,
+	model.NewError("you have no access to this rule"),
+)
+
+var ImportFileMissing = httpresp.New(
+	http.StatusBadRequest,
+	model.NewError("file to import is missing"),
+)
+
+var ImportFormatUnknown = httpresp.New(
+	http.StatusBadRequest,
+	model.NewError("unknown format of the file to import"),
+)
+
+var ImportReportNotFound = httpresp.New(
+	http.StatusNotFound,
+	model.NewError("import report not found"),
+)
+
+var ImportReportForbidden = httpresp.New(
+	http.StatusForbidden,
+	model.NewError("you have no access to this import report"),
+)
+
+var OIDCNotConfigured = httpresp.New(
+	http.StatusNotFound,
+	model.NewError("oidc login is not configured"),
+)
+
+var InvalidOIDCStateToken = httpresp.New(
+	http.StatusUnauthorized,
+	model.NewError("invalid or expired oidc state token"),
+)
+
+var OIDCLoginRejected = httpresp.New(
+	http.StatusUnauthorized,
+	model.NewError("identity provider rejected the login"),
+)
+
+var OIDCIdentityNotLinked = httpresp.New(
+	http.StatusForbidden,
+	model.NewError("no user is linked to this identity"),
+)
+
+var OIDCIdentityLinkedToAnotherUser = httpresp.New(
+	http.StatusConflict,
+	model.NewError("this identity is linked to another user"),
+)
+
+var UserDisabled = httpresp.New(
+	http.StatusForbidden,
+	model.NewError("user is disabled"),
+)
+
+var CannotDisableCurrentUser = httpresp.New(
+	http.StatusConflict,
+	model.NewError("you can not disable yourself"),
+)
+
+var RegistrationClosed = httpresp.New(
+	http.StatusForbidden,
+	model.NewError("registration is closed"),
+)
+
+var InviteCodeRequired = httpresp.New(
+	http.StatusForbidden,
+	model.NewError("invite code is required"),
+)
+
+var InvalidInviteCode = httpresp.New(
+	http.StatusForbidden,
+	model.NewError("invalid, expired or used up invite code"),
+)
+
+var InviteCodesForbidden = httpresp.New(
+	http.StatusForbidden,
+	model.NewError("you are not allowed to create invite codes"),
+)
+
+var InviteCodeNotFound = httpresp.New(
+	http.StatusNotFound,
+	model.NewError("invite code not found"),
+)
+
+var InviteCodeForbidden = httpresp.New(
+	http.StatusForbidden,
+	model.NewError("you have no access to this invite code"),
+)
+
+var LedgerNotFound = httpresp.New(
+	http.StatusNotFound,
+	model.NewError("ledger not found"),
+)
+
+var LedgerForbidden = httpresp.New(
+	http.StatusForbidden,
+	model.NewError("you have no access to this ledger"),
+)
+
+var LedgerRoleInsufficient = httpresp.New(
+	http.StatusForbidden,
+	model.NewError("your role in the ledger does not allow this action"),
+)
+
+var PersonalLedgerNotShareable = httpresp.New(
+	http.StatusConflict,
+	model.NewError("personal ledger can not be shared"),
+)
+
+var LedgerMemberNotFound = httpresp.New(
+	http.StatusNotFound,
+	model.NewError("ledger member not found"),
+)
+
+var LedgerMemberExists = httpresp.New(
+	http.StatusConflict,
+	model.NewError("user is already a member of this ledger"),
+)
+
+var LastLedgerOwner = httpresp.New(
+	http.StatusConflict,
+	model.NewError("ledger must have at least one owner"),
+)
+
+var UserIsLastLedgerOwner = httpresp.New(
+	http.StatusConflict,
+	model.NewError("user is the only owner of a shared ledger, make another member an owner first"),
+)
+
+var UserHasBalance = httpresp.New(
+	http.StatusConflict,
+	model.NewError("user must settle up in all ledgers before being deleted"),
+)
+
+var LedgerMemberHasBalance = httpresp.New(
+	http.StatusConflict,
+	model.NewError("ledger member must settle up before leaving the ledger"),
+)
+
+var ExpenseNotShareable = httpresp.New(
+	http.StatusBadRequest,
+	model.NewError("only expenses (transactions with negative amounts) can be shared"),
+)
+
+var InvalidExpenseShares = httpresp.New(
+	http.StatusBadRequest,
+	model.NewError("shares of the expense are invalid or do not sum up to its amount"),
+)
+
+var ImportConflict = httpresp.New(
+	http.StatusConflict,
+	model.NewError("some entries were imported concurrently, repeat the import to skip them"),
+)
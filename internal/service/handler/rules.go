@@ -0,0 +1,600 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/groshi-project/groshi/internal/rules"
+	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
+	"github.com/groshi-project/groshi/internal/service/handler/model"
+	"github.com/groshi-project/groshi/internal/service/handler/response"
+	"net/http"
+)
+
+// rulesEngine fetches rules of the user and creates an engine which applies them.
+func (h *Handler) rulesEngine(ctx context.Context, user *database.User) (*rules.Engine, []database.Rule, error) {
+	userRules := make([]database.Rule, 0)
+	if err := h.database.SelectRulesByOwnerID(ctx, user.ID, &userRules); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, err
+		}
+	}
+
+	engine, err := rules.New(userRules)
+	if err != nil {
+		return nil, nil, err
+	}
+	return engine, userRules, nil
+}
+
+type rulesParams struct {
+	Name string `json:"name" example:"Supermarkets" validate:"required"`
+
+	// Rules are applied in ascending order of their positions.
+	Position int `json:"position" example:"10"`
+
+	// Conditions:
+	MatchField   string `json:"match_field" example:"payee" validate:"omitempty,oneof=description payee"`
+	MatchType    string `json:"match_type" example:"substring" validate:"omitempty,oneof=substring regex"`
+	Pattern      string `json:"pattern" example:"rewe"`
+	AmountMin    *int32 `json:"amount_min" example:"-10000"`
+	AmountMax    *int32 `json:"amount_max" example:"0"`
+	CurrencyCode string `json:"currency" example:"EUR"`
+
+	// Actions:
+	CategoryUUID string   `json:"category" example:"02983837-7ab0-492a-90b6-285491936067"`
+	TagUUIDs     []string `json:"tags" example:"5f0c2d6e-5c1a-4b8e-9d39-7f9b3b1d2a61"`
+	RenamePayee  string   `json:"rename_payee" example:"REWE"`
+}
+
+// fillRule fills the rule using request params, checks if objects referred by params belong to the user
+// and validates the rule. Renders an error response and returns false if the rule could not be filled.
+func (h *Handler) fillRule(w http.ResponseWriter, r *http.Request, params *rulesParams, user *database.User, rule *database.Rule) bool {
+	rule.Name = params.Name
+	rule.Position = params.Position
+	rule.MatchField = params.MatchField
+	rule.MatchType = params.MatchType
+	rule.Pattern = params.Pattern
+	rule.AmountMin = params.AmountMin
+	rule.AmountMax = params.AmountMax
+	rule.PayeeName = database.CleanPayeeName(params.RenamePayee)
+	rule.OwnerID = user.ID
+
+	// fetch provided currency:
+	rule.CurrencyID = 0
+	if params.CurrencyCode != "" {
+		currency := &database.Currency{}
+		if err := h.database.SelectCurrencyByCode(r.Context(), params.CurrencyCode, currency); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				httpresp.Render(w, response.CurrencyNotFound)
+				return false
+			}
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return false
+		}
+		rule.CurrencyID = currency.ID
+	}
+
+	// fetch provided category and check if the current user can edit its ledger:
+	rule.CategoryID = 0
+	if params.CategoryUUID != "" {
+		category, ok := h.accessibleCategory(w, r, params.CategoryUUID, user, database.LedgerRoleEditor)
+		if !ok {
+			return false
+		}
+		rule.CategoryID = category.ID
+	}
+
+	// fetch provided tags and check if they belong to the current user:
+	rule.TagIDs = make([]int64, 0, len(params.TagUUIDs))
+	for _, tagUUID := range params.TagUUIDs {
+		tag, ok := h.ownedTag(w, r, tagUUID, user)
+		if !ok {
+			return false
+		}
+		rule.TagIDs = append(rule.TagIDs, tag.ID)
+	}
+
+	// validate the rule:
+	if err := rules.Validate(rule); err != nil {
+		httpresp.Render(w, httpresp.New(http.StatusBadRequest, model.NewError(fmt.Sprintf("invalid rule: %s", err))))
+		return false
+	}
+
+	return true
+}
+
+type rulesCreateResponse struct {
+	UUID string `json:"uuid" example:"4c8f6a3e-2b7d-4e1f-9a5c-8d3b2e1f0a94"`
+}
+
+// RulesCreate creates a new auto-categorization rule and returns its UUID.
+//
+//	@Summary		Create a new rule
+//	@Description	Creates a new auto-categorization rule and returns its UUID.
+//	@Description	Rule matches a transaction if its description or payee contains the substring or matches the regular expression,
+//	@Description	its amount is within the range and its currency is the given one (each condition is optional, at least one is required).
+//	@Description	Matched rule sets category, adds tags or renames payee of the transaction (at least one action is required).
+//	@Tags			rules
+//	@Accept			json
+//	@Produce		json
+//	@Param			rule	body		rulesParams			true	"Rule"
+//	@Success		200		{object}	rulesCreateResponse	"Successful operation"
+//	@Failure		400		{object}	model.Error			"Invalid request body format, invalid request params or invalid rule"
+//	@Failure		403		{object}	model.Error			"Access to the category or a tag is forbidden"
+//	@Failure		404		{object}	model.Error			"User, currency, category or tag not found"
+//	@Failure		500		{object}	model.Error			"Internal server error"
+//	@Security		Bearer
+//	@Router			/rules [post]
+func (h *Handler) RulesCreate(w http.ResponseWriter, r *http.Request) {
+	// decode request params:
+	params := &rulesParams{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return
+	}
+
+	// validate request params:
+	if err := h.paramsValidate.Struct(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return
+	}
+
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// create a new rule owned by the current user:
+	rule := &database.Rule{}
+	if !h.fillRule(w, r, params, user, rule) {
+		return
+	}
+	if err := h.database.CreateRule(r.Context(), rule); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	resp := &rulesCreateResponse{
+		UUID: rule.UUID.String(),
+	}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
+
+type rulesGetResponseItem struct {
+	UUID     string `json:"uuid" example:"4c8f6a3e-2b7d-4e1f-9a5c-8d3b2e1f0a94"`
+	Name     string `json:"name" example:"Supermarkets"`
+	Position int    `json:"position" example:"10"`
+
+	MatchField   string `json:"match_field,omitempty" example:"payee"`
+	MatchType    string `json:"match_type,omitempty" example:"substring"`
+	Pattern      string `json:"pattern,omitempty" example:"rewe"`
+	AmountMin    *int32 `json:"amount_min,omitempty" example:"-10000"`
+	AmountMax    *int32 `json:"amount_max,omitempty" example:"0"`
+	CurrencyCode string `json:"currency,omitempty" example:"EUR"`
+
+	CategoryUUID string   `json:"category,omitempty" example:"02983837-7ab0-492a-90b6-285491936067"`
+	TagUUIDs     []string `json:"tags,omitempty" example:"5f0c2d6e-5c1a-4b8e-9d39-7f9b3b1d2a61"`
+	RenamePayee  string   `json:"rename_payee,omitempty" example:"REWE"`
+}
+
+type rulesGetResponse []rulesGetResponseItem
+
+// RulesGet returns all rules of user in the order they are applied in.
+//
+//	@Summary		Fetch all rules
+//	@Description	Returns all auto-categorization rules of user in the order they are applied in.
+//	@Tags			rules
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	rulesGetResponse	"Successful operation"
+//	@Failure		404	{object}	model.Error			"User not found"
+//	@Failure		500	{object}	model.Error			"Internal server error"
+//	@Security		Bearer
+//	@Router			/rules [get]
+func (h *Handler) RulesGet(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch rules of the current user:
+	userRules := make([]database.Rule, 0)
+	if err := h.database.SelectRulesByOwnerID(r.Context(), user.ID, &userRules); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+
+	// fetch tags of the current user to resolve their UUIDs:
+	tags := make([]database.Tag, 0)
+	if err := h.database.SelectTagsByOwnerID(r.Context(), user.ID, &tags); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+	tagUUIDs := make(map[int64]string, len(tags))
+	for _, tag := range tags {
+		tagUUIDs[tag.ID] = tag.UUID.String()
+	}
+
+	// respond:
+	resp := make(rulesGetResponse, 0, len(userRules))
+	for _, rule := range userRules {
+		item := rulesGetResponseItem{
+			UUID:         rule.UUID.String(),
+			Name:         rule.Name,
+			Position:     rule.Position,
+			MatchField:   rule.MatchField,
+			MatchType:    rule.MatchType,
+			Pattern:      rule.Pattern,
+			AmountMin:    rule.AmountMin,
+			AmountMax:    rule.AmountMax,
+			CurrencyCode: rule.Currency.Code,
+			RenamePayee:  rule.PayeeName,
+		}
+		if rule.CategoryID != 0 {
+			item.CategoryUUID = rule.Category.UUID.String()
+		}
+		for _, tagID := range rule.TagIDs {
+			if tagUUID, ok := tagUUIDs[tagID]; ok {
+				item.TagUUIDs = append(item.TagUUIDs, tagUUID)
+			}
+		}
+		resp = append(resp, item)
+	}
+	httpresp.Render(w, httpresp.NewOK(&resp))
+}
+
+// ownedRule fetches rule with the given UUID and checks that it belongs to the user.
+// Renders an error response and returns false if the rule could not be fetched or is not owned by the user.
+func (h *Handler) ownedRule(w http.ResponseWriter, r *http.Request, uuid string, user *database.User) (*database.Rule, bool) {
+	rule := &database.Rule{}
+	if err := h.database.SelectRuleByUUID(r.Context(), uuid, rule); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpresp.Render(w, response.RuleNotFound)
+			return nil, false
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return nil, false
+	}
+
+	if rule.OwnerID != user.ID {
+		httpresp.Render(w, response.RuleForbidden)
+		return nil, false
+	}
+
+	return rule, true
+}
+
+type rulesUpdateResponse struct {
+	UUID string `json:"uuid" example:"4c8f6a3e-2b7d-4e1f-9a5c-8d3b2e1f0a94"`
+}
+
+// RulesUpdate replaces conditions and actions of a rule.
+//
+//	@Summary		Update a rule
+//	@Description	Replaces conditions and actions of a rule and returns its UUID
+//	@Tags			rules
+//	@Accept			json
+//	@Produce		json
+//	@Param			uuid	path		string				true	"Rule UUID"
+//	@Param			rule	body		rulesParams			true	"Rule"
+//	@Success		200		{object}	rulesUpdateResponse	"Successful operation"
+//	@Failure		400		{object}	model.Error			"Invalid request body format, invalid request params or invalid rule"
+//	@Failure		403		{object}	model.Error			"Access to the rule, category or a tag is forbidden"
+//	@Failure		404		{object}	model.Error			"User, rule, currency, category or tag not found"
+//	@Failure		500		{object}	model.Error			"Internal server error"
+//	@Security		Bearer
+//	@Router			/rules/{uuid} [put]
+func (h *Handler) RulesUpdate(w http.ResponseWriter, r *http.Request) {
+	// decode request params:
+	params := &rulesParams{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return
+	}
+
+	// validate request params:
+	if err := h.paramsValidate.Struct(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return
+	}
+
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch the rule and check if it belongs to the current user:
+	rule, ok := h.ownedRule(w, r, chi.URLParam(r, "uuid"), user)
+	if !ok {
+		return
+	}
+
+	// update the rule:
+	if !h.fillRule(w, r, params, user, rule) {
+		return
+	}
+	if err := h.database.UpdateRule(r.Context(), rule); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	resp := &rulesUpdateResponse{
+		UUID: rule.UUID.String(),
+	}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
+
+type rulesDeleteResponse struct {
+	UUID string `json:"uuid" example:"4c8f6a3e-2b7d-4e1f-9a5c-8d3b2e1f0a94"`
+}
+
+// RulesDelete deletes a rule.
+//
+//	@Summary		Delete a rule
+//	@Description	Deletes a rule and returns its UUID
+//	@Tags			rules
+//	@Accept			json
+//	@Produce		json
+//	@Param			uuid	path		string				true	"Rule UUID"
+//	@Success		200		{object}	rulesDeleteResponse	"Successful operation"
+//	@Failure		403		{object}	model.Error			"Access to the rule is forbidden"
+//	@Failure		404		{object}	model.Error			"User or rule not found"
+//	@Failure		500		{object}	model.Error			"Internal server error"
+//	@Security		Bearer
+//	@Router			/rules/{uuid} [delete]
+func (h *Handler) RulesDelete(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch the rule and check if it belongs to the current user:
+	rule, ok := h.ownedRule(w, r, chi.URLParam(r, "uuid"), user)
+	if !ok {
+		return
+	}
+
+	// delete the rule from the database:
+	if err := h.database.DeleteRuleByID(r.Context(), rule.ID); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	resp := &rulesDeleteResponse{UUID: rule.UUID.String()}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
+
+type rulesApplyParams struct {
+	// Only report changes which would be made, do not save them.
+	DryRun bool `json:"dry_run" example:"true"`
+
+	// Apply rules to categorized transactions too, by default only uncategorized ones are processed.
+	IncludeCategorized bool `json:"include_categorized" example:"false"`
+}
+
+type rulesApplyResponseItem struct {
+	TransactionUUID string `json:"transaction" example:"3be1ed0a-c307-49de-872e-38730200f301"`
+	Description     string `json:"description" example:"REWE SAGT DANKE 1234"`
+
+	// UUIDs of the matched rules.
+	RuleUUIDs []string `json:"rules" example:"4c8f6a3e-2b7d-4e1f-9a5c-8d3b2e1f0a94"`
+
+	// UUID of the new category, empty if category is not changed.
+	CategoryUUID string `json:"category,omitempty" example:"02983837-7ab0-492a-90b6-285491936067"`
+
+	// UUIDs of the added tags.
+	AddedTagUUIDs []string `json:"added_tags,omitempty" example:"5f0c2d6e-5c1a-4b8e-9d39-7f9b3b1d2a61"`
+
+	// Name of the new payee, empty if payee is not changed.
+	Payee string `json:"payee,omitempty" example:"REWE"`
+}
+
+type rulesApplyResponse struct {
+	DryRun  bool                     `json:"dry_run" example:"true"`
+	Changes []rulesApplyResponseItem `json:"changes"`
+}
+
+// RulesApply re-applies rules to existing transactions.
+//
+//	@Summary		Re-apply rules to existing transactions
+//	@Description	Applies rules of user to existing transactions of the ledger which are not split across categories
+//	@Description	(only to uncategorized ones unless `include_categorized` is set) and returns changes made.
+//	@Description	Categories of other ledgers set by the rules are not applied. In dry-run mode changes are only reported and not saved.
+//	@Tags			rules
+//	@Accept			json
+//	@Produce		json
+//	@Param			ledger	query		string				false	"Ledger UUID, the personal ledger of user is used if it is not set"
+//	@Param			params	body		rulesApplyParams	true	"Options"
+//	@Success		200		{object}	rulesApplyResponse	"Successful operation"
+//	@Failure		400		{object}	model.Error			"Invalid request body format"
+//	@Failure		403		{object}	model.Error			"Access to the ledger is forbidden or user is its viewer"
+//	@Failure		404		{object}	model.Error			"User or ledger not found"
+//	@Failure		500		{object}	model.Error			"Internal server error"
+//	@Security		Bearer
+//	@Router			/rules/apply [post]
+func (h *Handler) RulesApply(w http.ResponseWriter, r *http.Request) {
+	// decode request params:
+	params := &rulesApplyParams{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return
+	}
+
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch rules of the current user:
+	engine, userRules, err := h.rulesEngine(r.Context(), user)
+	if err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+	ruleUUIDs := make(map[int64]string, len(userRules))
+	for _, rule := range userRules {
+		ruleUUIDs[rule.ID] = rule.UUID.String()
+	}
+
+	// fetch the ledger and check if the current user can edit it:
+	ledger, ok := h.requestLedger(w, r, user, database.LedgerRoleEditor)
+	if !ok {
+		return
+	}
+
+	// fetch categories of the ledger and tags of the current user, rules may refer to deleted ones
+	// and to categories of other ledgers:
+	categories := make([]database.Category, 0)
+	if err := h.database.SelectCategoriesByLedgerID(r.Context(), ledger.ID, &categories); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+	categoriesByID := make(map[int64]database.Category, len(categories))
+	for _, category := range categories {
+		categoriesByID[category.ID] = category
+	}
+
+	tags := make([]database.Tag, 0)
+	if err := h.database.SelectTagsByOwnerID(r.Context(), user.ID, &tags); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+	tagsByID := make(map[int64]database.Tag, len(tags))
+	for _, tag := range tags {
+		tagsByID[tag.ID] = tag
+	}
+
+	// fetch transactions of the ledger, settlements are not categorized:
+	transactions := make([]database.Transaction, 0)
+	filter := &database.TransactionFilter{LedgerID: ledger.ID, ExcludeSettlements: true}
+	if err := h.database.SelectTransactions(r.Context(), filter, &transactions); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+
+	resp := &rulesApplyResponse{
+		DryRun:  params.DryRun,
+		Changes: make([]rulesApplyResponseItem, 0),
+	}
+	changed := make([]*database.Transaction, 0)
+	for i := range transactions {
+		transaction := &transactions[i]
+		if len(transaction.Splits) != 0 || (transaction.CategoryID != 0 && !params.IncludeCategorized) {
+			continue
+		}
+
+		result := engine.Apply(rules.Input{
+			Description: transaction.Description,
+			Payee:       transaction.Payee.Name,
+			Amount:      transaction.Amount,
+			CurrencyID:  transaction.CurrencyID,
+		})
+		if !result.Matched() {
+			continue
+		}
+
+		// collect changes the matched rules make:
+		change := rulesApplyResponseItem{
+			TransactionUUID: transaction.UUID.String(),
+			Description:     transaction.Description,
+		}
+		for _, ruleID := range result.RuleIDs {
+			change.RuleUUIDs = append(change.RuleUUIDs, ruleUUIDs[ruleID])
+		}
+
+		if category, ok := categoriesByID[result.CategoryID]; ok && category.ID != transaction.CategoryID {
+			change.CategoryUUID = category.UUID.String()
+			transaction.CategoryID = category.ID
+		}
+
+		for _, tagID := range result.TagIDs {
+			tag, ok := tagsByID[tagID]
+			if !ok || containsTag(transaction.Tags, tagID) {
+				continue
+			}
+			change.AddedTagUUIDs = append(change.AddedTagUUIDs, tag.UUID.String())
+			transaction.Tags = append(transaction.Tags, tag)
+		}
+
+		if result.PayeeName != "" && database.NormalizePayeeName(result.PayeeName) != database.NormalizePayeeName(transaction.Payee.Name) {
+			change.Payee = result.PayeeName
+		}
+
+		if change.CategoryUUID == "" && len(change.AddedTagUUIDs) == 0 && change.Payee == "" {
+			continue
+		}
+		resp.Changes = append(resp.Changes, change)
+		changed = append(changed, transaction)
+	}
+
+	// save the changes atomically, so that a failure does not leave them applied partially:
+	if !params.DryRun {
+		err := h.database.RunInTx(r.Context(), func(ctx context.Context, db database.Database) error {
+			for i, transaction := range changed {
+				if payeeName := resp.Changes[i].Payee; payeeName != "" {
+					payee := &database.Payee{}
+					if err := db.SelectOrCreatePayee(ctx, user.ID, payeeName, payee); err != nil {
+						return err
+					}
+					transaction.PayeeID = payee.ID
+				}
+				if err := db.UpdateTransaction(ctx, transaction); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+
+	// respond:
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
+
+// containsTag returns true if the tags contain the tag with the given ID.
+func containsTag(tags []database.Tag, tagID int64) bool {
+	for _, tag := range tags {
+		if tag.ID == tagID {
+			return true
+		}
+	}
+	return false
+}
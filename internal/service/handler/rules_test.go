@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHandler_Rules(t *testing.T) {
+	const (
+		testUserID   int64 = 6
+		testUsername       = "test-username"
+
+		otherUserID int64 = 7
+	)
+
+	var (
+		handler = newTestHandler()
+		db      = handler.database.(*mockDatabase)
+		ctx     = testUserContext(context.Background(), handler, testUsername)
+	)
+
+	// create a test user, categories, a tag and a currency:
+	if err := db.CreateUser(ctx, &database.User{ID: testUserID, Username: testUsername}); err != nil {
+		panic(err)
+	}
+	groceries := &database.Category{ID: 1, UUID: uuid.New(), Name: "Groceries", LedgerID: testPersonalLedgerID(db, testUserID), OwnerID: testUserID}
+	if err := db.CreateCategory(ctx, groceries); err != nil {
+		panic(err)
+	}
+	foreign := &database.Category{ID: 2, UUID: uuid.New(), Name: "Foreign", LedgerID: testPersonalLedgerID(db, otherUserID), OwnerID: otherUserID}
+	if err := db.CreateCategory(ctx, foreign); err != nil {
+		panic(err)
+	}
+	food := &database.Tag{ID: 1, UUID: uuid.New(), Name: "food", OwnerID: testUserID}
+	if err := db.CreateTag(ctx, food); err != nil {
+		panic(err)
+	}
+	db.currencies = append(db.currencies, &database.Currency{ID: 1, Code: "EUR", Rate: 1})
+
+	// create a transaction before any rule exists:
+	params := &transactionsCreateParams{
+		Amount:       -1250,
+		CurrencyCode: "EUR",
+		Timestamp:    time.Now(),
+		Description:  "REWE SAGT DANKE 1234",
+	}
+	if rec := testRequest(ctx, params, handler.TransactionsCreate); rec.Code != http.StatusOK {
+		panic(rec.Body.String())
+	}
+	existing := db.transactions[0]
+
+	// create a settlement matching the rule, which must not be changed by it:
+	settlement := &database.Transaction{
+		ID:          100,
+		UUID:        uuid.New(),
+		LedgerID:    existing.LedgerID,
+		OwnerID:     testUserID,
+		PayerID:     testUserID,
+		CurrencyID:  1,
+		Amount:      -500,
+		Timestamp:   time.Now(),
+		Description: "Settlement with Rewe colleague",
+		Settlement:  true,
+	}
+	db.transactions = append(db.transactions, settlement)
+
+	t.Run("create invalid rules", func(t *testing.T) {
+		testCases := []struct {
+			name   string
+			params *rulesParams
+			code   int
+		}{
+			{
+				"rule without conditions",
+				&rulesParams{Name: "no conditions", CategoryUUID: groceries.UUID.String()},
+				http.StatusBadRequest,
+			},
+			{
+				"rule without actions",
+				&rulesParams{Name: "no actions", MatchField: "description", MatchType: "substring", Pattern: "rewe"},
+				http.StatusBadRequest,
+			},
+			{
+				"rule with invalid regular expression",
+				&rulesParams{Name: "bad regex", MatchField: "description", MatchType: "regex", Pattern: "(", CategoryUUID: groceries.UUID.String()},
+				http.StatusBadRequest,
+			},
+			{
+				"rule with category of another user",
+				&rulesParams{Name: "foreign", MatchField: "description", MatchType: "substring", Pattern: "rewe", CategoryUUID: foreign.UUID.String()},
+				http.StatusForbidden,
+			},
+		}
+
+		for _, testCase := range testCases {
+			rec := testRequest(ctx, testCase.params, handler.RulesCreate)
+			assert.Equal(t, testCase.code, rec.Code, testCase.name)
+		}
+		assert.Empty(t, db.rules)
+	})
+
+	t.Run("create a rule", func(t *testing.T) {
+		rec := testRequest(ctx, &rulesParams{
+			Name:         "Supermarkets",
+			MatchField:   "description",
+			MatchType:    "substring",
+			Pattern:      "rewe",
+			CategoryUUID: groceries.UUID.String(),
+			TagUUIDs:     []string{food.UUID.String()},
+			RenamePayee:  "REWE",
+		}, handler.RulesCreate)
+		if assert.Equal(t, http.StatusOK, rec.Code) && assert.Len(t, db.rules, 1) {
+			assert.Equal(t, groceries.ID, db.rules[0].CategoryID)
+			assert.Equal(t, []int64{food.ID}, db.rules[0].TagIDs)
+		}
+	})
+
+	t.Run("new transaction is categorized by the rule", func(t *testing.T) {
+		params := &transactionsCreateParams{
+			Amount:       -830,
+			CurrencyCode: "EUR",
+			Timestamp:    time.Now(),
+			Description:  "Rewe Markt GmbH",
+		}
+		rec := testRequest(ctx, params, handler.TransactionsCreate)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			transaction := db.transactions[len(db.transactions)-1]
+			assert.Equal(t, groceries.ID, transaction.CategoryID)
+			if assert.Len(t, transaction.Tags, 1) {
+				assert.Equal(t, food.ID, transaction.Tags[0].ID)
+			}
+			assert.NotZero(t, transaction.PayeeID)
+		}
+	})
+
+	t.Run("explicit category is not overridden by the rule", func(t *testing.T) {
+		params := &transactionsCreateParams{
+			Amount:       -830,
+			CurrencyCode: "EUR",
+			Timestamp:    time.Now(),
+			Description:  "Rewe Markt GmbH",
+			CategoryUUID: groceries.UUID.String(),
+		}
+		rec := testRequest(ctx, params, handler.TransactionsCreate)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			transaction := db.transactions[len(db.transactions)-1]
+			assert.Empty(t, transaction.Tags)
+			assert.Zero(t, transaction.PayeeID)
+		}
+	})
+
+	t.Run("apply rules in dry-run mode", func(t *testing.T) {
+		rec := testRequest(ctx, &rulesApplyParams{DryRun: true}, handler.RulesApply)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			resp := rulesApplyResponse{}
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp)) && assert.Len(t, resp.Changes, 1) {
+				change := resp.Changes[0]
+				assert.Equal(t, existing.UUID.String(), change.TransactionUUID)
+				assert.Equal(t, groceries.UUID.String(), change.CategoryUUID)
+				assert.Equal(t, []string{food.UUID.String()}, change.AddedTagUUIDs)
+				assert.Equal(t, "REWE", change.Payee)
+			}
+			assert.Zero(t, existing.CategoryID)
+		}
+	})
+
+	t.Run("apply rules", func(t *testing.T) {
+		rec := testRequest(ctx, &rulesApplyParams{}, handler.RulesApply)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			assert.Equal(t, groceries.ID, existing.CategoryID)
+			assert.Len(t, existing.Tags, 1)
+			assert.NotZero(t, existing.PayeeID)
+			assert.Zero(t, settlement.CategoryID)
+			assert.Empty(t, settlement.Tags)
+		}
+	})
+
+	t.Run("delete the rule", func(t *testing.T) {
+		ruleCtx := withURLParam(ctx, "uuid", db.rules[0].UUID.String())
+		rec := testRequest(ruleCtx, nil, handler.RulesDelete)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, db.rules)
+	})
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
+	"github.com/groshi-project/groshi/internal/service/handler/response"
+	"net/http"
+	"time"
+)
+
+type sessionsGetResponseItem struct {
+	UUID       string    `json:"uuid" example:"0b8e5f7a-2f4c-4d8e-a3c1-6a5b9e2d7f10"`
+	DeviceName string    `json:"device_name" example:"Pixel 8"`
+	UserAgent  string    `json:"user_agent" example:"groshi-android/1.4.0"`
+	IP         string    `json:"ip" example:"203.0.113.7"`
+	CreatedAt  time.Time `json:"created_at" example:"2026-03-20T12:57:38Z"`
+	LastSeenAt time.Time `json:"last_seen_at" example:"2026-03-22T08:14:02Z"`
+
+	// True for the session the request is made from.
+	Current bool `json:"current" example:"true"`
+}
+
+type sessionsGetResponse []sessionsGetResponseItem
+
+// SessionsGet returns active sessions of the current user.
+//
+//	@Summary		Fetch active sessions
+//	@Description	Returns sessions of the current user which are neither revoked nor expired, the most recently seen ones first
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	sessionsGetResponse	"Successful operation"
+//	@Failure		404	{object}	model.Error			"User not found"
+//	@Failure		500	{object}	model.Error			"Internal server error"
+//	@Security		Bearer
+//	@Router			/user/sessions [get]
+func (h *Handler) SessionsGet(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user and session:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	current, ok := h.currentSession(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch active sessions of the user from the database:
+	sessions := make([]database.Session, 0)
+	if err := h.database.SelectActiveSessionsByOwnerID(r.Context(), user.ID, &sessions); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+
+	// respond:
+	resp := make(sessionsGetResponse, 0, len(sessions))
+	for _, session := range sessions {
+		resp = append(resp, sessionsGetResponseItem{
+			UUID:       session.UUID.String(),
+			DeviceName: session.DeviceName,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    session.ID == current.ID,
+		})
+	}
+	httpresp.Render(w, httpresp.NewOK(&resp))
+}
+
+// ownedSession fetches session with the given UUID and checks that it belongs to the user.
+// Renders an error response and returns false if the session could not be fetched or is not owned by the user.
+func (h *Handler) ownedSession(w http.ResponseWriter, r *http.Request, uuid string, user *database.User) (*database.Session, bool) {
+	session := &database.Session{}
+	if err := h.database.SelectSessionByUUID(r.Context(), uuid, session); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpresp.Render(w, response.SessionNotFound)
+			return nil, false
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return nil, false
+	}
+
+	if session.OwnerID != user.ID {
+		httpresp.Render(w, response.SessionForbidden)
+		return nil, false
+	}
+
+	return session, true
+}
+
+type sessionsDeleteOneResponse struct {
+	UUID string `json:"uuid" example:"0b8e5f7a-2f4c-4d8e-a3c1-6a5b9e2d7f10"`
+}
+
+// SessionsDeleteOne revokes a session of the current user.
+//
+//	@Summary		Revoke a session
+//	@Description	Revokes a session of the current user together with all its tokens and returns its UUID
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			uuid	path		string						true	"Session UUID"
+//	@Success		200		{object}	sessionsDeleteOneResponse	"Successful operation"
+//	@Failure		403		{object}	model.Error					"Access to the session is forbidden"
+//	@Failure		404		{object}	model.Error					"User or session not found"
+//	@Failure		500		{object}	model.Error					"Internal server error"
+//	@Security		Bearer
+//	@Router			/user/sessions/{uuid} [delete]
+func (h *Handler) SessionsDeleteOne(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch the session and check if it belongs to the current user:
+	session, ok := h.ownedSession(w, r, chi.URLParam(r, "uuid"), user)
+	if !ok {
+		return
+	}
+
+	// revoke the session:
+	if err := h.database.RevokeSession(r.Context(), session.ID); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	resp := &sessionsDeleteOneResponse{UUID: session.UUID.String()}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
+
+// SessionsDelete revokes all sessions of the current user except the current one.
+//
+//	@Summary		Revoke all other sessions
+//	@Description	Revokes all sessions of the current user except the session the request is made from
+//	@Tags			user
+//	@Success		204	"Successful operation"
+//	@Failure		404	{object}	model.Error	"User not found"
+//	@Failure		500	{object}	model.Error	"Internal server error"
+//	@Security		Bearer
+//	@Router			/user/sessions [delete]
+func (h *Handler) SessionsDelete(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user and session:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	current, ok := h.currentSession(w, r)
+	if !ok {
+		return
+	}
+
+	// revoke all other sessions:
+	if err := h.database.RevokeSessionsByOwnerID(r.Context(), user.ID, current.ID); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/groshi-project/groshi/internal/middleware"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+// testSessionContext logs in twice as a test user and returns context of the first session.
+func testSessionContext(handler *Handler) context.Context {
+	ctx := context.Background()
+	testLogin(ctx, handler)
+	rec := testRequest(ctx, &authLoginParams{Username: "test-username", Password: "test-password", DeviceName: "another-device"}, handler.AuthLogin)
+	if rec.Code != http.StatusOK {
+		panic("could not log in")
+	}
+
+	session := handler.database.(*mockDatabase).sessions[0]
+	ctx = testUserContext(ctx, handler, "test-username")
+	return context.WithValue(ctx, middleware.SessionIDContextKey, session.UUID.String())
+}
+
+func TestHandler_SessionsGet(t *testing.T) {
+	var (
+		handler = newTestHandler()
+		ctx     = testSessionContext(handler)
+	)
+
+	rec := testGetRequest(ctx, "/user/sessions", handler.SessionsGet)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		resp := sessionsGetResponse{}
+		if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp)) && assert.Len(t, resp, 2) {
+			for _, session := range resp {
+				assert.Equal(t, session.DeviceName == "test-device", session.Current)
+				assert.NotEmpty(t, session.IP)
+			}
+		}
+	}
+}
+
+func TestHandler_SessionsDeleteOne(t *testing.T) {
+	t.Run("revoke a session", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testSessionContext(handler)
+			other   = handler.database.(*mockDatabase).sessions[1]
+		)
+
+		rec := testRequest(withURLParam(ctx, "uuid", other.UUID.String()), nil, handler.SessionsDeleteOne)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			assert.False(t, other.RevokedAt.IsZero())
+			assert.True(t, handler.database.(*mockDatabase).sessions[0].RevokedAt.IsZero())
+		}
+	})
+
+	t.Run("revoke a session of another user", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testSessionContext(handler)
+			other   = handler.database.(*mockDatabase).sessions[1]
+		)
+		other.OwnerID = -1
+
+		rec := testRequest(withURLParam(ctx, "uuid", other.UUID.String()), nil, handler.SessionsDeleteOne)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.True(t, other.RevokedAt.IsZero())
+	})
+
+	t.Run("revoke a non-existent session", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testSessionContext(handler)
+		)
+
+		rec := testRequest(withURLParam(ctx, "uuid", "c8a8a0d4-7c0e-4b7e-8a62-5d5f0d1b7a9e"), nil, handler.SessionsDeleteOne)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestHandler_SessionsDelete(t *testing.T) {
+	var (
+		handler = newTestHandler()
+		ctx     = testSessionContext(handler)
+		mockDb  = handler.database.(*mockDatabase)
+	)
+
+	rec := testRequest(ctx, nil, handler.SessionsDelete)
+	if assert.Equal(t, http.StatusNoContent, rec.Code) {
+		assert.True(t, mockDb.sessions[0].RevokedAt.IsZero())
+		assert.False(t, mockDb.sessions[1].RevokedAt.IsZero())
+
+		revoked, _ := mockDb.TokenRevoked(ctx, mockDb.refreshTokens[1].AccessTokenID)
+		assert.True(t, revoked)
+	}
+}
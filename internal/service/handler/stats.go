@@ -1,9 +1,304 @@
 package handler
 
 import (
+	"database/sql"
+	"errors"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
+	"github.com/groshi-project/groshi/internal/service/handler/response"
+	"math"
 	"net/http"
 )
 
 func (h *Handler) StatsTotal(w http.ResponseWriter, r *http.Request) {
 
 }
+
+// statsCurrency fetches the currency statistics should be converted to from the `in` URL query param.
+// Renders an error response and returns false if the currency could not be fetched.
+func (h *Handler) statsCurrency(w http.ResponseWriter, r *http.Request) (*database.Currency, bool) {
+	code := r.URL.Query().Get("in")
+	if code == "" {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return nil, false
+	}
+
+	currency := &database.Currency{}
+	if err := h.database.SelectCurrencyByCode(r.Context(), code, currency); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpresp.Render(w, response.CurrencyNotFound)
+			return nil, false
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return nil, false
+	}
+
+	return currency, true
+}
+
+// convertBaseAmount converts amount in the base currency to the given currency.
+func convertBaseAmount(baseAmount float64, currency *database.Currency) int64 {
+	return int64(math.Round(baseAmount * currency.Rate))
+}
+
+type statsTagsResponseItem struct {
+	UUID              string `json:"uuid" example:"5f0c2d6e-5c1a-4b8e-9d39-7f9b3b1d2a61"`
+	Name              string `json:"name" example:"vacation-2026"`
+	TransactionsCount int64  `json:"transactions_count" example:"12"`
+	Amount            int64  `json:"amount" example:"154000"`
+}
+
+type statsTagsResponse struct {
+	Currency string                  `json:"currency" example:"EUR"`
+	Tags     []statsTagsResponseItem `json:"tags"`
+}
+
+// StatsTags returns amounts spent per tag.
+//
+//	@Summary		Fetch statistics by tags
+//	@Description	Returns count and total amount of transactions marked with each tag, converted to the given currency.
+//	@Description	Transactions are filtered the same way as in the transaction listing, settlements between ledger members are excluded.
+//	@Tags			stats
+//	@Accept			json
+//	@Produce		json
+//	@Param			in			query		string				true	"Code of the currency amounts will be converted to"
+//	@Param			ledger		query		string				false	"Ledger UUID, the personal ledger of user is used if it is not set"
+//	@Param			start_time	query		string				false	"Count transactions which happened at or after this moment (RFC 3339)"
+//	@Param			end_time	query		string				false	"Count transactions which happened before this moment (RFC 3339)"
+//	@Param			currency	query		string				false	"Count only transactions in this currency"
+//	@Param			category	query		string				false	"Category UUID"
+//	@Param			payee		query		string				false	"Payee UUID"
+//	@Param			tag			query		[]string			false	"Tag UUID, may be repeated"
+//	@Param			tag_mode	query		string				false	"Whether transactions must have `any` (default) or `all` of the given tags"
+//	@Success		200			{object}	statsTagsResponse	"Successful operation"
+//	@Failure		400			{object}	model.Error			"Invalid request params"
+//	@Failure		403			{object}	model.Error			"Access to the ledger, the category, payee or a tag is forbidden"
+//	@Failure		404			{object}	model.Error			"User, ledger, currency, category, payee or tag not found"
+//	@Failure		500			{object}	model.Error			"Internal server error"
+//	@Security		Bearer
+//	@Router			/stats/tags [get]
+func (h *Handler) StatsTags(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch the currency amounts will be converted to:
+	currency, ok := h.statsCurrency(w, r)
+	if !ok {
+		return
+	}
+
+	// build transaction filter from request params:
+	filter, ok := h.transactionFilter(w, r, user)
+	if !ok {
+		return
+	}
+
+	// settlements of debts between ledger members are not expenses:
+	filter.ExcludeSettlements = true
+
+	// fetch statistics:
+	stats := make([]database.TagStat, 0)
+	if err := h.database.SelectTagStats(r.Context(), filter, &stats); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+
+	// respond:
+	resp := &statsTagsResponse{
+		Currency: currency.Code,
+		Tags:     make([]statsTagsResponseItem, 0, len(stats)),
+	}
+	for _, stat := range stats {
+		resp.Tags = append(resp.Tags, statsTagsResponseItem{
+			UUID:              stat.TagUUID.String(),
+			Name:              stat.TagName,
+			TransactionsCount: stat.TransactionsCount,
+			Amount:            convertBaseAmount(stat.BaseAmount, currency),
+		})
+	}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
+
+type statsCategoriesResponseItem struct {
+	UUID              string `json:"uuid" example:"02983837-7ab0-492a-90b6-285491936067"`
+	Name              string `json:"name" example:"Groceries"`
+	TransactionsCount int64  `json:"transactions_count" example:"31"`
+	Amount            int64  `json:"amount" example:"42300"`
+}
+
+type statsCategoriesResponse struct {
+	Currency   string                        `json:"currency" example:"EUR"`
+	Categories []statsCategoriesResponseItem `json:"categories"`
+}
+
+// StatsCategories returns amounts spent per category.
+//
+//	@Summary		Fetch statistics by categories
+//	@Description	Returns count and total amount of transactions attributed to each category, converted to the given currency.
+//	@Description	Split transactions are attributed to categories of their split lines.
+//	@Description	Transactions are filtered the same way as in the transaction listing, settlements between ledger members are excluded.
+//	@Tags			stats
+//	@Accept			json
+//	@Produce		json
+//	@Param			in			query		string					true	"Code of the currency amounts will be converted to"
+//	@Param			ledger		query		string					false	"Ledger UUID, the personal ledger of user is used if it is not set"
+//	@Param			start_time	query		string					false	"Count transactions which happened at or after this moment (RFC 3339)"
+//	@Param			end_time	query		string					false	"Count transactions which happened before this moment (RFC 3339)"
+//	@Param			currency	query		string					false	"Count only transactions in this currency"
+//	@Param			category	query		string					false	"Category UUID"
+//	@Param			payee		query		string					false	"Payee UUID"
+//	@Param			tag			query		[]string				false	"Tag UUID, may be repeated"
+//	@Param			tag_mode	query		string					false	"Whether transactions must have `any` (default) or `all` of the given tags"
+//	@Success		200			{object}	statsCategoriesResponse	"Successful operation"
+//	@Failure		400			{object}	model.Error				"Invalid request params"
+//	@Failure		403			{object}	model.Error				"Access to the ledger, the category, payee or a tag is forbidden"
+//	@Failure		404			{object}	model.Error				"User, ledger, currency, category, payee or tag not found"
+//	@Failure		500			{object}	model.Error				"Internal server error"
+//	@Security		Bearer
+//	@Router			/stats/categories [get]
+func (h *Handler) StatsCategories(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch the currency amounts will be converted to:
+	currency, ok := h.statsCurrency(w, r)
+	if !ok {
+		return
+	}
+
+	// build transaction filter from request params:
+	filter, ok := h.transactionFilter(w, r, user)
+	if !ok {
+		return
+	}
+
+	// settlements of debts between ledger members are not expenses:
+	filter.ExcludeSettlements = true
+
+	// fetch statistics:
+	stats := make([]database.CategoryStat, 0)
+	if err := h.database.SelectCategoryStats(r.Context(), filter, &stats); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+
+	// respond:
+	resp := &statsCategoriesResponse{
+		Currency:   currency.Code,
+		Categories: make([]statsCategoriesResponseItem, 0, len(stats)),
+	}
+	for _, stat := range stats {
+		resp.Categories = append(resp.Categories, statsCategoriesResponseItem{
+			UUID:              stat.CategoryUUID.String(),
+			Name:              stat.CategoryName,
+			TransactionsCount: stat.TransactionsCount,
+			Amount:            convertBaseAmount(stat.BaseAmount, currency),
+		})
+	}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
+
+type statsPayeesResponseItem struct {
+	UUID              string `json:"uuid" example:"b5a1f7c4-3f3e-4a58-9a0e-3c2f5e7d8a91"`
+	Name              string `json:"name" example:"Corner Store"`
+	TransactionsCount int64  `json:"transactions_count" example:"27"`
+	Amount            int64  `json:"amount" example:"-61250"`
+}
+
+type statsPayeesResponse struct {
+	Currency string                    `json:"currency" example:"EUR"`
+	Payees   []statsPayeesResponseItem `json:"payees"`
+}
+
+// StatsPayees returns payees with the largest total amount.
+//
+//	@Summary		Fetch top payees
+//	@Description	Returns count and total amount of transactions of the top payees, converted to the given currency.
+//	@Description	Payees with the largest absolute total amount come first.
+//	@Description	Transactions are filtered the same way as in the transaction listing, settlements between ledger members are excluded.
+//	@Tags			stats
+//	@Accept			json
+//	@Produce		json
+//	@Param			in			query		string				true	"Code of the currency amounts will be converted to"
+//	@Param			limit		query		int					false	"Maximum number of payees to return (1-100, default 10)"
+//	@Param			ledger		query		string				false	"Ledger UUID, the personal ledger of user is used if it is not set"
+//	@Param			start_time	query		string				false	"Count transactions which happened at or after this moment (RFC 3339)"
+//	@Param			end_time	query		string				false	"Count transactions which happened before this moment (RFC 3339)"
+//	@Param			currency	query		string				false	"Count only transactions in this currency"
+//	@Param			category	query		string				false	"Category UUID"
+//	@Param			payee		query		string				false	"Payee UUID"
+//	@Param			tag			query		[]string			false	"Tag UUID, may be repeated"
+//	@Param			tag_mode	query		string				false	"Whether transactions must have `any` (default) or `all` of the given tags"
+//	@Success		200			{object}	statsPayeesResponse	"Successful operation"
+//	@Failure		400			{object}	model.Error			"Invalid request params"
+//	@Failure		403			{object}	model.Error			"Access to the ledger, the category, payee or a tag is forbidden"
+//	@Failure		404			{object}	model.Error			"User, ledger, currency, category, payee or tag not found"
+//	@Failure		500			{object}	model.Error			"Internal server error"
+//	@Security		Bearer
+//	@Router			/stats/payees [get]
+func (h *Handler) StatsPayees(w http.ResponseWriter, r *http.Request) {
+	// parse request params:
+	limit, ok := payeesLimit(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch the currency amounts will be converted to:
+	currency, ok := h.statsCurrency(w, r)
+	if !ok {
+		return
+	}
+
+	// build transaction filter from request params:
+	filter, ok := h.transactionFilter(w, r, user)
+	if !ok {
+		return
+	}
+
+	// settlements of debts between ledger members are not expenses:
+	filter.ExcludeSettlements = true
+
+	// fetch statistics:
+	stats := make([]database.PayeeStat, 0)
+	if err := h.database.SelectPayeeStats(r.Context(), filter, limit, &stats); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+
+	// respond:
+	resp := &statsPayeesResponse{
+		Currency: currency.Code,
+		Payees:   make([]statsPayeesResponseItem, 0, len(stats)),
+	}
+	for _, stat := range stats {
+		resp.Payees = append(resp.Payees, statsPayeesResponseItem{
+			UUID:              stat.PayeeUUID.String(),
+			Name:              stat.PayeeName,
+			TransactionsCount: stat.TransactionsCount,
+			Amount:            convertBaseAmount(stat.BaseAmount, currency),
+		})
+	}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
@@ -0,0 +1,277 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
+	"github.com/groshi-project/groshi/internal/service/handler/response"
+	"net/http"
+)
+
+type tagsCreateParams struct {
+	Name string `json:"name" example:"vacation-2026" validate:"required"`
+}
+
+type tagsCreateResponse struct {
+	UUID string `json:"uuid" example:"5f0c2d6e-5c1a-4b8e-9d39-7f9b3b1d2a61"`
+}
+
+// TagsCreate creates a new tag and returns its UUID.
+//
+//	@Summary		Create a new tag
+//	@Description	Creates a new tag and returns its UUID
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			tag	body		tagsCreateParams	true	"Tag name"
+//	@Success		200	{object}	tagsCreateResponse	"Successful operation"
+//	@Failure		400	{object}	model.Error			"Invalid request body format or invalid request params"
+//	@Failure		404	{object}	model.Error			"User not found"
+//	@Failure		409	{object}	model.Error			"Tag with such name already exists"
+//	@Failure		500	{object}	model.Error			"Internal server error"
+//	@Security		Bearer
+//	@Router			/tags [post]
+func (h *Handler) TagsCreate(w http.ResponseWriter, r *http.Request) {
+	// decode request params:
+	params := &tagsCreateParams{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return
+	}
+
+	// validate request params:
+	if err := h.paramsValidate.Struct(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return
+	}
+
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// check if the user already has a tag with such name:
+	exists, err := h.database.TagExistsByName(r.Context(), user.ID, params.Name)
+	if err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+	if exists {
+		httpresp.Render(w, response.TagAlreadyExists)
+		return
+	}
+
+	// create a new tag owned by the current user:
+	tag := &database.Tag{
+		Name:    params.Name,
+		OwnerID: user.ID,
+	}
+	if err := h.database.CreateTag(r.Context(), tag); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	resp := &tagsCreateResponse{
+		UUID: tag.UUID.String(),
+	}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
+
+type tagsGetResponseItem struct {
+	UUID string `json:"uuid" example:"5f0c2d6e-5c1a-4b8e-9d39-7f9b3b1d2a61"`
+	Name string `json:"name" example:"vacation-2026"`
+}
+
+type tagsGetResponse []tagsGetResponseItem
+
+// TagsGet returns all tags created by user.
+//
+//	@Summary		Fetch all tags
+//	@Description	Returns all tags created by user.
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	tagsGetResponse	"Successful operation"
+//	@Failure		404	{object}	model.Error		"User not found"
+//	@Failure		500	{object}	model.Error		"Internal server error"
+//	@Security		Bearer
+//	@Router			/tags [get]
+func (h *Handler) TagsGet(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch tags that belong to this user from the database:
+	tags := make([]database.Tag, 0)
+	if err := h.database.SelectTagsByOwnerID(r.Context(), user.ID, &tags); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+
+	// respond:
+	resp := make(tagsGetResponse, 0, len(tags))
+	for _, tag := range tags {
+		resp = append(resp, tagsGetResponseItem{
+			UUID: tag.UUID.String(),
+			Name: tag.Name,
+		})
+	}
+	httpresp.Render(w, httpresp.NewOK(&resp))
+}
+
+// ownedTag fetches tag with the given UUID and checks that it belongs to the user.
+// Renders an error response and returns false if the tag could not be fetched or is not owned by the user.
+func (h *Handler) ownedTag(w http.ResponseWriter, r *http.Request, uuid string, user *database.User) (*database.Tag, bool) {
+	tag := &database.Tag{}
+	if err := h.database.SelectTagByUUID(r.Context(), uuid, tag); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpresp.Render(w, response.TagNotFound)
+			return nil, false
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return nil, false
+	}
+
+	if tag.OwnerID != user.ID {
+		httpresp.Render(w, response.TagForbidden)
+		return nil, false
+	}
+
+	return tag, true
+}
+
+type tagsUpdateParams struct {
+	Name string `json:"name" example:"reimbursable" validate:"required"`
+}
+
+type tagsUpdateResponse struct {
+	UUID string `json:"uuid" example:"5f0c2d6e-5c1a-4b8e-9d39-7f9b3b1d2a61"`
+}
+
+// TagsUpdate renames a tag.
+//
+//	@Summary		Update a tag
+//	@Description	Renames a tag and returns its UUID
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			uuid	path		string				true	"Tag UUID"
+//	@Param			tag		body		tagsUpdateParams	true	"New tag name"
+//	@Success		200		{object}	tagsUpdateResponse	"Successful operation"
+//	@Failure		400		{object}	model.Error			"Invalid request body format or invalid request params"
+//	@Failure		403		{object}	model.Error			"Access to the tag is forbidden"
+//	@Failure		404		{object}	model.Error			"User or tag not found"
+//	@Failure		409		{object}	model.Error			"Tag with such name already exists"
+//	@Failure		500		{object}	model.Error			"Internal server error"
+//	@Security		Bearer
+//	@Router			/tags/{uuid} [put]
+func (h *Handler) TagsUpdate(w http.ResponseWriter, r *http.Request) {
+	// decode request params:
+	params := &tagsUpdateParams{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return
+	}
+
+	// validate request params:
+	if err := h.paramsValidate.Struct(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return
+	}
+
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch the tag and check if it belongs to the current user:
+	tag, ok := h.ownedTag(w, r, chi.URLParam(r, "uuid"), user)
+	if !ok {
+		return
+	}
+
+	// check if the new name is not taken by another tag:
+	if params.Name != tag.Name {
+		exists, err := h.database.TagExistsByName(r.Context(), user.ID, params.Name)
+		if err != nil {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+		if exists {
+			httpresp.Render(w, response.TagAlreadyExists)
+			return
+		}
+	}
+
+	// update tag name:
+	tag.Name = params.Name
+	if err := h.database.UpdateTag(r.Context(), tag); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	resp := &tagsUpdateResponse{
+		UUID: tag.UUID.String(),
+	}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
+
+type tagsDeleteResponse struct {
+	UUID string `json:"uuid" example:"5f0c2d6e-5c1a-4b8e-9d39-7f9b3b1d2a61"`
+}
+
+// TagsDelete deletes a tag and unlinks it from all transactions.
+//
+//	@Summary		Delete a tag
+//	@Description	Deletes a tag, unlinks it from all transactions and returns its UUID
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			uuid	path		string				true	"Tag UUID"
+//	@Success		200		{object}	tagsDeleteResponse	"Successful operation"
+//	@Failure		403		{object}	model.Error			"Access to the tag is forbidden"
+//	@Failure		404		{object}	model.Error			"User or tag not found"
+//	@Failure		500		{object}	model.Error			"Internal server error"
+//	@Security		Bearer
+//	@Router			/tags/{uuid} [delete]
+func (h *Handler) TagsDelete(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch the tag and check if it belongs to the current user:
+	tag, ok := h.ownedTag(w, r, chi.URLParam(r, "uuid"), user)
+	if !ok {
+		return
+	}
+
+	// delete the tag from the database:
+	if err := h.database.DeleteTagByID(r.Context(), tag.ID); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	resp := &tagsDeleteResponse{UUID: tag.UUID.String()}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_TagsCreate(t *testing.T) {
+	const (
+		testUserID   int64 = 5
+		testUsername       = "test-username"
+		testTagName        = "vacation-2026"
+	)
+
+	t.Run("create a new tag owned by an existing user", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testUserContext(context.Background(), handler, testUsername)
+		)
+
+		// create a test user:
+		if err := handler.database.CreateUser(ctx, &database.User{
+			ID:       testUserID,
+			Username: testUsername,
+		}); err != nil {
+			panic(err)
+		}
+
+		params := &tagsCreateParams{
+			Name: testTagName,
+		}
+		rec := testRequest(ctx, params, handler.TagsCreate)
+		var createdTagUUID string
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			resp := &tagsCreateResponse{}
+			err := json.NewDecoder(rec.Body).Decode(resp)
+			if assert.NoError(t, err) {
+				if assert.NotEmpty(t, resp.UUID) {
+					createdTagUUID = resp.UUID
+				}
+			}
+		}
+
+		// check if the tag was added to the database:
+		tag := &database.Tag{}
+		err := handler.database.SelectTagByUUID(ctx, createdTagUUID, tag)
+		if assert.NoError(t, err) {
+			assert.Equal(t, testTagName, tag.Name)
+			assert.Equal(t, testUserID, tag.OwnerID)
+		}
+	})
+
+	t.Run("create a tag with name which is already taken", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testUserContext(context.Background(), handler, testUsername)
+		)
+
+		// create a test user and a tag owned by them:
+		if err := handler.database.CreateUser(ctx, &database.User{
+			ID:       testUserID,
+			Username: testUsername,
+		}); err != nil {
+			panic(err)
+		}
+		if err := handler.database.CreateTag(ctx, &database.Tag{
+			Name:    testTagName,
+			OwnerID: testUserID,
+		}); err != nil {
+			panic(err)
+		}
+
+		params := &tagsCreateParams{
+			Name: testTagName,
+		}
+		rec := testRequest(ctx, params, handler.TagsCreate)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("call the handler with no params", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testUserContext(context.Background(), handler, testUsername)
+		)
+
+		rec := testRequest(ctx, nil, handler.TagsCreate)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestHandler_TagsGet(t *testing.T) {
+	const (
+		testUserID   int64 = 5
+		testUsername       = "test-username"
+	)
+
+	var (
+		handler = newTestHandler()
+		ctx     = testUserContext(context.Background(), handler, testUsername)
+		tags    = []*database.Tag{
+			{UUID: uuid.New(), Name: "reimbursable", OwnerID: testUserID},
+			{UUID: uuid.New(), Name: "vacation-2026", OwnerID: testUserID},
+			{UUID: uuid.New(), Name: "someone-else's", OwnerID: testUserID + 1},
+		}
+	)
+
+	// create a test user:
+	if err := handler.database.CreateUser(ctx, &database.User{
+		ID:       testUserID,
+		Username: testUsername,
+	}); err != nil {
+		panic(err)
+	}
+
+	// create tags:
+	for _, tag := range tags {
+		if err := handler.database.CreateTag(ctx, tag); err != nil {
+			panic(err)
+		}
+	}
+
+	rec := testRequest(ctx, nil, handler.TagsGet)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		body := &tagsGetResponse{}
+		err := json.NewDecoder(rec.Body).Decode(body)
+		if assert.NoError(t, err) {
+			assert.Len(t, *body, 2)
+		}
+	}
+}
+
+func TestHandler_TagsDelete(t *testing.T) {
+	const (
+		testUserID   int64 = 5
+		testUsername       = "test-username"
+	)
+
+	t.Run("delete a tag owned by the user", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			tag     = &database.Tag{UUID: uuid.New(), Name: "reimbursable", OwnerID: testUserID}
+			ctx     = withURLParam(
+				testUserContext(context.Background(), handler, testUsername),
+				"uuid", tag.UUID.String(),
+			)
+		)
+
+		if err := handler.database.CreateUser(ctx, &database.User{ID: testUserID, Username: testUsername}); err != nil {
+			panic(err)
+		}
+		if err := handler.database.CreateTag(ctx, tag); err != nil {
+			panic(err)
+		}
+
+		rec := testRequest(ctx, nil, handler.TagsDelete)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			err := handler.database.SelectTagByUUID(ctx, tag.UUID.String(), &database.Tag{})
+			assert.Error(t, err)
+		}
+	})
+
+	t.Run("delete a tag owned by another user", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			tag     = &database.Tag{UUID: uuid.New(), Name: "reimbursable", OwnerID: testUserID + 1}
+			ctx     = withURLParam(
+				testUserContext(context.Background(), handler, testUsername),
+				"uuid", tag.UUID.String(),
+			)
+		)
+
+		if err := handler.database.CreateUser(ctx, &database.User{ID: testUserID, Username: testUsername}); err != nil {
+			panic(err)
+		}
+		if err := handler.database.CreateTag(ctx, tag); err != nil {
+			panic(err)
+		}
+
+		rec := testRequest(ctx, nil, handler.TagsDelete)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("delete a non-existent tag", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = withURLParam(
+				testUserContext(context.Background(), handler, testUsername),
+				"uuid", uuid.NewString(),
+			)
+		)
+
+		if err := handler.database.CreateUser(ctx, &database.User{ID: testUserID, Username: testUsername}); err != nil {
+			panic(err)
+		}
+
+		rec := testRequest(ctx, nil, handler.TagsDelete)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -4,21 +4,42 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"github.com/go-chi/chi/v5"
 	"github.com/groshi-project/groshi/internal/database"
+	"github.com/groshi-project/groshi/internal/rules"
 	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
 	"github.com/groshi-project/groshi/internal/service/handler/response"
 	"net/http"
 	"time"
 )
 
+type transactionsCreateSplitParams struct {
+	CategoryUUID string `json:"category" example:"02983837-7ab0-492a-90b6-285491936067" validate:"required"`
+	Amount       int32  `json:"amount" example:"1500" validate:"required"`
+	Note         string `json:"note" example:"Dish soap"`
+}
+
 type transactionsCreateParams struct {
 	Amount       int32  `json:"amount" example:"2500" validate:"required"`
 	CurrencyCode string `json:"currency" example:"USD" validate:"required"`
 
 	Timestamp time.Time `json:"timestamp" example:"todo-timestamp" validate:"required"`
 
-	Description  string `json:"description" example:"Bought a donut for $2.5 only!"`
-	CategoryUUID string `json:"category" example:"02983837-7ab0-492a-90b6-285491936067"`
+	Description string `json:"description" example:"Bought a donut for $2.5 only!"`
+
+	// Name of the payee, a new payee is created if user has no payee with such name.
+	Payee string `json:"payee" example:"Corner Store"`
+
+	// Either category or splits may be provided. If neither is provided, category, tags and payee
+	// are set by the auto-categorization rules of the user.
+	CategoryUUID string                          `json:"category" example:"02983837-7ab0-492a-90b6-285491936067" validate:"excluded_with=Splits"`
+	Splits       []transactionsCreateSplitParams `json:"splits" validate:"omitempty,min=1,dive"`
+
+	// UUIDs of tags, each tag may be given only once.
+	TagUUIDs []string `json:"tags" example:"5f0c2d6e-5c1a-4b8e-9d39-7f9b3b1d2a61" validate:"unique"`
+
+	// Sharing of the expense between ledger members, optional. Only expenses (negative amounts) can be shared.
+	Sharing *transactionsCreateSharingParams `json:"sharing"`
 }
 
 type transactionsCreateResponse struct {
@@ -28,15 +49,22 @@ type transactionsCreateResponse struct {
 // TransactionsCreate creates a new transactions and returns its UUID.
 //
 //	@Summary		Create a new transaction
-//	@Description	Creates a new transaction and returns its UUID
+//	@Description	Creates a new transaction and returns its UUID.
+//	@Description	Transaction can be attributed either to a single category or split across several categories,
+//	@Description	in the latter case amounts of the splits must sum up to the transaction amount.
+//	@Description	If neither category nor splits are provided, auto-categorization rules of user are applied to the transaction.
+//	@Description	Categories must belong to the ledger the transaction is created in.
+//	@Description	An expense can be shared between ledger members: split equally, by shares or by exact amounts,
+//	@Description	the members then owe their shares to the member who paid for it.
 //	@Tags			transactions
 //	@Accept			json
 //	@Produce		json
+//	@Param			ledger	query		string						false	"Ledger UUID, the personal ledger of user is used if it is not set"
 //	@Param			user	body		transactionsCreateParams	true	"Transaction"
 //	@Success		200		{object}	transactionsCreateResponse	"Successful operation"
-//	@Failure		400		{object}	model.Error					"Invalid request body format or invalid request params"
-//	@Failure		403		{object}	model.Error					"Access to the category is forbidden"
-//	@Failure		404		{object}	model.Error					"User not found"
+//	@Failure		400		{object}	model.Error					"Invalid request body format, invalid request params, amounts of the splits or shares do not sum up to the transaction amount"
+//	@Failure		403		{object}	model.Error					"Access to the ledger, the category or a tag is forbidden, or user is a viewer of the ledger"
+//	@Failure		404		{object}	model.Error					"User, ledger, ledger member, currency, category or tag not found"
 //	@Failure		500		{object}	model.Error					"Internal server error"
 //	@Security		Bearer
 //	@Router			/transactions [post]
@@ -54,6 +82,18 @@ func (h *Handler) TransactionsCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// check if amounts of the splits sum up to the transaction amount:
+	if len(params.Splits) != 0 {
+		var splitsAmount int64
+		for _, split := range params.Splits {
+			splitsAmount += int64(split.Amount)
+		}
+		if splitsAmount != int64(params.Amount) {
+			httpresp.Render(w, response.SplitsAmountMismatch)
+			return
+		}
+	}
+
 	// fetch provided currency:
 	currency := &database.Currency{}
 	if err := h.database.SelectCurrencyByCode(r.Context(), params.CurrencyCode, currency); err != nil {
@@ -66,55 +106,129 @@ func (h *Handler) TransactionsCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// fetch provided category:
-	category := &database.Category{}
-	if err := h.database.SelectCategoryByUUID(r.Context(), params.CategoryUUID, category); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			httpresp.Render(w, response.CategoryNotFound)
-			return
-		}
-		h.internalServerErrorLogger.Println(err)
-		httpresp.Render(w, response.InternalServerError)
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
 		return
 	}
 
-	// extract current user's username from context:
-	username, ok := r.Context().Value("username").(string)
+	// fetch the ledger and check if the current user can edit it:
+	ledger, ok := h.requestLedger(w, r, user, database.LedgerRoleEditor)
 	if !ok {
-		httpresp.Render(w, response.InternalServerError)
 		return
 	}
 
-	// fetch the current user from the database:
-	user := &database.User{}
-	if err := h.database.SelectUserByUsername(r.Context(), username, user); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			httpresp.Render(w, response.UserNotFound)
+	// split the expense between ledger members if it is shared:
+	payerID, shares, ok := h.expenseShares(w, r, params.Amount, params.Sharing, ledger, user)
+	if !ok {
+		return
+	}
+
+	// fetch provided category and check if it belongs to the ledger:
+	var categoryID int64
+	if params.CategoryUUID != "" {
+		category, ok := h.ledgerCategory(w, r, params.CategoryUUID, ledger)
+		if !ok {
 			return
 		}
-		h.internalServerErrorLogger.Println(err)
-		httpresp.Render(w, response.InternalServerError)
-		return
+		categoryID = category.ID
 	}
 
-	// check if the given category belongs to the current user:
-	if category.OwnerID != user.ID {
-		httpresp.Render(w, response.CategoryForbidden)
-		return
+	// fetch categories of the provided splits and check if they belong to the ledger:
+	splits := make([]database.TransactionSplit, 0, len(params.Splits))
+	for _, split := range params.Splits {
+		category, ok := h.ledgerCategory(w, r, split.CategoryUUID, ledger)
+		if !ok {
+			return
+		}
+		splits = append(splits, database.TransactionSplit{
+			CategoryID: category.ID,
+			Amount:     split.Amount,
+			Note:       split.Note,
+		})
+	}
+
+	// fetch provided tags and check if they belong to the current user:
+	tags := make([]database.Tag, 0, len(params.TagUUIDs))
+	for _, tagUUID := range params.TagUUIDs {
+		tag, ok := h.ownedTag(w, r, tagUUID, user)
+		if !ok {
+			return
+		}
+		tags = append(tags, *tag)
+	}
+
+	// apply auto-categorization rules if the transaction is not categorized:
+	payeeName := params.Payee
+	if categoryID == 0 && len(splits) == 0 {
+		engine, _, err := h.rulesEngine(r.Context(), user)
+		if err != nil {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+
+		result := engine.Apply(rules.Input{
+			Description: params.Description,
+			Payee:       database.CleanPayeeName(params.Payee),
+			Amount:      params.Amount,
+			CurrencyID:  currency.ID,
+		})
+
+		// rules may refer to categories of other ledgers, such categories are not applied:
+		if result.CategoryID != 0 {
+			categories := make([]database.Category, 0)
+			if err := h.database.SelectCategoriesByLedgerID(r.Context(), ledger.ID, &categories); err != nil && !errors.Is(err, sql.ErrNoRows) {
+				h.internalServerErrorLogger.Println(err)
+				httpresp.Render(w, response.InternalServerError)
+				return
+			}
+			if containsCategory(categories, result.CategoryID) {
+				categoryID = result.CategoryID
+			}
+		}
+		for _, tagID := range result.TagIDs {
+			if !containsTag(tags, tagID) {
+				tags = append(tags, database.Tag{ID: tagID})
+			}
+		}
+		if result.PayeeName != "" {
+			payeeName = result.PayeeName
+		}
+	}
+
+	// fetch provided payee or create a new one:
+	var payeeID int64
+	if database.CleanPayeeName(payeeName) != "" {
+		payee := &database.Payee{}
+		if err := h.database.SelectOrCreatePayee(r.Context(), user.ID, payeeName, payee); err != nil {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+		payeeID = payee.ID
 	}
 
 	// convert provided timestamp to UTC timezone:
 	utcTimestamp := params.Timestamp.UTC()
 
-	// create a new transaction owned by the current user:
+	// create a new transaction in the ledger:
 	transaction := &database.Transaction{
 		Amount:     params.Amount,
 		CurrencyID: currency.ID,
 
 		Description: params.Description,
-		CategoryID:  category.ID,
+		PayeeID:     payeeID,
+		CategoryID:  categoryID,
+		Splits:      splits,
+
+		Tags: tags,
 
-		OwnerID: user.ID,
+		LedgerID: ledger.ID,
+		OwnerID:  user.ID,
+
+		PayerID: payerID,
+		Shares:  shares,
 
 		Timestamp: utcTimestamp,
 	}
@@ -128,22 +242,287 @@ func (h *Handler) TransactionsCreate(w http.ResponseWriter, r *http.Request) {
 	resp := &transactionsCreateResponse{
 		UUID: transaction.UUID.String(),
 	}
-	httpresp.NewOK(resp)
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
+
+type transactionTagItem struct {
+	UUID string `json:"uuid" example:"5f0c2d6e-5c1a-4b8e-9d39-7f9b3b1d2a61"`
+	Name string `json:"name" example:"vacation-2026"`
+}
+
+type transactionSplitItem struct {
+	CategoryUUID string `json:"category" example:"02983837-7ab0-492a-90b6-285491936067"`
+	Amount       int32  `json:"amount" example:"1500"`
+	Note         string `json:"note" example:"Dish soap"`
+}
+
+type transactionShareItem struct {
+	Username string `json:"username" example:"jieggii"`
+	Amount   int32  `json:"amount" example:"1250"`
+}
+
+type transactionItem struct {
+	UUID string `json:"uuid" example:"3be1ed0a-c307-49de-872e-38730200f301"`
+
+	Amount   int32  `json:"amount" example:"2500"`
+	Currency string `json:"currency" example:"USD"`
+
+	Description  string                 `json:"description" example:"Bought a donut for $2.5 only!"`
+	Payee        string                 `json:"payee,omitempty" example:"Corner Store"`
+	CategoryUUID string                 `json:"category,omitempty" example:"02983837-7ab0-492a-90b6-285491936067"`
+	Splits       []transactionSplitItem `json:"splits,omitempty"`
+	Tags         []transactionTagItem   `json:"tags"`
+
+	// Username of the ledger member who paid for the shared expense and shares owed to that member.
+	Payer  string                 `json:"payer,omitempty" example:"jieggii"`
+	Shares []transactionShareItem `json:"shares,omitempty"`
+
+	// True if the transaction is a payment settling debts between ledger members.
+	Settlement bool `json:"settlement"`
+
+	Timestamp time.Time  `json:"timestamp" example:"2024-03-20T12:57:38Z"`
+	ValueDate *time.Time `json:"value_date,omitempty" example:"2024-03-19T00:00:00Z"`
+	CreatedAt time.Time  `json:"created_at" example:"2024-03-20T12:57:38Z"`
+	UpdatedAt time.Time  `json:"updated_at" example:"2024-03-20T12:57:38Z"`
+}
+
+// newTransactionItem creates a new instance of [transactionItem] from a transaction model.
+// Currency, payee, category, splits, payer, shares and tags relations of the transaction are expected to be loaded.
+func newTransactionItem(t *database.Transaction) transactionItem {
+	var categoryUUID string
+	if t.CategoryID != 0 {
+		categoryUUID = t.Category.UUID.String()
+	}
+
+	var splits []transactionSplitItem
+	for _, split := range t.Splits {
+		splits = append(splits, transactionSplitItem{
+			CategoryUUID: split.Category.UUID.String(),
+			Amount:       split.Amount,
+			Note:         split.Note,
+		})
+	}
+
+	var shares []transactionShareItem
+	for _, share := range t.Shares {
+		shares = append(shares, transactionShareItem{
+			Username: share.User.Username,
+			Amount:   share.Amount,
+		})
+	}
+
+	tags := make([]transactionTagItem, 0, len(t.Tags))
+	for _, tag := range t.Tags {
+		tags = append(tags, transactionTagItem{
+			UUID: tag.UUID.String(),
+			Name: tag.Name,
+		})
+	}
+
+	var valueDate *time.Time
+	if !t.ValueDate.IsZero() {
+		valueDate = &t.ValueDate
+	}
+
+	return transactionItem{
+		UUID:         t.UUID.String(),
+		Amount:       t.Amount,
+		Currency:     t.Currency.Code,
+		Description:  t.Description,
+		Payee:        t.Payee.Name,
+		CategoryUUID: categoryUUID,
+		Splits:       splits,
+		Tags:         tags,
+		Payer:        t.Payer.Username,
+		Shares:       shares,
+		Settlement:   t.Settlement,
+		Timestamp:    t.Timestamp,
+		ValueDate:    valueDate,
+		CreatedAt:    t.CreatedAt,
+		UpdatedAt:    t.UpdatedAt,
+	}
 }
 
+type transactionsGetOneResponse transactionItem
+
+// TransactionsGetOne returns a single transaction.
+//
+//	@Summary		Fetch a transaction
+//	@Description	Returns a transaction of a ledger user is a member of
+//	@Tags			transactions
+//	@Accept			json
+//	@Produce		json
+//	@Param			uuid	path		string						true	"Transaction UUID"
+//	@Success		200		{object}	transactionsGetOneResponse	"Successful operation"
+//	@Failure		403		{object}	model.Error					"Access to the transaction is forbidden"
+//	@Failure		404		{object}	model.Error					"User or transaction not found"
+//	@Failure		500		{object}	model.Error					"Internal server error"
+//	@Security		Bearer
+//	@Router			/transactions/{uuid} [get]
 func (h *Handler) TransactionsGetOne(w http.ResponseWriter, r *http.Request) {
-	//uuid := chi.URLParam(r, "uuid")
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// fetch the transaction:
+	transaction := &database.Transaction{}
+	if err := h.database.SelectTransactionByUUID(r.Context(), chi.URLParam(r, "uuid"), transaction); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			httpresp.Render(w, response.TransactionNotFound)
+			return
+		}
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// check if the current user can read the ledger of the transaction:
+	if _, ok := h.ledgerMember(w, r, transaction.LedgerID, user, database.LedgerRoleViewer, response.TransactionForbidden); !ok {
+		return
+	}
+
+	// respond:
+	resp := transactionsGetOneResponse(newTransactionItem(transaction))
+	httpresp.Render(w, httpresp.NewOK(&resp))
 }
 
-type transactionsGetParams struct {
-	StartTime time.Time `json:"start_time"`
-	EndTime   time.Time `json:"end_time"`
-	Currency  string    `json:"currency"`
+// transactionFilter builds transaction filter for the current user from URL query params `ledger`, `start_time`,
+// `end_time`, `currency`, `category`, `payee`, `tag` (may be repeated) and `tag_mode` (`any` or `all`).
+// Renders an error response and returns false if params are invalid or refer to inaccessible objects.
+func (h *Handler) transactionFilter(w http.ResponseWriter, r *http.Request, user *database.User) (*database.TransactionFilter, bool) {
+	query := r.URL.Query()
+
+	// fetch the ledger and check if the current user can read it:
+	ledger, ok := h.requestLedger(w, r, user, database.LedgerRoleViewer)
+	if !ok {
+		return nil, false
+	}
+	filter := &database.TransactionFilter{LedgerID: ledger.ID}
+
+	// parse time bounds:
+	for param, dest := range map[string]*time.Time{"start_time": &filter.StartTime, "end_time": &filter.EndTime} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		timestamp, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			httpresp.Render(w, response.InvalidRequestParams)
+			return nil, false
+		}
+		*dest = timestamp.UTC()
+	}
+
+	// fetch currency:
+	if code := query.Get("currency"); code != "" {
+		currency := &database.Currency{}
+		if err := h.database.SelectCurrencyByCode(r.Context(), code, currency); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				httpresp.Render(w, response.CurrencyNotFound)
+				return nil, false
+			}
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return nil, false
+		}
+		filter.CurrencyID = currency.ID
+	}
+
+	// fetch category and check if it belongs to the ledger:
+	if categoryUUID := query.Get("category"); categoryUUID != "" {
+		category, ok := h.ledgerCategory(w, r, categoryUUID, ledger)
+		if !ok {
+			return nil, false
+		}
+		filter.CategoryID = category.ID
+	}
+
+	// fetch payee and check if it belongs to the current user:
+	if payeeUUID := query.Get("payee"); payeeUUID != "" {
+		payee, ok := h.ownedPayee(w, r, payeeUUID, user)
+		if !ok {
+			return nil, false
+		}
+		filter.PayeeID = payee.ID
+	}
+
+	// fetch tags and check if they belong to the current user:
+	for _, tagUUID := range query["tag"] {
+		tag, ok := h.ownedTag(w, r, tagUUID, user)
+		if !ok {
+			return nil, false
+		}
+		filter.TagIDs = append(filter.TagIDs, tag.ID)
+	}
+
+	switch query.Get("tag_mode") {
+	case "", "any":
+		filter.AllTags = false
+	case "all":
+		filter.AllTags = true
+	default:
+		httpresp.Render(w, response.InvalidRequestParams)
+		return nil, false
+	}
+
+	return filter, true
 }
 
-// todo: optionall by category, show transactions by categories
+type transactionsGetResponse []transactionItem
+
+// TransactionsGet returns transactions of the ledger which match the given filters.
+//
+//	@Summary		Fetch transactions
+//	@Description	Returns transactions of the ledger which match the given filters, newest first
+//	@Tags			transactions
+//	@Accept			json
+//	@Produce		json
+//	@Param			ledger		query		string					false	"Ledger UUID, the personal ledger of user is used if it is not set"
+//	@Param			start_time	query		string					false	"Select transactions which happened at or after this moment (RFC 3339)"
+//	@Param			end_time	query		string					false	"Select transactions which happened before this moment (RFC 3339)"
+//	@Param			currency	query		string					false	"Currency code"
+//	@Param			category	query		string					false	"Category UUID"
+//	@Param			payee		query		string					false	"Payee UUID"
+//	@Param			tag			query		[]string				false	"Tag UUID, may be repeated"
+//	@Param			tag_mode	query		string					false	"Whether transactions must have `any` (default) or `all` of the given tags"
+//	@Success		200			{object}	transactionsGetResponse	"Successful operation"
+//	@Failure		400			{object}	model.Error				"Invalid request params"
+//	@Failure		403			{object}	model.Error				"Access to the ledger, the category, payee or a tag is forbidden"
+//	@Failure		404			{object}	model.Error				"User, ledger, currency, category, payee or tag not found"
+//	@Failure		500			{object}	model.Error				"Internal server error"
+//	@Security		Bearer
+//	@Router			/transactions [get]
 func (h *Handler) TransactionsGet(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
 
+	// build transaction filter from request params:
+	filter, ok := h.transactionFilter(w, r, user)
+	if !ok {
+		return
+	}
+
+	// fetch transactions matching the filter:
+	transactions := make([]database.Transaction, 0)
+	if err := h.database.SelectTransactions(r.Context(), filter, &transactions); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+
+	// respond:
+	resp := make(transactionsGetResponse, 0, len(transactions))
+	for i := range transactions {
+		resp = append(resp, newTransactionItem(&transactions[i]))
+	}
+	httpresp.Render(w, httpresp.NewOK(&resp))
 }
 
 func (h *Handler) TransactionsUpdate(w http.ResponseWriter, r *http.Request) {
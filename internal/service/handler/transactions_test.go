@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHandler_TransactionsCreate(t *testing.T) {
+	const (
+		testUserID   int64 = 5
+		testUsername       = "test-username"
+	)
+
+	// newTestEnv creates a test handler with a test user, currency and categories.
+	newTestEnv := func() (*Handler, *mockDatabase, context.Context, []*database.Category) {
+		var (
+			handler    = newTestHandler()
+			db         = handler.database.(*mockDatabase)
+			ctx        = testUserContext(context.Background(), handler, testUsername)
+			categories = []*database.Category{
+				{UUID: uuid.New(), Name: "Groceries", LedgerID: testPersonalLedgerID(db, testUserID), OwnerID: testUserID},
+				{UUID: uuid.New(), Name: "Household", LedgerID: testPersonalLedgerID(db, testUserID), OwnerID: testUserID},
+				{UUID: uuid.New(), Name: "Someone else's", LedgerID: testPersonalLedgerID(db, testUserID+1), OwnerID: testUserID + 1},
+			}
+		)
+
+		if err := db.CreateUser(ctx, &database.User{ID: testUserID, Username: testUsername}); err != nil {
+			panic(err)
+		}
+		db.currencies = append(db.currencies, &database.Currency{ID: 1, Code: "EUR", Rate: 1})
+		for _, category := range categories {
+			if err := db.CreateCategory(ctx, category); err != nil {
+				panic(err)
+			}
+		}
+
+		return handler, db, ctx, categories
+	}
+
+	t.Run("create a transaction with a single category", func(t *testing.T) {
+		handler, db, ctx, categories := newTestEnv()
+
+		params := &transactionsCreateParams{
+			Amount:       -2500,
+			CurrencyCode: "EUR",
+			Timestamp:    time.Now(),
+			CategoryUUID: categories[0].UUID.String(),
+		}
+		rec := testRequest(ctx, params, handler.TransactionsCreate)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			resp := &transactionsCreateResponse{}
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(resp)) {
+				assert.NotEmpty(t, resp.UUID)
+			}
+			if assert.Len(t, db.transactions, 1) {
+				assert.Equal(t, categories[0].ID, db.transactions[0].CategoryID)
+				assert.Empty(t, db.transactions[0].Splits)
+			}
+		}
+	})
+
+	t.Run("create a transaction with duplicate tags", func(t *testing.T) {
+		handler, db, ctx, categories := newTestEnv()
+		tag := &database.Tag{UUID: uuid.New(), Name: "Weekly", OwnerID: testUserID}
+		if err := db.CreateTag(ctx, tag); err != nil {
+			panic(err)
+		}
+
+		params := &transactionsCreateParams{
+			Amount:       -2500,
+			CurrencyCode: "EUR",
+			Timestamp:    time.Now(),
+			CategoryUUID: categories[0].UUID.String(),
+			TagUUIDs:     []string{tag.UUID.String(), tag.UUID.String()},
+		}
+		rec := testRequest(ctx, params, handler.TransactionsCreate)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Empty(t, db.transactions)
+	})
+
+	t.Run("create a transaction split across categories", func(t *testing.T) {
+		handler, db, ctx, categories := newTestEnv()
+
+		params := &transactionsCreateParams{
+			Amount:       -4000,
+			CurrencyCode: "EUR",
+			Timestamp:    time.Now(),
+			Splits: []transactionsCreateSplitParams{
+				{CategoryUUID: categories[0].UUID.String(), Amount: -2500, Note: "Vegetables"},
+				{CategoryUUID: categories[1].UUID.String(), Amount: -1500, Note: "Dish soap"},
+			},
+		}
+		rec := testRequest(ctx, params, handler.TransactionsCreate)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			if assert.Len(t, db.transactions, 1) {
+				transaction := db.transactions[0]
+				assert.Zero(t, transaction.CategoryID)
+				if assert.Len(t, transaction.Splits, 2) {
+					assert.Equal(t, categories[0].ID, transaction.Splits[0].CategoryID)
+					assert.Equal(t, int32(-2500), transaction.Splits[0].Amount)
+					assert.Equal(t, categories[1].ID, transaction.Splits[1].CategoryID)
+					assert.Equal(t, "Dish soap", transaction.Splits[1].Note)
+				}
+			}
+		}
+	})
+
+	t.Run("create a transaction with splits which do not sum up to its amount", func(t *testing.T) {
+		handler, db, ctx, categories := newTestEnv()
+
+		params := &transactionsCreateParams{
+			Amount:       -4000,
+			CurrencyCode: "EUR",
+			Timestamp:    time.Now(),
+			Splits: []transactionsCreateSplitParams{
+				{CategoryUUID: categories[0].UUID.String(), Amount: -2500},
+				{CategoryUUID: categories[1].UUID.String(), Amount: -1000},
+			},
+		}
+		rec := testRequest(ctx, params, handler.TransactionsCreate)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Empty(t, db.transactions)
+	})
+
+	t.Run("create a transaction with both category and splits", func(t *testing.T) {
+		handler, _, ctx, categories := newTestEnv()
+
+		params := &transactionsCreateParams{
+			Amount:       -2500,
+			CurrencyCode: "EUR",
+			Timestamp:    time.Now(),
+			CategoryUUID: categories[0].UUID.String(),
+			Splits: []transactionsCreateSplitParams{
+				{CategoryUUID: categories[1].UUID.String(), Amount: -2500},
+			},
+		}
+		rec := testRequest(ctx, params, handler.TransactionsCreate)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("create a transaction with a split of someone else's category", func(t *testing.T) {
+		handler, db, ctx, categories := newTestEnv()
+
+		params := &transactionsCreateParams{
+			Amount:       -4000,
+			CurrencyCode: "EUR",
+			Timestamp:    time.Now(),
+			Splits: []transactionsCreateSplitParams{
+				{CategoryUUID: categories[0].UUID.String(), Amount: -2500},
+				{CategoryUUID: categories[2].UUID.String(), Amount: -1500},
+			},
+		}
+		rec := testRequest(ctx, params, handler.TransactionsCreate)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, db.transactions)
+	})
+
+	t.Run("create a transaction in a non-existent currency", func(t *testing.T) {
+		handler, _, ctx, categories := newTestEnv()
+
+		params := &transactionsCreateParams{
+			Amount:       -2500,
+			CurrencyCode: "XYZ",
+			Timestamp:    time.Now(),
+			CategoryUUID: categories[0].UUID.String(),
+		}
+		rec := testRequest(ctx, params, handler.TransactionsCreate)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -0,0 +1,289 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/groshi-project/groshi/internal/auth"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
+	"github.com/groshi-project/groshi/internal/service/handler/model"
+	"github.com/groshi-project/groshi/internal/service/handler/response"
+	"net/http"
+)
+
+const (
+	// totpIssuer is the issuer shown in authenticator apps.
+	totpIssuer = "groshi"
+
+	// recoveryCodesCount is the count of recovery codes generated when two-factor authentication is enabled.
+	recoveryCodesCount = 10
+)
+
+// verifySecondFactor returns true if the code is a valid TOTP code or an unused recovery code of the user.
+// Accepted codes can not be used again.
+func (h *Handler) verifySecondFactor(ctx context.Context, user *database.User, code string) (bool, error) {
+	if auth.IsTOTPCode(code) {
+		step, ok, err := auth.ValidateTOTP(user.TOTPSecret, code, h.now())
+		if err != nil || !ok {
+			return false, err
+		}
+		return h.database.UseUserTOTPStep(ctx, user.ID, step)
+	}
+
+	// recovery codes are hashed like passwords, so they are not checked if the code can not be one:
+	if !auth.IsRecoveryCode(code) {
+		return false, nil
+	}
+	code = auth.NormalizeRecoveryCode(code)
+	for _, hash := range user.RecoveryCodes {
+		ok, err := h.passwordAuth.VerifyPassword(code, hash)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return h.database.UseUserRecoveryCode(ctx, user.ID, hash)
+		}
+	}
+	return false, nil
+}
+
+type twoFactorEnrollResponse struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	URI    string `json:"uri" example:"otpauth://totp/groshi:jieggii?algorithm=SHA1&digits=6&issuer=groshi&period=30&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+}
+
+// TwoFactorEnroll starts enrollment of two-factor authentication.
+//
+//	@Summary		Start two-factor authentication enrollment
+//	@Description	Generates a new TOTP secret and returns it together with an `otpauth://` URI which can be added to an authenticator app. Two-factor authentication is enabled only after a code is verified using `/user/2fa/verify`
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	twoFactorEnrollResponse	"Successful operation"
+//	@Failure		404	{object}	model.Error				"User not found"
+//	@Failure		409	{object}	model.Error				"Two-factor authentication is already enabled"
+//	@Failure		500	{object}	model.Error				"Internal server error"
+//	@Security		Bearer
+//	@Router			/user/2fa/enroll [post]
+func (h *Handler) TwoFactorEnroll(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabled {
+		httpresp.Render(w, response.TwoFactorAlreadyEnabled)
+		return
+	}
+
+	// generate and store a new secret:
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+	if err := h.database.UpdateUserTOTP(r.Context(), user); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	resp := &twoFactorEnrollResponse{
+		Secret: secret,
+		URI:    auth.TOTPURI(totpIssuer, user.Username, secret),
+	}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
+
+type twoFactorVerifyParams struct {
+	Code string `json:"code" example:"492039" validate:"required"`
+}
+
+type twoFactorVerifyResponse struct {
+	// One-time recovery codes which are shown only once.
+	RecoveryCodes []string `json:"recovery_codes" example:"k3v9q-x7m2p,a8d4n-t6w1r"`
+}
+
+// TwoFactorVerify confirms enrollment of two-factor authentication and enables it.
+//
+//	@Summary		Enable two-factor authentication
+//	@Description	Verifies a TOTP code generated using the secret returned on enrollment, enables two-factor authentication and returns one-time recovery codes. Recovery codes are shown only once
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			code	body		twoFactorVerifyParams	true	"TOTP code"
+//	@Success		200		{object}	twoFactorVerifyResponse	"Successful operation"
+//	@Failure		400		{object}	model.Error				"Invalid request body format, invalid request params or invalid code"
+//	@Failure		404		{object}	model.Error				"User not found"
+//	@Failure		409		{object}	model.Error				"Two-factor authentication is already enabled or enrollment is not started"
+//	@Failure		500		{object}	model.Error				"Internal server error"
+//	@Security		Bearer
+//	@Router			/user/2fa/verify [post]
+func (h *Handler) TwoFactorVerify(w http.ResponseWriter, r *http.Request) {
+	// decode request params:
+	params := &twoFactorVerifyParams{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return
+	}
+
+	// validate request params:
+	if err := h.paramsValidate.Struct(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return
+	}
+
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if user.TOTPEnabled {
+		httpresp.Render(w, response.TwoFactorAlreadyEnabled)
+		return
+	}
+	if user.TOTPSecret == "" {
+		httpresp.Render(w, response.TwoFactorNotEnrolled)
+		return
+	}
+
+	// verify the code:
+	step, ok, err := auth.ValidateTOTP(user.TOTPSecret, params.Code, h.now())
+	if err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+	if !ok {
+		httpresp.Render(w, httpresp.New(http.StatusBadRequest, model.NewError("invalid code, check that the clock of your device is correct")))
+		return
+	}
+
+	// generate recovery codes and enable two-factor authentication:
+	recoveryCodes, err := auth.GenerateRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+	hashes := make([]string, 0, len(recoveryCodes))
+	for _, code := range recoveryCodes {
+		hash, err := h.passwordAuth.HashPassword(auth.NormalizeRecoveryCode(code))
+		if err != nil {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+		hashes = append(hashes, hash)
+	}
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step
+	user.RecoveryCodes = hashes
+	if err := h.database.UpdateUserTOTP(r.Context(), user); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	resp := &twoFactorVerifyResponse{RecoveryCodes: recoveryCodes}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
+
+type twoFactorDisableParams struct {
+	Password string `json:"password" example:"my-secret-password" validate:"required"`
+
+	// TOTP code or one of the recovery codes.
+	Code string `json:"code" example:"492039" validate:"required"`
+}
+
+// TwoFactorDisable disables two-factor authentication.
+//
+//	@Summary		Disable two-factor authentication
+//	@Description	Disables two-factor authentication of the current user if the password and the TOTP code or a recovery code are correct
+//	@Tags			user
+//	@Accept			json
+//	@Param			credentials	body	twoFactorDisableParams	true	"Password and code"
+//	@Success		204			"Successful operation"
+//	@Failure		400			{object}	model.Error	"Invalid request body format or invalid request params"
+//	@Failure		401			{object}	model.Error	"Invalid code"
+//	@Failure		403			{object}	model.Error	"Wrong password"
+//	@Failure		404			{object}	model.Error	"User not found"
+//	@Failure		409			{object}	model.Error	"Two-factor authentication is not enabled"
+//	@Failure		429			{object}	model.Error	"Too many failed attempts, see `Retry-After` header"
+//	@Failure		500			{object}	model.Error	"Internal server error"
+//	@Security		Bearer
+//	@Router			/user/2fa [delete]
+func (h *Handler) TwoFactorDisable(w http.ResponseWriter, r *http.Request) {
+	// decode request params:
+	params := &twoFactorDisableParams{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return
+	}
+
+	// validate request params:
+	if err := h.paramsValidate.Struct(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return
+	}
+
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if !user.TOTPEnabled {
+		httpresp.Render(w, response.TwoFactorNotEnabled)
+		return
+	}
+
+	// reject the attempt if there were too many failed attempts, the same limits as for login are applied,
+	// so that a stolen access token can not be used to guess the second factor:
+	if !h.loginAllowed(w, r, user.Username) {
+		return
+	}
+
+	// verify the password and the code:
+	ok, err := h.verifyUserPassword(user, params.Password)
+	if err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+	if !ok {
+		h.loginFailed(w, r, user.Username, response.WrongPassword)
+		return
+	}
+	ok, err = h.verifySecondFactor(r.Context(), user, params.Code)
+	if err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+	if !ok {
+		h.loginFailed(w, r, user.Username, response.InvalidTwoFactorCode)
+		return
+	}
+	if !h.loginSucceeded(w, r, user.Username) {
+		return
+	}
+
+	// disable two-factor authentication:
+	user.TOTPSecret = ""
+	user.TOTPEnabled = false
+	user.TOTPLastStep = 0
+	user.RecoveryCodes = nil
+	if err := h.database.UpdateUserTOTP(r.Context(), user); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	w.WriteHeader(http.StatusNoContent)
+}
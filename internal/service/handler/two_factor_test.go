@@ -0,0 +1,266 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/groshi-project/groshi/internal/auth"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// testClock is the fixed time used to generate and validate TOTP codes in tests.
+var testClock = time.Date(2026, time.March, 20, 12, 0, 0, 0, time.UTC)
+
+// testTOTPCode returns TOTP code of the secret at the given offset from testClock.
+func testTOTPCode(secret string, offset time.Duration) string {
+	code, err := auth.TOTPCode(secret, testClock.Add(offset))
+	if err != nil {
+		panic(err)
+	}
+	return code
+}
+
+// testEnableTwoFactor creates a test user, enables two-factor authentication and returns the secret and recovery codes.
+func testEnableTwoFactor(t *testing.T, handler *Handler) (string, []string) {
+	handler.now = func() time.Time { return testClock }
+	ctx := testUserContext(context.Background(), handler, "test-username")
+	if err := handler.database.CreateUser(ctx, &database.User{
+		Username: "test-username",
+		Password: "hash(test-password)",
+	}); err != nil {
+		panic(err)
+	}
+
+	rec := testRequest(ctx, nil, handler.TwoFactorEnroll)
+	if !assert.Equal(t, http.StatusOK, rec.Code) {
+		t.FailNow()
+	}
+	enrollResp := &twoFactorEnrollResponse{}
+	if err := json.NewDecoder(rec.Body).Decode(enrollResp); err != nil {
+		panic(err)
+	}
+
+	rec = testRequest(ctx, &twoFactorVerifyParams{Code: testTOTPCode(enrollResp.Secret, -30*time.Second)}, handler.TwoFactorVerify)
+	if !assert.Equal(t, http.StatusOK, rec.Code) {
+		t.FailNow()
+	}
+	verifyResp := &twoFactorVerifyResponse{}
+	if err := json.NewDecoder(rec.Body).Decode(verifyResp); err != nil {
+		panic(err)
+	}
+
+	return enrollResp.Secret, verifyResp.RecoveryCodes
+}
+
+func TestHandler_TwoFactorEnroll(t *testing.T) {
+	var (
+		handler = newTestHandler()
+		ctx     = testUserContext(context.Background(), handler, "test-username")
+	)
+	if err := handler.database.CreateUser(ctx, &database.User{Username: "test-username"}); err != nil {
+		panic(err)
+	}
+
+	rec := testRequest(ctx, nil, handler.TwoFactorEnroll)
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		resp := &twoFactorEnrollResponse{}
+		if assert.NoError(t, json.NewDecoder(rec.Body).Decode(resp)) {
+			assert.NotEmpty(t, resp.Secret)
+			assert.Contains(t, resp.URI, "otpauth://totp/groshi:test-username?")
+			assert.Contains(t, resp.URI, "secret="+resp.Secret)
+		}
+
+		// enrollment is not finished until a code is verified:
+		user := handler.database.(*mockDatabase).users[0]
+		assert.Equal(t, resp.Secret, user.TOTPSecret)
+		assert.False(t, user.TOTPEnabled)
+	}
+}
+
+func TestHandler_TwoFactorVerify(t *testing.T) {
+	t.Run("verify a valid code", func(t *testing.T) {
+		handler := newTestHandler()
+		_, recoveryCodes := testEnableTwoFactor(t, handler)
+
+		user := handler.database.(*mockDatabase).users[0]
+		assert.True(t, user.TOTPEnabled)
+		assert.Len(t, recoveryCodes, recoveryCodesCount)
+		if assert.Len(t, user.RecoveryCodes, recoveryCodesCount) {
+			assert.Equal(t, "hash("+auth.NormalizeRecoveryCode(recoveryCodes[0])+")", user.RecoveryCodes[0])
+		}
+	})
+
+	t.Run("verify an invalid code", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testUserContext(context.Background(), handler, "test-username")
+		)
+		handler.now = func() time.Time { return testClock }
+		if err := handler.database.CreateUser(ctx, &database.User{Username: "test-username", TOTPSecret: "JBSWY3DPEHPK3PXP"}); err != nil {
+			panic(err)
+		}
+
+		rec := testRequest(ctx, &twoFactorVerifyParams{Code: testTOTPCode("JBSWY3DPEHPK3PXP", 10*time.Minute)}, handler.TwoFactorVerify)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.False(t, handler.database.(*mockDatabase).users[0].TOTPEnabled)
+	})
+
+	t.Run("verify without enrollment", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testUserContext(context.Background(), handler, "test-username")
+		)
+		if err := handler.database.CreateUser(ctx, &database.User{Username: "test-username"}); err != nil {
+			panic(err)
+		}
+
+		rec := testRequest(ctx, &twoFactorVerifyParams{Code: "123456"}, handler.TwoFactorVerify)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
+
+func TestHandler_AuthSecondFactor(t *testing.T) {
+	// login returns a challenge token instead of the tokens:
+	login := func(t *testing.T, handler *Handler) string {
+		rec := testRequest(context.Background(), &authLoginParams{Username: "test-username", Password: "test-password", DeviceName: "test-device"}, handler.AuthLogin)
+		if !assert.Equal(t, http.StatusAccepted, rec.Code) {
+			t.FailNow()
+		}
+		resp := &authChallengeResponse{}
+		if err := json.NewDecoder(rec.Body).Decode(resp); err != nil {
+			panic(err)
+		}
+		assert.True(t, resp.TwoFactorRequired)
+		assert.Empty(t, handler.database.(*mockDatabase).sessions)
+		return resp.ChallengeToken
+	}
+
+	t.Run("complete login with a TOTP code", func(t *testing.T) {
+		handler := newTestHandler()
+		secret, _ := testEnableTwoFactor(t, handler)
+		challenge := login(t, handler)
+
+		params := &authSecondFactorParams{ChallengeToken: challenge, Code: testTOTPCode(secret, 0)}
+		rec := testRequest(context.Background(), params, handler.AuthSecondFactor)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			resp := &authTokensResponse{}
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(resp)) {
+				assert.NotEmpty(t, resp.Token)
+				assert.NotEmpty(t, resp.RefreshToken)
+			}
+			if assert.Len(t, handler.database.(*mockDatabase).sessions, 1) {
+				assert.Equal(t, "test-device", handler.database.(*mockDatabase).sessions[0].DeviceName)
+			}
+		}
+
+		// the same code can not be used twice:
+		rec = testRequest(context.Background(), params, handler.AuthSecondFactor)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("complete login with a recovery code", func(t *testing.T) {
+		handler := newTestHandler()
+		_, recoveryCodes := testEnableTwoFactor(t, handler)
+		challenge := login(t, handler)
+
+		params := &authSecondFactorParams{ChallengeToken: challenge, Code: recoveryCodes[3]}
+		rec := testRequest(context.Background(), params, handler.AuthSecondFactor)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			assert.Len(t, handler.database.(*mockDatabase).users[0].RecoveryCodes, recoveryCodesCount-1)
+		}
+
+		// the same recovery code can not be used twice:
+		rec = testRequest(context.Background(), params, handler.AuthSecondFactor)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("complete login with a wrong code", func(t *testing.T) {
+		handler := newTestHandler()
+		secret, _ := testEnableTwoFactor(t, handler)
+		challenge := login(t, handler)
+
+		params := &authSecondFactorParams{ChallengeToken: challenge, Code: testTOTPCode(secret, 5*time.Minute)}
+		rec := testRequest(context.Background(), params, handler.AuthSecondFactor)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Empty(t, handler.database.(*mockDatabase).sessions)
+	})
+
+	t.Run("reuse a completed challenge", func(t *testing.T) {
+		handler := newTestHandler()
+		secret, recoveryCodes := testEnableTwoFactor(t, handler)
+		challenge := login(t, handler)
+
+		params := &authSecondFactorParams{ChallengeToken: challenge, Code: testTOTPCode(secret, 0)}
+		rec := testRequest(context.Background(), params, handler.AuthSecondFactor)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// the challenge can not be exchanged for tokens again even with another valid code:
+		params = &authSecondFactorParams{ChallengeToken: challenge, Code: recoveryCodes[0]}
+		rec = testRequest(context.Background(), params, handler.AuthSecondFactor)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Len(t, handler.database.(*mockDatabase).sessions, 1)
+	})
+
+	t.Run("complete login after the password has changed", func(t *testing.T) {
+		handler := newTestHandler()
+		secret, _ := testEnableTwoFactor(t, handler)
+		challenge := login(t, handler)
+		handler.database.(*mockDatabase).users[0].TokenVersion++
+
+		params := &authSecondFactorParams{ChallengeToken: challenge, Code: testTOTPCode(secret, 0)}
+		rec := testRequest(context.Background(), params, handler.AuthSecondFactor)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Empty(t, handler.database.(*mockDatabase).sessions)
+	})
+
+	t.Run("complete login with a code of neither format", func(t *testing.T) {
+		handler := newTestHandler()
+		_, recoveryCodes := testEnableTwoFactor(t, handler)
+		challenge := login(t, handler)
+
+		params := &authSecondFactorParams{ChallengeToken: challenge, Code: recoveryCodes[0] + "0"}
+		rec := testRequest(context.Background(), params, handler.AuthSecondFactor)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Len(t, handler.database.(*mockDatabase).users[0].RecoveryCodes, recoveryCodesCount)
+	})
+
+	t.Run("complete login with an invalid challenge token", func(t *testing.T) {
+		handler := newTestHandler()
+		secret, _ := testEnableTwoFactor(t, handler)
+
+		params := &authSecondFactorParams{ChallengeToken: "invalid-challenge", Code: testTOTPCode(secret, 0)}
+		rec := testRequest(context.Background(), params, handler.AuthSecondFactor)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
+
+func TestHandler_TwoFactorDisable(t *testing.T) {
+	t.Run("disable with correct password and code", func(t *testing.T) {
+		handler := newTestHandler()
+		secret, _ := testEnableTwoFactor(t, handler)
+		ctx := testUserContext(context.Background(), handler, "test-username")
+
+		params := &twoFactorDisableParams{Password: "test-password", Code: testTOTPCode(secret, 0)}
+		rec := testRequest(ctx, params, handler.TwoFactorDisable)
+		if assert.Equal(t, http.StatusNoContent, rec.Code) {
+			user := handler.database.(*mockDatabase).users[0]
+			assert.False(t, user.TOTPEnabled)
+			assert.Empty(t, user.TOTPSecret)
+			assert.Empty(t, user.RecoveryCodes)
+		}
+	})
+
+	t.Run("disable with wrong password", func(t *testing.T) {
+		handler := newTestHandler()
+		secret, _ := testEnableTwoFactor(t, handler)
+		ctx := testUserContext(context.Background(), handler, "test-username")
+
+		params := &twoFactorDisableParams{Password: "wrong-password", Code: testTOTPCode(secret, 0)}
+		rec := testRequest(ctx, params, handler.TwoFactorDisable)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.True(t, handler.database.(*mockDatabase).users[0].TOTPEnabled)
+	})
+}
@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"github.com/groshi-project/groshi/internal/auth"
 	"github.com/groshi-project/groshi/internal/database"
 	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
-	"github.com/groshi-project/groshi/internal/service/handler/model"
 	"github.com/groshi-project/groshi/internal/service/handler/response"
 	"net/http"
 )
@@ -12,6 +15,9 @@ import (
 type userCreateParams struct {
 	Username string `json:"username" example:"username" validate:"required"`
 	Password string `json:"password" example:"my-secret-password" validate:"required"`
+
+	// Invite code, it is required if registration is invite-only and is optional if registration is open.
+	InviteCode string `json:"invite_code,omitempty" example:"mfrggzdfmztwq2lknnwg23tp" validate:"max=128"`
 }
 
 type userCreateResponse struct {
@@ -22,17 +28,23 @@ type userCreateResponse struct {
 //
 //	@Summary		Create a new user
 //	@Summary		Create a new user
-//	@Description	Creates a new user and returns its username
+//	@Description	Creates a new user and returns its username. Depending on the registration mode of the instance, anyone can create a user, a valid invite code is required or creation of users is forbidden. If an invite code is provided, the user is recorded as invited by the creator of the code
 //	@Tags			user
 //	@Accept			json
 //	@Produce		json
-//	@Param			user	body		userCreateParams	true	"Username and password"
+//	@Param			user	body		userCreateParams	true	"Username, password and invite code"
 //	@Success		200		{object}	userCreateResponse	"Successful operation"
 //	@Failure		409		{object}	model.Error			"User with such username already exists"
 //	@Failure		400		{object}	model.Error			"Invalid request body format or invalid request params"
+//	@Failure		403		{object}	model.Error			"Registration is closed, or the invite code is missing, invalid, expired or used up"
 //	@Failure		500		{object}	model.Error			"Internal server error"
 //	@Router			/user [post]
 func (h *Handler) UserCreate(w http.ResponseWriter, r *http.Request) {
+	if h.registrationMode == auth.RegistrationClosed {
+		httpresp.Render(w, response.RegistrationClosed)
+		return
+	}
+
 	// parse request params:
 	params := &userCreateParams{}
 	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
@@ -46,6 +58,11 @@ func (h *Handler) UserCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.registrationMode == auth.RegistrationInviteOnly && params.InviteCode == "" {
+		httpresp.Render(w, response.InviteCodeRequired)
+		return
+	}
+
 	// check if user with such username already exist:
 	exists, err := h.database.UserExistsByUsername(r.Context(), params.Username)
 	if err != nil {
@@ -54,7 +71,7 @@ func (h *Handler) UserCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if exists {
-		httpresp.Render(w, httpresp.New(http.StatusConflict, model.NewError("user already exists")))
+		httpresp.Render(w, response.UserAlreadyExists)
 		return
 	}
 
@@ -69,11 +86,45 @@ func (h *Handler) UserCreate(w http.ResponseWriter, r *http.Request) {
 		Username: params.Username,
 		Password: passwordHash,
 	}
-	if err := h.database.CreateUser(r.Context(), user); err != nil {
+	invalidInviteCode := false
+	err = h.database.RunInTx(r.Context(), func(ctx context.Context, db database.Database) error {
+		// use the invite code and record who invited the user:
+		if params.InviteCode != "" {
+			inviteCode := &database.InviteCode{}
+			if err := db.SelectInviteCodeByHash(ctx, auth.HashInviteCode(params.InviteCode), inviteCode); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					invalidInviteCode = true
+					return nil
+				}
+				return err
+			}
+			used, err := db.UseInviteCode(ctx, inviteCode.ID)
+			if err != nil {
+				return err
+			}
+			if !used {
+				invalidInviteCode = true
+				return nil
+			}
+			user.InvitedByID = inviteCode.CreatorID
+		}
+
+		return db.CreateUser(ctx, user)
+	})
+	if err != nil {
+		// the username may be taken concurrently by another request:
+		if database.IsUniqueViolation(err) {
+			httpresp.Render(w, response.UserAlreadyExists)
+			return
+		}
 		h.internalServerErrorLogger.Println(err)
 		httpresp.Render(w, response.InternalServerError)
 		return
 	}
+	if invalidInviteCode {
+		httpresp.Render(w, response.InvalidInviteCode)
+		return
+	}
 
 	// respond:
 	resp := &userCreateResponse{
@@ -83,7 +134,10 @@ func (h *Handler) UserCreate(w http.ResponseWriter, r *http.Request) {
 }
 
 type userGetResponse struct {
+	// UUID of the user, it does not change when the user changes username.
+	UUID     string `json:"uuid" example:"3f0c9a52-6a1e-4d1b-9b1e-5a2f7c8d9e0f"`
 	Username string `json:"username" example:"jieggii"`
+	Role     string `json:"role" example:"user" enums:"user,admin"`
 }
 
 // UserGet returns information about the current user.
@@ -99,31 +153,227 @@ type userGetResponse struct {
 //	@Security		Bearer
 //	@Router			/user [get]
 func (h *Handler) UserGet(w http.ResponseWriter, r *http.Request) {
-	// extract current user's username from context:
-	username, ok := r.Context().Value("username").(string)
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// respond:
+	resp := &userGetResponse{
+		UUID:     user.UUID.String(),
+		Username: user.Username,
+		Role:     user.Role,
+	}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
+
+type userUpdateParams struct {
+	Username string `json:"username" example:"jieggii" validate:"required"`
+}
+
+type userUpdateResponse struct {
+	UUID     string `json:"uuid" example:"3f0c9a52-6a1e-4d1b-9b1e-5a2f7c8d9e0f"`
+	Username string `json:"username" example:"jieggii"`
+}
+
+// UserUpdate changes username of the current user.
+//
+//	@Summary		Update the current user
+//	@Description	Changes username of the current user. Issued tokens stay valid, as they identify the user by UUID
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			user	body		userUpdateParams	true	"New username"
+//	@Success		200		{object}	userUpdateResponse	"Successful operation"
+//	@Failure		400		{object}	model.Error			"Invalid request body format or invalid request params"
+//	@Failure		409		{object}	model.Error			"User with such username already exists"
+//	@Failure		500		{object}	model.Error			"Internal server error"
+//	@Security		Bearer
+//	@Router			/user [put]
+func (h *Handler) UserUpdate(w http.ResponseWriter, r *http.Request) {
+	// decode request params:
+	params := &userUpdateParams{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return
+	}
+
+	// validate request params:
+	if err := h.paramsValidate.Struct(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return
+	}
+
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
 	if !ok {
+		return
+	}
+
+	// change username if it is not taken by another user:
+	if params.Username != user.Username {
+		exists, err := h.database.UserExistsByUsername(r.Context(), params.Username)
+		if err != nil {
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+		if exists {
+			httpresp.Render(w, response.UserAlreadyExists)
+			return
+		}
+
+		if err := h.database.UpdateUsername(r.Context(), user.ID, params.Username); err != nil {
+			// the username may be taken concurrently by another request:
+			if database.IsUniqueViolation(err) {
+				httpresp.Render(w, response.UserAlreadyExists)
+				return
+			}
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+			return
+		}
+	}
+
+	// respond:
+	resp := &userUpdateResponse{
+		UUID:     user.UUID.String(),
+		Username: params.Username,
+	}
+	httpresp.Render(w, httpresp.NewOK(resp))
+}
+
+type userPasswordUpdateParams struct {
+	OldPassword string `json:"old_password" example:"my-secret-password" validate:"required"`
+	NewPassword string `json:"new_password" example:"my-new-secret-password" validate:"required"`
+}
+
+// UserPasswordUpdate changes password of the current user.
+//
+//	@Summary		Change password of the current user
+//	@Description	Changes password of the current user if the current password is correct. All previously issued tokens of the user are invalidated and all sessions are revoked, so the user has to log in again
+//	@Tags			user
+//	@Accept			json
+//	@Param			passwords	body	userPasswordUpdateParams	true	"Current and new password"
+//	@Success		204			"Successful operation"
+//	@Failure		403			{object}	model.Error	"Wrong current password"
+//	@Failure		400			{object}	model.Error	"Invalid request body format or invalid request params"
+//	@Failure		404			{object}	model.Error	"User not found"
+//	@Failure		500			{object}	model.Error	"Internal server error"
+//	@Security		Bearer
+//	@Router			/user/password [put]
+func (h *Handler) UserPasswordUpdate(w http.ResponseWriter, r *http.Request) {
+	// decode request params:
+	params := &userPasswordUpdateParams{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return
+	}
+
+	// validate request params:
+	if err := h.paramsValidate.Struct(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return
+	}
+
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// verify the current password:
+	ok, err := h.verifyUserPassword(user, params.OldPassword)
+	if err != nil {
+		h.internalServerErrorLogger.Println(err)
 		httpresp.Render(w, response.InternalServerError)
 		return
 	}
+	if !ok {
+		httpresp.Render(w, response.WrongPassword)
+		return
+	}
 
-	// check if user exists:
-	exists, err := h.database.UserExistsByUsername(r.Context(), username)
+	// set the new password and invalidate all tokens of the user:
+	passwordHash, err := h.passwordAuth.HashPassword(params.NewPassword)
 	if err != nil {
+		h.internalServerErrorLogger.Println(err)
 		httpresp.Render(w, response.InternalServerError)
 		return
 	}
-	if !exists {
-		httpresp.Render(w, response.UserNotFound)
+	if err := h.database.ChangeUserPassword(r.Context(), user.ID, passwordHash); err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
 		return
 	}
 
 	// respond:
-	resp := &userGetResponse{Username: username}
-	httpresp.Render(w, httpresp.NewOK(resp))
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *Handler) UserUpdate(w http.ResponseWriter, r *http.Request) {
-	panic("implement me")
+type userPasswordSetParams struct {
+	Password string `json:"password" example:"my-secret-password" validate:"required"`
+}
+
+// UserPasswordSet sets the first password of the current user.
+//
+//	@Summary		Set password of the current user
+//	@Description	Sets password of the current user who has no password, for example, created on the first OIDC login, so that the user can also log in using the username and the password. Tokens and sessions of the user are not affected. Use `PUT /user/password` to change an existing password
+//	@Tags			user
+//	@Accept			json
+//	@Param			password	body	userPasswordSetParams	true	"New password"
+//	@Success		204			"Successful operation"
+//	@Failure		400			{object}	model.Error	"Invalid request body format or invalid request params"
+//	@Failure		404			{object}	model.Error	"User not found"
+//	@Failure		409			{object}	model.Error	"The user already has a password"
+//	@Failure		500			{object}	model.Error	"Internal server error"
+//	@Security		Bearer
+//	@Router			/user/password [post]
+func (h *Handler) UserPasswordSet(w http.ResponseWriter, r *http.Request) {
+	// decode request params:
+	params := &userPasswordSetParams{}
+	if err := json.NewDecoder(r.Body).Decode(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return
+	}
+
+	// validate request params:
+	if err := h.paramsValidate.Struct(params); err != nil {
+		httpresp.Render(w, response.InvalidRequestParams)
+		return
+	}
+
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	if user.Password != "" {
+		httpresp.Render(w, response.PasswordAlreadySet)
+		return
+	}
+
+	// set the password unless it was set concurrently by another request:
+	passwordHash, err := h.passwordAuth.HashPassword(params.Password)
+	if err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+	ok, err = h.database.SetUserPassword(r.Context(), user.ID, passwordHash)
+	if err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+	if !ok {
+		httpresp.Render(w, response.PasswordAlreadySet)
+		return
+	}
+
+	// respond:
+	w.WriteHeader(http.StatusNoContent)
 }
 
 type userDeleteResponse struct {
@@ -133,37 +383,35 @@ type userDeleteResponse struct {
 // UserDelete deletes the current user.
 //
 //	@Summary		Delete the current user
-//	@Description	Deletes the current user and returns its username
+//	@Description	Deletes the current user together with its personal ledger and returns its username. The user leaves all shared ledgers, categories and transactions created by the user stay in them.
+//	@Description	The user can not be deleted while being the only owner of a shared ledger or while owing or being owed money in a ledger
 //	@Tags			user
 //	@Accept			json
 //	@Produce		json
 //	@Success		200	{object}	userDeleteResponse	"Successful operation"
 //	@Failure		404	{object}	model.Error			"User not found"
+//	@Failure		409	{object}	model.Error			"The user is the only owner of a shared ledger or has not settled up"
 //	@Failure		500	{object}	model.Error			"Internal server error"
 //	@Security		Bearer
 //	@Router			/user [delete]
 func (h *Handler) UserDelete(w http.ResponseWriter, r *http.Request) {
-	// extract current user's username from context:
-	username, ok := r.Context().Value("username").(string)
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
 	if !ok {
-		httpresp.Render(w, response.InternalServerError)
 		return
 	}
 
-	// check if the user exists:
-	exists, err := h.database.UserExistsByUsername(r.Context(), username)
-	if err != nil {
-		h.internalServerErrorLogger.Println(err)
-		httpresp.Render(w, response.InternalServerError)
-		return
-	}
-	if !exists {
-		httpresp.Render(w, response.UserNotFound)
-		return
-	}
-
-	// delete the user from the database:
-	if err := h.database.DeleteUserByUsername(r.Context(), username); err != nil {
+	// delete the user from the database unless a shared ledger would be left without owners
+	// or debts which could not be settled:
+	if err := h.database.DeleteUserByID(r.Context(), user.ID); err != nil {
+		if errors.Is(err, database.ErrLastLedgerOwner) {
+			httpresp.Render(w, response.UserIsLastLedgerOwner)
+			return
+		}
+		if errors.Is(err, database.ErrUserHasBalance) {
+			httpresp.Render(w, response.UserHasBalance)
+			return
+		}
 		h.internalServerErrorLogger.Println(err)
 		httpresp.Render(w, response.InternalServerError)
 		return
@@ -171,7 +419,7 @@ func (h *Handler) UserDelete(w http.ResponseWriter, r *http.Request) {
 
 	// respond:
 	resp := &userDeleteResponse{
-		Username: username,
+		Username: user.Username,
 	}
 	httpresp.Render(w, httpresp.NewOK(resp))
 }
@@ -0,0 +1,812 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/groshi-project/groshi/internal/rules"
+	"github.com/groshi-project/groshi/internal/service/handler/httpresp"
+	"github.com/groshi-project/groshi/internal/service/handler/model"
+	"github.com/groshi-project/groshi/internal/service/handler/response"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// userArchiveVersion is the version of the user archive format. It must be increased on incompatible changes
+// of the format, archives of newer versions are rejected by the import.
+const userArchiveVersion = 1
+
+// maxUserArchiveSize is the maximum size of the user archive which can be imported.
+const maxUserArchiveSize = 256 << 20
+
+// maxUserArchiveConflicts is the maximum count of conflicts listed in the error message.
+const maxUserArchiveConflicts = 10
+
+const (
+	// userImportOnConflictSkip makes the import reuse existing categories, tags and payees with the same names
+	// and skip rules with the same names and transactions which already exist.
+	userImportOnConflictSkip = "skip"
+
+	// userImportOnConflictFail makes the import fail without changes if any conflict is found.
+	userImportOnConflictFail = "fail"
+)
+
+// errUserArchiveConflict is returned by the import if the archive conflicts with existing data of the user.
+var errUserArchiveConflict = errors.New("archive conflicts with existing data")
+
+// userArchiveError describes why the archive is invalid.
+type userArchiveError struct {
+	message string
+}
+
+func (e *userArchiveError) Error() string {
+	return "invalid archive: " + e.message
+}
+
+// newUserArchiveError creates a new [userArchiveError] with formatted message.
+func newUserArchiveError(format string, a ...any) error {
+	return &userArchiveError{message: fmt.Sprintf(format, a...)}
+}
+
+type userArchiveCategory struct {
+	UUID string `json:"uuid" example:"02983837-7ab0-492a-90b6-285491936067"`
+	Name string `json:"name" example:"Groceries"`
+}
+
+type userArchiveTag struct {
+	UUID string `json:"uuid" example:"5f0c2d6e-5c1a-4b8e-9d39-7f9b3b1d2a61"`
+	Name string `json:"name" example:"vacation-2026"`
+}
+
+type userArchivePayee struct {
+	UUID string `json:"uuid" example:"8b0e4f1a-7c2d-4e3b-9a6f-1d5c8e2b7a90"`
+	Name string `json:"name" example:"Corner Store"`
+}
+
+type userArchiveRule struct {
+	UUID     string `json:"uuid" example:"4c8f6a3e-2b7d-4e1f-9a5c-8d3b2e1f0a94"`
+	Name     string `json:"name" example:"Supermarkets"`
+	Position int    `json:"position" example:"10"`
+
+	MatchField   string `json:"match_field,omitempty" example:"payee"`
+	MatchType    string `json:"match_type,omitempty" example:"substring"`
+	Pattern      string `json:"pattern,omitempty" example:"rewe"`
+	AmountMin    *int32 `json:"amount_min,omitempty" example:"-10000"`
+	AmountMax    *int32 `json:"amount_max,omitempty" example:"0"`
+	CurrencyCode string `json:"currency,omitempty" example:"EUR"`
+
+	// UUIDs refer to the categories and tags of the archive.
+	CategoryUUID string   `json:"category,omitempty" example:"02983837-7ab0-492a-90b6-285491936067"`
+	TagUUIDs     []string `json:"tags"`
+	RenamePayee  string   `json:"rename_payee,omitempty" example:"REWE"`
+}
+
+type userArchiveSplit struct {
+	CategoryUUID string `json:"category" example:"02983837-7ab0-492a-90b6-285491936067"`
+	Amount       int32  `json:"amount" example:"1500"`
+	Note         string `json:"note,omitempty" example:"Dish soap"`
+}
+
+type userArchiveTransaction struct {
+	UUID         string `json:"uuid" example:"3be1ed0a-c307-49de-872e-38730200f301"`
+	Amount       int32  `json:"amount" example:"-2500"`
+	CurrencyCode string `json:"currency" example:"USD"`
+	Description  string `json:"description,omitempty" example:"Bought a donut for $2.5 only!"`
+
+	// UUIDs refer to the payees, categories and tags of the archive.
+	PayeeUUID    string             `json:"payee,omitempty" example:"8b0e4f1a-7c2d-4e3b-9a6f-1d5c8e2b7a90"`
+	CategoryUUID string             `json:"category,omitempty" example:"02983837-7ab0-492a-90b6-285491936067"`
+	Splits       []userArchiveSplit `json:"splits,omitempty"`
+	TagUUIDs     []string           `json:"tags"`
+
+	ExternalID string     `json:"external_id,omitempty" example:"ofx:12345678:20260305001"`
+	Timestamp  time.Time  `json:"timestamp" example:"2026-03-05T14:30:00Z"`
+	ValueDate  *time.Time `json:"value_date,omitempty" example:"2026-03-04T00:00:00Z"`
+}
+
+// userArchive represents archive of all data owned by a user. Objects refer to each other by UUIDs,
+// which are remapped on import if they are already used on the instance.
+type userArchive struct {
+	Version    int       `json:"version" example:"1"`
+	ExportedAt time.Time `json:"exported_at" example:"2026-03-20T12:57:38Z"`
+	Username   string    `json:"username" example:"jieggii"`
+
+	Categories   []userArchiveCategory    `json:"categories"`
+	Tags         []userArchiveTag         `json:"tags"`
+	Payees       []userArchivePayee       `json:"payees"`
+	Rules        []userArchiveRule        `json:"rules"`
+	Transactions []userArchiveTransaction `json:"transactions"`
+}
+
+// newUserArchive collects all data owned by the user into a new archive.
+func (h *Handler) newUserArchive(ctx context.Context, user *database.User) (*userArchive, error) {
+	archive := &userArchive{
+		Version:      userArchiveVersion,
+		ExportedAt:   time.Now().UTC(),
+		Username:     user.Username,
+		Categories:   make([]userArchiveCategory, 0),
+		Tags:         make([]userArchiveTag, 0),
+		Payees:       make([]userArchivePayee, 0),
+		Rules:        make([]userArchiveRule, 0),
+		Transactions: make([]userArchiveTransaction, 0),
+	}
+
+	// UUIDs of the objects by their IDs, used to refer to them:
+	categoryUUIDs := make(map[int64]string)
+	tagUUIDs := make(map[int64]string)
+	payeeUUIDs := make(map[int64]string)
+
+	// categories and transactions are taken from the personal ledger of the user, shared ledgers are not archived:
+	ledger := &database.Ledger{}
+	if err := h.database.SelectOrCreatePersonalLedger(ctx, user.ID, ledger); err != nil {
+		return nil, err
+	}
+
+	categories := make([]database.Category, 0)
+	if err := h.database.SelectCategoriesByLedgerID(ctx, ledger.ID, &categories); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	for _, category := range categories {
+		categoryUUIDs[category.ID] = category.UUID.String()
+		archive.Categories = append(archive.Categories, userArchiveCategory{UUID: category.UUID.String(), Name: category.Name})
+	}
+
+	tags := make([]database.Tag, 0)
+	if err := h.database.SelectTagsByOwnerID(ctx, user.ID, &tags); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	for _, tag := range tags {
+		tagUUIDs[tag.ID] = tag.UUID.String()
+		archive.Tags = append(archive.Tags, userArchiveTag{UUID: tag.UUID.String(), Name: tag.Name})
+	}
+
+	payees := make([]database.PayeeUsage, 0)
+	if err := h.database.SelectPayeeUsages(ctx, user.ID, "", 0, &payees); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	for _, payee := range payees {
+		payeeUUIDs[payee.ID] = payee.UUID.String()
+		archive.Payees = append(archive.Payees, userArchivePayee{UUID: payee.UUID.String(), Name: payee.Name})
+	}
+
+	userRules := make([]database.Rule, 0)
+	if err := h.database.SelectRulesByOwnerID(ctx, user.ID, &userRules); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	for _, rule := range userRules {
+		item := userArchiveRule{
+			UUID:         rule.UUID.String(),
+			Name:         rule.Name,
+			Position:     rule.Position,
+			MatchField:   rule.MatchField,
+			MatchType:    rule.MatchType,
+			Pattern:      rule.Pattern,
+			AmountMin:    rule.AmountMin,
+			AmountMax:    rule.AmountMax,
+			CurrencyCode: rule.Currency.Code,
+			CategoryUUID: categoryUUIDs[rule.CategoryID],
+			TagUUIDs:     make([]string, 0, len(rule.TagIDs)),
+			RenamePayee:  rule.PayeeName,
+		}
+		for _, tagID := range rule.TagIDs {
+			item.TagUUIDs = append(item.TagUUIDs, tagUUIDs[tagID])
+		}
+		archive.Rules = append(archive.Rules, item)
+	}
+
+	transactions := make([]database.Transaction, 0)
+	if err := h.database.SelectTransactions(ctx, &database.TransactionFilter{LedgerID: ledger.ID}, &transactions); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	for _, transaction := range transactions {
+		item := userArchiveTransaction{
+			UUID:         transaction.UUID.String(),
+			Amount:       transaction.Amount,
+			CurrencyCode: transaction.Currency.Code,
+			Description:  transaction.Description,
+			PayeeUUID:    payeeUUIDs[transaction.PayeeID],
+			CategoryUUID: categoryUUIDs[transaction.CategoryID],
+			TagUUIDs:     make([]string, 0, len(transaction.Tags)),
+			ExternalID:   transaction.ExternalID,
+			Timestamp:    transaction.Timestamp,
+		}
+		for _, split := range transaction.Splits {
+			item.Splits = append(item.Splits, userArchiveSplit{
+				CategoryUUID: categoryUUIDs[split.CategoryID],
+				Amount:       split.Amount,
+				Note:         split.Note,
+			})
+		}
+		for _, tag := range transaction.Tags {
+			item.TagUUIDs = append(item.TagUUIDs, tagUUIDs[tag.ID])
+		}
+		if !transaction.ValueDate.IsZero() {
+			valueDate := transaction.ValueDate
+			item.ValueDate = &valueDate
+		}
+		archive.Transactions = append(archive.Transactions, item)
+	}
+
+	return archive, nil
+}
+
+// UserExport returns archive of all data owned by the current user.
+//
+//	@Summary		Export all data of the current user
+//	@Description	Returns a versioned JSON archive of all data owned by the current user: categories, tags, payees, rules and transactions.
+//	@Description	The archive can be imported into an account on this or another instance using `POST /user/import`.
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	userArchive	"Successful operation"
+//	@Failure		404	{object}	model.Error	"User not found"
+//	@Failure		500	{object}	model.Error	"Internal server error"
+//	@Security		Bearer
+//	@Router			/user/export [get]
+func (h *Handler) UserExport(w http.ResponseWriter, r *http.Request) {
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// collect data of the user:
+	archive, err := h.newUserArchive(r.Context(), user)
+	if err != nil {
+		h.internalServerErrorLogger.Println(err)
+		httpresp.Render(w, response.InternalServerError)
+		return
+	}
+
+	// respond:
+	filename := fmt.Sprintf("groshi-%s-%s.json", user.Username, archive.ExportedAt.Format(time.DateOnly))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	httpresp.Render(w, httpresp.NewOK(archive))
+}
+
+type userImportCounts struct {
+	Categories   int `json:"categories" example:"12"`
+	Tags         int `json:"tags" example:"4"`
+	Payees       int `json:"payees" example:"37"`
+	Rules        int `json:"rules" example:"6"`
+	Transactions int `json:"transactions" example:"1520"`
+}
+
+type userImportResponse struct {
+	// Objects created by the import.
+	Imported userImportCounts `json:"imported"`
+
+	// Objects which already existed: categories, tags and payees which were reused, rules and transactions which were skipped.
+	Skipped userImportCounts `json:"skipped"`
+
+	// Count of the imported objects which got new UUIDs because their UUIDs from the archive are already in use.
+	Remapped int `json:"remapped" example:"0"`
+}
+
+// userArchiveImport imports an archive into the account of a user.
+type userArchiveImport struct {
+	db   database.Database
+	user *database.User
+
+	// personal ledger of the user, categories and transactions are imported into it.
+	ledger *database.Ledger
+
+	// If true, conflicts are collected and the import fails, otherwise conflicting objects are reused or skipped.
+	failOnConflict bool
+	conflicts      []string
+
+	// IDs of the imported or reused objects by their UUIDs in the archive:
+	categoryIDs map[string]int64
+	tagIDs      map[string]int64
+	payeeIDs    map[string]int64
+
+	// currencies by their codes:
+	currencies map[string]*database.Currency
+
+	result userImportResponse
+}
+
+// conflict records a conflict if conflicts are not allowed, the conflicting object is reused or skipped otherwise.
+func (i *userArchiveImport) conflict(format string, a ...any) {
+	if i.failOnConflict {
+		i.conflicts = append(i.conflicts, fmt.Sprintf(format, a...))
+	}
+}
+
+// freeUUID parses UUID from the archive and returns it if it is not used by any object yet, otherwise a new UUID
+// is returned. The taken function reports if the UUID is in use.
+func (i *userArchiveImport) freeUUID(s string, taken func(id string) (bool, error)) (uuid.UUID, error) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, newUserArchiveError("invalid UUID %q", s)
+	}
+	exists, err := taken(id.String())
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if exists {
+		i.result.Remapped++
+		return uuid.New(), nil
+	}
+	return id, nil
+}
+
+// uuidTaken returns a function for [userArchiveImport.freeUUID] which uses the given select method.
+func uuidTaken[T any](ctx context.Context, selectByUUID func(ctx context.Context, uuid string, model *T) error) func(id string) (bool, error) {
+	return func(id string) (bool, error) {
+		var model T
+		if err := selectByUUID(ctx, id, &model); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+}
+
+// reference resolves UUID referring to an object of the archive to its ID, empty UUID is resolved to zero.
+func reference(ids map[string]int64, kind string, id string) (int64, error) {
+	if id == "" {
+		return 0, nil
+	}
+	resolved, ok := ids[id]
+	if !ok {
+		return 0, newUserArchiveError("unknown %s %q", kind, id)
+	}
+	return resolved, nil
+}
+
+// currency returns currency with the given code.
+func (i *userArchiveImport) currency(ctx context.Context, code string) (*database.Currency, error) {
+	if currency, ok := i.currencies[code]; ok {
+		return currency, nil
+	}
+	currency := &database.Currency{}
+	if err := i.db.SelectCurrencyByCode(ctx, code, currency); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, newUserArchiveError("unknown currency %q", code)
+		}
+		return nil, err
+	}
+	i.currencies[code] = currency
+	return currency, nil
+}
+
+// run imports the archive. Returns [userArchiveError] if the archive is invalid
+// and errUserArchiveConflict if it conflicts with existing data and conflicts are not allowed.
+func (i *userArchiveImport) run(ctx context.Context, archive *userArchive) error {
+	steps := []func(ctx context.Context, archive *userArchive) error{
+		i.importCategories, i.importTags, i.importPayees, i.importRules, i.importTransactions,
+	}
+	for _, step := range steps {
+		if err := step(ctx, archive); err != nil {
+			return err
+		}
+	}
+
+	if len(i.conflicts) != 0 {
+		return errUserArchiveConflict
+	}
+	return nil
+}
+
+func (i *userArchiveImport) importCategories(ctx context.Context, archive *userArchive) error {
+	existing := make([]database.Category, 0)
+	if err := i.db.SelectCategoriesByLedgerID(ctx, i.ledger.ID, &existing); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	existingIDs := make(map[string]int64)
+	for _, category := range existing {
+		existingIDs[category.Name] = category.ID
+	}
+
+	for _, item := range archive.Categories {
+		if _, ok := i.categoryIDs[item.UUID]; ok {
+			return newUserArchiveError("duplicate category %q", item.UUID)
+		}
+		if item.Name == "" {
+			return newUserArchiveError("category %q has no name", item.UUID)
+		}
+		if id, ok := existingIDs[item.Name]; ok {
+			i.conflict("category %q already exists", item.Name)
+			i.categoryIDs[item.UUID] = id
+			i.result.Skipped.Categories++
+			continue
+		}
+
+		id, err := i.freeUUID(item.UUID, func(id string) (bool, error) {
+			return i.db.CategoryExistsByUUID(ctx, id)
+		})
+		if err != nil {
+			return err
+		}
+		category := &database.Category{UUID: id, Name: item.Name, LedgerID: i.ledger.ID, OwnerID: i.user.ID}
+		if err := i.db.CreateCategory(ctx, category); err != nil {
+			return err
+		}
+		i.categoryIDs[item.UUID] = category.ID
+		existingIDs[category.Name] = category.ID
+		i.result.Imported.Categories++
+	}
+	return nil
+}
+
+func (i *userArchiveImport) importTags(ctx context.Context, archive *userArchive) error {
+	existing := make([]database.Tag, 0)
+	if err := i.db.SelectTagsByOwnerID(ctx, i.user.ID, &existing); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	existingIDs := make(map[string]int64)
+	for _, tag := range existing {
+		existingIDs[tag.Name] = tag.ID
+	}
+
+	for _, item := range archive.Tags {
+		if _, ok := i.tagIDs[item.UUID]; ok {
+			return newUserArchiveError("duplicate tag %q", item.UUID)
+		}
+		if item.Name == "" {
+			return newUserArchiveError("tag %q has no name", item.UUID)
+		}
+		if id, ok := existingIDs[item.Name]; ok {
+			i.conflict("tag %q already exists", item.Name)
+			i.tagIDs[item.UUID] = id
+			i.result.Skipped.Tags++
+			continue
+		}
+
+		id, err := i.freeUUID(item.UUID, uuidTaken(ctx, i.db.SelectTagByUUID))
+		if err != nil {
+			return err
+		}
+		tag := &database.Tag{UUID: id, Name: item.Name, OwnerID: i.user.ID}
+		if err := i.db.CreateTag(ctx, tag); err != nil {
+			return err
+		}
+		i.tagIDs[item.UUID] = tag.ID
+		existingIDs[tag.Name] = tag.ID
+		i.result.Imported.Tags++
+	}
+	return nil
+}
+
+// importPayees imports payees, payees with the same normalized names are always merged and are not conflicts.
+// Payees get new UUIDs.
+func (i *userArchiveImport) importPayees(ctx context.Context, archive *userArchive) error {
+	existing := make([]database.PayeeUsage, 0)
+	if err := i.db.SelectPayeeUsages(ctx, i.user.ID, "", 0, &existing); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	existingIDs := make(map[int64]bool)
+	for _, payee := range existing {
+		existingIDs[payee.ID] = true
+	}
+
+	for _, item := range archive.Payees {
+		if _, ok := i.payeeIDs[item.UUID]; ok {
+			return newUserArchiveError("duplicate payee %q", item.UUID)
+		}
+		if database.CleanPayeeName(item.Name) == "" {
+			return newUserArchiveError("payee %q has no name", item.UUID)
+		}
+
+		payee := &database.Payee{}
+		if err := i.db.SelectOrCreatePayee(ctx, i.user.ID, item.Name, payee); err != nil {
+			return err
+		}
+		i.payeeIDs[item.UUID] = payee.ID
+		if existingIDs[payee.ID] {
+			i.result.Skipped.Payees++
+		} else {
+			existingIDs[payee.ID] = true
+			i.result.Imported.Payees++
+		}
+	}
+	return nil
+}
+
+func (i *userArchiveImport) importRules(ctx context.Context, archive *userArchive) error {
+	existing := make([]database.Rule, 0)
+	if err := i.db.SelectRulesByOwnerID(ctx, i.user.ID, &existing); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	existingNames := make(map[string]bool)
+	for _, rule := range existing {
+		existingNames[rule.Name] = true
+	}
+
+	for _, item := range archive.Rules {
+		if existingNames[item.Name] {
+			i.conflict("rule %q already exists", item.Name)
+			i.result.Skipped.Rules++
+			continue
+		}
+
+		rule := &database.Rule{
+			Name:       item.Name,
+			Position:   item.Position,
+			MatchField: item.MatchField,
+			MatchType:  item.MatchType,
+			Pattern:    item.Pattern,
+			AmountMin:  item.AmountMin,
+			AmountMax:  item.AmountMax,
+			TagIDs:     make([]int64, 0, len(item.TagUUIDs)),
+			PayeeName:  database.CleanPayeeName(item.RenamePayee),
+			OwnerID:    i.user.ID,
+		}
+		if item.CurrencyCode != "" {
+			currency, err := i.currency(ctx, item.CurrencyCode)
+			if err != nil {
+				return err
+			}
+			rule.CurrencyID = currency.ID
+		}
+		categoryID, err := reference(i.categoryIDs, "category", item.CategoryUUID)
+		if err != nil {
+			return err
+		}
+		rule.CategoryID = categoryID
+		for _, tagUUID := range item.TagUUIDs {
+			tagID, err := reference(i.tagIDs, "tag", tagUUID)
+			if err != nil {
+				return err
+			}
+			rule.TagIDs = append(rule.TagIDs, tagID)
+		}
+		if err := rules.Validate(rule); err != nil {
+			return newUserArchiveError("rule %q: %s", item.Name, err)
+		}
+
+		rule.UUID, err = i.freeUUID(item.UUID, uuidTaken(ctx, i.db.SelectRuleByUUID))
+		if err != nil {
+			return err
+		}
+		if err := i.db.CreateRule(ctx, rule); err != nil {
+			return err
+		}
+		existingNames[rule.Name] = true
+		i.result.Imported.Rules++
+	}
+	return nil
+}
+
+// importTransactions imports transactions. Transactions which have the same UUIDs as the transactions of the user
+// (archive is imported into the account it was exported from) or the same external IDs already exist.
+func (i *userArchiveImport) importTransactions(ctx context.Context, archive *userArchive) error {
+	externalIDs := make([]string, 0)
+	for _, item := range archive.Transactions {
+		if item.ExternalID != "" {
+			externalIDs = append(externalIDs, item.ExternalID)
+		}
+	}
+	existing := make([]string, 0)
+	if err := i.db.SelectTransactionExternalIDs(ctx, i.ledger.ID, externalIDs, &existing); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	existingExternalIDs := make(map[string]bool)
+	for _, externalID := range existing {
+		existingExternalIDs[externalID] = true
+	}
+
+	seen := make(map[string]bool)
+	transactions := make([]*database.Transaction, 0, len(archive.Transactions))
+	for _, item := range archive.Transactions {
+		if seen[item.UUID] {
+			return newUserArchiveError("duplicate transaction %q", item.UUID)
+		}
+		seen[item.UUID] = true
+
+		id, err := uuid.Parse(item.UUID)
+		if err != nil {
+			return newUserArchiveError("invalid UUID %q", item.UUID)
+		}
+
+		// check if the transaction already exists:
+		existing := &database.Transaction{}
+		if err := i.db.SelectTransactionByUUID(ctx, id.String(), existing); err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+		} else if existing.LedgerID == i.ledger.ID {
+			i.conflict("transaction %q already exists", item.UUID)
+			i.result.Skipped.Transactions++
+			continue
+		} else {
+			// the UUID is used by a transaction of another ledger:
+			id = uuid.New()
+			i.result.Remapped++
+		}
+		if existingExternalIDs[item.ExternalID] {
+			i.conflict("transaction %q was already imported from %q", item.UUID, item.ExternalID)
+			i.result.Skipped.Transactions++
+			continue
+		}
+		if item.ExternalID != "" {
+			existingExternalIDs[item.ExternalID] = true
+		}
+
+		transaction, err := i.newTransaction(ctx, &item)
+		if err != nil {
+			return err
+		}
+		transaction.UUID = id
+		transactions = append(transactions, transaction)
+	}
+
+	if len(transactions) != 0 {
+		if err := i.db.CreateTransactions(ctx, transactions); err != nil {
+			return err
+		}
+	}
+	i.result.Imported.Transactions = len(transactions)
+	return nil
+}
+
+// newTransaction creates a new transaction of the personal ledger of the user from the archive item and resolves its references.
+func (i *userArchiveImport) newTransaction(ctx context.Context, item *userArchiveTransaction) (*database.Transaction, error) {
+	if item.Amount == 0 {
+		return nil, newUserArchiveError("transaction %q has zero amount", item.UUID)
+	}
+	if item.CategoryUUID != "" && len(item.Splits) != 0 {
+		return nil, newUserArchiveError("transaction %q has both category and splits", item.UUID)
+	}
+
+	currency, err := i.currency(ctx, item.CurrencyCode)
+	if err != nil {
+		return nil, err
+	}
+	transaction := &database.Transaction{
+		Amount:      item.Amount,
+		CurrencyID:  currency.ID,
+		Description: item.Description,
+		Splits:      make([]database.TransactionSplit, 0, len(item.Splits)),
+		Tags:        make([]database.Tag, 0, len(item.TagUUIDs)),
+		ExternalID:  item.ExternalID,
+		LedgerID:    i.ledger.ID,
+		OwnerID:     i.user.ID,
+		Timestamp:   item.Timestamp.UTC(),
+	}
+	if item.ValueDate != nil {
+		transaction.ValueDate = item.ValueDate.UTC()
+	}
+
+	if transaction.PayeeID, err = reference(i.payeeIDs, "payee", item.PayeeUUID); err != nil {
+		return nil, err
+	}
+	if transaction.CategoryID, err = reference(i.categoryIDs, "category", item.CategoryUUID); err != nil {
+		return nil, err
+	}
+
+	var splitsAmount int64
+	for _, split := range item.Splits {
+		if split.CategoryUUID == "" {
+			return nil, newUserArchiveError("split of transaction %q has no category", item.UUID)
+		}
+		categoryID, err := reference(i.categoryIDs, "category", split.CategoryUUID)
+		if err != nil {
+			return nil, err
+		}
+		transaction.Splits = append(transaction.Splits, database.TransactionSplit{
+			CategoryID: categoryID,
+			Amount:     split.Amount,
+			Note:       split.Note,
+		})
+		splitsAmount += int64(split.Amount)
+	}
+	if len(item.Splits) != 0 && splitsAmount != int64(item.Amount) {
+		return nil, newUserArchiveError("amounts of the splits of transaction %q do not sum up to its amount", item.UUID)
+	}
+
+	for _, tagUUID := range item.TagUUIDs {
+		tagID, err := reference(i.tagIDs, "tag", tagUUID)
+		if err != nil {
+			return nil, err
+		}
+		if !containsTag(transaction.Tags, tagID) {
+			transaction.Tags = append(transaction.Tags, database.Tag{ID: tagID})
+		}
+	}
+
+	return transaction, nil
+}
+
+// UserImport imports archive into the account of the current user.
+//
+//	@Summary		Import data into the account of the current user
+//	@Description	Imports a JSON archive produced by `GET /user/export` into the account of the current user atomically.
+//	@Description	Objects keep their UUIDs from the archive unless the UUIDs are already in use, then new UUIDs are assigned.
+//	@Description	If `on_conflict` is `skip` (default), existing categories, tags and payees with the same names are reused,
+//	@Description	rules with the same names and transactions which already exist (have the same UUIDs or external IDs) are skipped.
+//	@Description	If `on_conflict` is `fail`, nothing is imported if any conflict is found.
+//	@Tags			user
+//	@Accept			json
+//	@Produce		json
+//	@Param			on_conflict	query		string				false	"How to handle conflicts with existing data: `skip` (default) or `fail`"
+//	@Param			archive		body		userArchive			true	"Archive"
+//	@Success		200			{object}	userImportResponse	"Successful operation"
+//	@Failure		400			{object}	model.Error			"Invalid request body format, invalid request params, unsupported archive version or invalid archive"
+//	@Failure		404			{object}	model.Error			"User not found"
+//	@Failure		409			{object}	model.Error			"Archive conflicts with existing data"
+//	@Failure		500			{object}	model.Error			"Internal server error"
+//	@Security		Bearer
+//	@Router			/user/import [post]
+func (h *Handler) UserImport(w http.ResponseWriter, r *http.Request) {
+	// parse request params:
+	onConflict := r.URL.Query().Get("on_conflict")
+	switch onConflict {
+	case "":
+		onConflict = userImportOnConflictSkip
+	case userImportOnConflictSkip, userImportOnConflictFail:
+	default:
+		httpresp.Render(w, response.InvalidRequestParams)
+		return
+	}
+
+	// decode the archive:
+	archive := &userArchive{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxUserArchiveSize)).Decode(archive); err != nil {
+		httpresp.Render(w, response.InvalidRequestBodyFormat)
+		return
+	}
+	if archive.Version < 1 || archive.Version > userArchiveVersion {
+		httpresp.Render(w, httpresp.New(http.StatusBadRequest, model.NewError(fmt.Sprintf("unsupported archive version %d", archive.Version))))
+		return
+	}
+
+	// fetch the current user:
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	// import the archive in a single database transaction:
+	var (
+		result    userImportResponse
+		conflicts []string
+	)
+	err := h.database.RunInTx(r.Context(), func(ctx context.Context, db database.Database) error {
+		ledger := &database.Ledger{}
+		if err := db.SelectOrCreatePersonalLedger(ctx, user.ID, ledger); err != nil {
+			return err
+		}
+		archiveImport := &userArchiveImport{
+			db:             db,
+			user:           user,
+			ledger:         ledger,
+			failOnConflict: onConflict == userImportOnConflictFail,
+			categoryIDs:    make(map[string]int64),
+			tagIDs:         make(map[string]int64),
+			payeeIDs:       make(map[string]int64),
+			currencies:     make(map[string]*database.Currency),
+		}
+		err := archiveImport.run(ctx, archive)
+		result, conflicts = archiveImport.result, archiveImport.conflicts
+		return err
+	})
+	if err != nil {
+		var archiveErr *userArchiveError
+		switch {
+		case errors.As(err, &archiveErr):
+			httpresp.Render(w, httpresp.New(http.StatusBadRequest, model.NewError(archiveErr.Error())))
+		case errors.Is(err, errUserArchiveConflict):
+			if len(conflicts) > maxUserArchiveConflicts {
+				conflicts = append(conflicts[:maxUserArchiveConflicts], fmt.Sprintf("and %d more", len(conflicts)-maxUserArchiveConflicts))
+			}
+			message := fmt.Sprintf("%s: %s", errUserArchiveConflict, strings.Join(conflicts, "; "))
+			httpresp.Render(w, httpresp.New(http.StatusConflict, model.NewError(message)))
+		default:
+			h.internalServerErrorLogger.Println(err)
+			httpresp.Render(w, response.InternalServerError)
+		}
+		return
+	}
+
+	// respond:
+	httpresp.Render(w, httpresp.NewOK(&result))
+}
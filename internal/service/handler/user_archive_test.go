@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/groshi-project/groshi/internal/database"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testImportRequest makes a test request to [Handler.UserImport] with the archive and returns the recorder.
+func testImportRequest(ctx context.Context, handler *Handler, target string, archive any) *httptest.ResponseRecorder {
+	body, err := json.Marshal(archive)
+	if err != nil {
+		panic(err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	handler.UserImport(rec, req.WithContext(ctx))
+	return rec
+}
+
+func TestHandler_UserArchive(t *testing.T) {
+	const (
+		testUserID        int64 = 12
+		testUsername            = "test-username"
+		testOtherUserID   int64 = 13
+		testOtherUsername       = "test-other-username"
+	)
+
+	var (
+		handler  = newTestHandler()
+		db       = handler.database.(*mockDatabase)
+		ctx      = testUserContext(context.Background(), handler, testUsername)
+		otherCtx = testUserContext(context.Background(), handler, testOtherUsername)
+	)
+
+	// create test users and data of the first one:
+	for _, user := range []*database.User{{ID: testUserID, Username: testUsername}, {ID: testOtherUserID, Username: testOtherUsername}} {
+		if err := db.CreateUser(ctx, user); err != nil {
+			panic(err)
+		}
+	}
+	usd := &database.Currency{ID: 1, Code: "USD", Rate: 1}
+	db.currencies = append(db.currencies, usd)
+	ledgerID := testPersonalLedgerID(db, testUserID)
+
+	groceries := &database.Category{ID: 1, UUID: uuid.New(), Name: "Groceries", LedgerID: ledgerID, OwnerID: testUserID}
+	household := &database.Category{ID: 2, UUID: uuid.New(), Name: "Household", LedgerID: ledgerID, OwnerID: testUserID}
+	for _, category := range []*database.Category{groceries, household} {
+		if err := db.CreateCategory(ctx, category); err != nil {
+			panic(err)
+		}
+	}
+	food := &database.Tag{ID: 1, UUID: uuid.New(), Name: "food", OwnerID: testUserID}
+	if err := db.CreateTag(ctx, food); err != nil {
+		panic(err)
+	}
+	store := &database.Payee{}
+	if err := db.SelectOrCreatePayee(ctx, testUserID, "Corner Store", store); err != nil {
+		panic(err)
+	}
+	if err := db.CreateRule(ctx, &database.Rule{
+		Name: "Supermarkets", MatchField: "payee", MatchType: "substring", Pattern: "store",
+		CategoryID: groceries.ID, TagIDs: []int64{food.ID}, OwnerID: testUserID,
+	}); err != nil {
+		panic(err)
+	}
+	for _, transaction := range []*database.Transaction{
+		{
+			Amount: -2500, CurrencyID: usd.ID, Currency: *usd, CategoryID: groceries.ID, PayeeID: store.ID,
+			Tags: []database.Tag{*food}, ExternalID: "ofx:1:1", LedgerID: ledgerID, OwnerID: testUserID, Timestamp: time.Date(2026, time.March, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Amount: -1500, CurrencyID: usd.ID, Currency: *usd, LedgerID: ledgerID, OwnerID: testUserID, Timestamp: time.Date(2026, time.March, 6, 0, 0, 0, 0, time.UTC),
+			Splits: []database.TransactionSplit{{CategoryID: groceries.ID, Amount: -1000}, {CategoryID: household.ID, Amount: -500, Note: "Soap"}},
+		},
+	} {
+		if err := db.CreateTransaction(ctx, transaction); err != nil {
+			panic(err)
+		}
+	}
+
+	// export the data of the first user:
+	archive := &userArchive{}
+	t.Run("export", func(t *testing.T) {
+		rec := testGetRequest(ctx, "/user/export", handler.UserExport)
+		if assert.Equal(t, http.StatusOK, rec.Code) && assert.NoError(t, json.NewDecoder(rec.Body).Decode(archive)) {
+			assert.Equal(t, userArchiveVersion, archive.Version)
+			assert.Equal(t, testUsername, archive.Username)
+			assert.Len(t, archive.Categories, 2)
+			assert.Len(t, archive.Tags, 1)
+			assert.Len(t, archive.Payees, 1)
+			if assert.Len(t, archive.Rules, 1) {
+				assert.Equal(t, []string{food.UUID.String()}, archive.Rules[0].TagUUIDs)
+				assert.Equal(t, groceries.UUID.String(), archive.Rules[0].CategoryUUID)
+			}
+			if assert.Len(t, archive.Transactions, 2) {
+				assert.Equal(t, "USD", archive.Transactions[0].CurrencyCode)
+				assert.Equal(t, store.UUID.String(), archive.Transactions[0].PayeeUUID)
+				assert.Len(t, archive.Transactions[1].Splits, 2)
+			}
+		}
+	})
+
+	t.Run("import into another account", func(t *testing.T) {
+		rec := testImportRequest(otherCtx, handler, "/user/import", archive)
+		if !assert.Equal(t, http.StatusOK, rec.Code) {
+			return
+		}
+		resp := userImportResponse{}
+		if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp)) {
+			assert.Equal(t, userImportCounts{Categories: 2, Tags: 1, Payees: 1, Rules: 1, Transactions: 2}, resp.Imported)
+			assert.Equal(t, userImportCounts{}, resp.Skipped)
+			// UUIDs of all objects except payees, which always get new UUIDs, are taken by the first user:
+			assert.Equal(t, 6, resp.Remapped)
+		}
+
+		// check that references are remapped to the objects of the other user:
+		transactions := make([]database.Transaction, 0)
+		if err := db.SelectTransactions(ctx, &database.TransactionFilter{LedgerID: testPersonalLedgerID(db, testOtherUserID)}, &transactions); err != nil {
+			panic(err)
+		}
+		if assert.Len(t, transactions, 2) {
+			category := &database.Category{}
+			for _, c := range db.categories {
+				if c.ID == transactions[0].CategoryID {
+					category = c
+				}
+			}
+			assert.Equal(t, testOtherUserID, category.OwnerID)
+			assert.Equal(t, "Groceries", category.Name)
+			assert.NotEqual(t, archive.Transactions[0].UUID, transactions[0].UUID.String())
+		}
+	})
+
+	t.Run("import into the same account", func(t *testing.T) {
+		rec := testImportRequest(ctx, handler, "/user/import", archive)
+		if !assert.Equal(t, http.StatusOK, rec.Code) {
+			return
+		}
+		resp := userImportResponse{}
+		if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp)) {
+			assert.Equal(t, userImportCounts{}, resp.Imported)
+			assert.Equal(t, userImportCounts{Categories: 2, Tags: 1, Payees: 1, Rules: 1, Transactions: 2}, resp.Skipped)
+		}
+	})
+
+	t.Run("import with conflicts not allowed", func(t *testing.T) {
+		rec := testImportRequest(ctx, handler, "/user/import?on_conflict=fail", archive)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("import archive of unsupported version", func(t *testing.T) {
+		rec := testImportRequest(ctx, handler, "/user/import", &userArchive{Version: userArchiveVersion + 1})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("import archive with unknown reference", func(t *testing.T) {
+		rec := testImportRequest(otherCtx, handler, "/user/import", &userArchive{
+			Version: userArchiveVersion,
+			Transactions: []userArchiveTransaction{
+				{UUID: uuid.NewString(), Amount: 100, CurrencyCode: "USD", CategoryUUID: uuid.NewString()},
+			},
+		})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
@@ -3,11 +3,12 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"github.com/groshi-project/groshi/internal/auth"
 	"github.com/groshi-project/groshi/internal/database"
-	"github.com/groshi-project/groshi/internal/middleware"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"testing"
+	"time"
 )
 
 func TestHandler_UserCreate(t *testing.T) {
@@ -61,6 +62,27 @@ func TestHandler_UserCreate(t *testing.T) {
 		assert.Equal(t, http.StatusConflict, rec.Code)
 	})
 
+	t.Run("create a user with username which is taken concurrently", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = context.Background()
+			mockDb  = handler.database.(*mockDatabase)
+		)
+		handler.database = &racingDatabase{mockDatabase: mockDb, race: func(m *mockDatabase) {
+			if err := m.CreateUser(ctx, &database.User{Username: testUsername}); err != nil {
+				panic(err)
+			}
+		}}
+
+		params := &userCreateParams{
+			Username: testUsername,
+			Password: testPassword,
+		}
+		rec := testRequest(ctx, params, handler.UserCreate)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Len(t, mockDb.users, 1)
+	})
+
 	t.Run("call the handler without params", func(t *testing.T) {
 		var (
 			handler = newTestHandler()
@@ -69,6 +91,75 @@ func TestHandler_UserCreate(t *testing.T) {
 		rec := testRequest(ctx, nil, handler.UserCreate)
 		assert.Equal(t, http.StatusBadRequest, rec.Code)
 	})
+
+	t.Run("create a user when registration is closed", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = context.Background()
+		)
+		handler.registrationMode = auth.RegistrationClosed
+
+		params := &userCreateParams{Username: testUsername, Password: testPassword}
+		rec := testRequest(ctx, params, handler.UserCreate)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, handler.database.(*mockDatabase).users)
+	})
+
+	t.Run("create a user with an invite code when registration is invite-only", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = context.Background()
+			mockDb  = handler.database.(*mockDatabase)
+		)
+		handler.registrationMode = auth.RegistrationInviteOnly
+		testLogin(ctx, handler)
+		code := testInviteCode(handler, 1, time.Now().Add(time.Hour))
+
+		// the code is required:
+		params := &userCreateParams{Username: testUsername, Password: testPassword}
+		rec := testRequest(ctx, params, handler.UserCreate)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		params.InviteCode = code
+		rec = testRequest(ctx, params, handler.UserCreate)
+		if assert.Equal(t, http.StatusOK, rec.Code) && assert.Len(t, mockDb.users, 2) {
+			assert.Equal(t, mockDb.users[0].ID, mockDb.users[1].InvitedByID)
+			assert.Equal(t, 1, mockDb.inviteCodes[0].Uses)
+		}
+
+		// the code is single-use:
+		params.Username = "another-test-user"
+		rec = testRequest(ctx, params, handler.UserCreate)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Len(t, mockDb.users, 2)
+	})
+
+	t.Run("create a user with an expired invite code", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = context.Background()
+		)
+		testLogin(ctx, handler)
+		code := testInviteCode(handler, 1, time.Now().Add(-time.Hour))
+
+		params := &userCreateParams{Username: testUsername, Password: testPassword, InviteCode: code}
+		rec := testRequest(ctx, params, handler.UserCreate)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Len(t, handler.database.(*mockDatabase).users, 1)
+	})
+
+	t.Run("create a user with an unknown invite code", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = context.Background()
+		)
+		handler.registrationMode = auth.RegistrationInviteOnly
+
+		params := &userCreateParams{Username: testUsername, Password: testPassword, InviteCode: "unknown-code"}
+		rec := testRequest(ctx, params, handler.UserCreate)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, handler.database.(*mockDatabase).users)
+	})
 }
 
 func TestHandler_UserGet(t *testing.T) {
@@ -90,7 +181,7 @@ func TestHandler_UserGet(t *testing.T) {
 			panic(err)
 		}
 
-		ctx = context.WithValue(ctx, middleware.UsernameContextKey, testUsername)
+		ctx = testUserContext(ctx, handler, testUsername)
 		rec := testRequest(ctx, nil, handler.UserGet)
 		if assert.Equal(t, http.StatusOK, rec.Code) {
 			resp := &userGetResponse{}
@@ -98,22 +189,15 @@ func TestHandler_UserGet(t *testing.T) {
 			if assert.NoError(t, err) {
 				if assert.NotEmpty(t, resp) {
 					assert.Equal(t, testUsername, resp.Username)
+					assert.NotEmpty(t, resp.UUID)
+					assert.Equal(t, database.RoleUser, resp.Role)
 				}
 			}
 		}
 
 	})
 
-	t.Run("get a non-existent user", func(t *testing.T) {
-		var (
-			handler = newTestHandler()
-			ctx     = context.WithValue(context.Background(), middleware.UsernameContextKey, "i-dont-exist")
-		)
-		rec := testRequest(ctx, nil, handler.UserGet)
-		assert.Equal(t, http.StatusNotFound, rec.Code)
-	})
-
-	t.Run("call the handler without username context value", func(t *testing.T) {
+	t.Run("call the handler without user context value", func(t *testing.T) {
 		var (
 			handler = newTestHandler()
 			ctx     = context.Background()
@@ -141,7 +225,7 @@ func TestHandler_UserDelete(t *testing.T) {
 			panic(err)
 		}
 
-		ctx = context.WithValue(ctx, middleware.UsernameContextKey, testUsername)
+		ctx = testUserContext(ctx, handler, testUsername)
 		rec := testRequest(ctx, nil, handler.UserDelete)
 		if assert.Equal(t, http.StatusOK, rec.Code) {
 			resp := &userDeleteResponse{}
@@ -159,16 +243,82 @@ func TestHandler_UserDelete(t *testing.T) {
 		}
 	})
 
-	t.Run("delete non-existent user", func(t *testing.T) {
+	t.Run("delete a user with ledgers", func(t *testing.T) {
 		var (
 			handler = newTestHandler()
-			ctx     = context.WithValue(context.Background(), middleware.UsernameContextKey, "i-dont-exist")
+			ctx     = context.Background()
+			mockDb  = handler.database.(*mockDatabase)
 		)
-		rec := testRequest(ctx, nil, handler.UserDelete)
-		assert.Equal(t, http.StatusNotFound, rec.Code)
+		for _, user := range []*database.User{{ID: 1, Username: testUsername}, {ID: 2, Username: "other"}} {
+			if err := mockDb.CreateUser(ctx, user); err != nil {
+				panic(err)
+			}
+		}
+		personalLedgerID := testPersonalLedgerID(mockDb, 1)
+		mockDb.categories = append(mockDb.categories, &database.Category{Name: "Food", LedgerID: personalLedgerID, OwnerID: 1})
+		shared := testSharedLedger(mockDb, 2, map[int64]string{1: database.LedgerRoleOwner})
+
+		rec := testRequest(testUserContext(ctx, handler, testUsername), nil, handler.UserDelete)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			assert.Empty(t, mockDb.categories)
+			if assert.Len(t, mockDb.ledgers, 1) {
+				assert.Equal(t, shared.ID, mockDb.ledgers[0].ID)
+			}
+			if assert.Len(t, mockDb.ledgerMembers, 1) {
+				assert.Equal(t, int64(2), mockDb.ledgerMembers[0].UserID)
+			}
+		}
+	})
+
+	t.Run("delete the only owner of a shared ledger", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = context.Background()
+			mockDb  = handler.database.(*mockDatabase)
+		)
+		for _, user := range []*database.User{{ID: 1, Username: testUsername}, {ID: 2, Username: "other"}} {
+			if err := mockDb.CreateUser(ctx, user); err != nil {
+				panic(err)
+			}
+		}
+		testSharedLedger(mockDb, 1, map[int64]string{2: database.LedgerRoleEditor})
+
+		rec := testRequest(testUserContext(ctx, handler, testUsername), nil, handler.UserDelete)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Len(t, mockDb.users, 2)
+		assert.Len(t, mockDb.ledgerMembers, 2)
 	})
 
-	t.Run("call the handler without username context value", func(t *testing.T) {
+	t.Run("delete a user who has not settled up", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = context.Background()
+			mockDb  = handler.database.(*mockDatabase)
+		)
+		for _, user := range []*database.User{{ID: 1, Username: testUsername}, {ID: 2, Username: "other"}} {
+			if err := mockDb.CreateUser(ctx, user); err != nil {
+				panic(err)
+			}
+		}
+		ledger := testSharedLedger(mockDb, 2, map[int64]string{1: database.LedgerRoleEditor})
+
+		// the other user paid for an expense shared with the test user:
+		mockDb.transactions = append(mockDb.transactions, &database.Transaction{
+			ID:         1,
+			LedgerID:   ledger.ID,
+			PayerID:    2,
+			CurrencyID: 1,
+			Amount:     -1000,
+			Shares:     []database.ExpenseShare{{UserID: 1, Amount: 500}, {UserID: 2, Amount: 500}},
+		})
+
+		rec := testRequest(testUserContext(ctx, handler, testUsername), nil, handler.UserDelete)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Len(t, mockDb.users, 2)
+		assert.Len(t, mockDb.ledgerMembers, 2)
+	})
+
+	t.Run("call the handler without user context value", func(t *testing.T) {
 		var (
 			handler = newTestHandler()
 			ctx     = context.Background()
@@ -177,3 +327,151 @@ func TestHandler_UserDelete(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 	})
 }
+
+func TestHandler_UserUpdate(t *testing.T) {
+	const (
+		testUsername      = "test-username"
+		testNewUsername   = "test-new-username"
+		testOtherUsername = "test-other-username"
+	)
+
+	t.Run("change username", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testUserContext(context.Background(), handler, testUsername)
+			mockDb  = handler.database.(*mockDatabase)
+		)
+
+		// create a test user:
+		if err := handler.database.CreateUser(ctx, &database.User{Username: testUsername}); err != nil {
+			panic(err)
+		}
+
+		rec := testRequest(ctx, &userUpdateParams{Username: testNewUsername}, handler.UserUpdate)
+		if assert.Equal(t, http.StatusOK, rec.Code) {
+			resp := &userUpdateResponse{}
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(resp)) {
+				assert.Equal(t, testNewUsername, resp.Username)
+				assert.Equal(t, mockDb.users[0].UUID.String(), resp.UUID)
+			}
+			assert.Equal(t, testNewUsername, mockDb.users[0].Username)
+		}
+	})
+
+	t.Run("change username to a taken one", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testUserContext(context.Background(), handler, testUsername)
+			mockDb  = handler.database.(*mockDatabase)
+		)
+
+		// create test users:
+		for _, username := range []string{testUsername, testOtherUsername} {
+			if err := handler.database.CreateUser(ctx, &database.User{Username: username}); err != nil {
+				panic(err)
+			}
+		}
+
+		rec := testRequest(ctx, &userUpdateParams{Username: testOtherUsername}, handler.UserUpdate)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, testUsername, mockDb.users[0].Username)
+	})
+
+	t.Run("call the handler without params", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testUserContext(context.Background(), handler, testUsername)
+		)
+
+		rec := testRequest(ctx, nil, handler.UserUpdate)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestHandler_UserPasswordUpdate(t *testing.T) {
+	t.Run("change password with correct current password", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testSessionContext(handler)
+			mockDb  = handler.database.(*mockDatabase)
+		)
+
+		params := &userPasswordUpdateParams{OldPassword: "test-password", NewPassword: "new-password"}
+		rec := testRequest(ctx, params, handler.UserPasswordUpdate)
+		if assert.Equal(t, http.StatusNoContent, rec.Code) {
+			user := mockDb.users[0]
+			assert.Equal(t, "hash(new-password)", user.Password)
+			assert.Equal(t, 1, user.TokenVersion)
+			for _, session := range mockDb.sessions {
+				assert.False(t, session.RevokedAt.IsZero())
+			}
+		}
+	})
+
+	t.Run("change password with wrong current password", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testSessionContext(handler)
+			mockDb  = handler.database.(*mockDatabase)
+		)
+
+		params := &userPasswordUpdateParams{OldPassword: "wrong-password", NewPassword: "new-password"}
+		rec := testRequest(ctx, params, handler.UserPasswordUpdate)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, "hash(test-password)", mockDb.users[0].Password)
+		assert.Zero(t, mockDb.users[0].TokenVersion)
+	})
+
+	t.Run("call the handler without params", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testSessionContext(handler)
+		)
+
+		rec := testRequest(ctx, nil, handler.UserPasswordUpdate)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestHandler_UserPasswordSet(t *testing.T) {
+	t.Run("set password of a user without password", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testSessionContext(handler)
+			mockDb  = handler.database.(*mockDatabase)
+		)
+		mockDb.users[0].Password = ""
+
+		rec := testRequest(ctx, &userPasswordSetParams{Password: "new-password"}, handler.UserPasswordSet)
+		if assert.Equal(t, http.StatusNoContent, rec.Code) {
+			user := mockDb.users[0]
+			assert.Equal(t, "hash(new-password)", user.Password)
+			assert.Zero(t, user.TokenVersion)
+			for _, session := range mockDb.sessions {
+				assert.True(t, session.RevokedAt.IsZero())
+			}
+		}
+	})
+
+	t.Run("set password of a user with password", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testSessionContext(handler)
+			mockDb  = handler.database.(*mockDatabase)
+		)
+
+		rec := testRequest(ctx, &userPasswordSetParams{Password: "new-password"}, handler.UserPasswordSet)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, "hash(test-password)", mockDb.users[0].Password)
+	})
+
+	t.Run("call the handler without params", func(t *testing.T) {
+		var (
+			handler = newTestHandler()
+			ctx     = testSessionContext(handler)
+		)
+
+		rec := testRequest(ctx, nil, handler.UserPasswordSet)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
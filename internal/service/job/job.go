@@ -1,10 +1,32 @@
 package job
 
 import (
+	"context"
 	"github.com/groshi-project/groshi/internal/database"
 	"log"
+	"sync"
+	"time"
 )
 
+// Status describes a job started by [Job.Start] and its last run.
+type Status struct {
+	Name     string
+	Interval time.Duration
+
+	// Running is true while the job is being run.
+	Running bool
+
+	// Count of finished runs and count of runs which failed.
+	Runs     int
+	Failures int
+
+	LastStartedAt  time.Time
+	LastFinishedAt time.Time
+
+	// Error returned by the last run, it is empty if the last run succeeded.
+	LastError string
+}
+
 // Job represents dependencies for the service jobs.
 type Job struct {
 	// database used to store and retrieve data.
@@ -12,11 +34,78 @@ type Job struct {
 
 	// errLogger used to log warnings and errors.
 	errLogger *log.Logger
+
+	// statuses of the started jobs in order they were started.
+	mu       sync.Mutex
+	statuses []*Status
 }
 
 // New creates a new instance of [Job] and returns pointer to it.
-func New(database database.Database) *Job {
-	return &Job{database: database}
+func New(database database.Database, errLogger *log.Logger) *Job {
+	return &Job{database: database, errLogger: errLogger}
+}
+
+// Start runs the job in a goroutine immediately and then repeatedly with the interval until ctx is done.
+// Errors returned by the job are logged and reported by [Job.Statuses].
+func (j *Job) Start(ctx context.Context, name string, interval time.Duration, job func() error) {
+	status := &Status{Name: name, Interval: interval}
+	j.mu.Lock()
+	j.statuses = append(j.statuses, status)
+	j.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			j.run(status, job)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// run runs the job once and updates its status.
+func (j *Job) run(status *Status, job func() error) {
+	j.mu.Lock()
+	status.Running = true
+	status.LastStartedAt = time.Now()
+	j.mu.Unlock()
+
+	err := job()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	status.Running = false
+	status.LastFinishedAt = time.Now()
+	status.Runs++
+	status.LastError = ""
+	if err != nil {
+		status.Failures++
+		status.LastError = err.Error()
+		if j.errLogger != nil {
+			j.errLogger.Printf("job %s failed: %s", status.Name, err)
+		}
+	}
+}
+
+// Statuses returns copies of statuses of the started jobs in order they were started.
+func (j *Job) Statuses() []Status {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	statuses := make([]Status, 0, len(j.statuses))
+	for _, status := range j.statuses {
+		statuses = append(statuses, *status)
+	}
+	return statuses
+}
+
+// PruneExpiredTokens deletes expired refresh tokens and records of revoked access tokens.
+func (j *Job) PruneExpiredTokens(ctx context.Context) error {
+	return j.database.DeleteExpiredTokens(ctx, time.Now())
 }
 
 // UpdateCurrencies updates currencies and their rates
@@ -0,0 +1,49 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// waitRuns waits until the job with the index has finished the given count of runs and returns its status.
+func waitRuns(t *testing.T, j *Job, index int, runs int) Status {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		statuses := j.Statuses()
+		if len(statuses) > index && statuses[index].Runs >= runs {
+			return statuses[index]
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("job %d has not finished %d runs", index, runs)
+	return Status{}
+}
+
+func TestJob_Start(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	j := New(nil, nil)
+
+	j.Start(ctx, "succeeding", time.Millisecond, func() error { return nil })
+	j.Start(ctx, "failing", time.Hour, func() error { return errors.New("test error") })
+
+	t.Run("run the job repeatedly", func(t *testing.T) {
+		status := waitRuns(t, j, 0, 3)
+		assert.Equal(t, "succeeding", status.Name)
+		assert.Equal(t, time.Millisecond, status.Interval)
+		assert.Zero(t, status.Failures)
+		assert.Empty(t, status.LastError)
+		assert.False(t, status.LastFinishedAt.IsZero())
+	})
+
+	t.Run("report errors of the job", func(t *testing.T) {
+		status := waitRuns(t, j, 1, 1)
+		assert.Equal(t, "failing", status.Name)
+		assert.Equal(t, 1, status.Runs)
+		assert.Equal(t, 1, status.Failures)
+		assert.Equal(t, "test error", status.LastError)
+	})
+}
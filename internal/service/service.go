@@ -1,11 +1,13 @@
 package service
 
 import (
+	"context"
 	"github.com/groshi-project/groshi/internal/auth"
 	"github.com/groshi-project/groshi/internal/database"
 	"github.com/groshi-project/groshi/internal/service/handler"
 	"github.com/groshi-project/groshi/internal/service/job"
 	"log"
+	"time"
 )
 
 // Service represents groshi service containing all its dependencies.
@@ -18,13 +20,35 @@ type Service struct {
 
 	// Jobs and their dependencies.
 	job *job.Job
+
+	// Login guard whose outdated counters are pruned by a job.
+	loginGuard *auth.LoginGuard
 }
 
+// loginFailuresPruneInterval is the interval between removals of outdated counters of failed login attempts.
+const loginFailuresPruneInterval = time.Hour
+
+// tokensPruneInterval is the interval between removals of expired refresh tokens and records of revoked access tokens.
+const tokensPruneInterval = time.Hour
+
 // New creates a new instance of [Service] and returns pointer to it.
-func New(database database.Database, jwtAuthenticator auth.JWTAuthenticator, passwordAuthenticator auth.PasswordAuthenticator, internalServerErrorLogger *log.Logger, swagger bool) *Service {
+func New(database database.Database, jwtAuthenticator auth.JWTAuthenticator, passwordAuthenticator auth.PasswordAuthenticator, loginGuard *auth.LoginGuard, oidc *auth.OIDCProvider, registrationMode string, refreshTokenTTL time.Duration, internalServerErrorLogger *log.Logger, swagger bool) *Service {
+	jobs := job.New(database, internalServerErrorLogger)
 	return &Service{
-		Handler:       handler.New(database, jwtAuthenticator, passwordAuthenticator, internalServerErrorLogger),
+		Handler:       handler.New(database, jwtAuthenticator, passwordAuthenticator, loginGuard, oidc, jobs, registrationMode, refreshTokenTTL, internalServerErrorLogger),
 		SwaggerEnable: swagger,
-		job:           job.New(database),
+		job:           jobs,
+		loginGuard:    loginGuard,
 	}
 }
+
+// StartJobs starts the service jobs, they are run repeatedly until ctx is done.
+// [job.Job.UpdateCurrencies] is not started as it is not implemented yet.
+func (s *Service) StartJobs(ctx context.Context) {
+	s.job.Start(ctx, "prune-login-failures", loginFailuresPruneInterval, func() error {
+		return s.loginGuard.Prune(ctx)
+	})
+	s.job.Start(ctx, "prune-expired-tokens", tokensPruneInterval, func() error {
+		return s.job.PruneExpiredTokens(ctx)
+	})
+}
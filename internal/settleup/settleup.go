@@ -0,0 +1,318 @@
+// Package settleup implements splitting of expenses between people and computation of settle-up plans.
+// Amounts are integers in minor units of a single currency.
+package settleup
+
+import (
+	"errors"
+	"math/bits"
+	"sort"
+)
+
+// Methods of splitting an expense between participants.
+const (
+	// MethodEqual splits the expense into equal parts.
+	MethodEqual = "equal"
+
+	// MethodShares splits the expense proportionally to numbers of shares of participants.
+	MethodShares = "shares"
+
+	// MethodExact assigns exact amounts to participants, they must sum up to the expense amount.
+	MethodExact = "exact"
+)
+
+var (
+	// ErrInvalidMethod is returned when splitting method is unknown.
+	ErrInvalidMethod = errors.New("invalid splitting method")
+
+	// ErrInvalidAmount is returned when the expense amount is not positive.
+	ErrInvalidAmount = errors.New("expense amount must be positive")
+
+	// ErrNoParticipants is returned when the expense has no participants.
+	ErrNoParticipants = errors.New("expense has no participants")
+
+	// ErrInvalidShares is returned when some numbers of shares or exact amounts are negative, or all shares are zero.
+	ErrInvalidShares = errors.New("invalid shares")
+
+	// ErrAmountMismatch is returned when exact amounts do not sum up to the expense amount.
+	ErrAmountMismatch = errors.New("exact amounts do not sum up to the expense amount")
+)
+
+// Split splits the positive amount between participants using the method and returns amounts of participants
+// in the same order. Weights contain numbers of shares for [MethodShares] and exact amounts for [MethodExact],
+// one per participant, and are only used to count participants for [MethodEqual].
+// Minor units which can not be divided evenly are given one by one to participants with the largest remainders,
+// the first participants win ties.
+func Split(amount int64, method string, weights []int64) ([]int64, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if len(weights) == 0 {
+		return nil, ErrNoParticipants
+	}
+	for _, weight := range weights {
+		if weight < 0 {
+			return nil, ErrInvalidShares
+		}
+	}
+
+	switch method {
+	case MethodEqual:
+		equal := make([]int64, len(weights))
+		for i := range equal {
+			equal[i] = 1
+		}
+		return splitProportionally(amount, equal), nil
+
+	case MethodShares:
+		var total int64
+		for _, weight := range weights {
+			total += weight
+		}
+		if total == 0 {
+			return nil, ErrInvalidShares
+		}
+		return splitProportionally(amount, weights), nil
+
+	case MethodExact:
+		var total int64
+		for _, weight := range weights {
+			total += weight
+		}
+		if total != amount {
+			return nil, ErrAmountMismatch
+		}
+		return append([]int64(nil), weights...), nil
+
+	default:
+		return nil, ErrInvalidMethod
+	}
+}
+
+// splitProportionally splits amount proportionally to the weights using the largest remainder method.
+// Weights must be non-negative and not all zero.
+func splitProportionally(amount int64, weights []int64) []int64 {
+	var total int64
+	for _, weight := range weights {
+		total += weight
+	}
+
+	parts := make([]int64, len(weights))
+	remainders := make([]int64, len(weights))
+	rest := amount
+	for i, weight := range weights {
+		parts[i] = amount * weight / total
+		remainders[i] = amount * weight % total
+		rest -= parts[i]
+	}
+
+	// give the rest to participants with the largest remainders:
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+	for i := int64(0); i < rest; i++ {
+		parts[order[i]]++
+	}
+
+	return parts
+}
+
+// Debt represents amount the debtor owes to the creditor.
+type Debt struct {
+	DebtorID   int64
+	CreditorID int64
+	Amount     int64
+}
+
+// Net nets out debts between each pair of people, so that at most one of them owes the other.
+// Debts of people to themselves and pairs which owe each other nothing are dropped.
+// The result is ordered by debtor and creditor IDs.
+func Net(debts []Debt) []Debt {
+	type pair struct{ a, b int64 }
+
+	// balance of each pair is positive if a owes b and negative otherwise:
+	balances := make(map[pair]int64)
+	for _, debt := range debts {
+		switch {
+		case debt.DebtorID < debt.CreditorID:
+			balances[pair{debt.DebtorID, debt.CreditorID}] += debt.Amount
+		case debt.DebtorID > debt.CreditorID:
+			balances[pair{debt.CreditorID, debt.DebtorID}] -= debt.Amount
+		}
+	}
+
+	netted := make([]Debt, 0, len(balances))
+	for p, balance := range balances {
+		switch {
+		case balance > 0:
+			netted = append(netted, Debt{DebtorID: p.a, CreditorID: p.b, Amount: balance})
+		case balance < 0:
+			netted = append(netted, Debt{DebtorID: p.b, CreditorID: p.a, Amount: -balance})
+		}
+	}
+	sortDebts(netted)
+	return netted
+}
+
+// Balances returns net balance of each person involved in the debts: positive if the person is owed money
+// and negative if the person owes money. Balances of all people sum up to zero.
+func Balances(debts []Debt) map[int64]int64 {
+	balances := make(map[int64]int64)
+	for _, debt := range debts {
+		balances[debt.DebtorID] -= debt.Amount
+		balances[debt.CreditorID] += debt.Amount
+	}
+	return balances
+}
+
+// maxExactPlanPeople is the largest number of people with non-zero balances, for which [Plan] searches for
+// the plan with the fewest payments.
+const maxExactPlanPeople = 15
+
+// balance is the net balance of a person: positive if the person is owed money and negative otherwise.
+type balance struct {
+	id     int64
+	amount int64
+}
+
+// Plan computes a settle-up plan with as few payments as possible: payments after which nobody owes anyone anything.
+// People with non-zero balances are partitioned into the largest number of groups whose balances sum up to zero,
+// and each group of n people settles up with n-1 payments, which is the fewest payments possible.
+// The search takes exponential time, so when more than 15 people have non-zero balances, people whose balances
+// cancel each other out exactly are paired and then the largest debtor repeatedly pays the largest creditor,
+// which still takes at most one payment fewer than there are people with non-zero balances.
+// The result is ordered by debtor and creditor IDs.
+func Plan(debts []Debt) []Debt {
+	var balances []balance
+	for id, amount := range Balances(debts) {
+		if amount != 0 {
+			balances = append(balances, balance{id, amount})
+		}
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		return balances[i].id < balances[j].id
+	})
+
+	payments := make([]Debt, 0)
+	if len(balances) > maxExactPlanPeople {
+		payments = append(payments, planGreedy(balances)...)
+	} else {
+		for _, group := range zeroSumGroups(balances) {
+			payments = append(payments, planGreedy(group)...)
+		}
+	}
+
+	sortDebts(payments)
+	return payments
+}
+
+// zeroSumGroups partitions balances into the largest number of groups whose balances sum up to zero.
+// Balances must sum up to zero and there must be few of them, as all their subsets are enumerated.
+func zeroSumGroups(balances []balance) [][]balance {
+	full := 1<<len(balances) - 1
+
+	// sums[mask] is the sum of balances in the subset mask, and counts[mask] is the largest number of zero-sum
+	// groups the subset can be partitioned into, not counting its rest with a non-zero sum:
+	sums := make([]int64, full+1)
+	counts := make([]int, full+1)
+	for mask := 1; mask <= full; mask++ {
+		sums[mask] = sums[mask&(mask-1)] + balances[bits.TrailingZeros(uint(mask))].amount
+		for i := range balances {
+			if mask&(1<<i) != 0 && counts[mask^1<<i] > counts[mask] {
+				counts[mask] = counts[mask^1<<i]
+			}
+		}
+		if sums[mask] == 0 {
+			counts[mask]++
+		}
+	}
+
+	// remove people one by one following the best counts, a group ends each time the rest sums up to zero:
+	groups := make([][]balance, 0, counts[full])
+	var group []balance
+	for mask := full; mask != 0; {
+		count := counts[mask]
+		if sums[mask] == 0 {
+			count--
+		}
+		for i := range balances {
+			if mask&(1<<i) != 0 && counts[mask^1<<i] == count {
+				group = append(group, balances[i])
+				mask ^= 1 << i
+				break
+			}
+		}
+		if sums[mask] == 0 {
+			groups = append(groups, group)
+			group = nil
+		}
+	}
+	return groups
+}
+
+// planGreedy computes a settle-up plan for balances which sum up to zero: people whose balances cancel each other
+// out exactly are paired first, then the largest debtor repeatedly pays the largest creditor.
+// The plan has at most one payment fewer than there are people with non-zero balances.
+func planGreedy(balances []balance) []Debt {
+	var debtors, creditors []balance
+	for _, b := range balances {
+		switch {
+		case b.amount < 0:
+			debtors = append(debtors, balance{b.id, -b.amount})
+		case b.amount > 0:
+			creditors = append(creditors, b)
+		}
+	}
+	byAmount := func(people []balance) func(i, j int) bool {
+		return func(i, j int) bool {
+			if people[i].amount != people[j].amount {
+				return people[i].amount > people[j].amount
+			}
+			return people[i].id < people[j].id
+		}
+	}
+	sort.Slice(debtors, byAmount(debtors))
+	sort.Slice(creditors, byAmount(creditors))
+
+	payments := make([]Debt, 0)
+
+	// pair debtors and creditors with equal amounts:
+	for i := range debtors {
+		for j := range creditors {
+			if creditors[j].amount != 0 && creditors[j].amount == debtors[i].amount {
+				payments = append(payments, Debt{DebtorID: debtors[i].id, CreditorID: creditors[j].id, Amount: debtors[i].amount})
+				debtors[i].amount, creditors[j].amount = 0, 0
+				break
+			}
+		}
+	}
+
+	// the largest remaining debtor pays the largest remaining creditor:
+	for {
+		sort.Slice(debtors, byAmount(debtors))
+		sort.Slice(creditors, byAmount(creditors))
+		if len(debtors) == 0 || len(creditors) == 0 || debtors[0].amount == 0 || creditors[0].amount == 0 {
+			break
+		}
+
+		amount := min(debtors[0].amount, creditors[0].amount)
+		payments = append(payments, Debt{DebtorID: debtors[0].id, CreditorID: creditors[0].id, Amount: amount})
+		debtors[0].amount -= amount
+		creditors[0].amount -= amount
+	}
+
+	return payments
+}
+
+// sortDebts sorts debts by debtor and creditor IDs.
+func sortDebts(debts []Debt) {
+	sort.Slice(debts, func(i, j int) bool {
+		if debts[i].DebtorID != debts[j].DebtorID {
+			return debts[i].DebtorID < debts[j].DebtorID
+		}
+		return debts[i].CreditorID < debts[j].CreditorID
+	})
+}
@@ -0,0 +1,133 @@
+package settleup
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	testCases := []struct {
+		name    string
+		amount  int64
+		method  string
+		weights []int64
+		parts   []int64
+		err     error
+	}{
+		{"equal parts", 900, MethodEqual, []int64{0, 0, 0}, []int64{300, 300, 300}, nil},
+		{"equal parts with remainder", 1000, MethodEqual, []int64{0, 0, 0}, []int64{334, 333, 333}, nil},
+		{"shares", 1000, MethodShares, []int64{2, 1, 1}, []int64{500, 250, 250}, nil},
+		{"shares with remainder", 1000, MethodShares, []int64{1, 2}, []int64{333, 667}, nil},
+		{"shares with zero share", 1000, MethodShares, []int64{1, 0}, []int64{1000, 0}, nil},
+		{"only zero shares", 1000, MethodShares, []int64{0, 0}, nil, ErrInvalidShares},
+		{"negative shares", 1000, MethodShares, []int64{2, -1}, nil, ErrInvalidShares},
+		{"exact amounts", 1000, MethodExact, []int64{700, 300}, []int64{700, 300}, nil},
+		{"exact amounts mismatch", 1000, MethodExact, []int64{700, 200}, nil, ErrAmountMismatch},
+		{"no participants", 1000, MethodEqual, nil, nil, ErrNoParticipants},
+		{"non-positive amount", 0, MethodEqual, []int64{0}, nil, ErrInvalidAmount},
+		{"unknown method", 1000, "percent", []int64{50, 50}, nil, ErrInvalidMethod},
+	}
+
+	for _, testCase := range testCases {
+		parts, err := Split(testCase.amount, testCase.method, testCase.weights)
+		if testCase.err != nil {
+			assert.ErrorIs(t, err, testCase.err, testCase.name)
+			continue
+		}
+		if assert.NoError(t, err, testCase.name) {
+			assert.Equal(t, testCase.parts, parts, testCase.name)
+		}
+	}
+}
+
+func TestNet(t *testing.T) {
+	debts := []Debt{
+		{DebtorID: 2, CreditorID: 1, Amount: 500},
+		{DebtorID: 1, CreditorID: 2, Amount: 200},
+		{DebtorID: 3, CreditorID: 1, Amount: 300},
+		{DebtorID: 1, CreditorID: 3, Amount: 300},
+		{DebtorID: 1, CreditorID: 1, Amount: 100},
+		{DebtorID: 3, CreditorID: 2, Amount: 50},
+	}
+	assert.Equal(t, []Debt{
+		{DebtorID: 2, CreditorID: 1, Amount: 300},
+		{DebtorID: 3, CreditorID: 2, Amount: 50},
+	}, Net(debts))
+}
+
+func TestPlan(t *testing.T) {
+	t.Run("chain of debts", func(t *testing.T) {
+		// 3 owes 2 and 2 owes 1 the same amount, so 3 can pay 1 directly:
+		debts := []Debt{
+			{DebtorID: 3, CreditorID: 2, Amount: 1000},
+			{DebtorID: 2, CreditorID: 1, Amount: 1000},
+		}
+		assert.Equal(t, []Debt{{DebtorID: 3, CreditorID: 1, Amount: 1000}}, Plan(debts))
+	})
+
+	t.Run("trip with friends", func(t *testing.T) {
+		// 1 paid 900 for 1, 2 and 3; 2 paid 300 for 2 and 4; 4 paid 100 for 1:
+		debts := []Debt{
+			{DebtorID: 2, CreditorID: 1, Amount: 300},
+			{DebtorID: 3, CreditorID: 1, Amount: 300},
+			{DebtorID: 4, CreditorID: 2, Amount: 150},
+			{DebtorID: 1, CreditorID: 4, Amount: 100},
+		}
+		plan := Plan(debts)
+
+		// the plan settles all balances:
+		assert.Empty(t, nonZero(Balances(append(plan, reversed(debts)...))))
+		assert.LessOrEqual(t, len(plan), 3)
+	})
+
+	t.Run("fewer payments than greedy", func(t *testing.T) {
+		// balances are 500 for 1 and 800 for 6, and -300, -200, -400 and -400 for 2, 3, 4 and 5:
+		debts := []Debt{
+			{DebtorID: 2, CreditorID: 1, Amount: 300},
+			{DebtorID: 3, CreditorID: 6, Amount: 200},
+			{DebtorID: 4, CreditorID: 6, Amount: 400},
+			{DebtorID: 5, CreditorID: 1, Amount: 400},
+			{DebtorID: 1, CreditorID: 6, Amount: 200},
+		}
+
+		// the largest debtor paying the largest creditor takes 5 payments:
+		balances := []balance{{1, 500}, {2, -300}, {3, -200}, {4, -400}, {5, -400}, {6, 800}}
+		assert.Len(t, planGreedy(balances), 5)
+
+		// while 1, 2 and 3 settle up separately from 4, 5 and 6 with 4 payments:
+		assert.Equal(t, []Debt{
+			{DebtorID: 2, CreditorID: 1, Amount: 300},
+			{DebtorID: 3, CreditorID: 1, Amount: 200},
+			{DebtorID: 4, CreditorID: 6, Amount: 400},
+			{DebtorID: 5, CreditorID: 6, Amount: 400},
+		}, Plan(debts))
+	})
+
+	t.Run("nothing to settle", func(t *testing.T) {
+		debts := []Debt{
+			{DebtorID: 1, CreditorID: 2, Amount: 100},
+			{DebtorID: 2, CreditorID: 1, Amount: 100},
+		}
+		assert.Empty(t, Plan(debts))
+	})
+}
+
+// reversed returns debts with swapped debtors and creditors, which cancel out the given ones.
+func reversed(debts []Debt) []Debt {
+	result := make([]Debt, 0, len(debts))
+	for _, debt := range debts {
+		result = append(result, Debt{DebtorID: debt.CreditorID, CreditorID: debt.DebtorID, Amount: debt.Amount})
+	}
+	return result
+}
+
+// nonZero returns balances without zero ones.
+func nonZero(balances map[int64]int64) map[int64]int64 {
+	result := make(map[int64]int64)
+	for id, balance := range balances {
+		if balance != 0 {
+			result[id] = balance
+		}
+	}
+	return result
+}
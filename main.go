@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/go-chi/chi/v5"
@@ -15,6 +16,7 @@ import (
 	httpSwagger "github.com/swaggo/http-swagger"
 	"log"
 	"net/http"
+	"net/netip"
 	"os"
 	"strings"
 	"time"
@@ -39,14 +41,47 @@ type Options struct {
 	} `group:"Development"`
 
 	Service struct {
-		BcryptCost int `long:"bcrypt-cost" env:"GROSHI_BCRYPT_COST" default:"10" description:"todo"`
+		PasswordHashing string `long:"password-hashing" env:"GROSHI_PASSWORD_HASHING" choice:"argon2id" choice:"bcrypt" default:"argon2id" description:"algorithm used to hash passwords, hashes made by another algorithm or with other parameters are replaced on login"`
+
+		BcryptCost int `long:"bcrypt-cost" env:"GROSHI_BCRYPT_COST" default:"10" description:"bcrypt cost"`
+
+		Argon2Memory      uint32 `long:"argon2-memory" env:"GROSHI_ARGON2_MEMORY" default:"65536" description:"memory used by Argon2id in KiB"`
+		Argon2Time        uint32 `long:"argon2-time" env:"GROSHI_ARGON2_TIME" default:"3" description:"count of Argon2id passes over the memory"`
+		Argon2Parallelism uint8  `long:"argon2-parallelism" env:"GROSHI_ARGON2_PARALLELISM" default:"2" description:"count of Argon2id threads"`
 
 		JWTSecretKey     string `long:"jwt-secret-key" env:"GROSHI_JWT_SECRET_KEY" description:"a secret key which will be used to generate JSON Web Tokens"`
 		JWTSecretKeyFile string `long:"jwt-secret-key-file" env:"GROSHI_JWT_SECRET_KEY_FILE" description:"file containing a secret key which will be used to generate JSON web tokens"`
 
-		JWTTimeToLive time.Duration `long:"jwt-ttl" env:"GROSHI_JWT_TTL" description:"jwt time-to-live" default:"744h"`
+		JWTPrivateKeyFile       string   `long:"jwt-private-key-file" env:"GROSHI_JWT_PRIVATE_KEY_FILE" description:"PEM file containing a private RSA or Ed25519 key which will be used to sign JSON Web Tokens instead of the secret key"`
+		JWTVerificationKeyFiles []string `long:"jwt-verification-key-file" env:"GROSHI_JWT_VERIFICATION_KEY_FILES" env-delim:"," description:"PEM file containing a public or private key tokens signed with which are still accepted, for example, the previous private key during rotation (can be provided multiple times)"`
+
+		JWTTimeToLive time.Duration `long:"jwt-ttl" env:"GROSHI_JWT_TTL" description:"jwt time-to-live" default:"15m"`
+
+		RefreshTokenTimeToLive time.Duration `long:"refresh-token-ttl" env:"GROSHI_REFRESH_TOKEN_TTL" description:"refresh token time-to-live" default:"720h"`
+
+		LoginGuardStore         string        `long:"login-guard-store" env:"GROSHI_LOGIN_GUARD_STORE" choice:"memory" choice:"database" default:"memory" description:"where counters of failed login attempts are stored, database must be used to share them between several replicas"`
+		LoginLockoutThreshold   int           `long:"login-lockout-threshold" env:"GROSHI_LOGIN_LOCKOUT_THRESHOLD" default:"10" description:"count of failed login attempts of a user after which login is locked"`
+		LoginIPLockoutThreshold int           `long:"login-ip-lockout-threshold" env:"GROSHI_LOGIN_IP_LOCKOUT_THRESHOLD" default:"50" description:"count of failed login attempts from an IP address after which login from it is locked"`
+		LoginLockoutDuration    time.Duration `long:"login-lockout-duration" env:"GROSHI_LOGIN_LOCKOUT_DURATION" default:"15m" description:"duration of a login lockout"`
+
+		TrustedProxies []string `long:"trusted-proxy" env:"GROSHI_TRUSTED_PROXIES" env-delim:"," description:"IP address or CIDR prefix of a reverse proxy, client addresses are taken from X-Forwarded-For and X-Real-IP headers only for requests coming from trusted proxies (can be provided multiple times)"`
+
+		RegistrationMode string `long:"registration-mode" env:"GROSHI_REGISTRATION_MODE" choice:"open" choice:"invite-only" choice:"closed" default:"open" description:"who can create a new user: anyone, only people with an invite code or nobody (does not affect users created on the first OIDC login and by the admin create command)"`
 	} `group:"Service options"`
 
+	OIDC struct {
+		Issuer string `long:"oidc-issuer" env:"GROSHI_OIDC_ISSUER" description:"URL of an OpenID Connect identity provider users can log in with, OIDC login is disabled if it is not provided"`
+
+		ClientID         string `long:"oidc-client-id" env:"GROSHI_OIDC_CLIENT_ID" description:"client ID of groshi registered at the identity provider"`
+		ClientSecret     string `long:"oidc-client-secret" env:"GROSHI_OIDC_CLIENT_SECRET" description:"client secret of groshi registered at the identity provider"`
+		ClientSecretFile string `long:"oidc-client-secret-file" env:"GROSHI_OIDC_CLIENT_SECRET_FILE" description:"file containing client secret of groshi registered at the identity provider"`
+
+		RedirectURL string   `long:"oidc-redirect-url" env:"GROSHI_OIDC_REDIRECT_URL" description:"URL of the client application the identity provider redirects to after login"`
+		Scopes      []string `long:"oidc-scope" env:"GROSHI_OIDC_SCOPES" env-delim:"," default:"profile" default:"email" description:"scope requested in addition to openid (can be provided multiple times)"`
+
+		CreateUsers bool `long:"oidc-create-users" env:"GROSHI_OIDC_CREATE_USERS" description:"create a new user on the first OIDC login of an identity which is not linked to any user"`
+	} `group:"OpenID Connect options"`
+
 	Postgres struct {
 		Host string `long:"postgres-host" env:"GROSHI_POSTGRES_HOST" required:"true" description:"host on which postgres is listening for groshi's connection"`
 		Port int    `long:"postgres-port" env:"GROSHI_POSTGRES_PORT" default:"5432" description:"host on which postgres is listening for groshi's connection"`
@@ -60,8 +95,51 @@ type Options struct {
 		Database     string `long:"postgres-database" env:"GROSHI_POSTGRES_DATABASE" description:"todo"`
 		DatabaseFile string `long:"postgres-database-file" env:"GROSHI_POSTGRES_DATABASE_FILE" description:"todo"`
 	} `group:"PostgreSQL options"`
+
+	Backup struct {
+		Output string `short:"o" long:"output" description:"file to write the backup to, standard output is used if not provided"`
+	} `command:"backup" description:"write a compressed logical backup of the database"`
+
+	Restore struct {
+		Clean bool `long:"clean" description:"delete all existing data before restoring"`
+
+		Args struct {
+			Input string `positional-arg-name:"file" description:"backup file, standard input is used if not provided"`
+		} `positional-args:"yes"`
+	} `command:"restore" description:"restore the database from a backup made by the backup command"`
+
+	ResetPassword struct {
+		Args struct {
+			Username string `positional-arg-name:"username" required:"yes" description:"username of the user"`
+		} `positional-args:"yes" required:"yes"`
+	} `command:"reset-password" description:"set a new random password of a user, revoke all its sessions and print the password"`
+
+	Unlock struct {
+		Args struct {
+			Username string `positional-arg-name:"username" required:"yes" description:"username of the user"`
+		} `positional-args:"yes" required:"yes"`
+	} `command:"unlock" description:"reset failed login attempts of a user, so that the user can log in immediately (requires database login guard store)"`
+
+	Admin struct {
+		Create struct {
+			Args struct {
+				Username string `positional-arg-name:"username" required:"yes" description:"username of the admin"`
+			} `positional-args:"yes" required:"yes"`
+		} `command:"create" description:"create a new admin with a random password and print the password, or make an existing user an admin"`
+	} `command:"admin" description:"manage admins"`
 }
 
+// Commands which can be run by groshi. The server is started if no command is provided.
+const (
+	commandServe   = ""
+	commandBackup  = "backup"
+	commandRestore = "restore"
+
+	commandResetPassword = "reset-password"
+	commandUnlock        = "unlock"
+	commandAdminCreate   = "admin create"
+)
+
 // parseOptionsPair parses option pair. Option pair means option and its "file" pair.
 // For example, `--postgres-password` and `--postgres-password-file`.
 func parseOptionsPair(cliFlag string, envVar string, value *string, valueFile string) error {
@@ -85,11 +163,12 @@ func parseOptionsPair(cliFlag string, envVar string, value *string, valueFile st
 	return nil
 }
 
-// getOptions parses options from CLI and environmental variables.
+// getOptions parses options from CLI and environmental variables and returns them with the name of the command to run.
 // Prints error message and terminates program with code 1 on error.
-func getOptions() *Options {
+func getOptions() (*Options, string) {
 	var options Options
 	parser := flags.NewParser(&options, flags.Default)
+	parser.SubcommandsOptional = true
 
 	// parse options using parser:
 	if _, err := parser.Parse(); err != nil {
@@ -101,10 +180,35 @@ func getOptions() *Options {
 		}
 	}
 
+	// name of a nested command consists of names of all its parents, for example, "admin create":
+	names := make([]string, 0)
+	for active := parser.Active; active != nil; active = active.Active {
+		names = append(names, active.Name)
+	}
+	command := strings.Join(names, " ")
+
 	// additionally parse options from paired options:
 	parsingErrors := make([]error, 0)
-	if err := parseOptionsPair("--jwt-secret-key", "GROSHI_JWT_SECRET_KEY", &options.Service.JWTSecretKey, options.Service.JWTSecretKeyFile); err != nil {
-		parsingErrors = append(parsingErrors, err)
+	if command == commandServe {
+		if options.Service.JWTPrivateKeyFile == "" {
+			if err := parseOptionsPair("--jwt-secret-key", "GROSHI_JWT_SECRET_KEY", &options.Service.JWTSecretKey, options.Service.JWTSecretKeyFile); err != nil {
+				parsingErrors = append(parsingErrors, err)
+			}
+			if len(options.Service.JWTVerificationKeyFiles) != 0 {
+				parsingErrors = append(parsingErrors, errors.New("`--jwt-verification-key-file` ($GROSHI_JWT_VERIFICATION_KEY_FILES) requires `--jwt-private-key-file` ($GROSHI_JWT_PRIVATE_KEY_FILE)"))
+			}
+		} else if options.Service.JWTSecretKey != "" || options.Service.JWTSecretKeyFile != "" {
+			parsingErrors = append(parsingErrors, errors.New("both `--jwt-secret-key` ($GROSHI_JWT_SECRET_KEY) and `--jwt-private-key-file` ($GROSHI_JWT_PRIVATE_KEY_FILE) are provided, expected only one of them"))
+		}
+
+		if options.OIDC.Issuer != "" {
+			if err := parseOptionsPair("--oidc-client-secret", "GROSHI_OIDC_CLIENT_SECRET", &options.OIDC.ClientSecret, options.OIDC.ClientSecretFile); err != nil {
+				parsingErrors = append(parsingErrors, err)
+			}
+			if options.OIDC.ClientID == "" || options.OIDC.RedirectURL == "" {
+				parsingErrors = append(parsingErrors, errors.New("`--oidc-client-id` ($GROSHI_OIDC_CLIENT_ID) and `--oidc-redirect-url` ($GROSHI_OIDC_REDIRECT_URL) are required when `--oidc-issuer` ($GROSHI_OIDC_ISSUER) is provided"))
+			}
+		}
 	}
 
 	if err := parseOptionsPair("--postgres-user", "GROSHI_POSTGRES_USER", &options.Postgres.User, options.Postgres.UserFile); err != nil {
@@ -128,7 +232,7 @@ func getOptions() *Options {
 		os.Exit(1)
 	}
 
-	return &options
+	return &options, command
 }
 
 // newMux creates and configures a new HTTP router for groshi service
@@ -144,27 +248,41 @@ func getOptions() *Options {
 //	@in							header
 //	@name						Authorization
 //	@description				Type "Bearer" followed by a space and JWT token.
-func newMux(groshi *service.Service) *chi.Mux {
+func newMux(groshi *service.Service, db database.Database, trustedProxies []netip.Prefix) *chi.Mux {
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(serviceMiddleware.NewRealIP(trustedProxies))
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(time.Duration(30) * time.Second))
 
-	jwtMiddleware := serviceMiddleware.NewJWT(groshi.Handler.JWTAuth)
+	timeout := middleware.Timeout(time.Duration(30) * time.Second)
+	jwtMiddleware := serviceMiddleware.NewJWT(groshi.Handler.JWTAuth, db)
+
+	// exports are streamed and may take long for large histories, so they are not limited by the timeout:
+	r.Group(func(r chi.Router) {
+		r.Use(jwtMiddleware)
+		r.With(serviceMiddleware.RequireFullAccess).Get("/user/export", groshi.Handler.UserExport)
+		r.With(serviceMiddleware.RequireScope(auth.ScopeTransactionsRead)).Get("/export", groshi.Handler.Export)
+	})
 
 	// public routes:
 	r.Group(func(r chi.Router) {
+		r.Use(timeout)
 		if groshi.SwaggerEnable {
 			r.Route("/swagger", func(r chi.Router) {
 				r.Get("/*", httpSwagger.Handler())
 			})
 		}
 
+		r.Get("/.well-known/jwks.json", groshi.Handler.AuthJWKS)
+
 		r.Route("/auth", func(r chi.Router) {
 			r.Post("/login", groshi.Handler.AuthLogin)
+			r.Post("/refresh", groshi.Handler.AuthRefresh)
+			r.Post("/2fa", groshi.Handler.AuthSecondFactor)
+			r.Post("/oidc/login", groshi.Handler.AuthOIDCLogin)
+			r.Post("/oidc/callback", groshi.Handler.AuthOIDCCallback)
 		})
 
 	})
@@ -172,59 +290,129 @@ func newMux(groshi *service.Service) *chi.Mux {
 	// `/user` route which is partly public and partly protected:
 	r.Route("/user", func(r chi.Router) {
 		// public `/user` route:
-		r.Post("/", groshi.Handler.UserCreate)
+		r.With(timeout).Post("/", groshi.Handler.UserCreate)
 
-		// protected `/user` routes:
+		// protected `/user` routes, which are not available for API keys:
 		r.Group(func(r chi.Router) {
+			r.Use(timeout)
 			r.Use(jwtMiddleware)
+			r.Use(serviceMiddleware.RequireFullAccess)
 			r.Get("/", groshi.Handler.UserGet)
 			r.Put("/", groshi.Handler.UserUpdate)
 			r.Delete("/", groshi.Handler.UserDelete)
+			r.Put("/password", groshi.Handler.UserPasswordUpdate)
+			r.Post("/password", groshi.Handler.UserPasswordSet)
+			r.Post("/import", groshi.Handler.UserImport)
+			r.Get("/sessions", groshi.Handler.SessionsGet)
+			r.Delete("/sessions", groshi.Handler.SessionsDelete)
+			r.Delete("/sessions/{uuid}", groshi.Handler.SessionsDeleteOne)
+			r.Post("/2fa/enroll", groshi.Handler.TwoFactorEnroll)
+			r.Post("/2fa/verify", groshi.Handler.TwoFactorVerify)
+			r.Delete("/2fa", groshi.Handler.TwoFactorDisable)
+			r.Post("/api-keys", groshi.Handler.APIKeysCreate)
+			r.Get("/api-keys", groshi.Handler.APIKeysGet)
+			r.Delete("/api-keys/{uuid}", groshi.Handler.APIKeysDelete)
+			r.Post("/oidc", groshi.Handler.UserOIDCLink)
+			r.Post("/invites", groshi.Handler.InvitesCreate)
+			r.Get("/invites", groshi.Handler.InvitesGet)
+			r.Delete("/invites/{uuid}", groshi.Handler.InvitesDelete)
 		})
 	})
 
-	// protected routes:
+	// `/admin` routes, which are available only for admins and are not available for API keys:
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(timeout)
+		r.Use(jwtMiddleware)
+		r.Use(serviceMiddleware.RequireFullAccess)
+		r.Use(serviceMiddleware.RequireRole(database.RoleAdmin))
+		r.Get("/users", groshi.Handler.AdminUsersGet)
+		r.Post("/users/{uuid}/disable", groshi.Handler.AdminUsersDisable)
+		r.Post("/users/{uuid}/enable", groshi.Handler.AdminUsersEnable)
+		r.Post("/users/{uuid}/password-reset", groshi.Handler.AdminUsersPasswordReset)
+		r.Put("/users/{uuid}/permissions", groshi.Handler.AdminUsersPermissionsUpdate)
+		r.Get("/jobs", groshi.Handler.AdminJobsGet)
+	})
+
+	// protected routes, API keys are allowed to access them if they have the required scope:
 	r.Group(func(r chi.Router) {
+		r.Use(timeout)
 		r.Use(jwtMiddleware)
+		scope := serviceMiddleware.RequireScope
+
+		r.With(serviceMiddleware.RequireFullAccess).Post("/auth/logout", groshi.Handler.AuthLogout)
+
 		r.Route("/categories", func(r chi.Router) {
-			r.Post("/", groshi.Handler.CategoriesCreate)
-			r.Get("/", groshi.Handler.CategoriesGet)
-			r.Put("/{uuid}", groshi.Handler.CategoriesUpdate)
-			r.Delete("/{uuid}", groshi.Handler.CategoriesDelete)
+			r.With(scope(auth.ScopeCategoriesWrite)).Post("/", groshi.Handler.CategoriesCreate)
+			r.With(scope(auth.ScopeCategoriesRead)).Get("/", groshi.Handler.CategoriesGet)
+			r.With(scope(auth.ScopeCategoriesWrite)).Put("/{uuid}", groshi.Handler.CategoriesUpdate)
+			r.With(scope(auth.ScopeCategoriesWrite)).Delete("/{uuid}", groshi.Handler.CategoriesDelete)
+		})
+
+		r.Route("/tags", func(r chi.Router) {
+			r.With(scope(auth.ScopeTagsWrite)).Post("/", groshi.Handler.TagsCreate)
+			r.With(scope(auth.ScopeTagsRead)).Get("/", groshi.Handler.TagsGet)
+			r.With(scope(auth.ScopeTagsWrite)).Put("/{uuid}", groshi.Handler.TagsUpdate)
+			r.With(scope(auth.ScopeTagsWrite)).Delete("/{uuid}", groshi.Handler.TagsDelete)
+		})
+
+		r.Route("/payees", func(r chi.Router) {
+			r.With(scope(auth.ScopePayeesRead)).Get("/", groshi.Handler.PayeesGet)
+		})
+
+		r.Route("/rules", func(r chi.Router) {
+			r.With(scope(auth.ScopeRulesWrite)).Post("/", groshi.Handler.RulesCreate)
+			r.With(scope(auth.ScopeRulesRead)).Get("/", groshi.Handler.RulesGet)
+			r.With(scope(auth.ScopeRulesWrite), scope(auth.ScopeTransactionsWrite)).Post("/apply", groshi.Handler.RulesApply)
+			r.With(scope(auth.ScopeRulesWrite)).Put("/{uuid}", groshi.Handler.RulesUpdate)
+			r.With(scope(auth.ScopeRulesWrite)).Delete("/{uuid}", groshi.Handler.RulesDelete)
 		})
 
 		r.Route("/transactions", func(r chi.Router) {
-			r.Post("/", groshi.Handler.TransactionsCreate)
-			r.Get("/{uuid}", groshi.Handler.TransactionsGetOne)
-			r.Get("/", groshi.Handler.TransactionsGet)
+			r.With(scope(auth.ScopeTransactionsWrite)).Post("/", groshi.Handler.TransactionsCreate)
+			r.With(scope(auth.ScopeTransactionsRead)).Get("/{uuid}", groshi.Handler.TransactionsGetOne)
+			r.With(scope(auth.ScopeTransactionsRead)).Get("/", groshi.Handler.TransactionsGet)
+		})
+
+		r.Route("/import", func(r chi.Router) {
+			r.With(scope(auth.ScopeTransactionsWrite)).Post("/csv", groshi.Handler.ImportCSV)
+			r.With(scope(auth.ScopeTransactionsWrite)).Post("/statement", groshi.Handler.ImportStatement)
+			r.With(scope(auth.ScopeTransactionsRead)).Get("/reports", groshi.Handler.ImportReportsGet)
+			r.With(scope(auth.ScopeTransactionsRead)).Get("/reports/{uuid}", groshi.Handler.ImportReportsGetOne)
+		})
+
+		r.Route("/ledgers", func(r chi.Router) {
+			r.With(scope(auth.ScopeLedgersRead)).Get("/", groshi.Handler.LedgersGet)
+			r.With(scope(auth.ScopeLedgersRead)).Get("/{uuid}/members", groshi.Handler.LedgerMembersGet)
+			r.With(scope(auth.ScopeLedgersRead)).Get("/{uuid}/balances", groshi.Handler.LedgerBalancesGet)
+			r.With(scope(auth.ScopeLedgersRead)).Get("/{uuid}/settle-up", groshi.Handler.LedgerSettleUpGet)
+			r.With(scope(auth.ScopeTransactionsWrite)).Post("/{uuid}/settlements", groshi.Handler.LedgerSettlementsCreate)
+
+			// sharing of ledgers is managed only by users themselves:
+			r.Group(func(r chi.Router) {
+				r.Use(serviceMiddleware.RequireFullAccess)
+				r.Post("/", groshi.Handler.LedgersCreate)
+				r.Put("/{uuid}", groshi.Handler.LedgersUpdate)
+				r.Post("/{uuid}/members", groshi.Handler.LedgerMembersAdd)
+				r.Put("/{uuid}/members/{username}", groshi.Handler.LedgerMembersUpdate)
+				r.Delete("/{uuid}/members/{username}", groshi.Handler.LedgerMembersDelete)
+			})
 		})
 
 		r.Route("/stats", func(r chi.Router) {
+			r.Use(scope(auth.ScopeStatsRead))
 			r.Get("/total", groshi.Handler.StatsTotal)
-
+			r.Get("/tags", groshi.Handler.StatsTags)
+			r.Get("/categories", groshi.Handler.StatsCategories)
+			r.Get("/payees", groshi.Handler.StatsPayees)
 		})
 	})
 
 	return r
 }
 
-// startJob starts repeating job which will be run in a goroutine.
-//func startJob(job func(args ...any), args []any, interval time.Duration) {
-//	go func() {
-//		job(args...)
-//		for range time.Tick(interval) {
-//			job(args...)
-//		}
-//	}()
-//}
-
-func main() {
-	// get options provided using CLI and environmental variables:
-	options := getOptions()
-
-	infoLog.Printf("starting groshi")
-
-	// initialize postgres:
+// connectDatabase connects to the database using the provided options.
+// Terminates program with code 1 if the database is not reachable.
+func connectDatabase(options *Options) *database.DefaultDatabase {
 	db := database.New(database.Credentials{
 		Host:     options.Postgres.Host,
 		Port:     options.Postgres.Port,
@@ -235,21 +423,143 @@ func main() {
 	if err := db.TestConnection(); err != nil {
 		fatalLog.Fatalf("could not connect to the database: %s", err)
 	}
+	return db
+}
+
+// newPasswordAuthenticator creates a password authenticator which uses the configured algorithm.
+// Terminates program with code 1 if Argon2id parameters are invalid.
+func newPasswordAuthenticator(options *Options) auth.PasswordAuthenticator {
+	if options.Service.PasswordHashing == "bcrypt" {
+		return auth.NewPasswordAuthenticator(options.Service.BcryptCost)
+	}
+	params := auth.Argon2idParams{
+		Memory:      options.Service.Argon2Memory,
+		Time:        options.Service.Argon2Time,
+		Parallelism: options.Service.Argon2Parallelism,
+	}
+	if err := params.Validate(); err != nil {
+		fatalLog.Fatalf("invalid Argon2id parameters: %s", err)
+	}
+	return auth.NewArgon2idPasswordAuthenticator(params)
+}
+
+// newLoginGuard creates a login guard which stores counters of failed login attempts in the configured store.
+func newLoginGuard(options *Options, db *database.DefaultDatabase) *auth.LoginGuard {
+	var store auth.LoginFailureStore = auth.NewMemoryLoginFailureStore()
+	if options.Service.LoginGuardStore == "database" {
+		store = db
+	}
+
+	config := auth.DefaultLoginGuardConfig()
+	config.UserLockoutThreshold = options.Service.LoginLockoutThreshold
+	config.IPLockoutThreshold = options.Service.LoginIPLockoutThreshold
+	config.LockoutDuration = options.Service.LoginLockoutDuration
+	return auth.NewLoginGuard(store, config)
+}
+
+// readJWTKey reads a PEM-encoded JWT key from the file.
+func readJWTKey(path string) (*auth.JWTKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := auth.ParseJWTKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// newJWTAuthenticator creates a JWT authenticator which signs tokens with the private key if it is provided
+// and with the secret key otherwise.
+func newJWTAuthenticator(options *Options) (*auth.DefaultJWTAuthenticator, error) {
+	if options.Service.JWTPrivateKeyFile == "" {
+		return auth.NewJWTAuthenticator(options.Service.JWTSecretKey, options.Service.JWTTimeToLive), nil
+	}
+
+	signingKey, err := readJWTKey(options.Service.JWTPrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	verificationKeys := make([]*auth.JWTKey, 0, len(options.Service.JWTVerificationKeyFiles))
+	for _, path := range options.Service.JWTVerificationKeyFiles {
+		key, err := readJWTKey(path)
+		if err != nil {
+			return nil, err
+		}
+		verificationKeys = append(verificationKeys, key)
+	}
+	return auth.NewAsymmetricJWTAuthenticator(signingKey, verificationKeys, options.Service.JWTTimeToLive)
+}
+
+// oidcRequestTimeout is the timeout of requests to the OpenID Connect identity provider.
+const oidcRequestTimeout = 10 * time.Second
+
+// newOIDCProvider creates the OpenID Connect identity provider using the provided options.
+// Returns nil if OIDC login is disabled.
+func newOIDCProvider(options *Options) (*auth.OIDCProvider, error) {
+	if options.OIDC.Issuer == "" {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oidcRequestTimeout)
+	defer cancel()
+	return auth.NewOIDCProvider(ctx, auth.OIDCConfig{
+		Issuer:       options.OIDC.Issuer,
+		ClientID:     options.OIDC.ClientID,
+		ClientSecret: options.OIDC.ClientSecret,
+		RedirectURL:  options.OIDC.RedirectURL,
+		Scopes:       options.OIDC.Scopes,
+		CreateUsers:  options.OIDC.CreateUsers,
+	}, &http.Client{Timeout: oidcRequestTimeout})
+}
+
+// serve starts groshi service.
+func serve(options *Options) {
+	infoLog.Printf("starting groshi")
+
+	// initialize postgres:
+	db := connectDatabase(options)
 	if err := db.Init(context.Background()); err != nil {
 		fatalLog.Printf("could not initialize database: %s", err)
 	}
 
+	// load JWT keys:
+	jwtAuth, err := newJWTAuthenticator(options)
+	if err != nil {
+		fatalLog.Fatalf("could not load JWT keys: %s", err)
+	}
+
+	// discover the OpenID Connect identity provider:
+	oidc, err := newOIDCProvider(options)
+	if err != nil {
+		fatalLog.Fatalf("could not initialize OpenID Connect identity provider: %s", err)
+	}
+
+	// parse addresses of trusted proxies:
+	trustedProxies, err := serviceMiddleware.ParseTrustedProxies(options.Service.TrustedProxies)
+	if err != nil {
+		fatalLog.Fatalf("could not parse trusted proxies: %s", err)
+	}
+
 	// create a groshi service:
 	groshi := service.New(
 		db,
-		auth.NewJWTAuthenticator(options.Service.JWTSecretKey, options.Service.JWTTimeToLive),
-		auth.NewPasswordAuthenticator(options.Service.BcryptCost),
+		jwtAuth,
+		newPasswordAuthenticator(options),
+		newLoginGuard(options, db),
+		oidc,
+		options.Service.RegistrationMode,
+		options.Service.RefreshTokenTimeToLive,
 		log.New(os.Stderr, "[internal server error]: ", loggingBaseFlags|log.Llongfile),
 		options.Development.Swagger,
 	)
 
+	// start jobs:
+	groshi.StartJobs(context.Background())
+
 	// create an HTTP router:
-	router := newMux(groshi)
+	router := newMux(groshi, db, trustedProxies)
 
 	// start listening:
 	addr := fmt.Sprintf("%s:%d", options.General.Host, options.General.Port)
@@ -258,3 +568,153 @@ func main() {
 		fatalLog.Fatal(err)
 	}
 }
+
+// backup writes a backup of the database to the output file or to the standard output.
+// Nothing is logged to the standard output, so that it can be piped.
+func backup(options *Options) {
+	db := connectDatabase(options)
+
+	output := os.Stdout
+	if options.Backup.Output != "" {
+		file, err := os.Create(options.Backup.Output)
+		if err != nil {
+			fatalLog.Fatalf("could not create backup file: %s", err)
+		}
+		output = file
+	}
+
+	if err := db.Backup(context.Background(), output); err != nil {
+		_ = output.Close()
+		fatalLog.Fatalf("could not make a backup: %s", err)
+	}
+	if err := output.Close(); err != nil {
+		fatalLog.Fatalf("could not write backup file: %s", err)
+	}
+}
+
+// restore loads a backup from the input file or from the standard input into the database.
+func restore(options *Options) {
+	db := connectDatabase(options)
+	if err := db.Init(context.Background()); err != nil {
+		fatalLog.Fatalf("could not initialize database: %s", err)
+	}
+
+	input := os.Stdin
+	if options.Restore.Args.Input != "" {
+		file, err := os.Open(options.Restore.Args.Input)
+		if err != nil {
+			fatalLog.Fatalf("could not open backup file: %s", err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	if err := db.Restore(context.Background(), input, options.Restore.Clean); err != nil {
+		fatalLog.Fatalf("could not restore the backup: %s", err)
+	}
+	infoLog.Printf("backup was restored")
+}
+
+// resetPassword sets a new random password of the user, invalidates all its tokens and prints the password.
+func resetPassword(options *Options) {
+	db := connectDatabase(options)
+	ctx := context.Background()
+
+	user := &database.User{}
+	if err := db.SelectUserByUsername(ctx, options.ResetPassword.Args.Username, user); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			fatalLog.Fatalf("user %s not found", options.ResetPassword.Args.Username)
+		}
+		fatalLog.Fatalf("could not fetch the user: %s", err)
+	}
+
+	password, err := auth.GeneratePassword()
+	if err != nil {
+		fatalLog.Fatalf("could not generate a password: %s", err)
+	}
+	passwordHash, err := newPasswordAuthenticator(options).HashPassword(password)
+	if err != nil {
+		fatalLog.Fatalf("could not hash the password: %s", err)
+	}
+	if err := db.ChangeUserPassword(ctx, user.ID, passwordHash); err != nil {
+		fatalLog.Fatalf("could not change the password: %s", err)
+	}
+
+	fmt.Println(password)
+}
+
+// unlock resets failed login attempts of the user. Counters stored in memory belong to the server process,
+// so only counters stored in the database can be reset.
+func unlock(options *Options) {
+	if options.Service.LoginGuardStore != "database" {
+		fatalLog.Fatalf("counters of failed login attempts are stored in memory of the server, restart it to reset them")
+	}
+
+	db := connectDatabase(options)
+	if err := newLoginGuard(options, db).Unlock(context.Background(), options.Unlock.Args.Username); err != nil {
+		fatalLog.Fatalf("could not unlock the user: %s", err)
+	}
+	infoLog.Printf("user %s was unlocked", options.Unlock.Args.Username)
+}
+
+// adminCreate creates a new admin with a random password and prints the password.
+// If the user already exists, it is made an admin and its password is not changed.
+func adminCreate(options *Options) {
+	db := connectDatabase(options)
+	ctx := context.Background()
+	if err := db.Init(ctx); err != nil {
+		fatalLog.Fatalf("could not initialize database: %s", err)
+	}
+	username := options.Admin.Create.Args.Username
+
+	user := &database.User{}
+	err := db.SelectUserByUsername(ctx, username, user)
+	switch {
+	case err == nil:
+		if err := db.UpdateUserRole(ctx, user.ID, database.RoleAdmin); err != nil {
+			fatalLog.Fatalf("could not update role of the user: %s", err)
+		}
+		infoLog.Printf("user %s is an admin now", username)
+		return
+	case !errors.Is(err, sql.ErrNoRows):
+		fatalLog.Fatalf("could not fetch the user: %s", err)
+	}
+
+	password, err := auth.GeneratePassword()
+	if err != nil {
+		fatalLog.Fatalf("could not generate a password: %s", err)
+	}
+	passwordHash, err := newPasswordAuthenticator(options).HashPassword(password)
+	if err != nil {
+		fatalLog.Fatalf("could not hash the password: %s", err)
+	}
+	if err := db.CreateUser(ctx, &database.User{
+		Username: username,
+		Password: passwordHash,
+		Role:     database.RoleAdmin,
+	}); err != nil {
+		fatalLog.Fatalf("could not create the admin: %s", err)
+	}
+
+	fmt.Println(password)
+}
+
+func main() {
+	// get options provided using CLI and environmental variables:
+	options, command := getOptions()
+
+	switch command {
+	case commandBackup:
+		backup(options)
+	case commandRestore:
+		restore(options)
+	case commandResetPassword:
+		resetPassword(options)
+	case commandUnlock:
+		unlock(options)
+	case commandAdminCreate:
+		adminCreate(options)
+	default:
+		serve(options)
+	}
+}